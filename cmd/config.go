@@ -0,0 +1,204 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+	"gopkg.in/yaml.v3"
+)
+
+// parseOptions parses the command line flags. With --config the settings of that YAML or INI file
+// are applied as defaults first, so flags override the config file and the config file overrides
+// the built-in defaults. Unknown settings in the config file are an error.
+func parseOptions(args []string) (Options, *flags.Parser, error) {
+	options := Options{}
+	goflags := flags.NewParser(&options, flags.Default)
+	goflags.LongDescription = exitCodesHelp
+
+	if _, err := goflags.ParseArgs(args); err != nil || options.Config == "" {
+		return options, goflags, err
+	}
+
+	configFile := options.Config
+	options = Options{}
+	goflags = flags.NewParser(&options, flags.Default)
+	goflags.LongDescription = exitCodesHelp
+
+	if err := readConfig(goflags, configFile); err != nil {
+		return options, goflags, fmt.Errorf("error reading config: %v", err)
+	}
+
+	_, err := goflags.ParseArgs(args)
+	return options, goflags, err
+}
+
+// readConfig applies the settings of configFile to the options of goflags, the extension of
+// configFile tells its format: YAML for .yaml and .yml, INI for .ini.
+func readConfig(goflags *flags.Parser, configFile string) error {
+	switch extension := strings.ToLower(filepath.Ext(configFile)); extension {
+	case ".yaml", ".yml":
+	case ".ini":
+		return flags.NewIniParser(goflags).ParseFile(configFile)
+	default:
+		return fmt.Errorf("%s: unsupported config format %q, expected .yaml, .yml or .ini", configFile, extension)
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	ini, err := yamlToINI(goflags, data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", configFile, err)
+	}
+	return flags.NewIniParser(goflags).Parse(strings.NewReader(ini))
+}
+
+// yamlToINI translates a YAML config, a mapping of long flag names to a value or, for repeatable
+// flags, a list of values, into the INI settings the flags parser reads.
+func yamlToINI(goflags *flags.Parser, data []byte) (string, error) {
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ini strings.Builder
+	for _, name := range names {
+		option := goflags.FindOptionByLongName(name)
+		if option == nil || option.Field().Tag.Get("no-ini") != "" {
+			return "", fmt.Errorf("unknown setting %q", name)
+		}
+
+		var values []interface{}
+		switch value := settings[name].(type) {
+		case nil:
+			continue
+		case []interface{}:
+			if reflect.ValueOf(option.Value()).Kind() != reflect.Slice {
+				return "", fmt.Errorf("setting %q takes a single value", name)
+			}
+			values = value
+		default:
+			values = []interface{}{value}
+		}
+
+		for _, value := range values {
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				return "", fmt.Errorf("setting %q has a nested value", name)
+			}
+			fmt.Fprintf(&ini, "%s = %s\n", name, strconv.Quote(fmt.Sprint(value)))
+		}
+	}
+	return ini.String(), nil
+}
+
+// printConfig writes the effective configuration as YAML config file, with credentials in URLs
+// redacted: passwords, query values and the paths of webhook URLs. Unset settings are written commented out.
+func printConfig(w io.Writer, goflags *flags.Parser) {
+	for _, group := range goflags.Groups() {
+		for _, option := range group.Options() {
+			if option.LongName == "" || option.Field().Tag.Get("no-ini") != "" {
+				continue
+			}
+
+			var values []string
+			value := reflect.ValueOf(option.Value())
+			switch {
+			case value.Kind() == reflect.Func:
+				continue
+			case value.Kind() == reflect.Ptr:
+				if !value.IsNil() {
+					values = append(values, fmt.Sprint(value.Elem().Interface()))
+				}
+			case value.Kind() == reflect.Slice:
+				for i := 0; i < value.Len(); i++ {
+					values = append(values, fmt.Sprint(value.Index(i).Interface()))
+				}
+			case !value.IsZero() || value.Kind() != reflect.String:
+				values = append(values, fmt.Sprint(value.Interface()))
+			}
+
+			switch {
+			case len(values) == 0:
+				fmt.Fprintf(w, "# %s:\n", option.LongName)
+			case value.Kind() == reflect.Slice:
+				fmt.Fprintf(w, "%s:\n", option.LongName)
+				for _, v := range values {
+					fmt.Fprintf(w, "  - %s\n", yamlScalar(redactURL(v, secretPaths[option.LongName])))
+				}
+			default:
+				fmt.Fprintf(w, "%s: %s\n", option.LongName, yamlScalar(redactURL(values[0], secretPaths[option.LongName])))
+			}
+		}
+	}
+}
+
+// yamlScalar returns value as YAML scalar, quoted when YAML would read it differently.
+func yamlScalar(value string) string {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err == nil && v != nil && fmt.Sprint(v) == value {
+		return value
+	}
+	quoted, err := yaml.Marshal(value)
+	if err != nil {
+		return strconv.Quote(value)
+	}
+	return strings.TrimSpace(string(quoted))
+}
+
+// secretPaths are the settings with URLs that carry their secret in the path, like the token of
+// a Slack or Teams incoming webhook.
+var secretPaths = map[string]bool{
+	"elasticsearch": true,
+	"notify":        true,
+	"webhook":       true,
+}
+
+// redactURL replaces the password and the query values in URLs, and with redactPath everything
+// after the host. Values that are no URL are returned unchanged.
+func redactURL(rawURL string, redactPath bool) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return rawURL
+	}
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), "REDACTED")
+		}
+	}
+	if redactPath && strings.Trim(u.EscapedPath(), "/") != "" {
+		u.Path, u.RawPath = "/REDACTED", ""
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			query[name] = []string{"REDACTED"}
+		}
+		u.RawQuery = query.Encode()
+	}
+	if u.Fragment != "" {
+		u.Fragment, u.RawFragment = "REDACTED", ""
+	}
+	return u.String()
+}
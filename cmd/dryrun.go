@@ -0,0 +1,103 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"nuclei-parse-enrich/pkg/parser"
+	"nuclei-parse-enrich/pkg/version"
+)
+
+// dryRunPlan describes what a run would do, without doing any lookups.
+type dryRunPlan struct {
+	Records    int `json:"records"`
+	EmptyIPs   int `json:"empty_ips"`
+	UniqueIPs  int `json:"unique_ips"`
+	SkippedIPs int `json:"skipped_private_ips"`
+	InvalidIPs int `json:"invalid_ips"`
+	// InvalidExamples lists the first few values that are not an IP address
+	InvalidExamples []string `json:"invalid_examples,omitempty"`
+	// ResolveHosts is the number of host names --resolve-hosts would look up
+	ResolveHosts int          `json:"hosts_to_resolve,omitempty"`
+	EnrichIPs    int          `json:"ips_to_enrich"`
+	Workers      int          `json:"workers"`
+	OutputFiles  []string     `json:"output_files"`
+	Version      version.Info `json:"version"`
+}
+
+// newDryRunPlan returns the plan for the parsed scan, in which hosts host names are resolved.
+func newDryRunPlan(scanParser *parser.Parser, hosts, workers int, outputFiles []string) dryRunPlan {
+	ipAddrs, stats := scanParser.UniqueIPs()
+
+	return dryRunPlan{
+		Records:         stats.Records,
+		EmptyIPs:        stats.Empty,
+		UniqueIPs:       stats.Unique,
+		SkippedIPs:      stats.Bogon,
+		InvalidIPs:      stats.Invalid,
+		InvalidExamples: stats.InvalidIPs,
+		ResolveHosts:    hosts,
+		// private and reserved IPs are written without lookups
+		EnrichIPs:   len(ipAddrs) - stats.Bogon,
+		Workers:     workers,
+		OutputFiles: outputFiles,
+		Version:     version.Get(),
+	}
+}
+
+// countHostnames returns the number of distinct host names of the scan records without IP
+// address, the ones --resolve-hosts looks up, see parser.RecordHostname.
+func countHostnames(scanParser *parser.Parser) int {
+	hostnames := make(map[string]struct{})
+	for _, record := range scanParser.ScanRecords {
+		if hostname := parser.RecordHostname(record); hostname != "" {
+			hostnames[hostname] = struct{}{}
+		}
+	}
+	return len(hostnames)
+}
+
+// writeDryRunPlan writes plan as JSON to planFile, when set.
+func writeDryRunPlan(plan dryRunPlan, planFile string) error {
+	if planFile == "" {
+		return nil
+	}
+
+	file, err := os.Create(planFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(plan)
+}
+
+func printDryRunPlan(w io.Writer, plan dryRunPlan) {
+	fmt.Fprintln(w, "Dry run, no lookups were done and no output was written")
+	fmt.Fprintf(w, "  records parsed:           %d\n", plan.Records)
+	fmt.Fprintf(w, "  records without IP:       %d\n", plan.EmptyIPs)
+	fmt.Fprintf(w, "  unique IPs:               %d\n", plan.UniqueIPs)
+	fmt.Fprintf(w, "  private/reserved skipped: %d\n", plan.SkippedIPs)
+	fmt.Fprintf(w, "  invalid IPs:              %d\n", plan.InvalidIPs)
+	if len(plan.InvalidExamples) > 0 {
+		fmt.Fprintf(w, "  invalid IP examples:      %q\n", plan.InvalidExamples)
+	}
+	if plan.ResolveHosts > 0 {
+		fmt.Fprintf(w, "  would resolve:            %d hosts\n", plan.ResolveHosts)
+	}
+	fmt.Fprintf(w, "  IPs to enrich:            %d (with %d workers)\n", plan.EnrichIPs, plan.Workers)
+	for _, outputFile := range plan.OutputFiles {
+		fmt.Fprintf(w, "  would write:              %s\n", outputFile)
+	}
+}
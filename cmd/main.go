@@ -7,96 +7,835 @@ package main
  */
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
-	"nuclei-parse-enrich/pkg/parser"
+	"nuclei-parse-enrich/pkg/annotate"
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/bgpview"
+	"nuclei-parse-enrich/pkg/cache"
+	"nuclei-parse-enrich/pkg/cloud"
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/credentials"
+	"nuclei-parse-enrich/pkg/csirt"
+	"nuclei-parse-enrich/pkg/cymru"
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/geofeed"
+	"nuclei-parse-enrich/pkg/irr"
+	"nuclei-parse-enrich/pkg/output"
+	"nuclei-parse-enrich/pkg/peeringdb"
+	"nuclei-parse-enrich/pkg/pipeline"
+	"nuclei-parse-enrich/pkg/radar"
+	"nuclei-parse-enrich/pkg/rdap"
+	"nuclei-parse-enrich/pkg/rdns"
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/rir"
+	"nuclei-parse-enrich/pkg/scope"
+	"nuclei-parse-enrich/pkg/tlscert"
+	"nuclei-parse-enrich/pkg/types"
+	"nuclei-parse-enrich/pkg/version"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/sirupsen/logrus"
 )
 
 type Options struct {
-	Input  string `short:"i" long:"input" description:"A file with the nuclei scan output" required:"false"`
-	IPfile string `short:"f" long:"file" description:"A simple IP file with one IP address per line" required:"false"`
-	Output string `short:"o" long:"output" description:"A file to write the enriched output to (default output.json)" required:"false"`
+	Input                  string        `short:"i" long:"input" description:"A file with the nuclei scan output" required:"false"`
+	IPfile                 string        `short:"f" long:"file" description:"A simple IP file with one IP address per line" required:"false"`
+	Output                 []string      `short:"o" long:"output" description:"A file to write the enriched output to, in the format of its extension (json, jsonl, csv, html, geojson or logfmt) or as format:path, where stix:path writes a STIX 2.1 bundle, misp:path a MISP event, prefixes:path one CSV row per prefix, protobuf:path (or .binpb) length delimited protobuf messages and failed:path lists the IPs that failed enrichment (can be repeated, default output.json)" required:"false"`
+	Annotate               []string      `long:"annotate" description:"Tag IPs covered by an annotation file with a label, as label=path (can be repeated)" required:"false"`
+	Workers                *int          `long:"workers" description:"The number of IPs to enrich concurrently (default: number of CPUs, at most 16)" required:"false"`
+	AdaptiveWorkers        int           `long:"adaptive-workers" description:"Start with this many concurrent IPs and ramp up to --workers while RipeSTAT doesn't rate limit, halving on rate limiting" required:"false"`
+	JobBuffer              int           `long:"job-buffer" description:"The number of IPs queued for the workers (default: none, handed over directly)" required:"false"`
+	ResultBuffer           int           `long:"result-buffer" description:"The number of results buffered for collection (default: one per worker)" required:"false"`
+	UnknownPlaceholder     bool          `long:"unknown-placeholder" description:"Write \"unknown\" in the fields that could not be determined instead of leaving them out, like earlier versions did" required:"false"`
+	PrefixAbuse            bool          `long:"abuse-per-prefix" description:"Look up the RipeSTAT abuse contacts once per announced prefix instead of for every IP" required:"false"`
+	AbuseTo                bool          `long:"abuse-to" description:"Also write the abuse contacts of every IP as an RFC 5322 address list, ready to paste into a To: header" required:"false"`
+	RegistryHandles        bool          `long:"registry-handles" description:"Record the abuse-c handle and organisation id of every IP from its registry objects" required:"false"`
+	CoveringPrefix         bool          `long:"covering-prefix" description:"Record the least specific announced prefix covering the prefix of every IP, and its origin ASN" required:"false"`
+	Registration           bool          `long:"registration" description:"Record the registration date and allocation status (ALLOCATED, ASSIGNED or LEGACY) of the address block of every IP" required:"false"`
+	RoleContactsOnly       bool          `long:"role-contacts-only" description:"Drop abuse contacts that look like personal addresses" required:"false"`
+	RoleLocalParts         []string      `long:"role-local-part" description:"A local-part of role mailboxes, like abuse or noc (can be repeated, replaces the default list)" required:"false"`
+	Geofeed                []string      `long:"geofeed" description:"An RFC 8805 geofeed URL or file overriding the RipeSTAT geolocation (can be repeated)" required:"false"`
+	ASNCategory            bool          `long:"asn-category" description:"Classify the AS of every IP as transit, eyeball, content or enterprise network" required:"false"`
+	ASNCategories          string        `long:"asn-categories" description:"A PeeringDB net dump or a TSV file of AS numbers and categories, extending the embedded dataset of --asn-category" required:"false"`
+	CERTRouting            string        `long:"cert-routing" description:"A TSV file of country codes and the abuse address of their national CERT, added to the abuse contacts of the IPs located in the country" required:"false"`
+	RejectPrivateASN       bool          `long:"reject-private-asn" description:"Treat private AS numbers (64512-65534 and 4200000000-4294967294) as unknown" required:"false"`
+	VerifyASN              bool          `long:"verify-asn" description:"Cross-check the RipeSTAT ASN with the Team Cymru whois service and flag mismatches" required:"false"`
+	Timeout                time.Duration `long:"timeout" description:"Stop enriching after this duration and write the partial results, e.g. 30m (exits with code 4)" required:"false"`
+	SourceApp              string        `long:"sourceapp" description:"The sourceapp identifying the requests to RipeSTAT, e.g. your company name or AS number and an application name (default: AS50559-DIVD_NL)" required:"false"`
+	RipeStatURL            string        `long:"ripestat-url" description:"The base URL of the RipeSTAT data API, e.g. a mirror or proxy (default: https://stat.ripe.net/data/)" required:"false"`
+	RipeStatHints          bool          `long:"ripestat-hints" description:"Record the time RipeSTAT queried the data of every IP, and keep it in the --cache for as long as RipeSTAT allows instead of --cache-ttl" required:"false"`
+	RipeStatTimeout        time.Duration `long:"ripestat-timeout" description:"The timeout of a single RipeSTAT request, e.g. 10s" required:"false"`
+	WhoisServer            []string      `long:"whois-server" description:"A whois server to retry with when a whois lookup is rate limited or finds no match, e.g. whois.ripe.net (can be repeated, tried in order)" required:"false"`
+	WhoisBreakerThreshold  int           `long:"whois-breaker-threshold" description:"Skip whois for --whois-breaker-cooldown after this many whois lookups in a row timed out, 0 never skips" default:"5" required:"false"`
+	WhoisBreakerCooldown   time.Duration `long:"whois-breaker-cooldown" description:"How long whois is skipped once --whois-breaker-threshold lookups in a row timed out" default:"5m" required:"false"`
+	WhoisTimeout           time.Duration `long:"whois-timeout" description:"The timeout of a single whois lookup, e.g. 10s" required:"false"`
+	IPTimeout              time.Duration `long:"ip-timeout" description:"The timeout of all lookups of a single IP, e.g. 30s" required:"false"`
+	MaxResponseSize        int           `long:"max-response-size" description:"The maximum size of a single RipeSTAT or whois response in KiB, larger responses fail the lookup" default:"4096" required:"false"`
+	ReverseDNS             bool          `long:"reverse-dns" description:"Resolve the PTR record of every IP and derive a hosting provider hint from it" required:"false"`
+	ProviderSuffixes       []string      `long:"provider-suffix" description:"A PTR suffix hinting at a hosting provider, as suffix=provider (can be repeated)" required:"false"`
+	LogLevel               string        `long:"log-level" description:"The log level: debug, info, warn or error" default:"info" required:"false"`
+	LogFormat              string        `long:"log-format" description:"The log format: text or json" default:"text" required:"false"`
+	LogFile                string        `long:"log-file" description:"A file to append the logs to instead of stderr" required:"false"`
+	NoProgress             bool          `long:"no-progress" description:"Do not show the enrichment progress" required:"false"`
+	Sort                   string        `long:"sort" description:"Write the records as a JSON array ordered by a comma separated list of keys: severity, abuse-score, country, asn, ip" required:"false"`
+	DryRun                 bool          `long:"dry-run" description:"Parse and validate the input and report what would be enriched, without doing any lookups or writing output" required:"false"`
+	Plan                   string        `long:"plan" description:"With --dry-run, also write the report as JSON to this file" required:"false"`
+	Cache                  string        `long:"cache" description:"A file caching enrichment results between runs, e.g. cache.db" required:"false"`
+	CacheTTL               time.Duration `long:"cache-ttl" description:"How long cached results stay fresh" default:"168h" required:"false"`
+	CacheReadOnly          bool          `long:"cache-readonly" description:"Use the cache without writing to it, e.g. for parallel jobs sharing one cache" required:"false"`
+	Elasticsearch          string        `long:"elasticsearch" description:"Also index the enrichment results into this Elasticsearch or OpenSearch URL, e.g. http://localhost:9200" required:"false"`
+	ElasticsearchIndex     string        `long:"elasticsearch-index" description:"The index to write the enrichment results to" default:"nuclei-enrichment" required:"false"`
+	Config                 string        `long:"config" description:"A config file with settings, YAML (.yaml or .yml) or INI (.ini), flags given on the command line take precedence" no-ini:"true" required:"false"`
+	PrintConfig            bool          `long:"print-config" description:"Print the effective configuration, with credentials redacted, and exit" no-ini:"true" required:"false"`
+	SlowQueryThreshold     time.Duration `long:"slow-query-threshold" description:"Log a warning for every RipeSTAT call taking longer than this, 0 disables the warning" default:"5s" required:"false"`
+	CredentialEnv          []string      `long:"credential-env" description:"Read the API key of a source from another environment variable, as source=ENV_VAR (can be repeated)" required:"false"`
+	NoWhois                bool          `long:"no-whois" description:"Never fall back to whois for abuse contacts, e.g. when outbound whois is blocked" required:"false"`
+	RIRs                   []string      `long:"rir" description:"Only enrich the IPs delegated to this RIR: AFRINIC, APNIC, ARIN, LACNIC or RIPE (can be repeated)" required:"false"`
+	WhoisRIRs              []string      `long:"whois-rir" description:"Only fall back to whois for the IPs delegated to this RIR: AFRINIC, APNIC, ARIN, LACNIC or RIPE (can be repeated)" required:"false"`
+	Version                bool          `long:"version" description:"Print the version and exit" no-ini:"true" required:"false"`
+	GeoJSON                string        `long:"geojson" description:"Also write the findings as a GeoJSON FeatureCollection to this file" required:"false"`
+	FailedReasons          bool          `long:"failed-reasons" description:"Follow every IP of a failed output with a tab and why its enrichment failed" required:"false"`
+	MISPEventInfo          string        `long:"misp-info" description:"The name (info) of the event of a misp:path output, default nuclei-parse-enrich and the date" required:"false"`
+	GeoJSONCountryFallback bool          `long:"geojson-country-fallback" description:"Place findings without coordinates at a reference point of their country instead of leaving them out of the GeoJSON" required:"false"`
+	Force                  bool          `long:"force" description:"Overwrite existing output files" required:"false"`
+	Webhook                string        `long:"webhook" description:"Also POST every enrichment result as JSON to this URL" required:"false"`
+	Notify                 string        `long:"notify" description:"Post a summary of the run to this Slack or Microsoft Teams incoming webhook URL" required:"false"`
+	NotifyFormat           string        `long:"notify-format" description:"The message format of --notify, auto picks it from the webhook host" choice:"auto" choice:"slack" choice:"teams" choice:"text" default:"auto" required:"false"`
+	Quiet                  bool          `long:"quiet" description:"Only log errors, and print a single JSON summary line to stderr on completion" required:"false"`
+	IRR                    bool          `long:"irr" description:"Fill in the prefix, ASN and holder from IRR route objects when RipeSTAT does not know them" required:"false"`
+	IRRServer              string        `long:"irr-server" description:"The IRR whois server queried with --irr" default:"whois.radb.net" required:"false"`
+	Fallback               []string      `long:"fallback" description:"A source filling in the ASN, prefix, holder and country when the RipeSTAT lookup fails: rdap, cymru or bgpview (can be repeated, tried in order)" choice:"rdap" choice:"cymru" choice:"bgpview" required:"false"`
+	PeeringDB              bool          `long:"peeringdb" description:"Record the number of internet exchanges and the facilities the AS of every IP is present at according to PeeringDB" required:"false"`
+	Checkpoint             string        `long:"checkpoint" description:"Append every enriched IP to this file, and skip the IPs in it when resuming an interrupted run" required:"false"`
+	CheckpointEvery        int           `long:"checkpoint-every" description:"Make the checkpoint durable after this many IPs" default:"100" required:"false"`
+	CheckpointInterval     time.Duration `long:"checkpoint-interval" description:"Make the checkpoint durable at least this often" default:"30s" required:"false"`
+	CheckpointRemove       bool          `long:"checkpoint-remove" description:"Remove the checkpoint when the run completes" required:"false"`
+	IncludeFile            []string      `long:"include-file" description:"Only enrich IPs covered by the IPs and CIDRs in this file (can be repeated)" required:"false"`
+	ExcludeFile            []string      `long:"exclude-file" description:"Never enrich IPs covered by the IPs and CIDRs in this file (can be repeated)" required:"false"`
+	Pprof                  string        `long:"pprof" description:"Serve net/http/pprof on this address during the run, e.g. localhost:6060" required:"false"`
+	CPUProfile             string        `long:"cpuprofile" description:"Write a CPU profile of the run to this file" required:"false"`
+	MemProfile             string        `long:"memprofile" description:"Write a heap profile at the end of the run to this file" required:"false"`
+	Trace                  string        `long:"trace" description:"Write an execution trace of the run to this file" required:"false"`
+	TLSCerts               bool          `long:"tls-certs" description:"Record issuer, subject, names and expiry of the TLS certificate of HTTPS findings" required:"false"`
+	TLSTimeout             time.Duration `long:"tls-timeout" description:"The timeout of a single TLS handshake" default:"5s" required:"false"`
+	Pipe                   bool          `long:"pipe" description:"Read nuclei JSONL from stdin and write every enriched record to stdout as soon as it is enriched" required:"false"`
+	PipeBuffer             int           `long:"pipe-buffer" description:"With --pipe, the number of records in flight (default: --workers)" required:"false"`
+	PipeRemember           int           `long:"pipe-remember" description:"With --pipe, the number of enrichments of the last IPs kept to enrich every IP once, 0 keeps all" required:"false"`
+	CloudRanges            bool          `long:"cloud-ranges" description:"Tag IPs in the published ranges of AWS and GCP, and of the providers given with --cloud-source, with their cloud provider and region" required:"false"`
+	CloudSources           []string      `long:"cloud-source" description:"The range file of a cloud provider (aws, gcp or azure), as provider=url-or-path (can be repeated)" required:"false"`
+	CloudCacheDir          string        `long:"cloud-cache-dir" description:"The directory the downloaded cloud range files are kept in (default: the user cache directory)" required:"false"`
+	CloudTTL               time.Duration `long:"cloud-ttl" description:"How long downloaded cloud range files are used before they are downloaded again" default:"24h" required:"false"`
+	AsOf                   string        `long:"as-of" description:"Also record who held every IP at this date according to the RIPE database history, as 2006-01-02 or RFC 3339" required:"false"`
+	ResolveHosts           bool          `long:"resolve-hosts" description:"Look up the IP addresses of the records with a host name instead of an IP address" required:"false"`
+	LegacyFieldNames       bool          `long:"legacy-field-names" description:"Write the enrichment fields with the names of schema version 1 (Ip, AbuseSource, ...) instead of snake_case, for one transition release" required:"false"`
+	MappedAs               string        `long:"mapped-as" description:"Enrich IPv6 addresses embedding an IPv4 address (::ffff:192.0.2.1, ::192.0.2.1) as the ipv4 address, or as ipv6" choice:"ipv4" choice:"ipv6" default:"ipv4" required:"false"`
+	Passthrough            bool          `long:"passthrough" description:"Keep every field of the nuclei records and write them with the enrichment under an \"enrichment\" key in the JSON outputs" required:"false"`
 }
 
+// Exit codes, documented in --help by exitCodesHelp.
+const (
+	exitCodeOK    = 0
+	exitCodeUsage = 1
+	exitCodeInput = 2
+	// exitCodeFailures is used when all IPs got enriched, but some lookups failed
+	exitCodeFailures = 3
+	// exitCodeTruncated is used when the run timed out or was interrupted and only part of the IPs got enriched
+	exitCodeTruncated = 4
+	exitCodeOutput    = 5
+	// exitCodePartialOutput is used when some of the outputs could not be written
+	exitCodePartialOutput = 6
+)
+
+const exitCodesHelp = `Run "serve --help" for the HTTP server mode.
+
+Exit codes:
+0  success
+1  usage or configuration error
+2  the input could not be read or parsed
+3  completed, but lookups failed for some IPs
+4  interrupted or timed out, the IPs enriched so far are written
+5  the output could not be written
+6  completed, but some of the outputs could not be written`
+
+// exitCode derives the exit code of a run from its outcome.
+func exitCode(summary pipeline.Summary, runErr *pipeline.Error) int {
+	switch {
+	case runErr == nil && len(summary.FailedOutputs) > 0:
+		return exitCodePartialOutput
+	case runErr == nil && summary.Failed > 0:
+		return exitCodeFailures
+	case runErr == nil:
+		return exitCodeOK
+	case runErr.Stage == pipeline.StageInput:
+		return exitCodeInput
+	case runErr.Stage == pipeline.StageEnrich:
+		return exitCodeTruncated
+	case runErr.Stage == pipeline.StageOutput:
+		return exitCodeOutput
+	default:
+		return exitCodeUsage
+	}
+}
+
+// maxDefaultWorkers caps the default number of workers, more workers than this mostly wait on
+// the RipeSTAT and whois rate limits anyway.
+const maxDefaultWorkers = 16
+
 func init() {
-	logrus.SetLevel(logrus.DebugLevel)
-	logrus.SetOutput(os.Stdout)
+	logrus.SetLevel(logrus.InfoLevel)
+	logrus.SetOutput(os.Stderr)
 	logrus.SetFormatter(&logrus.TextFormatter{
 		DisableColors: false,
 		FullTimestamp: true,
 	})
 }
 
-func main() {
+// configureLogging applies the logging flags to the standard logger and returns the --log-file
+// it logs to, nil when logging to stderr, for closeLogFile. Diagnostics never go to stdout, which
+// may carry piped results.
+func configureLogging(options Options) (*os.File, error) {
+	level, err := logrus.ParseLevel(options.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	logrus.SetLevel(level)
+
+	switch options.LogFormat {
+	case "text":
+	case "json":
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return nil, fmt.Errorf("unknown log format %q, expected text or json", options.LogFormat)
+	}
+
+	if options.LogFile == "" {
+		return nil, nil
+	}
+	logFile, err := os.OpenFile(options.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	logrus.SetOutput(logFile)
+	return logFile, nil
+}
+
+// closeLogFile flushes and closes the log file of configureLogging, logging to stderr again.
+func closeLogFile(logFile *os.File) {
+	if logFile == nil {
+		return
+	}
+	logrus.SetOutput(os.Stderr)
+	if err := logFile.Sync(); err != nil {
+		logrus.Errorf("Error syncing log file: %v", err)
+	}
+	if err := logFile.Close(); err != nil {
+		logrus.Errorf("Error closing log file: %v", err)
+	}
+}
 
-	options := Options{}
-	goflags := flags.NewParser(&options, flags.Default)
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	scanParser := parser.Parser{}
+// run runs the command line with args and returns the exit code, so deferred cleanup runs before
+// the process exits.
+func run(args []string) (code int) {
+	if len(args) > 0 && args[0] == "serve" {
+		return runServe(args[1:])
+	}
 
-	_, err := goflags.Parse()
+	options, goflags, err := parseOptions(args)
 	if err != nil {
 		if errFlags, ok := err.(*flags.Error); ok && errFlags.Type == flags.ErrHelp {
 			// flags automatically prints usage
-			os.Exit(0)
+			return exitCodeOK
 		}
-		logrus.Fatalf("Error parsing flags: %v", err)
+		logrus.Errorf("Error parsing flags: %v", err)
+		return exitCodeUsage
 	}
 
-	if noOutputProvided := options.Output == ""; noOutputProvided {
-		options.Output = "output.json"
+	if options.Version {
+		fmt.Println(version.Get())
+		return exitCodeOK
+	}
+
+	if options.PrintConfig {
+		printConfig(os.Stdout, goflags)
+		return exitCodeOK
+	}
+
+	logFile, err := configureLogging(options)
+	if err != nil {
+		logrus.Errorf("Error configuring logging: %v", err)
+		return exitCodeUsage
+	}
+	defer closeLogFile(logFile)
+	logrus.Debug(version.Get())
+
+	report := newRunReport()
+	if options.Quiet {
+		configureQuiet(report)
+		options.NoProgress = true
+		defer func() {
+			if err := report.write(os.Stderr, code); err != nil {
+				logrus.Errorf("Error writing summary: %v", err)
+			}
+		}()
+	}
+
+	if options.Pipe {
+		for _, conflict := range []struct {
+			flag string
+			set  bool
+		}{
+			{"--output", len(options.Output) > 0},
+			{"--geojson", options.GeoJSON != ""},
+			{"--sort", options.Sort != ""},
+			{"--webhook", options.Webhook != ""},
+			{"--notify", options.Notify != ""},
+			{"--elasticsearch", options.Elasticsearch != ""},
+			{"--checkpoint", options.Checkpoint != ""},
+			{"--dry-run", options.DryRun},
+		} {
+			if conflict.set {
+				logrus.Errorf("--pipe writes the enriched records to stdout and can't be combined with %s", conflict.flag)
+				return exitCodeUsage
+			}
+		}
+		if options.PipeBuffer < 0 || options.PipeRemember < 0 {
+			logrus.Errorf("Invalid --pipe-buffer or --pipe-remember, expected a positive integer or 0")
+			return exitCodeUsage
+		}
+		options.NoProgress = true
+	} else if options.PipeBuffer != 0 || options.PipeRemember != 0 {
+		logrus.Errorf("--pipe-buffer and --pipe-remember require --pipe")
+		return exitCodeUsage
+	} else if noOutputProvided := len(options.Output) == 0; noOutputProvided {
+		options.Output = []string{"output.json"}
+	}
+
+	start := time.Now()
+	inputPath := options.Input
+	if inputPath == "" {
+		inputPath = options.IPfile
+	}
+	outputSpecs := options.Output
+	if options.GeoJSON != "" {
+		outputSpecs = append(outputSpecs, output.FormatGeoJSON+":"+options.GeoJSON)
+	}
+	var outputTargets []output.Target
+	var outputFiles []string
+	for _, spec := range outputSpecs {
+		target, err := output.ParseTarget(spec)
+		if err != nil {
+			logrus.Errorf("Invalid output: %v", err)
+			return exitCodeUsage
+		}
+		target.Path, err = resolveOutputPath(target.Path, start, inputPath, target.Format)
+		if err != nil {
+			logrus.Error(err)
+			return exitCodeUsage
+		}
+		if err := checkOutputPath(target.Path, options.Force || options.DryRun); err != nil {
+			logrus.Error(err)
+			return exitCodeOutput
+		}
+		outputTargets = append(outputTargets, target)
+		outputFiles = append(outputFiles, target.Path)
+	}
+
+	workers := runtime.NumCPU()
+	if workers > maxDefaultWorkers {
+		workers = maxDefaultWorkers
+	}
+	if options.Workers != nil {
+		if *options.Workers < 1 {
+			logrus.Errorf("Invalid number of workers %d, expected a positive integer", *options.Workers)
+			return exitCodeUsage
+		}
+		workers = *options.Workers
+	}
+	if options.AdaptiveWorkers < 0 || options.AdaptiveWorkers > workers {
+		logrus.Errorf("Invalid --adaptive-workers %d, expected a positive integer of at most --workers (%d)", options.AdaptiveWorkers, workers)
+		return exitCodeUsage
+	}
+
+	sortKeys, err := output.ParseSortKeys(options.Sort)
+	if err != nil {
+		logrus.Errorf("Invalid --sort: %v", err)
+		return exitCodeUsage
+	}
+
+	for _, sourceTimeout := range []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"--ripestat-timeout", options.RipeStatTimeout},
+		{"--whois-timeout", options.WhoisTimeout},
+		{"--ip-timeout", options.IPTimeout},
+	} {
+		if sourceTimeout.timeout < 0 {
+			logrus.Errorf("Invalid %s %v, expected a positive duration", sourceTimeout.name, sourceTimeout.timeout)
+			return exitCodeUsage
+		}
+		if options.Timeout > 0 && sourceTimeout.timeout > options.Timeout {
+			logrus.Errorf("Invalid %s %v, it can't be larger than --timeout %v", sourceTimeout.name, sourceTimeout.timeout, options.Timeout)
+			return exitCodeUsage
+		}
+	}
+	if options.SourceApp != "" {
+		if err := ripestat.ValidateSourceApp(options.SourceApp); err != nil {
+			logrus.Errorf("Invalid --sourceapp: %v", err)
+			return exitCodeUsage
+		}
+	}
+	if options.RipeStatURL != "" {
+		if err := ripestat.ValidateBaseURL(options.RipeStatURL); err != nil {
+			logrus.Errorf("Invalid --ripestat-url: %v", err)
+			return exitCodeUsage
+		}
+	}
+	if options.LegacyFieldNames {
+		logrus.Warn("--legacy-field-names is deprecated and will be removed in a later release, move to the snake_case field names")
+	}
+	if options.CheckpointEvery < 1 {
+		logrus.Errorf("Invalid --checkpoint-every %d, expected a positive integer", options.CheckpointEvery)
+		return exitCodeUsage
+	}
+	if options.Passthrough && options.IPfile != "" {
+		logrus.Errorf("--passthrough keeps the fields of nuclei records and can't be combined with --file")
+		return exitCodeUsage
+	}
+	if options.NoWhois && options.VerifyASN {
+		logrus.Errorf("--no-whois can't be combined with --verify-asn, which uses the Team Cymru whois service")
+		return exitCodeUsage
+	}
+	if options.NoWhois && options.IRR {
+		logrus.Errorf("--no-whois can't be combined with --irr, which queries the IRR over whois")
+		return exitCodeUsage
+	}
+	for _, value := range append(append([]string(nil), options.RIRs...), options.WhoisRIRs...) {
+		if _, err := rir.Normalize(value); err != nil {
+			logrus.Errorf("Invalid --rir or --whois-rir %q, expected AFRINIC, APNIC, ARIN, LACNIC or RIPE", value)
+			return exitCodeUsage
+		}
+	}
+	for _, source := range options.Fallback {
+		if options.NoWhois && source == "cymru" {
+			logrus.Errorf("--no-whois can't be combined with --fallback cymru, which uses the Team Cymru whois service")
+			return exitCodeUsage
+		}
+	}
+	if options.JobBuffer < 0 || options.ResultBuffer < 0 {
+		logrus.Errorf("Invalid --job-buffer or --result-buffer, expected a positive integer")
+		return exitCodeUsage
+	}
+	if options.WhoisBreakerThreshold < 0 || options.WhoisBreakerCooldown <= 0 {
+		logrus.Errorf("Invalid --whois-breaker-threshold %d or --whois-breaker-cooldown %v, expected positive values", options.WhoisBreakerThreshold, options.WhoisBreakerCooldown)
+		return exitCodeUsage
+	}
+	whoisBreakerThreshold := options.WhoisBreakerThreshold
+	if whoisBreakerThreshold == 0 {
+		whoisBreakerThreshold = -1
+	}
+
+	if options.MaxResponseSize < 1 {
+		logrus.Errorf("Invalid --max-response-size %d, expected a positive number of KiB", options.MaxResponseSize)
+		return exitCodeUsage
+	}
+	if options.Timeout < 0 {
+		logrus.Errorf("Invalid --timeout %v, expected a positive duration", options.Timeout)
+		return exitCodeUsage
+	}
+
+	profiles, err := startProfiling(&options)
+	defer profiles.stop()
+	if err != nil {
+		logrus.Error(err)
+		return exitCodeUsage
+	}
+
+	cfg := pipeline.Config{
+		Input:                 os.Stdin,
+		Workers:               workers,
+		AdaptiveWorkers:       options.AdaptiveWorkers,
+		Timeout:               options.Timeout,
+		RipeStatTimeout:       options.RipeStatTimeout,
+		SourceApp:             options.SourceApp,
+		RipeStatURL:           options.RipeStatURL,
+		RipeStatHints:         options.RipeStatHints,
+		WhoisTimeout:          options.WhoisTimeout,
+		WhoisBreakerThreshold: whoisBreakerThreshold,
+		WhoisBreakerCooldown:  options.WhoisBreakerCooldown,
+		WhoisServers:          options.WhoisServer,
+		IPTimeout:             options.IPTimeout,
+		JobBuffer:             options.JobBuffer,
+		ResultBuffer:          options.ResultBuffer,
+		MaxResponseSize:       int64(options.MaxResponseSize) << 10,
+		SlowQueryThreshold:    options.SlowQueryThreshold,
+		NoWhois:               options.NoWhois,
+		RIRs:                  options.RIRs,
+		WhoisRIRs:             options.WhoisRIRs,
+		VerifyASN:             options.VerifyASN,
+		RejectPrivateASN:      options.RejectPrivateASN,
+		RoleContactsOnly:      options.RoleContactsOnly,
+		RegistryHandles:       options.RegistryHandles,
+		Registration:          options.Registration,
+		CoveringPrefix:        options.CoveringPrefix,
+		AbuseTo:               options.AbuseTo,
+		PrefixAbuse:           options.PrefixAbuse,
+		UnknownPlaceholder:    options.UnknownPlaceholder,
+
+		Checkpoint:         options.Checkpoint,
+		CheckpointEvery:    options.CheckpointEvery,
+		CheckpointInterval: options.CheckpointInterval,
+		CheckpointRemove:   options.CheckpointRemove,
+
+		Outputs:                outputTargets,
+		Force:                  options.Force,
+		Passthrough:            options.Passthrough,
+		ResolveHosts:           options.ResolveHosts,
+		SortKeys:               sortKeys,
+		GeoJSONCountryFallback: options.GeoJSONCountryFallback,
+		FailedReasons:          options.FailedReasons,
+		MISPEventInfo:          options.MISPEventInfo,
+		Webhook:                options.Webhook,
+		Notify:                 options.Notify,
+		LegacyFieldNames:       options.LegacyFieldNames,
+		MappedAsIPv6:           options.MappedAs == "ipv6",
+		NotifyFormat:           options.NotifyFormat,
+		Elasticsearch:          options.Elasticsearch,
+		ElasticsearchIndex:     options.ElasticsearchIndex,
+
+		Logger: logrus.StandardLogger(),
 	}
 
 	if options.Input == "" && options.IPfile == "" {
 		stat, err := os.Stdin.Stat()
 		if err != nil {
-			logrus.Fatalf("Error getting stdin stat: %v", err)
+			logrus.Errorf("Error getting stdin stat: %v", err)
+			return exitCodeInput
 		}
 		if stat.Mode()&os.ModeNamedPipe == 0 {
-			logrus.Fatalf("No input file provided and stdin is not a pipe")
+			logrus.Errorf("No input file provided and stdin is not a pipe")
+			return exitCodeUsage
 		}
-
-		scanParser = *scanParser.NewParser(os.Stdin)
 	} else if options.IPfile != "" {
 		file, err := os.Open(options.IPfile)
 		if err != nil {
-			logrus.Fatalf("Error opening ip file: %v", err)
+			logrus.Errorf("Error opening ip file: %v", err)
+			return exitCodeInput
 		}
 		defer file.Close()
-		scanParser = *scanParser.NewSimpleParser(file)
+		cfg.Input, cfg.InputFormat = file, pipeline.FormatIPList
 	} else {
 		file, err := os.Open(options.Input)
-
 		if err != nil {
-			logrus.Fatalf("Error opening input file: %v", err)
+			logrus.Errorf("Error opening input file: %v", err)
+			return exitCodeInput
 		}
 		defer file.Close()
+		cfg.Input = file
+	}
+
+	if len(options.IncludeFile) > 0 || len(options.ExcludeFile) > 0 {
+		cfg.Scope = scope.NewScope()
+		for _, path := range options.IncludeFile {
+			if err := cfg.Scope.LoadIncludeFile(path); err != nil {
+				logrus.Errorf("Error loading include file: %v", err)
+				return exitCodeInput
+			}
+		}
+		for _, path := range options.ExcludeFile {
+			if err := cfg.Scope.LoadExcludeFile(path); err != nil {
+				logrus.Errorf("Error loading exclude file: %v", err)
+				return exitCodeInput
+			}
+		}
+	}
+
+	if options.DryRun {
+		// host names are counted instead of resolved, before the scope drops the records without IP
+		parseCfg := cfg
+		parseCfg.ResolveHosts, parseCfg.Scope = false, nil
+		scanParser, err := pipeline.Parse(context.Background(), parseCfg)
+		if err != nil {
+			logrus.Errorf("Error %v", err)
+			return exitCodeInput
+		}
+		hosts := 0
+		if cfg.ResolveHosts {
+			hosts = countHostnames(scanParser)
+		}
+		if cfg.Scope != nil {
+			stats := scanParser.ApplyScope(cfg.Scope)
+			logrus.Infof("Dropped %d excluded IPs and %d IPs outside the include list", stats.Excluded, stats.NotIncluded)
+		}
+
+		plan := newDryRunPlan(scanParser, hosts, workers, outputFiles)
+		report.addPlan(plan)
+		if !options.Quiet {
+			printDryRunPlan(os.Stderr, plan)
+		}
+		if err := writeDryRunPlan(plan, options.Plan); err != nil {
+			logrus.Errorf("Error writing plan: %v", err)
+			return exitCodeOutput
+		}
+		return exitCodeOK
+	}
+
+	creds := credentials.NewStore()
+	for _, credentialEnv := range options.CredentialEnv {
+		source, envName, found := strings.Cut(credentialEnv, "=")
+		if !found || source == "" {
+			logrus.Errorf("Invalid --credential-env, expected source=ENV_VAR")
+			return exitCodeUsage
+		}
+		if err := creds.SetEnvName(source, envName); err != nil {
+			logrus.Error(err)
+			return exitCodeUsage
+		}
+	}
+
+	if len(options.RoleLocalParts) > 0 {
+		cfg.ContactClassifier = contact.NewClassifier(options.RoleLocalParts...)
+	}
+
+	if options.AsOf != "" {
+		cfg.AsOf, err = parseAsOf(options.AsOf)
+		if err != nil {
+			logrus.Errorf("Invalid --as-of %q, expected a date like 2006-01-02 or an RFC 3339 time", options.AsOf)
+			return exitCodeUsage
+		}
+		if cfg.AsOf.After(time.Now()) {
+			logrus.Errorf("Invalid --as-of %q, the date is in the future", options.AsOf)
+			return exitCodeUsage
+		}
+	}
+
+	if options.IRR {
+		cfg.IRR = irr.NewIRRClient()
+		cfg.IRR.Server = options.IRRServer
+	}
+
+	for _, source := range options.Fallback {
+		switch source {
+		case "rdap":
+			cfg.Fallback = append(cfg.Fallback, enricher.RDAPProvider(rdap.NewRDAPClient()))
+		case "cymru":
+			cfg.Fallback = append(cfg.Fallback, enricher.CymruProvider(cymru.NewCymruClient()))
+		case "bgpview":
+			cfg.Fallback = append(cfg.Fallback, enricher.BGPViewProvider(bgpview.NewBGPViewClient()))
+		}
+	}
+
+	if options.ReverseDNS {
+		cfg.ReverseDNS = rdns.NewHinter()
+		for _, providerSuffix := range options.ProviderSuffixes {
+			suffix, provider, found := strings.Cut(providerSuffix, "=")
+			if !found || suffix == "" || provider == "" {
+				logrus.Errorf("Invalid provider suffix %q, expected suffix=provider", providerSuffix)
+				return exitCodeUsage
+			}
+			cfg.ReverseDNS.AddSuffix(suffix, provider)
+		}
+	}
+
+	if options.TLSCerts {
+		cfg.TLSCerts = tlscert.NewTLSCertClient()
+		cfg.TLSCerts.Timeout = options.TLSTimeout
+	}
+
+	if len(options.Annotate) > 0 {
+		cfg.Annotator = annotate.NewAnnotator()
+		for _, annotation := range options.Annotate {
+			label, path, found := strings.Cut(annotation, "=")
+			if !found || label == "" || path == "" {
+				logrus.Errorf("Invalid annotation %q, expected label=path", annotation)
+				return exitCodeUsage
+			}
+			if err := cfg.Annotator.LoadFile(label, path); err != nil {
+				logrus.Errorf("Error loading annotations: %v", err)
+				return exitCodeUsage
+			}
+		}
+		logrus.Debug("loaded ", cfg.Annotator.Len(), " annotation entries")
+	}
+
+	if len(options.Geofeed) > 0 {
+		cfg.Geofeed = geofeed.NewFeed()
+		for _, source := range options.Geofeed {
+			stats, err := cfg.Geofeed.Load(source)
+			if err != nil {
+				logrus.Errorf("Error loading geofeed: %v", err)
+				return exitCodeUsage
+			}
+			if stats.Skipped > 0 {
+				logrus.Warnf("geofeed %s: skipped %d malformed rows", source, stats.Skipped)
+			}
+			logrus.Debugf("geofeed %s: loaded %d prefixes", source, stats.Loaded)
+		}
+	}
 
-		scanParser = *scanParser.NewParser(file)
+	if options.ASNCategory {
+		cfg.ASNClassifier = asn.NewClassifier()
+		if options.ASNCategories != "" {
+			loaded, err := cfg.ASNClassifier.Load(options.ASNCategories)
+			if err != nil {
+				logrus.Errorf("Error loading ASN categories: %v", err)
+				return exitCodeUsage
+			}
+			logrus.Debugf("loaded the categories of %d AS numbers from %s", loaded, options.ASNCategories)
+		}
+	} else if options.ASNCategories != "" {
+		logrus.Errorf("--asn-categories requires --asn-category")
+		return exitCodeUsage
 	}
 
-	// If we have an IP file onyl, start the simple scan.
-	// Else we asume it's a Nuclei .json file
-	if options.IPfile != "" {
-		scanParser.ProcessSimpleScan()
+	if options.CERTRouting != "" {
+		cfg.NationalCERTs = csirt.NewRouting()
+		loaded, err := cfg.NationalCERTs.Load(options.CERTRouting)
+		if err != nil {
+			logrus.Errorf("Error loading CERT routing: %v", err)
+			return exitCodeUsage
+		}
+		logrus.Debugf("loaded the national CERTs of %d countries from %s", loaded, options.CERTRouting)
+	}
+
+	if options.CloudRanges {
+		if code := loadCloudRanges(&cfg, options); code != exitCodeOK {
+			return code
+		}
+	} else if len(options.CloudSources) > 0 {
+		logrus.Errorf("--cloud-source requires --cloud-ranges")
+		return exitCodeUsage
+	}
+
+	if token, found := creds.Key(credentials.SourceRadar); found {
+		cfg.Radar = radar.NewRadarClient(token)
 	} else {
-		scanParser.ProcessNucleiScan()
+		logrus.Infof("%s not set, Cloudflare Radar enrichment disabled", creds.EnvName(credentials.SourceRadar))
+	}
+	cfg.ElasticsearchAPIKey, _ = creds.Key(credentials.SourceElasticsearch)
+	if options.PeeringDB {
+		// PeeringDB answers anonymous requests too, a key raises the rate limit
+		key, _ := creds.Key(credentials.SourcePeeringDB)
+		cfg.PeeringDB = peeringdb.NewPeeringDBClient(key)
+	}
+
+	if options.Cache != "" {
+		cfg.Cache, err = cache.Open(options.Cache, options.CacheTTL, options.CacheReadOnly)
+		if err != nil {
+			logrus.Errorf("Error opening cache: %v", err)
+			return exitCodeUsage
+		}
+		defer func() {
+			// results of an aborted run are cached as well, so a rerun picks up where this one stopped
+			if err := cfg.Cache.Close(); err != nil {
+				logrus.Errorf("Error writing cache: %v", err)
+			}
+		}()
 	}
 
-	scanParser.EnrichScanRecords()
-	logrus.Debug("nucleiScanParser: EnrichScanRecords - ended")
-	scanParser.MergeScanEnrichment()
+	var progress *progressDisplay
+	if !options.NoProgress {
+		progress = newProgressDisplay()
+		if progress.terminal && options.LogFile == "" {
+			logrus.SetOutput(progress)
+		}
+		cfg.Progress = progress.Update
+	}
 
-	outputFile, err := os.Create(options.Output)
+	ctx, stopSignals := withShutdownSignals(context.Background())
+	defer stopSignals()
 
+	var summary pipeline.Summary
+	if options.Pipe {
+		pipe := &pipeline.Pipeline{
+			Config:   cfg,
+			Sink:     pipeline.NewJSONLinesSink(os.Stdout, types.MarshalOptions{LegacyFieldNames: cfg.LegacyFieldNames}),
+			Buffer:   options.PipeBuffer,
+			Remember: options.PipeRemember,
+		}
+		summary, err = pipe.Run(ctx)
+	} else {
+		summary, err = pipeline.Run(ctx, cfg)
+	}
+	if progress != nil {
+		progress.Finish()
+		if logrus.StandardLogger().Out == progress {
+			logrus.SetOutput(os.Stderr)
+		}
+	}
+	report.Parsed, report.Enriched, report.Failed = summary.Parsed, summary.Enriched, summary.Failed
+	report.Skipped = summary.IPStats.Bogon + summary.IPStats.Empty
+	report.Invalid, report.InvalidIPs = summary.IPStats.Invalid, summary.IPStats.InvalidIPs
+	report.Outputs = append(report.Outputs, summary.Outputs...)
+	report.FailedOutputs = summary.FailedOutputs
+
+	var runErr *pipeline.Error
+	if err != nil && !errors.As(err, &runErr) {
+		runErr = &pipeline.Error{Stage: pipeline.StageConfig, Err: err}
+	}
+	if runErr != nil && runErr.Stage != pipeline.StageEnrich {
+		logrus.Errorf("Error %v", runErr)
+	}
+
+	return exitCode(summary, runErr)
+}
+
+// loadCloudRanges loads the published ranges of the default cloud providers and of the ones given
+// with --cloud-source into cfg.CloudRanges, downloading the range files that are stale.
+func loadCloudRanges(cfg *pipeline.Config, options Options) int {
+	cacheDir := options.CloudCacheDir
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			logrus.Warnf("No cache directory for the cloud range files, downloading them every run: %v", err)
+		} else {
+			cacheDir = filepath.Join(userCacheDir, "nuclei-parse-enrich", "cloud")
+		}
+	}
+
+	cfg.CloudRanges = cloud.NewRanges(cacheDir)
+	cfg.CloudRanges.TTL = options.CloudTTL
+	for provider, source := range cloud.DefaultSources {
+		_ = cfg.CloudRanges.AddSource(provider, source)
+	}
+	for _, providerSource := range options.CloudSources {
+		provider, source, found := strings.Cut(providerSource, "=")
+		if !found || provider == "" || source == "" {
+			logrus.Errorf("Invalid cloud source %q, expected provider=url-or-path", providerSource)
+			return exitCodeUsage
+		}
+		if err := cfg.CloudRanges.AddSource(provider, source); err != nil {
+			logrus.Errorf("Invalid cloud source %q: %v", providerSource, err)
+			return exitCodeUsage
+		}
+	}
+
+	loaded, err := cfg.CloudRanges.Refresh(context.Background())
 	if err != nil {
-		logrus.Fatal(err)
+		logrus.Errorf("Error loading cloud ranges: %v", err)
+		return exitCodeUsage
 	}
+	logrus.Debugf("loaded %d cloud prefixes", loaded)
 
-	defer scanParser.File.Close()
+	return exitCodeOK
+}
 
-	scanParser.WriteOutput(outputFile)
+// parseAsOf parses the --as-of date, a date without time is taken as midnight UTC.
+func parseAsOf(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
@@ -0,0 +1,76 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+// TestExitCodes runs the command line for every exit code of exitCodesHelp.
+func TestExitCodes(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("network-info", "193.0.6.140", ripestattest.Error(400, "bad request"))
+	slowResponse := ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`)
+	slowResponse.Latency = 2 * time.Second
+	server.Handle("network-info", "193.0.6.141", slowResponse)
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+
+	dir := t.TempDir()
+	scan := func(name, ipAddr string) string {
+		path := filepath.Join(dir, name)
+		record := `{"ip": "` + ipAddr + `", "host": "https://` + ipAddr + `", "template-id": "test", "info": {"severity": "high"}}` + "\n"
+		if err := os.WriteFile(path, []byte(record), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	enriched := scan("enriched.json", "193.0.6.139")
+	failing := scan("failing.json", "193.0.6.140")
+	slow := scan("slow.json", "193.0.6.141")
+	malformed := filepath.Join(dir, "malformed.json")
+	if err := os.WriteFile(malformed, []byte(`[{"ip": "193.0.6.139"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "output.json")
+	// below a file, so its directory can't be created
+	unwritable := filepath.Join(enriched, "output.json")
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"success", []string{"--input", enriched, "--output", output}, exitCodeOK},
+		{"help", []string{"--help"}, exitCodeOK},
+		{"usage", []string{"--input", enriched, "--output", output, "--workers", "0"}, exitCodeUsage},
+		{"unknown flag", []string{"--no-such-flag"}, exitCodeUsage},
+		{"missing input", []string{"--input", filepath.Join(dir, "missing.json"), "--output", output}, exitCodeInput},
+		{"malformed input", []string{"--input", malformed, "--output", output}, exitCodeInput},
+		{"failed lookups", []string{"--input", failing, "--output", output}, exitCodeFailures},
+		{"timed out", []string{"--input", slow, "--output", output, "--timeout", "200ms"}, exitCodeTruncated},
+		{"unwritable output", []string{"--input", enriched, "--output", unwritable}, exitCodeOutput},
+		{"one unwritable output", []string{"--input", enriched, "--output", output, "--output", "csv:" + unwritable}, exitCodePartialOutput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--ripestat-url", server.BaseURL(), "--no-whois", "--force", "--no-progress", "--log-level", "panic"}, tt.args...)
+			if code := run(args); code != tt.want {
+				t.Errorf("run(%q) = %d, want %d", tt.args, code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// outputPathData is available in templated output paths, e.g. "results/{{.Date}}-{{.Input}}-enriched.{{.Ext}}".
+type outputPathData struct {
+	// Date and Time are the run start time in UTC, as 2006-01-02 and 150405
+	Date string
+	Time string
+	// Timestamp is the run start time in UTC, as 20060102T150405Z
+	Timestamp string
+	// Input is the base name of the input file without extension, or "stdin"
+	Input string
+	// Ext is the extension of the output format, without dot
+	Ext string
+}
+
+// resolveOutputPath expands the template in pattern, paths without template actions are
+// returned unchanged.
+func resolveOutputPath(pattern string, start time.Time, input string, ext string) (string, error) {
+	if !strings.Contains(pattern, "{{") {
+		return pattern, nil
+	}
+
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid output path template %q: %v", pattern, err)
+	}
+
+	inputName := "stdin"
+	if input != "" {
+		inputName = strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+	}
+
+	start = start.UTC()
+	data := outputPathData{
+		Date:      start.Format("2006-01-02"),
+		Time:      start.Format("150405"),
+		Timestamp: start.Format("20060102T150405Z"),
+		Input:     inputName,
+		Ext:       ext,
+	}
+
+	var path strings.Builder
+	if err := tmpl.Execute(&path, data); err != nil {
+		return "", fmt.Errorf("invalid output path template %q: %v", pattern, err)
+	}
+
+	return path.String(), nil
+}
+
+// checkOutputPath fails when path exists and may not be overwritten.
+func checkOutputPath(path string, force bool) error {
+	if force {
+		return nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/sirupsen/logrus"
+)
+
+// profiling holds the profiles running for --pprof, --cpuprofile, --memprofile and --trace.
+type profiling struct {
+	listener   net.Listener
+	cpuFile    *os.File
+	traceFile  *os.File
+	memProfile string
+}
+
+// startProfiling starts the profiles requested in options. Call stop when the run is done to
+// write them, also when startProfiling returns an error.
+func startProfiling(options *Options) (*profiling, error) {
+	p := &profiling{memProfile: options.MemProfile}
+
+	if options.Pprof != "" {
+		listener, err := net.Listen("tcp", options.Pprof)
+		if err != nil {
+			return p, fmt.Errorf("pprof: %v", err)
+		}
+		p.listener = listener
+		logrus.Infof("Serving pprof on http://%s/debug/pprof/", listener.Addr())
+		go func() {
+			// net/http/pprof registers its handlers on the default mux
+			if err := http.Serve(listener, nil); err != nil {
+				logrus.Debugf("pprof: %v", err)
+			}
+		}()
+	}
+
+	if options.CPUProfile != "" {
+		file, err := os.Create(options.CPUProfile)
+		if err != nil {
+			return p, fmt.Errorf("cpu profile: %v", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return p, fmt.Errorf("cpu profile: %v", err)
+		}
+		p.cpuFile = file
+	}
+
+	if options.Trace != "" {
+		file, err := os.Create(options.Trace)
+		if err != nil {
+			return p, fmt.Errorf("trace: %v", err)
+		}
+		if err := trace.Start(file); err != nil {
+			file.Close()
+			return p, fmt.Errorf("trace: %v", err)
+		}
+		p.traceFile = file
+	}
+
+	return p, nil
+}
+
+// stop stops the running profiles and writes the heap profile.
+func (p *profiling) stop() {
+	if p.cpuFile != nil {
+		pprof.StopCPUProfile()
+		if err := p.cpuFile.Close(); err != nil {
+			logrus.Errorf("Error writing cpu profile: %v", err)
+		}
+	}
+
+	if p.traceFile != nil {
+		trace.Stop()
+		if err := p.traceFile.Close(); err != nil {
+			logrus.Errorf("Error writing trace: %v", err)
+		}
+	}
+
+	if p.memProfile != "" {
+		if err := writeHeapProfile(p.memProfile); err != nil {
+			logrus.Errorf("Error writing memory profile: %v", err)
+		}
+	}
+
+	if p.listener != nil {
+		p.listener.Close()
+	}
+}
+
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	// get up-to-date statistics
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,63 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+func TestProfiles(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "scan.json")
+	scan := `{"ip": "193.0.6.139", "host": "https://193.0.6.139", "template-id": "test", "info": {"severity": "high"}}` + "\n"
+	if err := os.WriteFile(input, []byte(scan), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := map[string]string{
+		"--cpuprofile": filepath.Join(dir, "cpu.pprof"),
+		"--memprofile": filepath.Join(dir, "mem.pprof"),
+		"--trace":      filepath.Join(dir, "trace.out"),
+	}
+	args := []string{
+		"--input", input,
+		"--output", filepath.Join(dir, "enriched.json"),
+		"--ripestat-url", server.BaseURL(),
+		"--no-whois",
+		"--quiet",
+	}
+	for flag, path := range profiles {
+		args = append(args, flag, path)
+	}
+
+	if code := run(args); code != exitCodeOK {
+		t.Fatalf("run exited with %d", code)
+	}
+	for flag, path := range profiles {
+		stat, err := os.Stat(path)
+		if err != nil {
+			t.Errorf("%s: %v", flag, err)
+			continue
+		}
+		if stat.Size() == 0 {
+			t.Errorf("%s wrote an empty file", flag)
+		}
+	}
+	server.AssertRequests(t, "network-info", "193.0.6.139", 1)
+	server.AssertNoUnexpected(t)
+}
@@ -0,0 +1,114 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"nuclei-parse-enrich/pkg/enricher"
+
+	"github.com/sirupsen/logrus"
+)
+
+// progressLogInterval is the interval between progress log lines when stderr is not a terminal.
+const progressLogInterval = 10 * time.Second
+
+// progressDisplay shows the progress of the enrichment on stderr. On a terminal it keeps a single
+// status line up to date, otherwise it logs the progress periodically.
+type progressDisplay struct {
+	mu       sync.Mutex
+	out      io.Writer
+	terminal bool
+	line     string
+	lastLog  time.Time
+}
+
+func newProgressDisplay() *progressDisplay {
+	return &progressDisplay{
+		out:      os.Stderr,
+		terminal: isTerminal(os.Stderr),
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// Update renders p, it is meant to be passed to enricher.WithProgress.
+func (d *progressDisplay) Update(p enricher.Progress) {
+	line := formatProgress(p)
+
+	if !d.terminal {
+		if time.Since(d.lastLog) >= progressLogInterval || p.Enriched == p.Total {
+			d.lastLog = time.Now()
+			logrus.Info(line)
+		}
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.line = line
+	fmt.Fprint(d.out, "\r\033[K"+d.line)
+}
+
+// Write writes log output above the status line, so the two never end up on the same line.
+// It is installed as log output while the display is active on a terminal.
+func (d *progressDisplay) Write(b []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.line != "" {
+		fmt.Fprint(d.out, "\r\033[K")
+	}
+
+	n, err := d.out.Write(b)
+
+	if d.line != "" {
+		fmt.Fprint(d.out, d.line)
+	}
+
+	return n, err
+}
+
+// Finish ends the status line.
+func (d *progressDisplay) Finish() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.terminal && d.line != "" {
+		fmt.Fprintln(d.out)
+		d.line = ""
+	}
+}
+
+func formatProgress(p enricher.Progress) string {
+	line := fmt.Sprintf("enriched %d/%d IPs, %d failed", p.Enriched, p.Total, p.Failed)
+
+	if p.Elapsed <= 0 || p.Enriched == 0 {
+		return line
+	}
+
+	rate := float64(p.Enriched) / p.Elapsed.Seconds()
+	line += fmt.Sprintf(", %.1f IPs/s", rate)
+
+	if remaining := p.Total - p.Enriched; remaining > 0 {
+		eta := time.Duration(float64(remaining) / rate * float64(time.Second))
+		line += fmt.Sprintf(", ETA %v", eta.Round(time.Second))
+	}
+
+	return line
+}
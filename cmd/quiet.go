@@ -0,0 +1,112 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runReport is the single JSON line --quiet prints on completion.
+type runReport struct {
+	Parsed   int `json:"parsed"`
+	Enriched int `json:"enriched"`
+	Failed   int `json:"failed"`
+	Skipped  int `json:"skipped"`
+	// Invalid counts the values that are not an IP address, InvalidIPs lists the first few
+	Invalid    int      `json:"invalid"`
+	InvalidIPs []string `json:"invalid_ips,omitempty"`
+	// Warnings and Errors count the log entries, including the suppressed warnings
+	Warnings        int      `json:"warnings"`
+	Errors          int      `json:"errors"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	Outputs         []string `json:"outputs"`
+	FailedOutputs   []string `json:"failed_outputs,omitempty"`
+	ExitCode        int      `json:"exit_code"`
+	// DryRun is the plan of --dry-run, the counts above are taken from it
+	DryRun *dryRunPlan `json:"dry_run,omitempty"`
+
+	start time.Time
+	mu    sync.Mutex
+}
+
+func newRunReport() *runReport {
+	return &runReport{
+		Outputs: []string{},
+		start:   time.Now(),
+	}
+}
+
+// Levels implements logrus.Hook, the report counts warnings and errors.
+func (r *runReport) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}
+}
+
+func (r *runReport) Fire(entry *logrus.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry.Level == logrus.WarnLevel {
+		r.Warnings++
+	} else {
+		r.Errors++
+	}
+	return nil
+}
+
+// addPlan takes the counts of a dry run from plan.
+func (r *runReport) addPlan(plan dryRunPlan) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Parsed = plan.Records
+	r.Skipped = plan.SkippedIPs + plan.EmptyIPs
+	r.Invalid, r.InvalidIPs = plan.InvalidIPs, plan.InvalidExamples
+	r.DryRun = &plan
+}
+
+func (r *runReport) write(w io.Writer, exitCode int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ExitCode = exitCode
+	r.DurationSeconds = time.Since(r.start).Seconds()
+	return json.NewEncoder(w).Encode(r)
+}
+
+// configureQuiet makes the standard logger only write errors, while warnings are still counted
+// by report.
+func configureQuiet(report *runReport) {
+	logger := logrus.StandardLogger()
+	logger.SetLevel(logrus.WarnLevel)
+	logger.AddHook(report)
+	logger.AddHook(&errorWriterHook{out: logger.Out, formatter: logger.Formatter})
+	logger.SetOutput(io.Discard)
+}
+
+// errorWriterHook writes error entries to out, for loggers whose own output is discarded.
+type errorWriterHook struct {
+	out       io.Writer
+	formatter logrus.Formatter
+}
+
+func (h *errorWriterHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (h *errorWriterHook) Fire(entry *logrus.Entry) error {
+	line, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.out.Write(line)
+	return err
+}
@@ -0,0 +1,178 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"nuclei-parse-enrich/pkg/cache"
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/server"
+	"nuclei-parse-enrich/pkg/types"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/sirupsen/logrus"
+)
+
+// cacheSaveInterval is the interval at which serve writes a changed cache to disk.
+const cacheSaveInterval = time.Minute
+
+type serveOptions struct {
+	Listen             string        `long:"listen" description:"The address to serve HTTP on" default:":8080"`
+	Workers            int           `long:"workers" description:"The maximum number of IPs enriched concurrently" default:"8"`
+	Cache              string        `long:"cache" description:"A file caching enrichment results, shared by all requests and saved periodically"`
+	CacheTTL           time.Duration `long:"cache-ttl" description:"How long cached results stay fresh" default:"168h"`
+	RipeStatURL        string        `long:"ripestat-url" description:"The base URL of the RipeSTAT data API, e.g. a mirror or proxy (default: https://stat.ripe.net/data/)"`
+	RipeStatTimeout    time.Duration `long:"ripestat-timeout" description:"The timeout of a single RipeSTAT request, e.g. 10s"`
+	WhoisTimeout       time.Duration `long:"whois-timeout" description:"The timeout of a single whois lookup, e.g. 10s"`
+	IPTimeout          time.Duration `long:"ip-timeout" description:"The timeout of all lookups of a single IP" default:"30s"`
+	NoWhois            bool          `long:"no-whois" description:"Never fall back to whois for abuse contacts"`
+	LegacyFieldNames   bool          `long:"legacy-field-names" description:"Answer with the enrichment fields named like schema version 1 (Ip, AbuseSource, ...) instead of snake_case, for one transition release"`
+	MappedAs           string        `long:"mapped-as" description:"Enrich IPv6 addresses embedding an IPv4 address (::ffff:192.0.2.1, ::192.0.2.1) as the ipv4 address, or as ipv6" choice:"ipv4" choice:"ipv6" default:"ipv4"`
+	UnknownPlaceholder bool          `long:"unknown-placeholder" description:"Write \"unknown\" in the fields that could not be determined instead of leaving them out"`
+	MaxBatch           int           `long:"max-batch" description:"The maximum number of IPs in a single POST /enrich request" default:"1000"`
+	ShutdownTimeout    time.Duration `long:"shutdown-timeout" description:"How long to wait for requests in flight on shutdown" default:"30s"`
+	LogLevel           string        `long:"log-level" description:"The log level: debug, info, warn or error" default:"info"`
+	LogFormat          string        `long:"log-format" description:"The log format: text or json" default:"text"`
+	LogFile            string        `long:"log-file" description:"A file to append the logs to instead of stderr"`
+}
+
+// runServe runs the serve command, which answers enrichment requests over HTTP until SIGINT or
+// SIGTERM. Requests in flight are finished before it exits.
+func runServe(args []string) int {
+	var options serveOptions
+	goflags := flags.NewParser(&options, flags.Default)
+	goflags.Usage = "serve [OPTIONS]"
+	if _, err := goflags.ParseArgs(args); err != nil {
+		if errFlags, ok := err.(*flags.Error); ok && errFlags.Type == flags.ErrHelp {
+			return exitCodeOK
+		}
+		logrus.Errorf("Error parsing flags: %v", err)
+		return exitCodeUsage
+	}
+
+	logFile, err := configureLogging(Options{LogLevel: options.LogLevel, LogFormat: options.LogFormat, LogFile: options.LogFile})
+	if err != nil {
+		logrus.Errorf("Error configuring logging: %v", err)
+		return exitCodeUsage
+	}
+	defer closeLogFile(logFile)
+	if options.Workers < 1 || options.MaxBatch < 1 {
+		logrus.Errorf("Invalid --workers or --max-batch, expected a positive integer")
+		return exitCodeUsage
+	}
+	if options.RipeStatURL != "" {
+		if err := ripestat.ValidateBaseURL(options.RipeStatURL); err != nil {
+			logrus.Errorf("Invalid --ripestat-url: %v", err)
+			return exitCodeUsage
+		}
+	}
+	if options.LegacyFieldNames {
+		logrus.Warn("--legacy-field-names is deprecated and will be removed in a later release, move to the snake_case field names")
+	}
+
+	var enricherOptions []enricher.Option
+	if options.RipeStatURL != "" {
+		enricherOptions = append(enricherOptions, enricher.WithRipeStatBaseURL(options.RipeStatURL))
+	}
+	if options.RipeStatTimeout > 0 {
+		enricherOptions = append(enricherOptions, enricher.WithRipeStatTimeout(options.RipeStatTimeout))
+	}
+	if options.WhoisTimeout > 0 {
+		enricherOptions = append(enricherOptions, enricher.WithWhoisTimeout(options.WhoisTimeout))
+	}
+	if options.IPTimeout > 0 {
+		enricherOptions = append(enricherOptions, enricher.WithPerIPTimeout(options.IPTimeout))
+	}
+	if options.NoWhois {
+		enricherOptions = append(enricherOptions, enricher.WithoutWhois())
+	}
+	if options.UnknownPlaceholder {
+		enricherOptions = append(enricherOptions, enricher.WithUnknownPlaceholder())
+	}
+	if options.MappedAs == "ipv6" {
+		enricherOptions = append(enricherOptions, enricher.WithMappedAsIPv6(true))
+	}
+
+	var enrichmentCache *cache.Cache
+	if options.Cache != "" {
+		var err error
+		enrichmentCache, err = cache.Open(options.Cache, options.CacheTTL, false)
+		if err != nil {
+			logrus.Errorf("Error opening cache: %v", err)
+			return exitCodeUsage
+		}
+		enricherOptions = append(enricherOptions, enricher.WithCache(enrichmentCache))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := server.NewServer(ctx, enricher.NewEnricher(enricherOptions...), options.Workers)
+	srv.MarshalOptions = types.MarshalOptions{LegacyFieldNames: options.LegacyFieldNames}
+	srv.MappedAsIPv6 = options.MappedAs == "ipv6"
+	srv.MaxBatch = options.MaxBatch
+	httpServer := &http.Server{
+		Addr:              options.Listen,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logrus.Infof("Serving enrichment requests on %s", options.Listen)
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	saveTicker := time.NewTicker(cacheSaveInterval)
+	defer saveTicker.Stop()
+
+	code := exitCodeOK
+loop:
+	for {
+		select {
+		case err := <-serveErr:
+			logrus.Errorf("Error serving: %v", err)
+			code = exitCodeUsage
+			break loop
+		case sig := <-signals:
+			logrus.Infof("Received %v, finishing the requests in flight", sig)
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), options.ShutdownTimeout)
+			err := httpServer.Shutdown(shutdownCtx)
+			cancelShutdown()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logrus.Warnf("Error shutting down: %v", err)
+			}
+			break loop
+		case <-saveTicker.C:
+			if enrichmentCache != nil {
+				if err := enrichmentCache.Save(); err != nil {
+					logrus.Errorf("Error writing cache: %v", err)
+				}
+			}
+		}
+	}
+
+	if enrichmentCache != nil {
+		if err := enrichmentCache.Close(); err != nil {
+			logrus.Errorf("Error writing cache: %v", err)
+		}
+	}
+
+	return code
+}
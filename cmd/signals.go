@@ -0,0 +1,54 @@
+package main
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+// exitCodeForced is used when a second signal stops the run without writing output, like a shell
+// reports a process killed by SIGINT.
+const exitCodeForced = 130
+
+// withShutdownSignals returns a context that is cancelled on the first SIGINT or SIGTERM, so the
+// lookups in flight stop and the IPs enriched so far are written. A second signal exits immediately.
+// The returned function stops catching the signals.
+func withShutdownSignals(ctx context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			logrus.Warnf("Received %v, stopping and writing the IPs enriched so far (send again to exit immediately)", sig)
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case sig := <-signals:
+			logrus.Errorf("Received %v again, exiting without writing output", sig)
+			os.Exit(exitCodeForced)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
@@ -0,0 +1,161 @@
+package annotate
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Annotator tags IP addresses with the labels of every annotation entry covering them.
+// Entries are read from files with one IP address or CIDR per line, optionally followed by
+// a label overriding the label of the file. Empty lines and everything after a # are ignored.
+type Annotator struct {
+	intervals []interval
+	tree      *node
+}
+
+func NewAnnotator() *Annotator {
+	return &Annotator{}
+}
+
+// LoadFile reads the annotation entries in path and tags them with label.
+func (a *Annotator) LoadFile(label, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("annotate: %v", err)
+	}
+	defer file.Close()
+
+	return a.Load(label, path, file)
+}
+
+// Load reads annotation entries from r, name is only used in error messages.
+func (a *Annotator) Load(label, name string, r io.Reader) error {
+	var intervals []interval
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return fmt.Errorf("annotate: %s:%d: expected an IP address or CIDR and an optional label, got %q", name, lineNumber, line)
+		}
+
+		entryLabel := label
+		if len(fields) == 2 {
+			entryLabel = fields[1]
+		}
+
+		prefix, err := parsePrefix(fields[0])
+		if err != nil {
+			return fmt.Errorf("annotate: %s:%d: %v", name, lineNumber, err)
+		}
+
+		intervals = append(intervals, interval{
+			start: prefix.Addr(),
+			end:   lastAddr(prefix),
+			label: entryLabel,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("annotate: %s: %v", name, err)
+	}
+
+	a.intervals = append(a.intervals, intervals...)
+	a.tree = buildTree(a.intervals)
+
+	return nil
+}
+
+// Tags returns the sorted, unique labels of all entries covering ipAddr.
+func (a *Annotator) Tags(ipAddr string) []string {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return nil
+	}
+
+	labels := a.tree.search(addr.Unmap().WithZone(""), nil)
+	if len(labels) == 0 {
+		return nil
+	}
+
+	sort.Strings(labels)
+
+	unique := labels[:1]
+	for _, label := range labels[1:] {
+		if label != unique[len(unique)-1] {
+			unique = append(unique, label)
+		}
+	}
+
+	return unique
+}
+
+// Covers reports whether any entry covers ipAddr.
+func (a *Annotator) Covers(ipAddr string) bool {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return false
+	}
+
+	return len(a.tree.search(addr.Unmap().WithZone(""), nil)) > 0
+}
+
+// Len returns the number of loaded annotation entries.
+func (a *Annotator) Len() int {
+	return len(a.intervals)
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+
+	return prefix.Masked(), nil
+}
+
+// lastAddr returns the highest address contained in prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr()
+	bytes := addr.AsSlice()
+
+	for bit := prefix.Bits(); bit < addr.BitLen(); bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+
+	last, _ := netip.AddrFromSlice(bytes)
+	return last
+}
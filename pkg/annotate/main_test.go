@@ -0,0 +1,179 @@
+package annotate
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestAnnotator returns an annotator with testdata/customers.txt and overlapping entries of
+// other labels loaded.
+func newTestAnnotator(t *testing.T) *Annotator {
+	a := NewAnnotator()
+	if err := a.LoadFile("customer", filepath.Join("testdata", "customers.txt")); err != nil {
+		t.Fatal(err)
+	}
+	entries := `
+193.0.0.0/21
+193.0.6.0/24 ripe-office
+10.0.0.0/8   # private
+2001:67c:2e8:22::c100:68b
+`
+	if err := a.Load("scope", "scope.txt", strings.NewReader(entries)); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestTags(t *testing.T) {
+	a := newTestAnnotator(t)
+	if got := a.Len(); got != 8 {
+		t.Errorf("Len = %d, want 8", got)
+	}
+
+	tests := []struct {
+		ipAddr string
+		want   []string
+	}{
+		// the overlapping CIDRs of both files and the single IP
+		{"193.0.6.139", []string{"customer", "ripe-office", "scope", "vip"}},
+		{"193.0.6.140", []string{"customer", "ripe-office", "scope"}},
+		{"193.0.7.255", []string{"customer", "scope"}},
+		{"193.0.8.0", []string{"customer"}},
+		{"193.0.255.255", []string{"customer"}},
+		{"193.1.0.0", nil},
+		{"192.255.255.255", nil},
+		{"10.255.255.255", []string{"scope"}},
+		{"11.0.0.0", nil},
+		// IPv4-mapped entries and addresses are IPv4
+		{"192.0.2.1", []string{"customer"}},
+		{"::ffff:193.0.8.1", []string{"customer"}},
+		{"[2001:67c:2e8:22::c100:68b]", []string{"customer", "scope"}},
+		{"2001:67c:2e8:22::c100:68c", []string{"customer"}},
+		{"2001:67c:2e8:22::c100:68b%eth0", []string{"customer", "scope"}},
+		{"2001:67c:2e9::1", nil},
+		{"not an IP", nil},
+	}
+
+	for _, tt := range tests {
+		if got := a.Tags(tt.ipAddr); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tags(%s) = %q, want %q", tt.ipAddr, got, tt.want)
+		}
+		if got := a.Covers(tt.ipAddr); got != (len(tt.want) > 0) {
+			t.Errorf("Covers(%s) = %v", tt.ipAddr, got)
+		}
+	}
+}
+
+func TestTagsUnique(t *testing.T) {
+	a := NewAnnotator()
+	if err := a.Load("scope", "scope.txt", strings.NewReader("193.0.0.0/16\n193.0.0.0/21\n193.0.6.139\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Tags("193.0.6.139"); !reflect.DeepEqual(got, []string{"scope"}) {
+		t.Errorf("Tags = %q, want the label once", got)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	a := NewAnnotator()
+	if got := a.Tags("193.0.6.139"); got != nil {
+		t.Errorf("Tags = %q without entries", got)
+	}
+	if a.Covers("193.0.6.139") {
+		t.Error("Covers without entries")
+	}
+	if err := a.Load("scope", "comments.txt", strings.NewReader("# nothing but comments\n\n   \n")); err != nil || a.Len() != 0 {
+		t.Errorf("Load = %v with %d entries", err, a.Len())
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries string
+		want    string
+	}{
+		{"invalid address", "193.0.0.0/16\nnot-an-ip\n", "scope.txt:2: "},
+		{"invalid prefix length", "# comment\n\n193.0.0.0/33\n", "scope.txt:3: "},
+		{"too many fields", "193.0.0.0/16\n193.0.6.139 vip extra\n", `scope.txt:2: expected an IP address or CIDR and an optional label, got "193.0.6.139 vip extra"`},
+		{"range", "193.0.0.0-193.0.7.255\n", "scope.txt:1: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAnnotator()
+			err := a.Load("scope", "scope.txt", strings.NewReader(tt.entries))
+			if err == nil || !strings.HasPrefix(err.Error(), "annotate: "+tt.want) {
+				t.Errorf("Load error = %v, want it to start with %q", err, "annotate: "+tt.want)
+			}
+			// nothing of a failed file is loaded
+			if a.Len() != 0 || a.Covers("193.0.6.139") {
+				t.Errorf("%d entries loaded from a failed file", a.Len())
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	a := newTestAnnotator(t)
+
+	path := filepath.Join("testdata", "malformed.txt")
+	err := a.LoadFile("malformed", path)
+	if err == nil || !strings.HasPrefix(err.Error(), "annotate: "+path+":4: ") {
+		t.Errorf("LoadFile error = %v, want the file and line", err)
+	}
+	if err := a.LoadFile("missing", filepath.Join("testdata", "missing.txt")); err == nil {
+		t.Error("LoadFile of a missing file succeeded")
+	}
+
+	// the entries loaded before are kept
+	if got := a.Tags("193.0.8.0"); !reflect.DeepEqual(got, []string{"customer"}) {
+		t.Errorf("Tags = %q after failed loads, want customer", got)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	tests := []struct {
+		s         string
+		wantStart string
+		wantEnd   string
+	}{
+		// a single IP address is an entry of one address
+		{"193.0.6.139", "193.0.6.139", "193.0.6.139"},
+		{"193.0.6.139/32", "193.0.6.139", "193.0.6.139"},
+		{"2001:67c:2e8:22::c100:68b", "2001:67c:2e8:22::c100:68b", "2001:67c:2e8:22::c100:68b"},
+		{"::ffff:193.0.6.139", "193.0.6.139", "193.0.6.139"},
+		// a CIDR is the range of all its addresses, masked
+		{"193.0.0.0/21", "193.0.0.0", "193.0.7.255"},
+		{"193.0.6.139/21", "193.0.0.0", "193.0.7.255"},
+		{"193.0.6.128/25", "193.0.6.128", "193.0.6.255"},
+		{"0.0.0.0/0", "0.0.0.0", "255.255.255.255"},
+		{"2001:67c:2e8::/48", "2001:67c:2e8::", "2001:67c:2e8:ffff:ffff:ffff:ffff:ffff"},
+		{"::ffff:192.0.2.0/120", "192.0.2.0", "192.0.2.255"},
+	}
+
+	for _, tt := range tests {
+		prefix, err := parsePrefix(tt.s)
+		if err != nil {
+			t.Errorf("parsePrefix(%q): %v", tt.s, err)
+			continue
+		}
+		if start, end := prefix.Addr().String(), lastAddr(prefix).String(); start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("parsePrefix(%q) = %s - %s, want %s - %s", tt.s, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+
+	for _, s := range []string{"", "193.0.6", "193.0.6.139/", "193.0.6.139/33", "2001:db8::/129", "193.0.6.0-193.0.6.255"} {
+		if _, err := parsePrefix(s); err == nil {
+			t.Errorf("parsePrefix(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package annotate
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// interval is an inclusive range of addresses carrying a single label.
+type interval struct {
+	start netip.Addr
+	end   netip.Addr
+	label string
+}
+
+func (iv interval) contains(addr netip.Addr) bool {
+	return iv.start.Compare(addr) <= 0 && addr.Compare(iv.end) <= 0
+}
+
+// node is a node of an augmented interval tree, maxEnd holds the highest end address in its subtree.
+type node struct {
+	interval
+	maxEnd      netip.Addr
+	left, right *node
+}
+
+// buildTree builds a balanced interval tree from the given intervals.
+func buildTree(intervals []interval) *node {
+	sorted := make([]interval, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].start.Less(sorted[j].start)
+	})
+
+	return buildNode(sorted)
+}
+
+func buildNode(intervals []interval) *node {
+	if len(intervals) == 0 {
+		return nil
+	}
+
+	mid := len(intervals) / 2
+	n := &node{
+		interval: intervals[mid],
+		maxEnd:   intervals[mid].end,
+		left:     buildNode(intervals[:mid]),
+		right:    buildNode(intervals[mid+1:]),
+	}
+
+	if n.left != nil && n.maxEnd.Less(n.left.maxEnd) {
+		n.maxEnd = n.left.maxEnd
+	}
+	if n.right != nil && n.maxEnd.Less(n.right.maxEnd) {
+		n.maxEnd = n.right.maxEnd
+	}
+
+	return n
+}
+
+// search appends the labels of every interval containing addr to labels.
+func (n *node) search(addr netip.Addr, labels []string) []string {
+	if n == nil || n.maxEnd.Less(addr) {
+		return labels
+	}
+
+	labels = n.left.search(addr, labels)
+
+	if addr.Less(n.start) {
+		// everything to the right starts even later
+		return labels
+	}
+
+	if n.contains(addr) {
+		labels = append(labels, n.label)
+	}
+
+	return n.right.search(addr, labels)
+}
@@ -0,0 +1,105 @@
+package annotate
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestSearch compares searching the interval tree with checking every interval, for overlapping
+// and nested intervals of both address families.
+func TestSearch(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	addr4 := func() netip.Addr {
+		// a small range, so intervals overlap
+		return netip.AddrFrom4([4]byte{193, 0, byte(random.Intn(4)), byte(random.Intn(256))})
+	}
+	addr6 := func() netip.Addr {
+		return netip.AddrFrom16([16]byte{0x20, 0x01, 0x0d, 0xb8, 15: byte(random.Intn(256))})
+	}
+
+	var intervals []interval
+	for i := 0; i < 200; i++ {
+		addr := addr4
+		if i%4 == 0 {
+			addr = addr6
+		}
+		start, end := addr(), addr()
+		if end.Less(start) {
+			start, end = end, start
+		}
+		intervals = append(intervals, interval{start: start, end: end, label: string(rune('a' + i%26))})
+	}
+	tree := buildTree(intervals)
+
+	for i := 0; i < 1000; i++ {
+		addr := addr4()
+		if i%4 == 0 {
+			addr = addr6()
+		}
+
+		var want []string
+		for _, iv := range intervals {
+			if iv.contains(addr) {
+				want = append(want, iv.label)
+			}
+		}
+		got := tree.search(addr, nil)
+		sort.Strings(want)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("search(%s) = %q, want %q", addr, got, want)
+		}
+	}
+}
+
+func TestSearchEdges(t *testing.T) {
+	iv := func(start, end, label string) interval {
+		return interval{start: netip.MustParseAddr(start), end: netip.MustParseAddr(end), label: label}
+	}
+	tree := buildTree([]interval{
+		iv("193.0.0.0", "193.0.255.255", "wide"),
+		iv("193.0.6.0", "193.0.6.255", "nested"),
+		iv("193.0.6.139", "193.0.6.139", "single"),
+		iv("193.0.7.0", "193.0.8.255", "overlapping"),
+		iv("2001:db8::", "2001:db8::ffff", "v6"),
+	})
+
+	tests := []struct {
+		addr string
+		want []string
+	}{
+		{"193.0.0.0", []string{"wide"}},
+		{"193.0.255.255", []string{"wide"}},
+		{"193.1.0.0", nil},
+		{"192.255.255.255", nil},
+		{"193.0.6.139", []string{"wide", "nested", "single"}},
+		{"193.0.6.255", []string{"wide", "nested"}},
+		{"193.0.7.0", []string{"wide", "overlapping"}},
+		{"193.0.8.255", []string{"wide", "overlapping"}},
+		{"2001:db8::1", []string{"v6"}},
+		{"2001:db8::1:0", nil},
+	}
+
+	for _, tt := range tests {
+		got := tree.search(netip.MustParseAddr(tt.addr), nil)
+		sort.Strings(got)
+		want := append([]string(nil), tt.want...)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("search(%s) = %q, want %q", tt.addr, got, want)
+		}
+	}
+
+	if got := (*node)(nil).search(netip.MustParseAddr("193.0.6.139"), nil); got != nil {
+		t.Errorf("search of an empty tree = %q", got)
+	}
+}
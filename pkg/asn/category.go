@@ -0,0 +1,150 @@
+package asn
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The categories an AS is classified in.
+const (
+	CategoryTransit    = "transit"
+	CategoryEyeball    = "eyeball"
+	CategoryContent    = "content"
+	CategoryEnterprise = "enterprise"
+	CategoryUnknown    = "unknown"
+)
+
+//go:embed categories.tsv
+var categoriesTSV []byte
+
+// peeringDBCategories maps the network types (info_type) of PeeringDB to categories
+var peeringDBCategories = map[string]string{
+	"nsp":                  CategoryTransit,
+	"cable/dsl/isp":        CategoryEyeball,
+	"content":              CategoryContent,
+	"enterprise":           CategoryEnterprise,
+	"educational/research": CategoryEnterprise,
+	"non-profit":           CategoryEnterprise,
+	"government":           CategoryEnterprise,
+}
+
+// Classifier maps AS numbers to the category of the network: transit, eyeball, content or enterprise.
+type Classifier struct {
+	categories map[uint32]string
+}
+
+// NewClassifier returns a classifier using the embedded dataset, a seed list of well-known networks.
+func NewClassifier() *Classifier {
+	c := &Classifier{categories: make(map[uint32]string)}
+	if _, err := c.parseTSV(categoriesTSV); err != nil {
+		panic(fmt.Sprintf("asn: embedded categories: %v", err))
+	}
+	return c
+}
+
+// Load adds the categories of the dataset at path, replacing the categories of AS numbers it
+// already had. The dataset is either a PeeringDB net dump (https://www.peeringdb.com/api/net) or
+// a TSV file of AS numbers and categories, like the embedded dataset. It returns the number of AS
+// numbers loaded.
+func (c *Classifier) Load(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var loaded int
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		loaded, err = c.parsePeeringDB(trimmed)
+	} else {
+		loaded, err = c.parseTSV(data)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("asn categories %s: %v", path, err)
+	}
+	return loaded, nil
+}
+
+// Category returns the category of the AS number s, or CategoryUnknown when it is not classified.
+func (c *Classifier) Category(s string) string {
+	number, err := Parse(s)
+	if err != nil {
+		return CategoryUnknown
+	}
+	if category, found := c.categories[number]; found {
+		return category
+	}
+	return CategoryUnknown
+}
+
+func (c *Classifier) parseTSV(data []byte) (int, error) {
+	loaded := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return 0, fmt.Errorf("line %d: expected an AS number and a category", line)
+		}
+		number, err := Parse(fields[0])
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %v", line, err)
+		}
+		category := strings.ToLower(fields[1])
+		if !isCategory(category) {
+			return 0, fmt.Errorf("line %d: unknown category %q", line, fields[1])
+		}
+
+		c.categories[number] = category
+		loaded++
+	}
+	return loaded, scanner.Err()
+}
+
+func (c *Classifier) parsePeeringDB(data []byte) (int, error) {
+	var dump struct {
+		Data []struct {
+			ASN      uint32 `json:"asn"`
+			InfoType string `json:"info_type"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return 0, err
+	}
+	if len(dump.Data) == 0 {
+		return 0, errors.New("no networks found")
+	}
+
+	loaded := 0
+	for _, network := range dump.Data {
+		// route servers and the like are left unclassified
+		if category, found := peeringDBCategories[strings.ToLower(network.InfoType)]; found && network.ASN != 0 {
+			c.categories[network.ASN] = category
+			loaded++
+		}
+	}
+	return loaded, nil
+}
+
+func isCategory(s string) bool {
+	switch s {
+	case CategoryTransit, CategoryEyeball, CategoryContent, CategoryEnterprise:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package asn
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCategory(t *testing.T) {
+	c := NewClassifier()
+
+	tests := []struct {
+		asn  string
+		want string
+	}{
+		{"1299", CategoryTransit},
+		{"AS3356", CategoryTransit},
+		{"as 7922", CategoryEyeball},
+		{"13335", CategoryContent},
+		// AS15169 in asdot notation
+		{"AS0.15169", CategoryContent},
+		{"3333", CategoryUnknown},
+		{"", CategoryUnknown},
+		{"not an AS", CategoryUnknown},
+	}
+	for _, tt := range tests {
+		if got := c.Category(tt.asn); got != tt.want {
+			t.Errorf("Category(%q) = %q, want %q", tt.asn, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCategories(t *testing.T) {
+	dir := t.TempDir()
+	peeringDB := filepath.Join(dir, "net.json")
+	if err := os.WriteFile(peeringDB, []byte(`{"data": [
+		{"asn": 1136, "info_type": "Cable/DSL/ISP"},
+		{"asn": 1103, "info_type": "Educational/Research"},
+		{"asn": 3333, "info_type": "Non-Profit"},
+		{"asn": 8283, "info_type": "NSP"},
+		{"asn": 2906, "info_type": "Enterprise"},
+		{"asn": 6777, "info_type": "Route Server"},
+		{"asn": 0, "info_type": "NSP"}
+	]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tsv := filepath.Join(dir, "categories.tsv")
+	if err := os.WriteFile(tsv, []byte("# comment\n\nAS64496\tContent\n1136 transit\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewClassifier()
+	if loaded, err := c.Load(peeringDB); err != nil || loaded != 5 {
+		t.Fatalf("Load(PeeringDB) = %d, %v, want 5", loaded, err)
+	}
+	if loaded, err := c.Load(tsv); err != nil || loaded != 2 {
+		t.Fatalf("Load(TSV) = %d, %v, want 2", loaded, err)
+	}
+
+	tests := []struct {
+		asn  string
+		want string
+	}{
+		// the last dataset loaded wins
+		{"1136", CategoryTransit},
+		{"1103", CategoryEnterprise},
+		{"3333", CategoryEnterprise},
+		{"8283", CategoryTransit},
+		// PeeringDB replaces the embedded category
+		{"2906", CategoryEnterprise},
+		{"6777", CategoryUnknown},
+		{"64496", CategoryContent},
+		{"1299", CategoryTransit},
+	}
+	for _, tt := range tests {
+		if got := c.Category(tt.asn); got != tt.want {
+			t.Errorf("Category(%q) = %q, want %q", tt.asn, got, tt.want)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"unknown-category.tsv": "3333\tcarrier\n",
+		"missing-category.tsv": "3333\n",
+		"invalid-asn.tsv":      "AS-RIPE\ttransit\n",
+		"empty.json":           `{"data": []}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Load(path); err == nil {
+			t.Errorf("Load(%s) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package asn
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrReserved is returned for AS0 and the other AS numbers that are never announced.
+	ErrReserved = errors.New("reserved AS number")
+	// ErrPrivate is returned by Normalize for private AS numbers when they are rejected.
+	ErrPrivate = errors.New("private AS number")
+)
+
+// Parse parses an AS number written as "3333", "AS3333" or "as 3333", or in the asdot notation
+// of 32-bit AS numbers ("AS1.10", RFC 5396).
+func Parse(s string) (uint32, error) {
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) >= 2 && strings.EqualFold(trimmed[:2], "AS") {
+		trimmed = strings.TrimSpace(trimmed[2:])
+	}
+
+	if high, low, found := strings.Cut(trimmed, "."); found {
+		highNumber, highErr := strconv.ParseUint(high, 10, 16)
+		lowNumber, lowErr := strconv.ParseUint(low, 10, 16)
+		if highErr != nil || lowErr != nil {
+			return 0, fmt.Errorf("invalid AS number %q", s)
+		}
+		return uint32(highNumber<<16 | lowNumber), nil
+	}
+
+	number, err := strconv.ParseUint(trimmed, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid AS number %q", s)
+	}
+	return uint32(number), nil
+}
+
+// Format returns the canonical form of an AS number: the decimal number without "AS" prefix
+// (asplain, RFC 5396), the form RipeSTAT and Team Cymru return.
+func Format(number uint32) string {
+	return strconv.FormatUint(uint64(number), 10)
+}
+
+// Normalize returns the canonical form of the AS number s, see Format. It fails for values that
+// are no AS number and for reserved AS numbers, and for private AS numbers when rejectPrivate is set.
+func Normalize(s string, rejectPrivate bool) (string, error) {
+	number, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if IsReserved(number) {
+		return "", fmt.Errorf("%w %d", ErrReserved, number)
+	}
+	if rejectPrivate && IsPrivate(number) {
+		return "", fmt.Errorf("%w %d", ErrPrivate, number)
+	}
+	return Format(number), nil
+}
+
+// IsPrivate reports whether number is reserved for private use (RFC 6996).
+func IsPrivate(number uint32) bool {
+	return (number >= 64512 && number <= 65534) || (number >= 4200000000 && number <= 4294967294)
+}
+
+// IsReserved reports whether number is never announced: AS0 (RFC 7607), AS_TRANS (RFC 6793),
+// the last 16-bit and 32-bit AS numbers (RFC 7300) and the documentation AS numbers (RFC 5398).
+func IsReserved(number uint32) bool {
+	switch {
+	case number == 0, number == 23456, number == 65535, number == 4294967295:
+		return true
+	case number >= 64496 && number <= 64511, number >= 65536 && number <= 65551:
+		return true
+	}
+	return false
+}
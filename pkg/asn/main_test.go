@@ -0,0 +1,199 @@
+package asn
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    uint32
+		wantErr bool
+	}{
+		{"3333", 3333, false},
+		{"AS3333", 3333, false},
+		{"as3333", 3333, false},
+		{"As3333", 3333, false},
+		{"AS 3333", 3333, false},
+		{" 3333\n", 3333, false},
+		{"\tAS3333 ", 3333, false},
+		{"0", 0, false},
+		{"4294967295", 4294967295, false},
+		// asdot
+		{"1.10", 65546, false},
+		{"AS1.10", 65546, false},
+		{"0.3333", 3333, false},
+		{"65535.65535", 4294967295, false},
+		{"", 0, true},
+		{"AS", 0, true},
+		{"ASN3333", 0, true},
+		{"-1", 0, true},
+		{"AS-3333", 0, true},
+		{"+3333", 0, true},
+		{"4294967296", 0, true},
+		{"99999999999", 0, true},
+		{"3333a", 0, true},
+		{"33 33", 0, true},
+		{"1.", 0, true},
+		{".10", 0, true},
+		{"1.2.3", 0, true},
+		{"65536.0", 0, true},
+		{"0.65536", 0, true},
+		{"-1.10", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.s)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Parse(%q) = %d, %v, want %d, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		number uint32
+		want   string
+	}{
+		{0, "0"},
+		{3333, "3333"},
+		{65546, "65546"},
+		{4294967295, "4294967295"},
+	}
+
+	for _, tt := range tests {
+		if got := Format(tt.number); got != tt.want {
+			t.Errorf("Format(%d) = %q, want %q", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestIsPrivateIsReserved(t *testing.T) {
+	tests := []struct {
+		number   uint32
+		private  bool
+		reserved bool
+	}{
+		{0, false, true},
+		{1, false, false},
+		{3333, false, false},
+		{23456, false, true},
+		// documentation
+		{64495, false, false},
+		{64496, false, true},
+		{64511, false, true},
+		{64512, true, false},
+		{65000, true, false},
+		{65534, true, false},
+		{65535, false, true},
+		// documentation
+		{65536, false, true},
+		{65551, false, true},
+		{65552, false, false},
+		{4199999999, false, false},
+		{4200000000, true, false},
+		{4294967294, true, false},
+		{4294967295, false, true},
+	}
+
+	for _, tt := range tests {
+		if got := IsPrivate(tt.number); got != tt.private {
+			t.Errorf("IsPrivate(%d) = %v, want %v", tt.number, got, tt.private)
+		}
+		if got := IsReserved(tt.number); got != tt.reserved {
+			t.Errorf("IsReserved(%d) = %v, want %v", tt.number, got, tt.reserved)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		s             string
+		rejectPrivate bool
+		want          string
+		wantErr       error
+	}{
+		{"AS3333", false, "3333", nil},
+		{"as3333", true, "3333", nil},
+		{" 3333 ", true, "3333", nil},
+		{"1.100", true, "65636", nil},
+		// asdot of a documentation AS number
+		{"1.10", true, "", ErrReserved},
+		{"AS0.3333", true, "3333", nil},
+		{"AS64512", false, "64512", nil},
+		{"AS64512", true, "", ErrPrivate},
+		{"4200000000", true, "", ErrPrivate},
+		{"4294967294", false, "4294967294", nil},
+		{"0", false, "", ErrReserved},
+		{"AS23456", false, "", ErrReserved},
+		{"65535", false, "", ErrReserved},
+		{"4294967295", false, "", ErrReserved},
+		{"64496", true, "", ErrReserved},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.s, tt.rejectPrivate)
+		if got != tt.want || !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+			t.Errorf("Normalize(%q, %v) = %q, %v, want %q, %v", tt.s, tt.rejectPrivate, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	for _, s := range []string{"", "AS", "-1", "4294967296", "AS 1.2.3"} {
+		if got, err := Normalize(s, false); err == nil || errors.Is(err, ErrReserved) || errors.Is(err, ErrPrivate) {
+			t.Errorf("Normalize(%q) = %q, %v, want a parse error", s, got, err)
+		}
+	}
+}
+
+// TestNormalizeJSON locks in the asplain form of normalized AS numbers in the JSON output, which
+// reads back to the same AS number and normalizes to itself.
+func TestNormalizeJSON(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"AS3333", `"asn":"3333"`},
+		{"as 3333", `"asn":"3333"`},
+		{"0.3333", `"asn":"3333"`},
+		{"AS1.100", `"asn":"65636"`},
+		{"4294967294", `"asn":"4294967294"`},
+	}
+
+	for _, tt := range tests {
+		normalized, err := Normalize(tt.s, false)
+		if err != nil {
+			t.Fatalf("Normalize(%q): %v", tt.s, err)
+		}
+
+		data, err := json.Marshal(types.EnrichInfo{Ip: "193.0.6.139", Asn: normalized})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("JSON of %q = %s, want %s", tt.s, data, tt.want)
+		}
+
+		var info types.EnrichInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := Parse(tt.s)
+		if got, err := Parse(info.Asn); err != nil || got != want {
+			t.Errorf("Parse of the JSON Asn %q = %d, %v, want %d", info.Asn, got, err, want)
+		}
+		if again, err := Normalize(info.Asn, false); err != nil || again != info.Asn {
+			t.Errorf("Normalize(%q) = %q, %v, want it unchanged", info.Asn, again, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package bgpview
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	API_URL = "https://api.bgpview.io/"
+
+	// DefaultMaxResponseSize bounds a single response, the prefixes of an IP address are a few kB
+	DefaultMaxResponseSize = 1 << 20
+)
+
+// Prefix is a prefix announcing an IP address, with its origin AS.
+type Prefix struct {
+	Prefix      string `json:"prefix"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CountryCode string `json:"country_code"`
+	Asn         struct {
+		Asn         int    `json:"asn"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		CountryCode string `json:"country_code"`
+	} `json:"asn"`
+}
+
+// IP is what BGPView knows about an IP address.
+type IP struct {
+	Ip            string   `json:"ip"`
+	Prefixes      []Prefix `json:"prefixes"`
+	RIRAllocation struct {
+		CountryCode string `json:"country_code"`
+		Prefix      string `json:"prefix"`
+	} `json:"rir_allocation"`
+}
+
+type Client struct {
+	BaseURL string
+	// MaxResponseSize bounds a response in bytes, zero means DefaultMaxResponseSize
+	MaxResponseSize int64
+
+	httpClient *http.Client
+}
+
+func NewBGPViewClient() *Client {
+	return &Client{
+		BaseURL:    API_URL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// LookupIP returns the prefixes announcing ipAddr and its allocation.
+func (c *Client) LookupIP(ctx context.Context, ipAddr string) (IP, error) {
+	requestURL := strings.TrimSuffix(c.BaseURL, "/") + "/ip/" + url.PathEscape(ipAddr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return IP{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return IP{}, fmt.Errorf("bgpview: %v", err)
+	}
+	defer resp.Body.Close()
+
+	limit := c.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return IP{}, fmt.Errorf("bgpview: %v", err)
+	}
+	if int64(len(body)) > limit {
+		return IP{}, fmt.Errorf("bgpview: response for %s larger than %d bytes", ipAddr, limit)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return IP{}, fmt.Errorf("bgpview: rate limited, retry after %q", resp.Header.Get("Retry-After"))
+	}
+
+	var envelope struct {
+		Status        string          `json:"status"`
+		StatusMessage string          `json:"status_message"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return IP{}, fmt.Errorf("bgpview: failed to unmarshal %s (status %d): %v", ipAddr, resp.StatusCode, err)
+	}
+	if envelope.Status != "ok" {
+		return IP{}, fmt.Errorf("bgpview: lookup of %s failed (status %d): %s", ipAddr, resp.StatusCode, envelope.StatusMessage)
+	}
+
+	var ip IP
+	if err := json.Unmarshal(envelope.Data, &ip); err != nil {
+		return IP{}, fmt.Errorf("bgpview: failed to unmarshal %s: %v", ipAddr, err)
+	}
+	return ip, nil
+}
+
+// MostSpecific returns the longest of the prefixes announcing the IP address.
+func (ip IP) MostSpecific() (Prefix, bool) {
+	best, bestBits := -1, -1
+	for i, p := range ip.Prefixes {
+		prefix, err := netip.ParsePrefix(p.Prefix)
+		if err != nil {
+			continue
+		}
+		if prefix.Bits() > bestBits {
+			best, bestBits = i, prefix.Bits()
+		}
+	}
+	if best < 0 {
+		return Prefix{}, false
+	}
+	return ip.Prefixes[best], true
+}
+
+// Holder returns the holder of the origin AS in the form of the RipeSTAT as-overview, e.g.
+// "CLOUDFLARENET - Cloudflare, Inc., US".
+func (p Prefix) Holder() string {
+	holder := p.Asn.Name
+	if p.Asn.Description != "" && p.Asn.Description != holder {
+		if holder != "" {
+			holder += " - "
+		}
+		holder += p.Asn.Description
+	}
+	if holder != "" && p.Asn.CountryCode != "" {
+		holder += ", " + p.Asn.CountryCode
+	}
+	return holder
+}
+
+// Origin returns the origin AS, or nothing when the prefix has none.
+func (p Prefix) Origin() string {
+	if p.Asn.Asn == 0 {
+		return ""
+	}
+	return strconv.Itoa(p.Asn.Asn)
+}
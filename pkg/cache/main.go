@@ -0,0 +1,200 @@
+package cache
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// FormatVersion is bumped whenever the layout of the cache file or of the cached records changes
+// in a way older versions can't read.
+const FormatVersion = 1
+
+const DefaultTTL = 7 * 24 * time.Hour
+
+// ErrIncompatibleVersion is returned when opening a cache written by an incompatible version.
+var ErrIncompatibleVersion = errors.New("incompatible cache version")
+
+type entry struct {
+	StoredAt time.Time        `json:"stored_at"`
+	Info     types.EnrichInfo `json:"info"`
+	// TTL replaces the ttl of the cache for this entry when set, see PutWithTTL
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+type file struct {
+	Version int              `json:"version"`
+	Entries map[string]entry `json:"entries"`
+}
+
+// Cache is a persistent cache of enrichment results keyed by IP address. It is loaded into memory
+// when opened and written back on Close, unless it was opened read-only.
+type Cache struct {
+	path     string
+	ttl      time.Duration
+	readOnly bool
+
+	mu      sync.RWMutex
+	entries map[string]entry
+	dirty   bool
+
+	hits   int64
+	misses int64
+}
+
+// Open loads the cache at path, a missing file yields an empty cache. Entries older than ttl are
+// treated as missing.
+func Open(path string, ttl time.Duration, readOnly bool) (*Cache, error) {
+	c := &Cache{
+		path:     path,
+		ttl:      ttl,
+		readOnly: readOnly,
+		entries:  make(map[string]entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cache: %v", err)
+	}
+
+	var contents file
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("cache: %s is not a cache file or is corrupt (%v), delete it to rebuild the cache", path, err)
+	}
+
+	if contents.Version != FormatVersion {
+		return nil, fmt.Errorf("cache: %w: %s has format version %d, this version uses %d; delete it to rebuild the cache",
+			ErrIncompatibleVersion, path, contents.Version, FormatVersion)
+	}
+
+	if contents.Entries != nil {
+		c.entries = contents.Entries
+	}
+
+	return c, nil
+}
+
+// Get returns the cached enrichment of ipAddr, if it is fresh.
+func (c *Cache) Get(ipAddr string) (types.EnrichInfo, bool) {
+	c.mu.RLock()
+	cached, found := c.entries[ipAddr]
+	c.mu.RUnlock()
+
+	if !found || c.expired(cached) {
+		atomic.AddInt64(&c.misses, 1)
+		return types.EnrichInfo{}, false
+	}
+
+	atomic.AddInt64(&c.hits, 1)
+	return cached.Info, true
+}
+
+// Put stores info, it is a no-op for read-only caches.
+func (c *Cache) Put(info types.EnrichInfo) {
+	c.put(entry{StoredAt: time.Now(), Info: info})
+}
+
+// PutWithTTL stores info to stay fresh for ttl instead of the ttl of the cache, e.g. for as long
+// as the sources of info said it may be cached. It is a no-op for read-only caches and ttl <= 0.
+func (c *Cache) PutWithTTL(info types.EnrichInfo, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.put(entry{StoredAt: time.Now(), Info: info, TTL: ttl})
+}
+
+func (c *Cache) put(e entry) {
+	if c.readOnly {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[e.Info.Ip] = e
+	c.dirty = true
+}
+
+// expired reports whether cached is older than its ttl.
+func (c *Cache) expired(cached entry) bool {
+	ttl := c.ttl
+	if cached.TTL > 0 {
+		ttl = cached.TTL
+	}
+	return ttl > 0 && time.Since(cached.StoredAt) > ttl
+}
+
+// Stats returns the number of cache hits and misses so far.
+func (c *Cache) Stats() (hits int, misses int) {
+	return int(atomic.LoadInt64(&c.hits)), int(atomic.LoadInt64(&c.misses))
+}
+
+// Close saves the cache, see Save.
+func (c *Cache) Close() error {
+	return c.Save()
+}
+
+// Save writes the cache back to disk when it changed. Expired entries are dropped. The file is
+// replaced atomically, so concurrent readers never see a partially written cache. The cache stays
+// usable, so long running processes can save it periodically.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.readOnly || !c.dirty {
+		return nil
+	}
+
+	contents := file{
+		Version: FormatVersion,
+		Entries: make(map[string]entry, len(c.entries)),
+	}
+	for ipAddr, cached := range c.entries {
+		if c.expired(cached) {
+			continue
+		}
+		contents.Entries[ipAddr] = cached
+	}
+
+	data, err := json.Marshal(contents)
+	if err != nil {
+		return fmt.Errorf("cache: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("cache: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cache: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cache: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("cache: %v", err)
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,173 @@
+package checkpoint
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+const (
+	DefaultFlushEvery    = 100
+	DefaultFlushInterval = 30 * time.Second
+)
+
+// Load reads the enrichment results recorded in the checkpoint at path, one JSON record per line.
+// A missing file yields no results. A truncated final record, left by a run that died while
+// writing it, is skipped.
+func Load(path string) ([]types.EnrichInfo, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %v", err)
+	}
+	defer file.Close()
+
+	var results []types.EnrichInfo
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// a final line without newline was not completely written
+			return results, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint: %v", err)
+		}
+
+		var result types.EnrichInfo
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, fmt.Errorf("checkpoint: %s:%d: %v", path, lineNumber, err)
+		}
+		results = append(results, result)
+	}
+}
+
+// Writer appends enrichment results to a checkpoint. Results are buffered and made durable every
+// FlushEvery results or FlushInterval, whichever comes first, and on Close.
+type Writer struct {
+	FlushEvery    int
+	FlushInterval time.Duration
+
+	mu        sync.Mutex
+	file      *os.File
+	buf       *bufio.Writer
+	pending   int
+	lastFlush time.Time
+}
+
+// Open opens the checkpoint at path for appending, creating it when needed. A truncated final
+// record is cut off first, so new records start on a line of their own.
+func Open(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint: %v", err)
+	}
+
+	end, err := completeRecordsEnd(file)
+	if err == nil {
+		err = file.Truncate(end)
+	}
+	if err == nil {
+		_, err = file.Seek(end, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("checkpoint: %v", err)
+	}
+
+	return &Writer{
+		FlushEvery:    DefaultFlushEvery,
+		FlushInterval: DefaultFlushInterval,
+		file:          file,
+		buf:           bufio.NewWriter(file),
+		lastFlush:     time.Now(),
+	}, nil
+}
+
+// completeRecordsEnd returns the offset just after the last newline in file.
+func completeRecordsEnd(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	const chunkSize = 4096
+	chunk := make([]byte, chunkSize)
+	for end := info.Size(); end > 0; {
+		start := end - chunkSize
+		if start < 0 {
+			start = 0
+		}
+		n, err := file.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+
+	return 0, nil
+}
+
+// Add appends result to the checkpoint.
+func (w *Writer) Add(result types.EnrichInfo) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("checkpoint: %v", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, err := w.buf.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("checkpoint: %v", err)
+	}
+	w.pending++
+
+	if w.pending >= w.FlushEvery || time.Since(w.lastFlush) >= w.FlushInterval {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *Writer) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("checkpoint: %v", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("checkpoint: %v", err)
+	}
+	w.pending = 0
+	w.lastFlush = time.Now()
+	return nil
+}
+
+// Close flushes the buffered results and closes the checkpoint.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.flush()
+	if closeErr := w.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("checkpoint: %v", closeErr)
+	}
+	return err
+}
@@ -0,0 +1,378 @@
+package cloud
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderAWS   = "AWS"
+	ProviderGCP   = "GCP"
+	ProviderAzure = "Azure"
+)
+
+// DefaultTTL is how long a downloaded range file is used before it is downloaded again.
+const DefaultTTL = 24 * time.Hour
+
+// DefaultSources are the official range files of the providers publishing them at a stable URL.
+// Azure publishes its service tags file under a weekly changing URL, so it has to be configured.
+var DefaultSources = map[string]string{
+	ProviderAWS: "https://ip-ranges.amazonaws.com/ip-ranges.json",
+	ProviderGCP: "https://www.gstatic.com/ipranges/cloud.json",
+}
+
+// maxRangeFileSize bounds a downloaded range file, the Azure service tags file is the largest by far.
+const maxRangeFileSize = 64 << 20
+
+// Range is a prefix published by a cloud provider. Region is empty for global prefixes.
+type Range struct {
+	Prefix   netip.Prefix
+	Provider string
+	Region   string
+}
+
+type index struct {
+	// ranges by prefix length, separately for IPv4 and IPv6
+	v4 [33]map[netip.Prefix]Range
+	v6 [129]map[netip.Prefix]Range
+}
+
+// Ranges holds the published prefixes of cloud providers, indexed for longest-prefix matching.
+// Range files are downloaded to CacheDir and downloaded again once they are older than TTL.
+type Ranges struct {
+	CacheDir string
+	TTL      time.Duration
+
+	httpClient *http.Client
+	sources    map[string]string
+
+	mu    sync.RWMutex
+	index *index
+}
+
+func NewRanges(cacheDir string) *Ranges {
+	return &Ranges{
+		CacheDir:   cacheDir,
+		TTL:        DefaultTTL,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		sources:    make(map[string]string),
+		index:      &index{},
+	}
+}
+
+// AddSource sets the range file of provider, which can either be an http(s) URL or a local file.
+func (r *Ranges) AddSource(provider, source string) error {
+	if _, err := parserFor(provider); err != nil {
+		return err
+	}
+	r.sources[canonicalProvider(provider)] = source
+	return nil
+}
+
+// Refresh loads the range files of all sources, downloading the ones missing from CacheDir or
+// older than TTL. A failed download falls back to the stale cached file when there is one. The
+// loaded ranges replace the ranges loaded before only when all sources could be loaded.
+func (r *Ranges) Refresh(ctx context.Context) (int, error) {
+	idx := &index{}
+	loaded := 0
+
+	providers := make([]string, 0, len(r.sources))
+	for provider := range r.sources {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	for _, provider := range providers {
+		parse, _ := parserFor(provider)
+
+		data, err := r.read(ctx, provider, r.sources[provider])
+		if err != nil {
+			return 0, fmt.Errorf("cloud: %s: %v", provider, err)
+		}
+
+		ranges, err := parse(data)
+		if err != nil {
+			return 0, fmt.Errorf("cloud: %s: %v", provider, err)
+		}
+
+		for _, rng := range ranges {
+			idx.add(rng)
+		}
+		loaded += len(ranges)
+	}
+
+	r.mu.Lock()
+	r.index = idx
+	r.mu.Unlock()
+
+	return loaded, nil
+}
+
+// Lookup returns the range with the longest prefix covering ipAddr.
+func (r *Ranges) Lookup(ipAddr string) (Range, bool) {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return Range{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	r.mu.RLock()
+	idx := r.index
+	r.mu.RUnlock()
+
+	if addr.Is4() {
+		return lookup(idx.v4[:], addr)
+	}
+	return lookup(idx.v6[:], addr)
+}
+
+func (r *Ranges) read(ctx context.Context, provider, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+
+	if r.CacheDir == "" {
+		return r.download(ctx, source)
+	}
+
+	cached := filepath.Join(r.CacheDir, strings.ToLower(provider)+".json")
+	stat, statErr := os.Stat(cached)
+	if statErr == nil && time.Since(stat.ModTime()) < r.TTL {
+		return os.ReadFile(cached)
+	}
+
+	data, err := r.download(ctx, source)
+	if err != nil {
+		if statErr == nil {
+			// a stale range file beats no range file
+			return os.ReadFile(cached)
+		}
+		return nil, err
+	}
+
+	if err := os.MkdirAll(r.CacheDir, 0o755); err != nil {
+		return nil, err
+	}
+	tmp := cached + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, cached); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (r *Ranges) download(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %q returned status %d", source, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRangeFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRangeFileSize {
+		return nil, fmt.Errorf("%q is larger than %d bytes", source, maxRangeFileSize)
+	}
+
+	return data, nil
+}
+
+func lookup(byLength []map[netip.Prefix]Range, addr netip.Addr) (Range, bool) {
+	for bits := len(byLength) - 1; bits >= 0; bits-- {
+		if byLength[bits] == nil {
+			continue
+		}
+
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+
+		if rng, ok := byLength[bits][prefix]; ok {
+			return rng, true
+		}
+	}
+
+	return Range{}, false
+}
+
+func (idx *index) add(rng Range) {
+	byLength := idx.v6[:]
+	if rng.Prefix.Addr().Is4() {
+		byLength = idx.v4[:]
+	}
+
+	bits := rng.Prefix.Bits()
+	if byLength[bits] == nil {
+		byLength[bits] = make(map[netip.Prefix]Range)
+	}
+
+	// a prefix listed for several regions or services keeps its first region
+	if existing, found := byLength[bits][rng.Prefix]; found && existing.Region != "" {
+		return
+	}
+	byLength[bits][rng.Prefix] = rng
+}
+
+func canonicalProvider(provider string) string {
+	switch strings.ToLower(provider) {
+	case "aws":
+		return ProviderAWS
+	case "gcp":
+		return ProviderGCP
+	case "azure":
+		return ProviderAzure
+	}
+	return provider
+}
+
+func parserFor(provider string) (func([]byte) ([]Range, error), error) {
+	switch canonicalProvider(provider) {
+	case ProviderAWS:
+		return parseAWS, nil
+	case ProviderGCP:
+		return parseGCP, nil
+	case ProviderAzure:
+		return parseAzure, nil
+	}
+	return nil, fmt.Errorf("cloud: unknown provider %q, expected aws, gcp or azure", provider)
+}
+
+func parsePrefix(s string) (netip.Prefix, bool) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix.Masked(), true
+}
+
+// normalizeRegion drops the placeholders the providers use for prefixes not bound to a region.
+func normalizeRegion(region string) string {
+	switch strings.ToLower(region) {
+	case "global", "":
+		return ""
+	}
+	return region
+}
+
+// parseAWS parses the ip-ranges.json format, see https://docs.aws.amazon.com/vpc/latest/userguide/aws-ip-ranges.html
+func parseAWS(data []byte) ([]Range, error) {
+	var file struct {
+		Prefixes []struct {
+			IPPrefix string `json:"ip_prefix"`
+			Region   string `json:"region"`
+		} `json:"prefixes"`
+		IPv6Prefixes []struct {
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+		} `json:"ipv6_prefixes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	var ranges []Range
+	for _, p := range file.Prefixes {
+		if prefix, ok := parsePrefix(p.IPPrefix); ok {
+			ranges = append(ranges, Range{Prefix: prefix, Provider: ProviderAWS, Region: normalizeRegion(p.Region)})
+		}
+	}
+	for _, p := range file.IPv6Prefixes {
+		if prefix, ok := parsePrefix(p.IPv6Prefix); ok {
+			ranges = append(ranges, Range{Prefix: prefix, Provider: ProviderAWS, Region: normalizeRegion(p.Region)})
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, errors.New("no prefixes found")
+	}
+	return ranges, nil
+}
+
+// parseGCP parses the cloud.json format, see https://cloud.google.com/compute/docs/faq#find_ip_range
+func parseGCP(data []byte) ([]Range, error) {
+	var file struct {
+		Prefixes []struct {
+			IPv4Prefix string `json:"ipv4Prefix"`
+			IPv6Prefix string `json:"ipv6Prefix"`
+			Scope      string `json:"scope"`
+		} `json:"prefixes"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	var ranges []Range
+	for _, p := range file.Prefixes {
+		for _, s := range []string{p.IPv4Prefix, p.IPv6Prefix} {
+			if prefix, ok := parsePrefix(s); ok {
+				ranges = append(ranges, Range{Prefix: prefix, Provider: ProviderGCP, Region: normalizeRegion(p.Scope)})
+			}
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, errors.New("no prefixes found")
+	}
+	return ranges, nil
+}
+
+// parseAzure parses the service tags file, see https://www.microsoft.com/download/details.aspx?id=56519
+func parseAzure(data []byte) ([]Range, error) {
+	var file struct {
+		Values []struct {
+			Properties struct {
+				Region          string   `json:"region"`
+				AddressPrefixes []string `json:"addressPrefixes"`
+			} `json:"properties"`
+		} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	var ranges []Range
+	for _, value := range file.Values {
+		for _, s := range value.Properties.AddressPrefixes {
+			if prefix, ok := parsePrefix(s); ok {
+				ranges = append(ranges, Range{Prefix: prefix, Provider: ProviderAzure, Region: normalizeRegion(value.Properties.Region)})
+			}
+		}
+	}
+
+	if len(ranges) == 0 {
+		return nil, errors.New("no prefixes found")
+	}
+	return ranges, nil
+}
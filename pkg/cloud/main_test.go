@@ -0,0 +1,130 @@
+package cloud
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestRanges returns ranges loaded from the range files of testdata.
+func newTestRanges(t *testing.T) *Ranges {
+	r := NewRanges("")
+	for provider, file := range map[string]string{"aws": "aws.json", "gcp": "gcp.json", "azure": "azure.json"} {
+		if err := r.AddSource(provider, filepath.Join("testdata", file)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestLookup(t *testing.T) {
+	r := newTestRanges(t)
+
+	tests := []struct {
+		ipAddr string
+		want   Range
+		found  bool
+	}{
+		// the longest prefix wins over the global one covering it
+		{"52.94.236.250", Range{Prefix: netip.MustParsePrefix("52.94.236.248/29"), Provider: ProviderAWS, Region: "us-east-1"}, true},
+		{"52.94.1.1", Range{Prefix: netip.MustParsePrefix("52.94.0.0/16"), Provider: ProviderAWS}, true},
+		{"3.5.141.7", Range{Prefix: netip.MustParsePrefix("3.5.140.0/22"), Provider: ProviderAWS, Region: "ap-northeast-2"}, true},
+		{"2600:1f14:fff::1", Range{Prefix: netip.MustParsePrefix("2600:1f14::/35"), Provider: ProviderAWS, Region: "us-west-2"}, true},
+		{"34.81.255.255", Range{Prefix: netip.MustParsePrefix("34.80.0.0/15"), Provider: ProviderGCP, Region: "asia-east1"}, true},
+		{"35.190.0.1", Range{Prefix: netip.MustParsePrefix("35.190.0.0/17"), Provider: ProviderGCP}, true},
+		{"[2600:1900:4000::1]", Range{Prefix: netip.MustParsePrefix("2600:1900:4000::/44"), Provider: ProviderGCP, Region: "europe-west1"}, true},
+		{"20.33.200.1", Range{Prefix: netip.MustParsePrefix("20.33.128.0/17"), Provider: ProviderAzure, Region: "westeurope"}, true},
+		{"20.33.1.1", Range{Prefix: netip.MustParsePrefix("20.33.0.0/16"), Provider: ProviderAzure}, true},
+		{"::ffff:13.69.1.1", Range{Prefix: netip.MustParsePrefix("13.69.0.0/17"), Provider: ProviderAzure, Region: "westeurope"}, true},
+		{"2603:1020:201::1", Range{Prefix: netip.MustParsePrefix("2603:1020:200::/46"), Provider: ProviderAzure, Region: "westeurope"}, true},
+		// misses
+		{"52.95.0.1", Range{}, false},
+		{"34.82.0.0", Range{}, false},
+		{"193.0.6.139", Range{}, false},
+		{"2001:67c:2e8:22::c100:68b", Range{}, false},
+		{"not an IP", Range{}, false},
+	}
+
+	for _, tt := range tests {
+		got, found := r.Lookup(tt.ipAddr)
+		if found != tt.found || got != tt.want {
+			t.Errorf("Lookup(%s) = %+v, %v, want %+v, %v", tt.ipAddr, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestRefreshDownload(t *testing.T) {
+	aws, err := os.ReadFile(filepath.Join("testdata", "aws.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var requests, failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(aws)
+	}))
+	defer server.Close()
+
+	r := NewRanges(t.TempDir())
+	if err := r.AddSource("AWS", server.URL+"/ip-ranges.json"); err != nil {
+		t.Fatal(err)
+	}
+	refresh := func() {
+		t.Helper()
+		if n, err := r.Refresh(context.Background()); err != nil || n != 5 {
+			t.Fatalf("Refresh = %d, %v, want the 5 valid prefixes", n, err)
+		}
+		if _, found := r.Lookup("52.94.236.250"); !found {
+			t.Error("Lookup of an AWS address failed after Refresh")
+		}
+	}
+
+	refresh()
+	// the cached file is used while it is fresh, ...
+	refresh()
+	if atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("downloaded %d times, want the cached file used", requests)
+	}
+
+	// ... and when it is stale but the download fails
+	r.TTL = time.Nanosecond
+	atomic.StoreInt32(&failing, 1)
+	refresh()
+	if atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("downloaded %d times, want another attempt for the stale file", requests)
+	}
+
+	// without a cached file a failed download fails the refresh
+	r.CacheDir = t.TempDir()
+	if _, err := r.Refresh(context.Background()); err == nil {
+		t.Error("Refresh succeeded without a range file")
+	}
+	if _, found := r.Lookup("52.94.236.250"); !found {
+		t.Error("a failed Refresh dropped the ranges loaded before")
+	}
+}
+
+func TestAddSourceUnknownProvider(t *testing.T) {
+	if err := NewRanges("").AddSource("oracle", "ranges.json"); err == nil {
+		t.Error("AddSource accepted an unknown provider")
+	}
+}
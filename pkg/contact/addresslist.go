@@ -0,0 +1,44 @@
+package contact
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// AddressList formats addresses as an RFC 5322 address-list, ready to paste into a To: header.
+// Addresses are deduplicated case-insensitively in order of appearance, the ones that don't
+// parse as an RFC 5322 address are dropped and returned as invalid.
+func AddressList(addresses []string) (list string, invalid []string) {
+	seen := make(map[string]struct{}, len(addresses))
+	formatted := make([]string, 0, len(addresses))
+
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		parsed, err := mail.ParseAddress(address)
+		if err != nil || !strings.Contains(parsed.Address, "@") {
+			invalid = append(invalid, address)
+			continue
+		}
+
+		key := strings.ToLower(parsed.Address)
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		// the display name is left out, the registries don't provide one worth addressing
+		formatted = append(formatted, (&mail.Address{Address: parsed.Address}).String())
+	}
+
+	return strings.Join(formatted, ", "), invalid
+}
@@ -0,0 +1,45 @@
+package contact
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddressList(t *testing.T) {
+	tests := []struct {
+		name        string
+		addresses   []string
+		want        string
+		wantInvalid []string
+	}{
+		{"none", nil, "", nil},
+		{"one", []string{"abuse@ripe.net"}, "<abuse@ripe.net>", nil},
+		{"several", []string{"abuse@ripe.net", "noc@ripe.net"}, "<abuse@ripe.net>, <noc@ripe.net>", nil},
+		// the first form of an address is kept
+		{"duplicates", []string{"abuse@ripe.net", "ABUSE@ripe.net", " abuse@ripe.net "}, "<abuse@ripe.net>", nil},
+		{"display name dropped", []string{"RIPE NCC <abuse@ripe.net>"}, "<abuse@ripe.net>", nil},
+		{"empty values", []string{"", "  "}, "", nil},
+		{
+			"valid and invalid mixed",
+			[]string{"abuse@ripe.net", "not an address", "noc@", "cert@ncsc.example", "abuse at ripe.net", "@ripe.net", "noc@ripe.net"},
+			"<abuse@ripe.net>, <cert@ncsc.example>, <noc@ripe.net>",
+			[]string{"not an address", "noc@", "abuse at ripe.net", "@ripe.net"},
+		},
+		{"only invalid", []string{"unknown", "n/a"}, "", []string{"unknown", "n/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, invalid := AddressList(tt.addresses)
+			if got != tt.want || !reflect.DeepEqual(invalid, tt.wantInvalid) {
+				t.Errorf("AddressList(%q) = %q, %q, want %q, %q", tt.addresses, got, invalid, tt.want, tt.wantInvalid)
+			}
+		})
+	}
+}
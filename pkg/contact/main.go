@@ -0,0 +1,79 @@
+package contact
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"strings"
+)
+
+const (
+	KindRole     = "role"
+	KindPersonal = "personal"
+)
+
+// DefaultRoleLocalParts are the local-parts of mailboxes that belong to a function rather than a person.
+var DefaultRoleLocalParts = []string{
+	"abuse", "admin", "cert", "contact", "csirt", "helpdesk", "hostmaster", "info", "ipadmin",
+	"lir", "ncc", "network", "noc", "office", "postmaster", "registry", "ripe", "security",
+	"servicedesk", "soc", "support", "sysadmin", "tech", "webmaster",
+}
+
+// Classifier tells role mailboxes like abuse@ or noc@ apart from personal addresses.
+type Classifier struct {
+	roles map[string]struct{}
+}
+
+// NewClassifier returns a Classifier treating the given local-parts as role mailboxes,
+// or DefaultRoleLocalParts when none are given.
+func NewClassifier(roleLocalParts ...string) *Classifier {
+	if len(roleLocalParts) == 0 {
+		roleLocalParts = DefaultRoleLocalParts
+	}
+
+	c := &Classifier{
+		roles: make(map[string]struct{}, len(roleLocalParts)),
+	}
+	for _, localPart := range roleLocalParts {
+		c.roles[strings.ToLower(strings.TrimSpace(localPart))] = struct{}{}
+	}
+
+	return c
+}
+
+// Classify returns KindRole when the local-part of address is a role local-part, optionally
+// followed by a separator or digits (abuse-nl@, noc24@, security.team@), and KindPersonal otherwise.
+func (c *Classifier) Classify(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return KindPersonal
+	}
+
+	localPart := strings.ToLower(address[:at])
+	if plus := strings.Index(localPart, "+"); plus >= 0 {
+		localPart = localPart[:plus]
+	}
+
+	if _, found := c.roles[localPart]; found {
+		return KindRole
+	}
+
+	for i := 1; i < len(localPart); i++ {
+		switch localPart[i] {
+		case '-', '_', '.', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			if _, found := c.roles[localPart[:i]]; found {
+				return KindRole
+			}
+		}
+	}
+
+	return KindPersonal
+}
+
+// IsRole reports whether address is a role mailbox.
+func (c *Classifier) IsRole(address string) bool {
+	return c.Classify(address) == KindRole
+}
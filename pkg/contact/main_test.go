@@ -0,0 +1,60 @@
+package contact
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"abuse@ripe.net", KindRole},
+		{"ABUSE@RIPE.NET", KindRole},
+		{"abuse-nl@example.net", KindRole},
+		{"noc24@example.net", KindRole},
+		{"security.team@example.net", KindRole},
+		{"soc_2@example.net", KindRole},
+		{"abuse+reports@example.net", KindRole},
+		{"john.doe@example.net", KindPersonal},
+		// a role local-part must be followed by a separator or digits
+		{"abuser@example.net", KindPersonal},
+		{"nocturnal@example.net", KindPersonal},
+		{"jane-abuse@example.net", KindPersonal},
+		{"abuse", KindPersonal},
+		{"", KindPersonal},
+	}
+
+	c := NewClassifier()
+	for _, tt := range tests {
+		if got := c.Classify(tt.address); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.address, got, tt.want)
+		}
+		if got := c.IsRole(tt.address); got != (tt.want == KindRole) {
+			t.Errorf("IsRole(%q) = %v", tt.address, got)
+		}
+	}
+}
+
+func TestClassifyCustomRoles(t *testing.T) {
+	c := NewClassifier(" Incident ", "cert")
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"incident@example.net", KindRole},
+		{"incident-response@example.net", KindRole},
+		{"cert@example.net", KindRole},
+		// the default role local-parts are replaced
+		{"abuse@example.net", KindPersonal},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(tt.address); got != tt.want {
+			t.Errorf("Classify(%q) = %s, want %s", tt.address, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package country
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	_ "embed"
+	"strconv"
+	"strings"
+)
+
+//go:embed iso3166.tsv
+var iso3166 string
+
+//go:embed points.tsv
+var pointsTSV string
+
+// userAssigned holds the user-assigned codes that registries and geolocation databases use in
+// place of a country. ZZ (unknown or unspecified) is deliberately absent so it normalizes to empty.
+var userAssigned = map[string]string{
+	"AP": "Asia/Pacific Region",
+	"EU": "European Union",
+	"XK": "Kosovo",
+}
+
+var names = loadNames()
+
+var points = loadPoints()
+
+func loadNames() map[string]string {
+	names := make(map[string]string, 256)
+
+	scanner := bufio.NewScanner(strings.NewReader(iso3166))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		code, name, found := strings.Cut(line, "\t")
+		if found {
+			names[code] = name
+		}
+	}
+
+	for code, name := range userAssigned {
+		names[code] = name
+	}
+
+	return names
+}
+
+func loadPoints() map[string][2]float64 {
+	points := make(map[string][2]float64, 256)
+
+	scanner := bufio.NewScanner(strings.NewReader(pointsTSV))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(fields[1], 64)
+		lon, lonErr := strconv.ParseFloat(fields[2], 64)
+		if latErr == nil && lonErr == nil {
+			points[fields[0]] = [2]float64{lat, lon}
+		}
+	}
+
+	return points
+}
+
+// Normalize returns code as an uppercase ISO 3166-1 alpha-2 code, or an empty string when code
+// is not a known country code (e.g. "?" or "ZZ").
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, found := names[code]; !found {
+		return ""
+	}
+	return code
+}
+
+// Name returns the English name of the country with the given code, or an empty string when
+// the code is unknown. The code is normalized first, so "nl" yields "Netherlands".
+func Name(code string) string {
+	return names[Normalize(code)]
+}
+
+// Point returns a reference point (latitude, longitude) in the country with the given code, to
+// place records on a map when only their country is known. It is the location of the country's
+// first time zone in the tz database, a populated place rather than a geometric centroid.
+func Point(code string) (float64, float64, bool) {
+	point, found := points[Normalize(code)]
+	return point[0], point[1], found
+}
@@ -0,0 +1,81 @@
+package credentials
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Sources using an API key. Their key is read from NPE_<SOURCE>_KEY unless another environment
+// variable is configured.
+const (
+	SourceRadar         = "radar"
+	SourceElasticsearch = "elasticsearch"
+	SourcePeeringDB     = "peeringdb"
+)
+
+const EnvPrefix = "NPE_"
+
+// legacyEnvNames are the environment variables read before the NPE_ convention, they are still
+// used when the NPE_ variable is not set.
+var legacyEnvNames = map[string]string{
+	SourceRadar:         "CLOUDFLARE_RADAR_TOKEN",
+	SourceElasticsearch: "ELASTICSEARCH_API_KEY",
+}
+
+var envNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Store resolves the API keys of sources from environment variables. Keys are never passed as
+// flags or stored in config files, so they don't show up in process listings or config dumps.
+type Store struct {
+	envNames map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{
+		envNames: make(map[string]string),
+	}
+}
+
+// SetEnvName reads the key of source from the environment variable envName instead of the default.
+func (s *Store) SetEnvName(source, envName string) error {
+	if !envNameRegexp.MatchString(envName) {
+		// don't echo the value, it may be a key pasted by mistake
+		return fmt.Errorf("invalid environment variable name for %s, expected letters, digits and underscores", source)
+	}
+	s.envNames[strings.ToLower(source)] = envName
+	return nil
+}
+
+// EnvName returns the environment variable holding the key of source.
+func (s *Store) EnvName(source string) string {
+	source = strings.ToLower(source)
+	if envName, found := s.envNames[source]; found {
+		return envName
+	}
+	return EnvPrefix + strings.ToUpper(source) + "_KEY"
+}
+
+// Key returns the key of source, and whether it is set.
+func (s *Store) Key(source string) (string, bool) {
+	if key := os.Getenv(s.EnvName(source)); key != "" {
+		return key, true
+	}
+
+	if _, configured := s.envNames[strings.ToLower(source)]; !configured {
+		if legacyEnvName, found := legacyEnvNames[strings.ToLower(source)]; found {
+			if key := os.Getenv(legacyEnvName); key != "" {
+				return key, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,87 @@
+package csirt
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/country"
+)
+
+// Routing maps countries to the abuse address of their national CERT, for notifications that
+// should be coordinated by the CERT of the country an IP address is located in.
+type Routing struct {
+	contacts map[string]string
+}
+
+// NewRouting returns an empty routing.
+func NewRouting() *Routing {
+	return &Routing{contacts: make(map[string]string)}
+}
+
+// Load adds the routes of the TSV file at path, a country code and an email address per line,
+// replacing the addresses of countries it already had. Empty lines and lines starting with #
+// are skipped. It returns the number of countries loaded.
+func (r *Routing) Load(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return 0, fmt.Errorf("cert routing %s: line %d: expected a country code and an email address", path, line)
+		}
+		if err := r.Add(fields[0], fields[1]); err != nil {
+			return 0, fmt.Errorf("cert routing %s: line %d: %v", path, line, err)
+		}
+		loaded++
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("cert routing %s: %v", path, err)
+	}
+	return loaded, nil
+}
+
+// Add routes the IP addresses located in the country with the given code to address.
+func (r *Routing) Add(countryCode, address string) error {
+	code := country.Normalize(countryCode)
+	if code == "" {
+		return fmt.Errorf("unknown country code %q", countryCode)
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %v", address, err)
+	}
+
+	r.contacts[code] = parsed.Address
+	return nil
+}
+
+// Contact returns the address of the national CERT of the country with the given code.
+func (r *Routing) Contact(countryCode string) (string, bool) {
+	address, found := r.contacts[country.Normalize(countryCode)]
+	return address, found
+}
+
+// Len returns the number of countries routed.
+func (r *Routing) Len() int {
+	return len(r.contacts)
+}
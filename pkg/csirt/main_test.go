@@ -0,0 +1,86 @@
+package csirt
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRouting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "certs.tsv")
+	if err := os.WriteFile(path, []byte("# national CERTs\n\nNL\tcert@ncsc.example\nde  <certbund@bsi.example>\nnl\tincident@ncsc.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouting()
+	if err := r.Add("EU", "cert@cert.europa.example"); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := r.Load(path); err != nil || loaded != 3 {
+		t.Fatalf("Load = %d, %v, want 3", loaded, err)
+	}
+	if r.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", r.Len())
+	}
+
+	tests := []struct {
+		country string
+		want    string
+		found   bool
+	}{
+		// the last route of a country wins
+		{"NL", "incident@ncsc.example", true},
+		{" nl ", "incident@ncsc.example", true},
+		{"DE", "certbund@bsi.example", true},
+		{"EU", "cert@cert.europa.example", true},
+		{"BE", "", false},
+		{"unknown", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, found := r.Contact(tt.country); got != tt.want || found != tt.found {
+			t.Errorf("Contact(%q) = %q, %v, want %q, %v", tt.country, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestRoutingInvalid(t *testing.T) {
+	r := NewRouting()
+	for _, tt := range []struct{ country, address string }{
+		{"XX", "cert@ncsc.example"},
+		{"", "cert@ncsc.example"},
+		{"NL", "not an address"},
+		{"NL", ""},
+	} {
+		if err := r.Add(tt.country, tt.address); err == nil {
+			t.Errorf("Add(%q, %q) succeeded", tt.country, tt.address)
+		}
+	}
+
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"unknown-country.tsv": "XX\tcert@ncsc.example\n",
+		"missing-address.tsv": "NL\n",
+		"invalid-address.tsv": "NL\tncsc.example\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Load(path); err == nil {
+			t.Errorf("Load(%s) succeeded", name)
+		}
+	}
+	if _, err := r.Load(filepath.Join(dir, "missing.tsv")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+	if r.Len() != 0 {
+		t.Errorf("Len() = %d after invalid routes, want 0", r.Len())
+	}
+}
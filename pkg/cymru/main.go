@@ -0,0 +1,129 @@
+package cymru
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/likexian/whois"
+)
+
+const WhoisServer = "whois.cymru.com"
+
+// Origin is the origin AS of an IP address according to the Team Cymru IP to ASN mapping. The
+// prefix, country and registry are only known to LookupNetwork.
+type Origin struct {
+	Asn         string
+	Ip          string
+	AsName      string
+	Prefix      string
+	CountryCode string
+	Registry    string
+}
+
+type Client struct {
+	Server string
+	whois  *whois.Client
+}
+
+func NewCymruClient() *Client {
+	return &Client{
+		Server: WhoisServer,
+		whois:  whois.NewClient(),
+	}
+}
+
+// Dialer dials the connections to the whois server, like net.Dialer.
+type Dialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+// SetDialer dials the connections to the whois server with d, e.g. a whoistest.Client in tests.
+func (c *Client) SetDialer(d Dialer) {
+	c.whois.SetDialer(d)
+}
+
+// LookupOrigin queries the Team Cymru whois service for the origin AS of ipAddr.
+func (c *Client) LookupOrigin(ctx context.Context, ipAddr string) (Origin, error) {
+	type whoisResult struct {
+		info string
+		err  error
+	}
+
+	resultCh := make(chan whoisResult, 1)
+	go func() {
+		info, err := c.whois.Whois(ipAddr, c.Server)
+		resultCh <- whoisResult{info, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return Origin{}, ctx.Err()
+	case result := <-resultCh:
+		if result.err != nil {
+			return Origin{}, fmt.Errorf("cymru: %v", result.err)
+		}
+		return ParseOrigin(result.info)
+	}
+}
+
+// LookupNetwork queries the Team Cymru whois service for the origin AS of ipAddr, together with
+// the announced prefix and the country and registry of the allocation (the verbose query).
+func (c *Client) LookupNetwork(ctx context.Context, ipAddr string) (Origin, error) {
+	return c.LookupOrigin(ctx, " -v "+ipAddr)
+}
+
+// ParseOrigin parses the response of the Team Cymru whois service, which looks like:
+//
+//	AS      | IP               | AS Name
+//	13335   | 1.1.1.1          | CLOUDFLARENET, US
+//
+// or, for verbose queries:
+//
+//	AS      | IP               | BGP Prefix          | CC | Registry | Allocated  | AS Name
+//	13335   | 1.1.1.1          | 1.1.1.0/24          | AU | apnic    | 2011-08-11 | CLOUDFLARENET, US
+func ParseOrigin(data string) (Origin, error) {
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "|")
+		if len(fields) < 3 {
+			continue
+		}
+
+		asn := strings.TrimSpace(fields[0])
+		if asn == "AS" {
+			// header
+			continue
+		}
+
+		if asn == "NA" || asn == "" {
+			return Origin{}, fmt.Errorf("cymru: no origin AS for %s", strings.TrimSpace(fields[1]))
+		}
+
+		origin := Origin{
+			Asn:    asn,
+			Ip:     strings.TrimSpace(fields[1]),
+			AsName: strings.TrimSpace(fields[len(fields)-1]),
+		}
+		if len(fields) >= 7 {
+			origin.Prefix = strings.TrimSpace(fields[2])
+			origin.CountryCode = strings.TrimSpace(fields[3])
+			origin.Registry = strings.TrimSpace(fields[4])
+		}
+		return origin, nil
+	}
+
+	if strings.Contains(data, "Error:") {
+		return Origin{}, fmt.Errorf("cymru: %s", strings.TrimSpace(data[strings.Index(data, "Error:"):]))
+	}
+
+	return Origin{}, fmt.Errorf("cymru: unexpected response")
+}
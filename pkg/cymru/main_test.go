@@ -0,0 +1,60 @@
+package cymru
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/whoistest"
+)
+
+func TestParseOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Origin
+		wantErr bool
+	}{
+		{"origin", "AS      | IP               | AS Name\n13335   | 1.1.1.1          | CLOUDFLARENET, US\n",
+			Origin{Asn: "13335", Ip: "1.1.1.1", AsName: "CLOUDFLARENET, US"}, false},
+		{"verbose", "AS      | IP               | BGP Prefix          | CC | Registry | Allocated  | AS Name\n" +
+			"13335   | 1.1.1.1          | 1.1.1.0/24          | AU | apnic    | 2011-08-11 | CLOUDFLARENET, US\n",
+			Origin{Asn: "13335", Ip: "1.1.1.1", AsName: "CLOUDFLARENET, US", Prefix: "1.1.1.0/24", CountryCode: "AU", Registry: "apnic"}, false},
+		{"not announced", "AS      | IP               | AS Name\nNA      | 192.0.2.1        | NA\n", Origin{}, true},
+		{"error", "Error: no ASN or IP match on line 1.\n", Origin{}, true},
+		{"empty", "", Origin{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOrigin(tt.data)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseOrigin = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupOrigin(t *testing.T) {
+	server := whoistest.NewClient()
+	server.Handle("193.0.6.139", WhoisServer, whoistest.Text("AS      | IP               | AS Name\n3333    | 193.0.6.139      | RIPE-NCC-AS, NL\n"))
+	server.Handle("-v 193.0.6.139", WhoisServer, whoistest.Text("AS      | IP               | BGP Prefix          | CC | Registry | Allocated  | AS Name\n"+
+		"3333    | 193.0.6.139      | 193.0.0.0/21        | NL | ripencc  | 1993-09-01 | RIPE-NCC-AS, NL\n"))
+	c := NewCymruClient()
+	c.SetDialer(server)
+
+	origin, err := c.LookupOrigin(context.Background(), "193.0.6.139")
+	if err != nil || origin.Asn != "3333" || origin.AsName != "RIPE-NCC-AS, NL" {
+		t.Errorf("LookupOrigin = %+v, %v, want AS3333", origin, err)
+	}
+	network, err := c.LookupNetwork(context.Background(), "193.0.6.139")
+	if err != nil || network.Asn != "3333" || network.Prefix != "193.0.0.0/21" || network.Registry != "ripencc" {
+		t.Errorf("LookupNetwork = %+v, %v, want the prefix and registry", network, err)
+	}
+	server.AssertNoUnexpected(t)
+}
@@ -0,0 +1,121 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// adaptiveLimit bounds the number of IP addresses enriched concurrently. It starts at lower and
+// adapts like TCP congestion control (AIMD): the limit grows by one after every limit IP addresses
+// enriched without rate limited responses up to upper, and halves, down to lower, when responses were.
+type adaptiveLimit struct {
+	lower, upper int
+	// rateLimited returns the number of rate limited responses so far
+	rateLimited func() int64
+	log         logrus.FieldLogger
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	limit    int
+	peak     int
+	inFlight int
+	// clean counts the IP addresses enriched since the limit last changed
+	clean int
+	seen  int64
+}
+
+func newAdaptiveLimit(lower, upper int, rateLimited func() int64, log logrus.FieldLogger) *adaptiveLimit {
+	if lower < 1 {
+		lower = 1
+	}
+	if upper < lower {
+		upper = lower
+	}
+
+	l := &adaptiveLimit{
+		lower:       lower,
+		upper:       upper,
+		rateLimited: rateLimited,
+		log:         log,
+		limit:       lower,
+		peak:        lower,
+		seen:        rateLimited(),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// acquire blocks until fewer IP addresses than the limit are being enriched. It returns false
+// without acquiring when ctx is done first.
+func (l *adaptiveLimit) acquire(ctx context.Context) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.inFlight >= l.limit && ctx.Err() == nil {
+		l.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+// release ends the enrichment of an IP address and adapts the limit to the rate limited
+// responses seen in the meantime.
+func (l *adaptiveLimit) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	if count := l.rateLimited(); count > l.seen {
+		l.seen = count
+		l.clean = 0
+		if l.limit > l.lower {
+			l.limit /= 2
+			if l.limit < l.lower {
+				l.limit = l.lower
+			}
+			l.log.Debugf("enricher: rate limited, enriching %d IPs concurrently", l.limit)
+		}
+	} else {
+		l.clean++
+		if l.clean >= l.limit && l.limit < l.upper {
+			l.clean = 0
+			l.limit++
+			if l.limit > l.peak {
+				l.peak = l.limit
+			}
+			l.log.Debugf("enricher: enriching %d IPs concurrently", l.limit)
+		}
+	}
+
+	l.cond.Broadcast()
+}
+
+// wakeOnDone wakes the callers blocked in acquire once ctx is done, until stop is closed.
+func (l *adaptiveLimit) wakeOnDone(ctx context.Context, stop <-chan struct{}) {
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.cond.Broadcast()
+		l.mu.Unlock()
+	case <-stop:
+	}
+}
+
+// peakLimit returns the highest limit reached.
+func (l *adaptiveLimit) peakLimit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.peak
+}
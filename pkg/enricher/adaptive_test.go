@@ -0,0 +1,102 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/ripestattest"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestAdaptiveLimit(t *testing.T) {
+	server := newTestServer(t)
+	// the lookups of 192.0.2.1 are rate limited, the others are not
+	server.Handle("network-info", "192.0.2.1", ripestattest.RateLimited())
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	client := ripestat.NewRipeStatClient("nuclei-parse-enrich", 0)
+	client.BaseURL = server.BaseURL()
+	client.Logger = logger
+
+	l := newAdaptiveLimit(1, 4, client.RateLimited, logger)
+	var limits []int
+	enrich := func(ipAddr string) {
+		if !l.acquire(context.Background()) {
+			t.Fatal("acquire failed")
+		}
+		_, _ = client.GetNetworkInfo(context.Background(), ipAddr)
+		l.release()
+		limits = append(limits, l.limit)
+	}
+
+	// the limit grows by one after every limit IP addresses, up to upper
+	for i := 0; i < 12; i++ {
+		enrich("193.0.6.139")
+	}
+	want := []int{2, 2, 3, 3, 3, 4, 4, 4, 4, 4, 4, 4}
+	if !reflect.DeepEqual(limits, want) {
+		t.Fatalf("limits while ramping up %v, want %v", limits, want)
+	}
+
+	// and halves for every rate limited IP address, down to lower
+	limits = nil
+	for i := 0; i < 3; i++ {
+		enrich("192.0.2.1")
+	}
+	if want := []int{2, 1, 1}; !reflect.DeepEqual(limits, want) {
+		t.Fatalf("limits while rate limited %v, want %v", limits, want)
+	}
+	if rateLimited := client.RateLimited(); rateLimited != 3 {
+		t.Errorf("RateLimited() = %d, want 3", rateLimited)
+	}
+
+	// to grow again once the responses are no longer rate limited
+	limits = nil
+	for i := 0; i < 3; i++ {
+		enrich("193.0.6.139")
+	}
+	if want := []int{2, 2, 3}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("limits after the rate limiting %v, want %v", limits, want)
+	}
+	if peak := l.peakLimit(); peak != 4 {
+		t.Errorf("peakLimit() = %d, want 4", peak)
+	}
+}
+
+func TestAdaptiveLimitAcquire(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	l := newAdaptiveLimit(1, 4, func() int64 { return 0 }, logger)
+
+	if !l.acquire(context.Background()) {
+		t.Fatal("acquire below the limit failed")
+	}
+
+	// the limit is reached, so acquire blocks until ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan struct{})
+	defer close(stop)
+	go l.wakeOnDone(ctx, stop)
+	acquired := make(chan bool)
+	go func() { acquired <- l.acquire(ctx) }()
+	cancel()
+	if <-acquired {
+		t.Error("acquire above the limit succeeded after ctx was done")
+	}
+
+	l.release()
+	if !l.acquire(context.Background()) {
+		t.Error("acquire after release failed")
+	}
+}
@@ -0,0 +1,141 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// asnCache holds the origin AS of the prefixes found by LookupASN, so the other IP addresses of a
+// prefix are answered without a lookup.
+type asnCache struct {
+	mu       sync.RWMutex
+	prefixes map[netip.Prefix]asnCacheEntry
+	// bits are the prefix lengths in prefixes, longest first
+	bits []int
+}
+
+// asnCacheEntry is the origin AS of a prefix and the more specific prefixes announced inside it,
+// whose IP addresses may have another origin AS.
+type asnCacheEntry struct {
+	asn           string
+	moreSpecifics []netip.Prefix
+}
+
+func newASNCache() *asnCache {
+	return &asnCache{prefixes: make(map[netip.Prefix]asnCacheEntry)}
+}
+
+// lookup returns the origin AS and the most specific prefix holding addr. IP addresses inside a
+// more specific announcement that isn't cached yet are not found.
+func (c *asnCache) lookup(addr netip.Addr) (string, netip.Prefix, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, bits := range c.bits {
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		entry, found := c.prefixes[prefix]
+		if !found {
+			continue
+		}
+		for _, more := range entry.moreSpecifics {
+			if more.Contains(addr) {
+				return "", netip.Prefix{}, false
+			}
+		}
+		return entry.asn, prefix, true
+	}
+	return "", netip.Prefix{}, false
+}
+
+func (c *asnCache) add(prefix netip.Prefix, asn string, moreSpecifics []netip.Prefix) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix = prefix.Masked()
+	if _, found := c.prefixes[prefix]; !found {
+		c.bits = appendBits(c.bits, prefix.Bits())
+	}
+	c.prefixes[prefix] = asnCacheEntry{asn: asn, moreSpecifics: moreSpecifics}
+}
+
+func appendBits(bits []int, add int) []int {
+	for _, b := range bits {
+		if b == add {
+			return bits
+		}
+	}
+	bits = append(bits, add)
+	sort.Sort(sort.Reverse(sort.IntSlice(bits)))
+	return bits
+}
+
+// LookupASN returns the origin AS and the announced prefix of ipAddr with a network-info call, for
+// when only the network of an IP address matters, e.g. to group many addresses by network. The
+// prefixes found are kept for the lifetime of the enricher, with the more specific prefixes
+// announced inside them according to a routing-status call: the other IP addresses of a prefix
+// are answered without a call, except those of a more specific announcement, which may have
+// another origin AS. The AS is empty for prefixes that are not announced, and both are empty when
+// RipeSTAT knows neither.
+func (e *Enricher) LookupASN(ctx context.Context, ipAddr string) (asn string, prefix string, err error) {
+	if err := ValidateIP(ipAddr); err != nil {
+		return "", "", err
+	}
+	addr, _ := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	addr = addr.Unmap()
+
+	if asn, cached, found := e.asnCache.lookup(addr); found {
+		return asn, cached.String(), nil
+	}
+
+	start := time.Now()
+	netInfo, err := e.rs.GetNetworkInfo(ctx, addr.String())
+	if err != nil {
+		e.lookupLog(addr.String(), "network-info", start).Warnf("asn lookup err: %v", err)
+		return "", "", err
+	}
+
+	if len(netInfo.ASNs) > 0 {
+		if asn, err = e.normalizeASN(netInfo.ASNs[0]); err != nil {
+			asn = ""
+		}
+	}
+
+	announced, err := netip.ParsePrefix(netInfo.Prefix)
+	if err != nil {
+		// without a prefix there is nothing to share with other IP addresses
+		return asn, netInfo.Prefix, nil
+	}
+	announced = announced.Masked()
+
+	start = time.Now()
+	status, err := e.rs.GetRoutingStatus(ctx, announced.String())
+	if err != nil {
+		// without the more specific announcements the prefix can't be shared safely
+		e.lookupLog(addr.String(), "routing-status", start).Warnf("more specific prefixes err: %v", err)
+		return asn, announced.String(), nil
+	}
+	var moreSpecifics []netip.Prefix
+	for _, more := range status.MoreSpecifics {
+		prefix, err := netip.ParsePrefix(more.Prefix)
+		if err != nil || prefix.Bits() <= announced.Bits() || !announced.Contains(prefix.Addr()) {
+			continue
+		}
+		moreSpecifics = append(moreSpecifics, prefix.Masked())
+	}
+
+	e.asnCache.add(announced, asn, moreSpecifics)
+	return asn, announced.String(), nil
+}
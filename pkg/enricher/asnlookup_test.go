@@ -0,0 +1,64 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+func TestLookupASNMoreSpecific(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "193.0.6.139", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/16"}`))
+	server.Handle("network-info", "193.0.5.1", ripestattest.JSON(`{"asns": ["1234"], "prefix": "193.0.5.0/24"}`))
+	server.Handle("routing-status", "193.0.0.0/16", ripestattest.JSON(`{"last_seen": {}, "more_specifics": [{"prefix": "193.0.5.0/24", "origin": 1234}]}`))
+	server.Handle("routing-status", "193.0.5.0/24", ripestattest.JSON(`{"last_seen": {}, "more_specifics": []}`))
+
+	e := NewEnricher(WithRipeStatBaseURL(server.BaseURL()), WithoutWhois())
+	tests := []struct {
+		ipAddr, asn, prefix string
+	}{
+		{"193.0.6.139", "3333", "193.0.0.0/16"},
+		// from the cache
+		{"193.0.7.1", "3333", "193.0.0.0/16"},
+		// inside the more specific announcement of another AS
+		{"193.0.5.1", "1234", "193.0.5.0/24"},
+		{"193.0.5.2", "1234", "193.0.5.0/24"},
+	}
+	for _, tt := range tests {
+		asn, prefix, err := e.LookupASN(context.Background(), tt.ipAddr)
+		if err != nil {
+			t.Fatalf("LookupASN(%s): %v", tt.ipAddr, err)
+		}
+		if asn != tt.asn || prefix != tt.prefix {
+			t.Errorf("LookupASN(%s) = %s %s, want %s %s", tt.ipAddr, asn, prefix, tt.asn, tt.prefix)
+		}
+	}
+
+	server.AssertRequests(t, "network-info", "", 2)
+	server.AssertRequests(t, "routing-status", "", 2)
+	server.AssertNoUnexpected(t)
+}
+
+func TestLookupASNRoutingStatusFailed(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/16"}`))
+	server.Handle("routing-status", "", ripestattest.Error(400, "bad request"))
+
+	e := NewEnricher(WithRipeStatBaseURL(server.BaseURL()), WithoutWhois())
+	for _, ipAddr := range []string{"193.0.6.139", "193.0.7.1"} {
+		if asn, _, err := e.LookupASN(context.Background(), ipAddr); err != nil || asn != "3333" {
+			t.Errorf("LookupASN(%s) = %q, %v, want 3333", ipAddr, asn, err)
+		}
+	}
+	// without the more specific prefixes nothing is served from the cache
+	server.AssertRequests(t, "network-info", "", 2)
+}
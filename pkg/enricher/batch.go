@@ -0,0 +1,197 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+const DefaultWorkers = 8
+
+// Progress describes a running EnrichIPs batch. Failed counts the enriched IP addresses for
+// which at least one lookup failed.
+type Progress struct {
+	Total    int
+	Enriched int
+	Failed   int
+	Elapsed  time.Duration
+}
+
+// Summary describes a finished EnrichIPs batch. Total counts the IP addresses to enrich, like
+// Progress.Total. Invalid counts the values that are not an IP address, which are left out of
+// Total and the results, InvalidIPs lists the first few of them.
+type Summary struct {
+	Total      int
+	Enriched   int
+	Failed     int
+	Invalid    int
+	InvalidIPs []string
+	Workers    int
+	// Concurrency is the number of lookups that could actually run in parallel, bounded by
+	// the number of workers, the number of IP addresses and the RipeSTAT request limit. With
+	// adaptive concurrency it is the highest number reached.
+	Concurrency int
+	Duration    time.Duration
+	CacheHits   int
+	CacheMisses int
+	// Latencies holds the RipeSTAT latencies by data call
+	Latencies map[string]ripestat.LatencyStats
+}
+
+// EnrichIPs enriches ipAddrs concurrently. Every IP address is enriched with its own context
+// derived from ctx, so once ctx is done the remaining work is aborted and the results of the
+// IP addresses enriched so far are returned together with the context error. The IP addresses
+// being enriched when ctx is done keep what was found, with a "Deadline" error when lookups were
+// cut short. Values that are not an IP address are counted in the summary instead of being enriched.
+func (e *Enricher) EnrichIPs(ctx context.Context, ipAddrs []string) ([]types.EnrichInfo, Summary, error) {
+	start := time.Now()
+
+	var summary Summary
+	valid := make([]string, 0, len(ipAddrs))
+	for _, ipAddr := range ipAddrs {
+		if err := ValidateIP(ipAddr); err != nil {
+			summary.Invalid++
+			if len(summary.InvalidIPs) < MaxInvalidExamples {
+				summary.InvalidIPs = append(summary.InvalidIPs, ipAddr)
+			}
+			continue
+		}
+		valid = append(valid, ipAddr)
+	}
+	if summary.Invalid > 0 {
+		e.log.Warnf("not enriching %d invalid IP addresses, e.g. %q", summary.Invalid, summary.InvalidIPs)
+	}
+	ipAddrs = valid
+	summary.Total = len(ipAddrs)
+
+	var hitsBefore, missesBefore int
+	if e.cache != nil {
+		hitsBefore, missesBefore = e.cache.Stats()
+	}
+
+	workers := e.workers
+	if workers < 1 {
+		workers = DefaultWorkers
+	}
+
+	summary.Workers = workers
+	summary.Concurrency = workers
+	if summary.Concurrency > len(ipAddrs) {
+		summary.Concurrency = len(ipAddrs)
+	}
+	if summary.Concurrency > e.rs.MaxConcurrentRequests() {
+		summary.Concurrency = e.rs.MaxConcurrentRequests()
+	}
+
+	resultBuffer := e.resultBuffer
+	if resultBuffer < 1 {
+		resultBuffer = workers
+	}
+	jobCh := make(chan string, e.jobBuffer)
+	resultCh := make(chan types.EnrichInfo, resultBuffer)
+
+	var limit *adaptiveLimit
+	if e.adaptiveMin > 0 {
+		limit = newAdaptiveLimit(e.adaptiveMin, workers, e.rs.RateLimited, e.log)
+		stop := make(chan struct{})
+		defer close(stop)
+		go limit.wakeOnDone(ctx, stop)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ipAddr := range jobCh {
+				if ctx.Err() != nil {
+					// handed over as ctx was done, not started
+					continue
+				}
+				if limit != nil && !limit.acquire(ctx) {
+					continue
+				}
+				ipCtx, cancel := context.WithCancel(ctx)
+				result := e.EnrichIP(ipCtx, ipAddr)
+				cancel()
+				if limit != nil {
+					limit.release()
+				}
+
+				if err := ctx.Err(); err != nil && len(result.Errors) > 0 {
+					// the lookups still running were cut short, the result is partial
+					addError(&result, "Deadline", fmt.Errorf("enrichment cut short: %v", err))
+				}
+				resultCh <- result
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobCh)
+		for _, ipAddr := range ipAddrs {
+			select {
+			case <-ctx.Done():
+				return
+			case jobCh <- ipAddr:
+				e.log.Debug("enriching IP: ", ipAddr)
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	results := make([]types.EnrichInfo, 0, len(ipAddrs))
+	for result := range resultCh {
+		results = append(results, result)
+		if e.onResult != nil {
+			e.onResult(result)
+		}
+
+		if len(result.Errors) > 0 {
+			summary.Failed++
+		}
+
+		if e.progress != nil {
+			e.progress(Progress{
+				Total:    len(ipAddrs),
+				Enriched: len(results),
+				Failed:   summary.Failed,
+				Elapsed:  time.Since(start),
+			})
+		}
+	}
+
+	summary.Enriched = len(results)
+	summary.Duration = time.Since(start)
+	if limit != nil && limit.peakLimit() < summary.Concurrency {
+		summary.Concurrency = limit.peakLimit()
+	}
+	summary.Latencies = e.rs.Latencies()
+
+	if e.cache != nil {
+		hits, misses := e.cache.Stats()
+		summary.CacheHits = hits - hitsBefore
+		summary.CacheMisses = misses - missesBefore
+	}
+
+	if err := ctx.Err(); err != nil {
+		e.log.Warnf("enrichment aborted after %d of %d IPs: %v", len(results), len(ipAddrs), err)
+		return results, summary, err
+	}
+
+	return results, summary, nil
+}
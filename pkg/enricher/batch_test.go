@@ -0,0 +1,123 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+// TestEnrichIPsDeadline runs out of time while one IP address is still being looked up, after
+// another one was enriched. Both are returned, the one cut short with a Deadline error.
+func TestEnrichIPsDeadline(t *testing.T) {
+	server := newTestServer(t)
+	slow := ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`)
+	slow.Latency = 5 * time.Second
+	server.Handle("network-info", "193.0.6.140", slow)
+	e := newTestEnricher(server, WithWorkers(2), WithUnknownPlaceholder())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results, summary, err := e.EnrichIPs(ctx, []string{"193.0.6.139", "193.0.6.140", "193.0.6.141"})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("EnrichIPs took %v after the deadline", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("EnrichIPs error = %v, want the deadline", err)
+	}
+
+	byIP := make(map[string]int)
+	for i, result := range results {
+		byIP[result.Ip] = i
+	}
+	if len(results) != 3 || summary.Enriched != 3 || summary.Failed != 1 {
+		t.Fatalf("returned %d results, summary = %+v, want all 3 of which 1 failed", len(results), summary)
+	}
+	for _, ipAddr := range []string{"193.0.6.139", "193.0.6.141"} {
+		if result := results[byIP[ipAddr]]; len(result.Errors) > 0 || result.Abuse != "abuse@ripe.net" {
+			t.Errorf("%s enriched as %+v, want the complete enrichment", ipAddr, result)
+		}
+	}
+	cut := results[byIP["193.0.6.140"]]
+	if cut.Errors["Deadline"] == "" || cut.Prefix != "unknown" {
+		t.Errorf("193.0.6.140 enriched as %+v, want the prefix unknown and a Deadline error", cut)
+	}
+}
+
+// TestEnrichIPsCancelledBeforeStart returns nothing for a batch that is done before it starts.
+func TestEnrichIPsCancelledBeforeStart(t *testing.T) {
+	server := newTestServer(t)
+	e := newTestEnricher(server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, _, err := e.EnrichIPs(ctx, []string{"193.0.6.139", "193.0.6.140"})
+	if !errors.Is(err, context.Canceled) || len(results) != 0 {
+		t.Errorf("EnrichIPs = %d results, %v, want none and context.Canceled", len(results), err)
+	}
+	if requests := server.TotalRequests(); requests > 0 {
+		t.Errorf("%d requests to RipeSTAT", requests)
+	}
+}
+
+// TestEnrichIPsInFlight counts the requests RipeSTAT has in flight at once, which the workers and
+// the request limit of the RipeSTAT client bound.
+func TestEnrichIPsInFlight(t *testing.T) {
+	tests := []struct {
+		workers int
+		want    int
+	}{
+		{1, 1},
+		{4, 4},
+		{16, ripestat.DefaultMaxConcurrentRequests},
+	}
+
+	var ipAddrs []string
+	for i := 1; i <= 32; i++ {
+		ipAddrs = append(ipAddrs, fmt.Sprintf("193.0.6.%d", i))
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("workers=%d", tt.workers), func(t *testing.T) {
+			server := newTestServer(t)
+			server.Latency = 5 * time.Millisecond
+			var inFlight, peak int64
+			handler := server.Config.Handler
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt64(&inFlight, 1)
+				defer atomic.AddInt64(&inFlight, -1)
+				for {
+					p := atomic.LoadInt64(&peak)
+					if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+						break
+					}
+				}
+				handler.ServeHTTP(w, r)
+			})
+			e := newTestEnricher(server, WithWorkers(tt.workers))
+
+			_, summary, err := e.EnrichIPs(context.Background(), ipAddrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := atomic.LoadInt64(&peak); got != int64(tt.want) {
+				t.Errorf("%d requests in flight at most, want %d", got, tt.want)
+			}
+			if summary.Concurrency != tt.want {
+				t.Errorf("summary concurrency %d, want %d", summary.Concurrency, tt.want)
+			}
+		})
+	}
+}
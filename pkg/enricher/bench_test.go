@@ -0,0 +1,60 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BenchmarkEnrichIPs enriches 500 IP addresses against ripestattest answering after a
+// millisecond, at several worker counts, e.g.
+//
+//	go test ./pkg/enricher -run XXX -bench EnrichIPs -benchmem
+func BenchmarkEnrichIPs(b *testing.B) {
+	const ips = 500
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Latency = time.Millisecond
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+
+	ipAddrs := make([]string, ips)
+	for i := range ipAddrs {
+		ipAddrs[i] = fmt.Sprintf("193.0.%d.%d", i/250, i%250+1)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	for _, workers := range []int{1, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			e := NewEnricher(WithRipeStatBaseURL(server.BaseURL()), WithoutWhois(), WithWorkers(workers), WithLogger(logger))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				results, _, err := e.EnrichIPs(context.Background(), ipAddrs)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(results) != ips {
+					b.Fatalf("enriched %d IPs, want %d", len(results), ips)
+				}
+			}
+			b.ReportMetric(float64(ips*b.N)/b.Elapsed().Seconds(), "IPs/s")
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// The kinds of IPv6 addresses embedding an IPv4 address, see EmbeddedIPv4
+const (
+	// IPv4Mapped is an address in ::ffff:0:0/96, e.g. ::ffff:192.0.2.1 (RFC 4291)
+	IPv4Mapped = "ipv4-mapped"
+	// IPv4Compatible is an address in the deprecated ::/96, e.g. ::192.0.2.1 (RFC 4291)
+	IPv4Compatible = "ipv4-compatible"
+)
+
+// EmbeddedIPv4 returns the IPv4 address embedded in addr and IPv4Mapped or IPv4Compatible, or an
+// invalid address and nothing when addr doesn't embed one. IPv4-compatible addresses with a zero
+// first octet, such as :: and ::1, are taken as IPv6.
+func EmbeddedIPv4(addr netip.Addr) (netip.Addr, string) {
+	if !addr.Is6() || addr.Zone() != "" {
+		return netip.Addr{}, ""
+	}
+	if addr.Is4In6() {
+		return addr.Unmap(), IPv4Mapped
+	}
+
+	b := addr.As16()
+	for _, octet := range b[:12] {
+		if octet != 0 {
+			return netip.Addr{}, ""
+		}
+	}
+	if b[12] == 0 {
+		return netip.Addr{}, ""
+	}
+	return netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]}), IPv4Compatible
+}
+
+// CanonicalIP returns the canonical text form of ipAddr, for IPv6 addresses the RFC 5952 form
+// (lower case, longest run of zero groups compressed, no brackets). IPv6 addresses embedding an
+// IPv4 address are returned as the IPv4 address. Values that are not an IP address are returned
+// unchanged.
+func CanonicalIP(ipAddr string) string {
+	return CanonicalIPAs(ipAddr, false)
+}
+
+// CanonicalIPAs returns the canonical text form of ipAddr like CanonicalIP, keeping IPv6 addresses
+// embedding an IPv4 address as IPv6 when mappedAsIPv6 is set, see WithMappedAsIPv6.
+func CanonicalIPAs(ipAddr string, mappedAsIPv6 bool) string {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return ipAddr
+	}
+	if !mappedAsIPv6 {
+		if v4, kind := EmbeddedIPv4(addr); kind != "" {
+			addr = v4
+		}
+	}
+	return addr.String()
+}
+
+// ipMapping returns how CanonicalIPAs treated the IPv4 address embedded in ipAddr, see
+// types.EnrichInfo.IpMapping.
+func ipMapping(ipAddr string, mappedAsIPv6 bool) string {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil || mappedAsIPv6 {
+		return ""
+	}
+	_, kind := EmbeddedIPv4(addr)
+	return kind
+}
+
+// WithMappedAsIPv6 looks up IPv6 addresses embedding an IPv4 address (::ffff:192.0.2.1, ::192.0.2.1)
+// as IPv6 addresses when keep is set. By default they are looked up as the IPv4 address, as RipeSTAT
+// and whois don't know the IPv6 form.
+func WithMappedAsIPv6(keep bool) Option {
+	return func(e *Enricher) {
+		e.mappedAsIPv6 = keep
+	}
+}
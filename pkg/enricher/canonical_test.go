@@ -0,0 +1,131 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+)
+
+func TestCanonicalIP(t *testing.T) {
+	tests := []struct {
+		ipAddr string
+		want   string
+	}{
+		{"2001:67c:2e8:22::c100:68b", "2001:67c:2e8:22::c100:68b"},
+		{"2001:067c:02e8:0022:0000:0000:c100:068b", "2001:67c:2e8:22::c100:68b"},
+		{"2001:67C:2E8:22::C100:68B", "2001:67c:2e8:22::c100:68b"},
+		{"[2001:67c:2e8:22::c100:68b]", "2001:67c:2e8:22::c100:68b"},
+		{"2001:67c:2e8:22:0:0:c100:68b", "2001:67c:2e8:22::c100:68b"},
+		// the longest run of zero groups is compressed, the first of equal runs
+		{"2001:db8:0:0:1:0:0:0", "2001:db8:0:0:1::"},
+		{"2001:db8:0:0:1:0:0:1", "2001:db8::1:0:0:1"},
+		// a single zero group isn't compressed
+		{"2001:db8:0:1:1:1:1:1", "2001:db8:0:1:1:1:1:1"},
+		{"0:0:0:0:0:0:0:1", "::1"},
+		// the zone stays, its case too
+		{"FE80::1%eth0", "fe80::1%eth0"},
+		{"193.0.6.139", "193.0.6.139"},
+		{"not an IP", "not an IP"},
+	}
+
+	for _, tt := range tests {
+		if got := CanonicalIP(tt.ipAddr); got != tt.want {
+			t.Errorf("CanonicalIP(%q) = %q, want %q", tt.ipAddr, got, tt.want)
+		}
+	}
+}
+
+func TestEnrichIPCanonical(t *testing.T) {
+	server := newTestServer(t)
+	e := newTestEnricher(server)
+
+	for _, ipAddr := range []string{"2001:67c:2e8:22::c100:68b", "2001:067C:02E8:0022:0000:0000:C100:068B", "[2001:67c:2e8:22::c100:68b]"} {
+		got := e.EnrichIP(context.Background(), ipAddr)
+		if got.Ip != "2001:67c:2e8:22::c100:68b" {
+			t.Errorf("EnrichIP(%q) has Ip %q", ipAddr, got.Ip)
+		}
+		wantRaw := ipAddr
+		if ipAddr == got.Ip {
+			wantRaw = ""
+		}
+		if got.IpRaw != wantRaw || got.IpMapping != "" {
+			t.Errorf("EnrichIP(%q) has IpRaw %q and IpMapping %q, want %q", ipAddr, got.IpRaw, got.IpMapping, wantRaw)
+		}
+	}
+	// RipeSTAT is only asked for the canonical form
+	server.AssertRequests(t, "network-info", "2001:67c:2e8:22::c100:68b", 3)
+	server.AssertNoUnexpected(t)
+}
+
+func TestEmbeddedIPv4(t *testing.T) {
+	tests := []struct {
+		addr     string
+		wantV4   string
+		wantKind string
+	}{
+		{"::ffff:193.0.6.139", "193.0.6.139", IPv4Mapped},
+		{"::FFFF:c100:68b", "193.0.6.139", IPv4Mapped},
+		{"::193.0.6.139", "193.0.6.139", IPv4Compatible},
+		{"0:0:0:0:0:0:c100:68b", "193.0.6.139", IPv4Compatible},
+		// a zero first octet is IPv6, as are zones
+		{"::1", "", ""},
+		{"::", "", ""},
+		{"::0.1.2.3", "", ""},
+		{"::ffff:193.0.6.139%eth0", "", ""},
+		{"64:ff9b::c100:68b", "", ""},
+		{"2001:67c:2e8:22::c100:68b", "", ""},
+		{"193.0.6.139", "", ""},
+	}
+	for _, tt := range tests {
+		v4, kind := EmbeddedIPv4(netip.MustParseAddr(tt.addr))
+		if kind != tt.wantKind || (v4.IsValid() && v4.String() != tt.wantV4) || v4.IsValid() != (tt.wantV4 != "") {
+			t.Errorf("EmbeddedIPv4(%s) = %v, %q, want %s, %q", tt.addr, v4, kind, tt.wantV4, tt.wantKind)
+		}
+	}
+}
+
+func TestIpMapping(t *testing.T) {
+	tests := []struct {
+		ipAddr       string
+		mappedAsIPv6 bool
+		wantIp       string
+		wantMapping  string
+	}{
+		{"::ffff:193.0.6.139", false, "193.0.6.139", IPv4Mapped},
+		{"[::FFFF:193.0.6.139]", false, "193.0.6.139", IPv4Mapped},
+		{"::193.0.6.139", false, "193.0.6.139", IPv4Compatible},
+		// looked up as IPv6, the form is canonical still
+		{"::FFFF:193.0.6.139", true, "::ffff:193.0.6.139", ""},
+		{"::193.0.6.139", true, "::c100:68b", ""},
+		{"193.0.6.139", false, "193.0.6.139", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s as IPv6 %v", tt.ipAddr, tt.mappedAsIPv6), func(t *testing.T) {
+			server := newTestServer(t)
+			got := newTestEnricher(server, WithMappedAsIPv6(tt.mappedAsIPv6)).EnrichIP(context.Background(), tt.ipAddr)
+
+			wantRaw := tt.ipAddr
+			if tt.ipAddr == tt.wantIp {
+				wantRaw = ""
+			}
+			if got.Ip != tt.wantIp || got.IpRaw != wantRaw || got.IpMapping != tt.wantMapping {
+				t.Errorf("Ip %q, IpRaw %q and IpMapping %q, want %q, %q and %q", got.Ip, got.IpRaw, got.IpMapping, tt.wantIp, wantRaw, tt.wantMapping)
+			}
+			if got.Asn != "3333" || len(got.Errors) > 0 {
+				t.Errorf("enriched %+v", got)
+			}
+			// RipeSTAT is asked for the address looked up only
+			server.AssertRequests(t, "network-info", tt.wantIp, 1)
+			server.AssertRequests(t, "abuse-contact-finder", tt.wantIp, 1)
+			server.AssertNoUnexpected(t)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"sort"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithContactClassifier classifies abuse contacts as role or personal mailbox with c
+// instead of the default classifier.
+func WithContactClassifier(c *contact.Classifier) Option {
+	return func(e *Enricher) {
+		e.classifier = c
+	}
+}
+
+// WithRoleContactsOnly drops abuse contacts that look like personal addresses.
+func WithRoleContactsOnly() Option {
+	return func(e *Enricher) {
+		e.roleContactsOnly = true
+	}
+}
+
+// WithAbuseAddressList records the abuse contacts of every IP address as an RFC 5322
+// address-list in AbuseTo, ready to paste into the To: header of a notification.
+func WithAbuseAddressList() Option {
+	return func(e *Enricher) {
+		e.abuseTo = true
+	}
+}
+
+// abuseSourcePrecedence orders the abuse contact sources from most to least authoritative. An
+// address reported by several sources is attributed to the most authoritative one.
+var abuseSourcePrecedence = []string{types.AbuseSourceRipeSTAT, types.AbuseSourceWhois}
+
+func abuseSourceRank(source string) int {
+	for rank, s := range abuseSourcePrecedence {
+		if s == source {
+			return rank
+		}
+	}
+	return len(abuseSourcePrecedence)
+}
+
+// classifyAbuseContacts classifies the ";" separated abuse addresses found by source, dropping
+// personal addresses when only role contacts are wanted. It returns the remaining addresses, their
+// sources and the contacts, or "unknown" when no address remains.
+func (e *Enricher) classifyAbuseContacts(abuse string, source string) (string, string, []types.AbuseContact) {
+	if abuse == "unknown" {
+		return abuse, source, nil
+	}
+
+	var contacts []types.AbuseContact
+	for _, address := range strings.Split(abuse, ";") {
+		kind := e.classifier.Classify(address)
+		if e.roleContactsOnly && kind != contact.KindRole {
+			e.log.Debug("enricher: dropping personal abuse contact ", address)
+			continue
+		}
+
+		contacts = append(contacts, types.AbuseContact{
+			Email:  address,
+			Kind:   kind,
+			Source: source,
+		})
+	}
+
+	contacts = mergeAbuseContacts(contacts)
+	if len(contacts) == 0 {
+		return "unknown", types.AbuseSourceNone, nil
+	}
+
+	addresses := make([]string, 0, len(contacts))
+	var sources []string
+	for _, c := range contacts {
+		addresses = append(addresses, c.Email)
+		if len(sources) == 0 || sources[len(sources)-1] != c.Source {
+			sources = append(sources, c.Source)
+		}
+	}
+
+	return strings.Join(addresses, ";"), strings.Join(sources, ";"), contacts
+}
+
+// mergeAbuseContacts returns every address of contacts once, regardless of case, attributed to
+// the most authoritative source reporting it. The result is ordered by source precedence, sources
+// without precedence by name, and keeps the order addresses were found in within a source, so the
+// same findings always give the same result.
+func mergeAbuseContacts(contacts []types.AbuseContact) []types.AbuseContact {
+	best := make(map[string]int, len(contacts))
+	var merged []types.AbuseContact
+
+	for _, c := range contacts {
+		key := strings.ToLower(c.Email)
+		i, seen := best[key]
+		if !seen {
+			best[key] = len(merged)
+			merged = append(merged, c)
+			continue
+		}
+
+		current := merged[i].Source
+		if abuseSourceRank(c.Source) < abuseSourceRank(current) ||
+			(abuseSourceRank(c.Source) == abuseSourceRank(current) && c.Source < current) {
+			merged[i].Source = c.Source
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		ri, rj := abuseSourceRank(merged[i].Source), abuseSourceRank(merged[j].Source)
+		if ri != rj {
+			return ri < rj
+		}
+		return merged[i].Source < merged[j].Source
+	})
+
+	return merged
+}
@@ -0,0 +1,169 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/csirt"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+	"nuclei-parse-enrich/pkg/whoistest"
+)
+
+func TestMergeAbuseContacts(t *testing.T) {
+	ripeStat := func(email string) types.AbuseContact {
+		return types.AbuseContact{Email: email, Kind: contact.KindRole, Source: types.AbuseSourceRipeSTAT}
+	}
+	whois := func(email string) types.AbuseContact {
+		return types.AbuseContact{Email: email, Kind: contact.KindRole, Source: types.AbuseSourceWhois}
+	}
+	cert := func(email string) types.AbuseContact {
+		return types.AbuseContact{Email: email, Kind: contact.KindRole, Source: types.AbuseSourceNationalCERT}
+	}
+
+	tests := []struct {
+		name     string
+		contacts []types.AbuseContact
+		want     []types.AbuseContact
+	}{
+		{
+			"three sources disagreeing",
+			[]types.AbuseContact{cert("cert@ncsc.nl"), whois("noc@ripe.net"), ripeStat("abuse@ripe.net")},
+			[]types.AbuseContact{ripeStat("abuse@ripe.net"), whois("noc@ripe.net"), cert("cert@ncsc.nl")},
+		},
+		{
+			// the address all three report is attributed to RipeSTAT, in the case it was found in first
+			"three sources agreeing on one address",
+			[]types.AbuseContact{cert("ABUSE@ripe.net"), whois("noc@ripe.net"), whois("Abuse@RIPE.net"), ripeStat("abuse@ripe.net"), cert("cert@ncsc.nl")},
+			[]types.AbuseContact{{Email: "ABUSE@ripe.net", Kind: contact.KindRole, Source: types.AbuseSourceRipeSTAT}, whois("noc@ripe.net"), cert("cert@ncsc.nl")},
+		},
+		{
+			// sources without precedence follow by name
+			"unranked sources",
+			[]types.AbuseContact{{Email: "b@example.net", Source: "peeringdb"}, cert("cert@ncsc.nl"), {Email: "a@example.net", Source: "irr"}, whois("noc@ripe.net")},
+			[]types.AbuseContact{whois("noc@ripe.net"), {Email: "a@example.net", Source: "irr"}, cert("cert@ncsc.nl"), {Email: "b@example.net", Source: "peeringdb"}},
+		},
+		{
+			"unranked sources agreeing",
+			[]types.AbuseContact{{Email: "a@example.net", Source: "peeringdb"}, {Email: "a@example.net", Source: "irr"}},
+			[]types.AbuseContact{{Email: "a@example.net", Source: "irr"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeAbuseContacts(tt.contacts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeAbuseContacts = %+v, want %+v", got, tt.want)
+			}
+
+			// the order the sources report in doesn't matter
+			reversed := make([]types.AbuseContact, len(tt.contacts))
+			for i, c := range tt.contacts {
+				reversed[len(reversed)-1-i] = c
+			}
+			sources := func(contacts []types.AbuseContact) []string {
+				var ret []string
+				for _, c := range contacts {
+					ret = append(ret, strings.ToLower(c.Email)+" "+c.Source)
+				}
+				return ret
+			}
+			if got := mergeAbuseContacts(reversed); !reflect.DeepEqual(sources(got), sources(tt.want)) {
+				t.Errorf("mergeAbuseContacts of the reversed contacts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbuseSource(t *testing.T) {
+	inetnum := readWhois(t, "ripe-inetnum.txt")
+	noContacts := ripestattest.JSON(`{"abuse_contacts": []}`)
+	certs := csirt.NewRouting()
+	if err := certs.Add("NL", "cert@ncsc.example"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		ipAddr string
+		// abuse are the abuse-contact-finder responses, the default one of newTestServer when nil
+		abuse []ripestattest.Response
+		// whois is the whois response, whois is left out when empty and RipeSTAT has no contacts
+		// otherwise, see newWhoisEnricher
+		whois     string
+		opts      []Option
+		want      string
+		wantAbuse string
+	}{
+		{"RipeSTAT", "193.0.6.139", nil, "", nil, types.AbuseSourceRipeSTAT, "abuse@ripe.net"},
+		{"whois", "193.0.6.139", []ripestattest.Response{noContacts}, inetnum, nil, types.AbuseSourceWhois, "abuse@ripe.net;ops@ripe.net"},
+		{"none without whois", "193.0.6.139", []ripestattest.Response{noContacts}, "", nil, types.AbuseSourceNone, ""},
+		{"none from whois", "193.0.6.139", []ripestattest.Response{noContacts}, "inetnum: 193.0.0.0 - 193.0.7.255\n", nil, types.AbuseSourceNone, ""},
+		{"error", "193.0.6.139", []ripestattest.Response{ripestattest.Error(http.StatusBadRequest, "bad request")}, "", nil, types.AbuseSourceError, ""},
+		{"source unavailable", "193.0.6.139", []ripestattest.Response{ripestattest.Maintenance("RIPEstat is in maintenance")}, "", nil, types.AbuseSourceUnavailable, types.SourceUnavailable},
+		{"skipped private", "10.0.0.1", nil, "", nil, types.AbuseSourceSkippedPrivate, ""},
+		{"skipped RIR", "193.0.6.139", nil, "", []Option{WithRIRs("arin")}, types.AbuseSourceSkippedRIR, ""},
+		{"skipped whois RIR", "193.0.6.139", []ripestattest.Response{noContacts}, inetnum, []Option{WithWhoisRIRs("arin")}, types.AbuseSourceSkippedRIR, ""},
+		// the national CERT is added to the contacts, the source still tells where the others were found
+		{"national CERT", "193.0.6.139", nil, "", []Option{WithNationalCERTs(certs)}, types.AbuseSourceRipeSTAT, "abuse@ripe.net;cert@ncsc.example"},
+		{"only the national CERT", "193.0.6.139", []ripestattest.Response{noContacts}, "", []Option{WithNationalCERTs(certs)}, types.AbuseSourceNone, "cert@ncsc.example"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			if tt.abuse != nil {
+				server.Handle("abuse-contact-finder", tt.ipAddr, tt.abuse...)
+			}
+
+			e := newTestEnricher(server, tt.opts...)
+			if tt.whois != "" {
+				client := whoistest.NewClient()
+				client.Handle(tt.ipAddr, "", whoistest.Text(tt.whois))
+				e = newWhoisEnricher(t, client, tt.opts...)
+			}
+
+			got := e.EnrichIP(context.Background(), tt.ipAddr)
+			if got.AbuseSource != tt.want || got.Abuse != tt.wantAbuse {
+				t.Errorf("abuse %q from %q, want %q from %q (errors %v)", got.Abuse, got.AbuseSource, tt.wantAbuse, tt.want, got.Errors)
+			}
+			if _, failed := got.Errors["Abuse"]; failed != (tt.want == types.AbuseSourceError || tt.want == types.AbuseSourceUnavailable) {
+				t.Errorf("errors %v for abuse source %s", got.Errors, got.AbuseSource)
+			}
+		})
+	}
+}
+
+func TestAbuseAddresses(t *testing.T) {
+	server := newTestServer(t)
+	server.Handle("abuse-contact-finder", "193.0.6.139",
+		ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net", "not an address", "NOC <noc@ripe.net>", "ABUSE@ripe.net", "@ripe.net"]}`))
+	server.Handle("abuse-contact-finder", "193.0.6.140", ripestattest.JSON(`{"abuse_contacts": ["not an address", "abuse at ripe.net"]}`))
+	e := newTestEnricher(server, WithAbuseAddressList())
+
+	// invalid addresses are dropped, duplicates listed once
+	got := e.EnrichIP(context.Background(), "193.0.6.139")
+	if want := []string{"abuse@ripe.net", "noc@ripe.net"}; !reflect.DeepEqual(got.AbuseAddresses, want) || !reflect.DeepEqual(got.AbuseList(), want) {
+		t.Errorf("AbuseAddresses %q and AbuseList %q, want %q", got.AbuseAddresses, got.AbuseList(), want)
+	}
+	if got.Abuse != "abuse@ripe.net;noc@ripe.net" || got.AbuseTo != "<abuse@ripe.net>, <noc@ripe.net>" {
+		t.Errorf("Abuse %q and AbuseTo %q", got.Abuse, got.AbuseTo)
+	}
+
+	// without a valid address there are none at all
+	got = e.EnrichIP(context.Background(), "193.0.6.140")
+	if got.AbuseAddresses != nil || got.AbuseList() != nil || got.Abuse != "" || got.AbuseTo != "" || got.AbuseSource != types.AbuseSourceNone {
+		t.Errorf("AbuseAddresses %q, Abuse %q, AbuseTo %q from %s, want none", got.AbuseAddresses, got.Abuse, got.AbuseTo, got.AbuseSource)
+	}
+}
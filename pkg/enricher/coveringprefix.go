@@ -0,0 +1,63 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+)
+
+// WithCoveringPrefix records the least specific announced prefix covering the prefix of every IP
+// address, and its origin AS, from the routing-status data call. network-info already returns the
+// most specific announced prefix holding the IP address, the covering prefix is the aggregate of
+// the whole address block, often announced by its holder or upstream.
+func WithCoveringPrefix() Option {
+	return func(e *Enricher) {
+		e.coveringPrefix = true
+	}
+}
+
+// enrichCoveringPrefix returns the shortest announced prefix covering prefix and its origin AS, see
+// WithCoveringPrefix. Both are empty when prefix is not covered by another announcement.
+func (e *Enricher) enrichCoveringPrefix(ctx context.Context, ipAddr string, prefix string) (string, string, error) {
+	announced, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid prefix %q: %v", prefix, err)
+	}
+
+	start := time.Now()
+	status, err := e.rs.GetRoutingStatus(ctx, prefix)
+	if err != nil {
+		e.lookupLog(ipAddr, "routing-status", start).Warnf("covering prefix err: %v", err)
+		return "", "", err
+	}
+
+	var covering ripestat.RoutedPrefix
+	coveringBits := announced.Bits()
+	for _, less := range status.LessSpecifics {
+		candidate, err := netip.ParsePrefix(less.Prefix)
+		if err != nil || !candidate.Contains(announced.Addr()) {
+			continue
+		}
+		if candidate.Bits() < coveringBits {
+			covering, coveringBits = less, candidate.Bits()
+		}
+	}
+	if coveringBits == announced.Bits() {
+		return "", "", nil
+	}
+
+	origin, err := e.normalizeASN(string(covering.Origin))
+	if err != nil || origin == "unknown" {
+		origin = ""
+	}
+	return covering.Prefix, origin, nil
+}
@@ -0,0 +1,100 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+func TestCoveringPrefix(t *testing.T) {
+	tests := []struct {
+		name          string
+		routingStatus ripestattest.Response
+		wantPrefix    string
+		wantAsn       string
+		wantErr       bool
+	}{
+		{
+			name: "nested less specifics",
+			routingStatus: ripestattest.JSON(`{"resource": "193.0.0.0/21", "less_specifics": [
+				{"prefix": "193.0.0.0/20", "origin": "3333"},
+				{"prefix": "193.0.0.0/8", "origin": 1299},
+				{"prefix": "193.0.0.0/16", "origin": "AS3333"},
+				{"prefix": "193.0.0.0/12", "origin": "2914"}
+			]}`),
+			wantPrefix: "193.0.0.0/8",
+			wantAsn:    "1299",
+		},
+		{
+			name: "single less specific",
+			routingStatus: ripestattest.JSON(`{"resource": "193.0.0.0/21", "less_specifics": [
+				{"prefix": "193.0.0.0/16", "origin": "AS3333"}
+			]}`),
+			wantPrefix: "193.0.0.0/16",
+			wantAsn:    "3333",
+		},
+		{
+			name: "no covering prefix",
+			routingStatus: ripestattest.JSON(`{"resource": "193.0.0.0/21", "less_specifics": [
+				{"prefix": "194.0.0.0/8", "origin": "1299"},
+				{"prefix": "193.0.0.0/21", "origin": "3333"},
+				{"prefix": "2001:600::/23", "origin": "3333"},
+				{"prefix": "not a prefix", "origin": "3333"}
+			]}`),
+		},
+		{
+			name:          "without less specifics",
+			routingStatus: ripestattest.JSON(`{"resource": "193.0.0.0/21", "less_specifics": []}`),
+		},
+		{
+			name: "unknown origin",
+			routingStatus: ripestattest.JSON(`{"resource": "193.0.0.0/21", "less_specifics": [
+				{"prefix": "193.0.0.0/8", "origin": ""}
+			]}`),
+			wantPrefix: "193.0.0.0/8",
+		},
+		{
+			name:          "error",
+			routingStatus: ripestattest.Error(http.StatusBadRequest, "bad request"),
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Handle("routing-status", "193.0.0.0/21", tt.routingStatus)
+
+			got := newTestEnricher(server, WithCoveringPrefix()).EnrichIP(context.Background(), "193.0.6.139")
+			// the covering prefix is recorded next to the most specific one, which stays the prefix
+			if got.Prefix != "193.0.0.0/21" || got.Asn != "3333" {
+				t.Errorf("prefix %q with ASN %q, want 193.0.0.0/21 with 3333", got.Prefix, got.Asn)
+			}
+			if got.CoveringPrefix != tt.wantPrefix || got.CoveringAsn != tt.wantAsn {
+				t.Errorf("covering prefix %q with ASN %q, want %q with %q", got.CoveringPrefix, got.CoveringAsn, tt.wantPrefix, tt.wantAsn)
+			}
+			if _, failed := got.Errors["CoveringPrefix"]; failed != tt.wantErr {
+				t.Errorf("errors %v, CoveringPrefix error expected: %v", got.Errors, tt.wantErr)
+			}
+			if got.Abuse != "abuse@ripe.net" {
+				t.Errorf("abuse %q, want the contact of the most specific prefix", got.Abuse)
+			}
+			server.AssertRequests(t, "routing-status", "193.0.0.0/21", 1)
+		})
+	}
+
+	// without the option routing-status isn't asked
+	server := newTestServer(t)
+	if got := newTestEnricher(server).EnrichIP(context.Background(), "193.0.6.139"); got.CoveringPrefix != "" {
+		t.Errorf("covering prefix %q without WithCoveringPrefix", got.CoveringPrefix)
+	}
+	server.AssertRequests(t, "routing-status", "193.0.0.0/21", 0)
+}
@@ -0,0 +1,51 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"time"
+
+	"nuclei-parse-enrich/pkg/cymru"
+)
+
+// WithASNCrossCheck also resolves the origin AS with the Team Cymru whois service and flags
+// records where it does not match the RipeSTAT AS.
+func WithASNCrossCheck() Option {
+	return func(e *Enricher) {
+		e.cymru = cymru.NewCymruClient()
+	}
+}
+
+// crossCheckASN returns the origin AS according to Team Cymru and whether it disagrees with
+// the AS found in RipeSTAT. Only two known values that differ count as a discrepancy.
+func (e *Enricher) crossCheckASN(ctx context.Context, ipAddr string, asNumber string) (string, bool, error) {
+	if e.whoisTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.whoisTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	origin, err := e.cymru.LookupOrigin(ctx, ipAddr)
+	if err != nil {
+		e.lookupLog(ipAddr, "cymru-whois", start).Warnf("asn cross-check err: %v", err)
+		return "unknown", false, err
+	}
+
+	whoisAsn, err := e.normalizeASN(origin.Asn)
+	if err != nil {
+		return whoisAsn, false, err
+	}
+
+	discrepancy := asNumber != "unknown" && asNumber != whoisAsn
+	if discrepancy {
+		e.log.Warnf("asn discrepancy for %s: RipeSTAT reports AS%s, Team Cymru reports AS%s", ipAddr, asNumber, whoisAsn)
+	}
+
+	return whoisAsn, discrepancy, nil
+}
@@ -0,0 +1,57 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/cymru"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/whoistest"
+)
+
+// TestASNCrossCheck compares the origin AS of RipeSTAT with the one of Team Cymru.
+func TestASNCrossCheck(t *testing.T) {
+	tests := []struct {
+		name            string
+		networkInfo     ripestattest.Response
+		cymru           whoistest.Response
+		wantAsn         string
+		wantWhoisAsn    string
+		wantDiscrepancy bool
+	}{
+		{"same", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`),
+			whoistest.Text("AS | IP | AS Name\n3333 | 193.0.6.139 | RIPE-NCC-AS, NL\n"), "3333", "3333", false},
+		{"different", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`),
+			whoistest.Text("AS | IP | AS Name\n1299 | 193.0.6.139 | TWELVE99, SE\n"), "3333", "1299", true},
+		// only two known AS numbers can disagree
+		{"unknown to RipeSTAT", ripestattest.JSON(`{"asns": [], "prefix": "193.0.0.0/21"}`),
+			whoistest.Text("AS | IP | AS Name\n1299 | 193.0.6.139 | TWELVE99, SE\n"), "unknown", "1299", false},
+		{"unknown to Team Cymru", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`),
+			whoistest.Text("AS | IP | AS Name\nNA | 193.0.6.139 | NA\n"), "3333", "unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Handle("network-info", "", tt.networkInfo)
+			server.Handle("routing-status", "", ripestattest.JSON(`{"last_seen": {}}`))
+			whois := whoistest.NewClient()
+			whois.Handle("193.0.6.139", cymru.WhoisServer, tt.cymru)
+			e := newTestEnricher(server, WithASNCrossCheck(), WithUnknownPlaceholder())
+			e.cymru.SetDialer(whois)
+
+			got := e.EnrichIP(context.Background(), "193.0.6.139")
+			if got.Asn != tt.wantAsn || got.WhoisAsn != tt.wantWhoisAsn || got.AsnDiscrepancy != tt.wantDiscrepancy {
+				t.Errorf("asn %s, whois asn %s and discrepancy %v, want %s, %s and %v",
+					got.Asn, got.WhoisAsn, got.AsnDiscrepancy, tt.wantAsn, tt.wantWhoisAsn, tt.wantDiscrepancy)
+			}
+			whois.AssertLookups(t, "193.0.6.139", 1)
+		})
+	}
+}
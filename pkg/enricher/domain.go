@@ -0,0 +1,223 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// errWhoisRestricted is recorded for registries that answer whois without registration details,
+// e.g. because of GDPR or because they only serve them to accredited parties.
+var errWhoisRestricted = errors.New("whois data restricted by the registry")
+
+// errNotInWhois is recorded for the fields missing from a whois response.
+var errNotInWhois = errors.New("not found in the whois data")
+
+var (
+	registrarKeys    = []string{"registrar", "sponsoring registrar", "registrar name", "registrar organization"}
+	creationDateKeys = []string{"creation date", "created", "created on", "registered on", "registered", "registration time", "domain registration date", "domain record activated"}
+	nameServerKeys   = []string{"name server", "name servers", "nameserver", "nameservers", "nserver", "domain nameservers"}
+	abuseEmailKeys   = []string{"registrar abuse contact email", "abuse-mailbox", "abuse contact email"}
+)
+
+// maxDomainNameServers bounds the name servers taken from a domain whois response, registries list
+// a handful
+const maxDomainNameServers = 32
+
+// EnrichDomain looks up the registrar, creation date, name servers and abuse contact of domain
+// using whois. Name servers missing from whois are resolved with DNS. Fields that can't be found
+// are left empty with the reason in Errors, next to the lookups that failed or registries
+// restricting whois. WithUnknownPlaceholder sets them to "unknown" instead.
+func (e *Enricher) EnrichDomain(ctx context.Context, domain string) types.DomainInfo {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+
+	ret := types.DomainInfo{
+		Domain:     domain,
+		EnrichedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// whoisErr is why the fields missing from whois are missing
+	whoisErr := errNotInWhois
+	if e.noWhois {
+		whoisErr = errors.New("whois lookups are disabled")
+		addDomainError(&ret, "Whois", whoisErr)
+	} else {
+		start := time.Now()
+		raw, err := e.whoisWithContext(ctx, domain)
+		if err != nil {
+			e.lookupLog(domain, "whois", start).Warnf("domain whois err: %v", err)
+			whoisErr = err
+			addDomainError(&ret, "Whois", err)
+		} else {
+			parsed := parseDomainWhois(raw)
+			ret.Registrar = parsed.registrar
+			ret.CreationDate = parsed.creationDate
+			ret.Abuse = parsed.abuse
+			ret.NameServers = parsed.nameServers
+
+			if parsed.registrar == "" && parsed.creationDate == "" && len(parsed.nameServers) == 0 {
+				whoisErr = errWhoisRestricted
+				addDomainError(&ret, "Whois", errWhoisRestricted)
+			}
+		}
+	}
+
+	for field, value := range map[string]string{"Registrar": ret.Registrar, "CreationDate": ret.CreationDate, "Abuse": ret.Abuse} {
+		if value == "" {
+			addDomainError(&ret, field, whoisErr)
+		}
+	}
+
+	if len(ret.NameServers) == 0 {
+		nameServers, err := net.DefaultResolver.LookupNS(ctx, domain)
+		if err != nil {
+			addDomainError(&ret, "NameServers", err)
+		}
+		for _, nameServer := range nameServers {
+			ret.NameServers = appendNameServer(ret.NameServers, nameServer.Host)
+		}
+		sort.Strings(ret.NameServers)
+	}
+
+	if e.unknownPlaceholder {
+		fillUnknownDomain(&ret)
+	}
+	return ret
+}
+
+// fillUnknownDomain sets the fields that could not be determined to "unknown", as written before
+// they were left empty, see WithUnknownPlaceholder.
+func fillUnknownDomain(info *types.DomainInfo) {
+	for _, field := range []*string{&info.Registrar, &info.CreationDate, &info.Abuse} {
+		if *field == "" {
+			*field = "unknown"
+		}
+	}
+}
+
+func addDomainError(info *types.DomainInfo, field string, err error) {
+	if err == nil {
+		return
+	}
+	if info.Errors == nil {
+		info.Errors = make(map[string]string)
+	}
+	info.Errors[field] = err.Error()
+}
+
+type domainWhois struct {
+	registrar    string
+	creationDate string
+	nameServers  []string
+	abuse        string
+}
+
+// parseDomainWhois extracts the registration details from a domain whois response. Registries
+// all use their own layout, so the common "key: value" spellings are recognised, as well as keys
+// followed by an indented block of values (e.g. "Name servers:" in .uk and .nl responses). The
+// first value found for a key wins, as thin registry data precedes the registrar's data.
+func parseDomainWhois(raw string) domainWhois {
+	if len(raw) > maxWhoisResponseSize {
+		raw = raw[:maxWhoisResponseSize]
+	}
+
+	var parsed domainWhois
+	blockKey, blockIndent := "", 0
+
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "%") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ">>>") {
+			blockKey = ""
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		key, value, found := strings.Cut(trimmed, ":")
+		switch {
+		case blockKey != "" && indent > blockIndent && !(found && isDomainWhoisKey(strings.ToLower(key))):
+			// a value in the block following a key without value
+			key, value = blockKey, trimmed
+		case !found:
+			blockKey = ""
+			continue
+		default:
+			key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+			blockKey = ""
+			if value == "" {
+				blockKey, blockIndent = key, indent
+				continue
+			}
+		}
+
+		switch {
+		case hasKey(registrarKeys, key):
+			if parsed.registrar == "" {
+				parsed.registrar = value
+			}
+		case hasKey(creationDateKeys, key):
+			if parsed.creationDate == "" {
+				parsed.creationDate = value
+			}
+		case hasKey(nameServerKeys, key):
+			// name servers can be followed by their glue addresses
+			parsed.nameServers = appendNameServer(parsed.nameServers, strings.Fields(value)[0])
+		case hasKey(abuseEmailKeys, key):
+			if parsed.abuse == "" {
+				if address, err := mail.ParseAddress(value); err == nil {
+					parsed.abuse = strings.ToLower(address.Address)
+				}
+			}
+		}
+	}
+
+	sort.Strings(parsed.nameServers)
+	return parsed
+}
+
+func hasKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isDomainWhoisKey(key string) bool {
+	for _, keys := range [][]string{registrarKeys, creationDateKeys, nameServerKeys, abuseEmailKeys} {
+		if hasKey(keys, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendNameServer appends the normalised name server to nameServers unless already present or
+// maxDomainNameServers are found.
+func appendNameServer(nameServers []string, nameServer string) []string {
+	nameServer = strings.TrimSuffix(strings.ToLower(nameServer), ".")
+	if nameServer == "" || len(nameServers) >= maxDomainNameServers {
+		return nameServers
+	}
+	for _, existing := range nameServers {
+		if existing == nameServer {
+			return nameServers
+		}
+	}
+	return append(nameServers, nameServer)
+}
@@ -0,0 +1,73 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDomainWhois(t *testing.T) {
+	tests := []struct {
+		file string
+		want domainWhois
+	}{
+		{"com-domain.txt", domainWhois{
+			registrar:    "RESERVED-Internet Assigned Numbers Authority",
+			creationDate: "1995-08-14T04:00:00Z",
+			nameServers:  []string{"a.iana-servers.net", "b.iana-servers.net"},
+			abuse:        "abuse@example.com",
+		}},
+		{"nl-domain.txt", domainWhois{
+			registrar:    "RIPE NCC",
+			creationDate: "1999-01-01",
+			nameServers:  []string{"ns3.nic.fr", "rirns.arin.net", "sns-pb.isc.org"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			raw, err := os.ReadFile(filepath.Join("testdata", "whois", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := parseDomainWhois(string(raw)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDomainWhois = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseDomainWhoisPathological parses responses of the maximum size built to be slow to parse.
+func TestParseDomainWhoisPathological(t *testing.T) {
+	var nameServers strings.Builder
+	for i := 0; nameServers.Len() < maxWhoisResponseSize; i++ {
+		fmt.Fprintf(&nameServers, "nserver: ns%d.example.net\n", i)
+	}
+	responses := map[string]string{
+		"distinct name servers": nameServers.String(),
+		"one long line":         "registrar: " + strings.Repeat("x", maxWhoisResponseSize),
+		"nested blocks":         strings.Repeat("name servers:\n ", maxWhoisResponseSize/16),
+		"colons":                strings.Repeat(":", maxWhoisResponseSize),
+		"over the limit":        strings.Repeat("abuse-mailbox: abuse@example.com\n", 2*maxWhoisResponseSize/33),
+	}
+
+	for name, raw := range responses {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			parseDomainWhois(raw)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("parsing took %v", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/bgpview"
+	"nuclei-parse-enrich/pkg/cymru"
+	"nuclei-parse-enrich/pkg/rdap"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// Network is what a FallbackProvider found for an IP address, empty fields are unknown to it.
+type Network struct {
+	Asn     string
+	Prefix  string
+	Holder  string
+	Country string
+}
+
+// FallbackProvider looks up the network of an IP address when RipeSTAT couldn't, see
+// WithFallbackProviders. Its name is recorded as the source of the fields it filled in.
+type FallbackProvider interface {
+	Name() string
+	LookupNetwork(ctx context.Context, ipAddr string) (Network, error)
+}
+
+// WithFallbackProviders fills in the ASN, prefix, holder and country of the IP addresses RipeSTAT
+// failed to look up them for, e.g. during an outage, from the providers in order: every field is
+// taken from the first provider knowing it, and the providers after it are only asked for the
+// fields still missing. The provider filling the ASN, holder or country is recorded in asn_source,
+// holder_source or geo_source.
+func WithFallbackProviders(providers ...FallbackProvider) Option {
+	return func(e *Enricher) {
+		e.fallback = providers
+	}
+}
+
+// RDAPProvider is the FallbackProvider "rdap", the network registered for the IP address. The
+// registry names the origin AS of the network at ARIN only.
+func RDAPProvider(c *rdap.Client) FallbackProvider {
+	return rdapProvider{c}
+}
+
+type rdapProvider struct {
+	client *rdap.Client
+}
+
+func (p rdapProvider) Name() string {
+	return "rdap"
+}
+
+func (p rdapProvider) LookupNetwork(ctx context.Context, ipAddr string) (Network, error) {
+	network, err := p.client.LookupIP(ctx, ipAddr)
+	if err != nil {
+		return Network{}, err
+	}
+	return Network{Asn: network.Asn, Prefix: network.Prefix, Holder: network.Holder, Country: network.Country}, nil
+}
+
+// CymruProvider is the FallbackProvider "cymru", the origin AS, prefix and allocation country of
+// the Team Cymru IP to ASN mapping.
+func CymruProvider(c *cymru.Client) FallbackProvider {
+	return cymruProvider{c}
+}
+
+type cymruProvider struct {
+	client *cymru.Client
+}
+
+func (p cymruProvider) Name() string {
+	return "cymru"
+}
+
+func (p cymruProvider) LookupNetwork(ctx context.Context, ipAddr string) (Network, error) {
+	origin, err := p.client.LookupNetwork(ctx, ipAddr)
+	if err != nil {
+		return Network{}, err
+	}
+	return Network{Asn: origin.Asn, Prefix: origin.Prefix, Holder: origin.AsName, Country: origin.CountryCode}, nil
+}
+
+// BGPViewProvider is the FallbackProvider "bgpview", the most specific prefix announcing the IP
+// address according to BGPView and its origin AS.
+func BGPViewProvider(c *bgpview.Client) FallbackProvider {
+	return bgpviewProvider{c}
+}
+
+type bgpviewProvider struct {
+	client *bgpview.Client
+}
+
+func (p bgpviewProvider) Name() string {
+	return "bgpview"
+}
+
+func (p bgpviewProvider) LookupNetwork(ctx context.Context, ipAddr string) (Network, error) {
+	ip, err := p.client.LookupIP(ctx, ipAddr)
+	if err != nil {
+		return Network{}, err
+	}
+
+	network := Network{Country: ip.RIRAllocation.CountryCode}
+	if prefix, found := ip.MostSpecific(); found {
+		network.Asn, network.Prefix, network.Holder = prefix.Origin(), prefix.Prefix, prefix.Holder()
+		if prefix.CountryCode != "" {
+			network.Country = prefix.CountryCode
+		}
+	}
+	return network, nil
+}
+
+// enrichFromFallback fills in the fields RipeSTAT failed to look up from the fallback providers,
+// see WithFallbackProviders. The errors of the providers are returned together.
+func (e *Enricher) enrichFromFallback(ctx context.Context, info *types.EnrichInfo) error {
+	failed := func(fields ...string) bool {
+		for _, field := range fields {
+			if info.Errors[field] != "" {
+				return true
+			}
+		}
+		return false
+	}
+	missing := func() (asn, prefix, holder, country bool) {
+		return info.Asn == "unknown" && failed("Prefix"),
+			info.Prefix == "unknown" && failed("Prefix"),
+			info.Holder == "unknown" && failed("Prefix", "Holder"),
+			sanitizeCountry(info.Country) == "unknown" && failed("Prefix", "Geolocation")
+	}
+
+	var errs []string
+	for _, provider := range e.fallback {
+		asn, prefix, holder, country := missing()
+		if !asn && !prefix && !holder && !country {
+			break
+		}
+
+		start := time.Now()
+		network, err := provider.LookupNetwork(ctx, info.Ip)
+		if err != nil {
+			e.lookupLog(info.Ip, provider.Name(), start).Warnf("fallback err: %v", err)
+			errs = append(errs, fmt.Sprintf("%s: %v", provider.Name(), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		if asn && network.Asn != "" {
+			if normalized, err := e.normalizeASN(network.Asn); err == nil {
+				info.Asn, info.AsnSource = normalized, provider.Name()
+			}
+		}
+		if prefix && network.Prefix != "" {
+			info.Prefix = network.Prefix
+		}
+		if holder && network.Holder != "" {
+			info.Holder, info.HolderSource = network.Holder, provider.Name()
+		}
+		if country && sanitizeCountry(network.Country) != "unknown" {
+			info.Country, info.GeoSource = sanitizeCountry(network.Country), provider.Name()
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/bgpview"
+	"nuclei-parse-enrich/pkg/rdap"
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+// rdapNetwork is the RDAP network of 193.0.6.139, without origin AS as RIPE doesn't list it
+const rdapNetwork = `{"objectClassName": "ip network", "handle": "193.0.0.0 - 193.0.7.255", "name": "RIPE-NCC",
+	"country": "NL", "startAddress": "193.0.0.0", "endAddress": "193.0.7.255",
+	"entities": [{"roles": ["registrant"], "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "RIPE Network Coordination Centre"]]]}]}`
+
+// bgpviewIP is what BGPView knows about 193.0.6.139
+const bgpviewIP = `{"status": "ok", "status_message": "Query was successful", "data": {"ip": "193.0.6.139",
+	"prefixes": [{"prefix": "193.0.0.0/21", "name": "RIPE-NCC", "description": "RIPE Network Coordination Centre", "country_code": "NL",
+		"asn": {"asn": 3333, "name": "RIPE-NCC-AS", "description": "Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", "country_code": "NL"}}],
+	"rir_allocation": {"country_code": "NL", "prefix": "193.0.0.0/21"}}}`
+
+// newStubServer returns a server answering every request with status and body, counting them in requests.
+func newStubServer(t *testing.T, status int, body string, requests *int) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if !strings.HasPrefix(r.URL.Path, "/ip/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestFallbackProviders(t *testing.T) {
+	const failed = `{"status": "error", "status_message": "Internal error"}`
+
+	tests := []struct {
+		name                         string
+		rdapStatus                   int
+		rdapBody                     string
+		bgpviewStatus                int
+		bgpviewBody                  string
+		wantAsn, wantAsnSource       string
+		wantPrefix                   string
+		wantHolder, wantHolderSource string
+		wantCountry, wantGeoSource   string
+		// wantErrs are the providers failing in the Fallback error
+		wantErrs []string
+	}{
+		{
+			"rdap fails", http.StatusInternalServerError, "", http.StatusOK, bgpviewIP,
+			"3333", "bgpview", "193.0.0.0/21",
+			"RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL", "bgpview", "NL", "bgpview",
+			[]string{"rdap"},
+		},
+		{
+			// RDAP doesn't know the AS, BGPView is asked for it
+			"rdap partial", http.StatusOK, rdapNetwork, http.StatusOK, bgpviewIP,
+			"3333", "bgpview", "193.0.0.0/21", "RIPE Network Coordination Centre", "rdap", "NL", "rdap",
+			nil,
+		},
+		{
+			"both fail", http.StatusNotFound, "", http.StatusInternalServerError, failed,
+			// the geolocation stays attributed to RipeSTAT
+			"", "", "", "", "", "", "RipeSTAT",
+			[]string{"rdap", "bgpview"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Handle("network-info", "193.0.6.139", ripestattest.Error(http.StatusBadRequest, "bad request"))
+
+			var rdapRequests, bgpviewRequests int
+			rdapClient := rdap.NewRDAPClient()
+			rdapClient.BaseURL = newStubServer(t, tt.rdapStatus, tt.rdapBody, &rdapRequests)
+			bgpviewClient := bgpview.NewBGPViewClient()
+			bgpviewClient.BaseURL = newStubServer(t, tt.bgpviewStatus, tt.bgpviewBody, &bgpviewRequests)
+
+			e := newTestEnricher(server, WithFallbackProviders(RDAPProvider(rdapClient), BGPViewProvider(bgpviewClient)))
+			got := e.EnrichIP(context.Background(), "193.0.6.139")
+
+			if got.Asn != tt.wantAsn || got.AsnSource != tt.wantAsnSource || got.Prefix != tt.wantPrefix {
+				t.Errorf("asn %q from %q and prefix %q, want %q from %q and %q", got.Asn, got.AsnSource, got.Prefix, tt.wantAsn, tt.wantAsnSource, tt.wantPrefix)
+			}
+			if got.Holder != tt.wantHolder || got.HolderSource != tt.wantHolderSource {
+				t.Errorf("holder %q from %q, want %q from %q", got.Holder, got.HolderSource, tt.wantHolder, tt.wantHolderSource)
+			}
+			if got.Country != tt.wantCountry || got.GeoSource != tt.wantGeoSource {
+				t.Errorf("country %q from %q, want %q from %q", got.Country, got.GeoSource, tt.wantCountry, tt.wantGeoSource)
+			}
+
+			fallbackErr := got.Errors["Fallback"]
+			for _, provider := range tt.wantErrs {
+				if !strings.Contains(fallbackErr, provider+": ") {
+					t.Errorf("fallback error %q lacks the error of %s", fallbackErr, provider)
+				}
+			}
+			if len(tt.wantErrs) == 0 && fallbackErr != "" {
+				t.Errorf("fallback error %q", fallbackErr)
+			}
+			if got.Errors["Prefix"] == "" {
+				t.Errorf("errors %v lack the RipeSTAT failure", got.Errors)
+			}
+			// every case leaves fields for BGPView to fill in
+			if rdapRequests != 1 || bgpviewRequests != 1 {
+				t.Errorf("%d RDAP and %d BGPView requests, want one each", rdapRequests, bgpviewRequests)
+			}
+		})
+	}
+}
+
+func TestFallbackProvidersComplete(t *testing.T) {
+	server := newTestServer(t)
+	server.Handle("network-info", "193.0.6.139", ripestattest.Error(http.StatusBadRequest, "bad request"))
+
+	var rdapRequests, bgpviewRequests int
+	rdapClient := rdap.NewRDAPClient()
+	rdapClient.BaseURL = newStubServer(t, http.StatusOK, rdapNetwork, &rdapRequests)
+	bgpviewClient := bgpview.NewBGPViewClient()
+	bgpviewClient.BaseURL = newStubServer(t, http.StatusOK, bgpviewIP, &bgpviewRequests)
+
+	// BGPView first knows everything, RDAP after it is not asked
+	e := newTestEnricher(server, WithFallbackProviders(BGPViewProvider(bgpviewClient), RDAPProvider(rdapClient)))
+	got := e.EnrichIP(context.Background(), "193.0.6.139")
+	if got.Asn != "3333" || got.HolderSource != "bgpview" || got.GeoSource != "bgpview" || got.Errors["Fallback"] != "" {
+		t.Errorf("enriched %+v, want everything from bgpview", got)
+	}
+	if bgpviewRequests != 1 || rdapRequests != 0 {
+		t.Errorf("%d BGPView and %d RDAP requests, want BGPView only", bgpviewRequests, rdapRequests)
+	}
+
+	// nor are the providers when RipeSTAT succeeded
+	bgpviewRequests = 0
+	newTestEnricher(newTestServer(t), WithFallbackProviders(BGPViewProvider(bgpviewClient), RDAPProvider(rdapClient))).
+		EnrichIP(context.Background(), "193.0.6.139")
+	if bgpviewRequests != 0 || rdapRequests != 0 {
+		t.Errorf("%d BGPView and %d RDAP requests after RipeSTAT succeeded", bgpviewRequests, rdapRequests)
+	}
+}
@@ -0,0 +1,87 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// addWhoisSeeds adds the whois responses of testdata/whois to the seed corpus of f.
+func addWhoisSeeds(f *testing.F) {
+	responses, err := filepath.Glob(filepath.Join("testdata", "whois", "*.txt"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range responses {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+}
+
+// checkEmail fails t unless address is a lower case email address as net/mail parses it.
+func checkEmail(t *testing.T, address string) {
+	t.Helper()
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		t.Fatalf("invalid email address %q: %v", address, err)
+	}
+	if parsed.Address != address || strings.ToLower(address) != address {
+		t.Fatalf("email address %q not normalized, parsed as %q", address, parsed.Address)
+	}
+}
+
+func FuzzParseDomainWhois(f *testing.F) {
+	addWhoisSeeds(f)
+	f.Add("Name Server:\n\t\n   ns1.example.com 192.0.2.1\nabuse-mailbox: <a@b>\n")
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		parsed := parseDomainWhois(raw)
+		if parsed.abuse != "" {
+			checkEmail(t, parsed.abuse)
+		}
+		if len(parsed.nameServers) > maxDomainNameServers {
+			t.Fatalf("%d name servers, at most %d expected", len(parsed.nameServers), maxDomainNameServers)
+		}
+		if !sort.StringsAreSorted(parsed.nameServers) {
+			t.Fatalf("name servers not sorted: %q", parsed.nameServers)
+		}
+		for i, nameServer := range parsed.nameServers {
+			if nameServer == "" || (i > 0 && nameServer == parsed.nameServers[i-1]) {
+				t.Fatalf("empty or repeated name server in %q", parsed.nameServers)
+			}
+		}
+	})
+}
+
+func FuzzExtractWhoisEmails(f *testing.F) {
+	addWhoisSeeds(f)
+	f.Add("abuse-mailbox: Abuse@Example.COM\ne-mail: abuse@example.com.\nremarks: .a@b..c a@-b.c\n")
+
+	f.Fuzz(func(t *testing.T, whoisInfo string) {
+		emails := extractWhoisEmails(whoisInfo)
+		if len(emails) > maxWhoisEmails {
+			t.Fatalf("%d email addresses, at most %d expected", len(emails), maxWhoisEmails)
+		}
+		if !sort.StringsAreSorted(emails) {
+			t.Fatalf("email addresses not sorted: %q", emails)
+		}
+		for i, email := range emails {
+			checkEmail(t, email)
+			if i > 0 && email == emails[i-1] {
+				t.Fatalf("repeated email address in %q", emails)
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithHistoricalWhois records who held every IP address at asOf, according to the RIPE database
+// history. Only resources registered in the RIPE database have a history.
+func WithHistoricalWhois(asOf time.Time) Option {
+	return func(e *Enricher) {
+		e.asOf = asOf
+	}
+}
+
+func (e *Enricher) enrichFromHistoricalWhois(ctx context.Context, info *types.EnrichInfo) error {
+	start := time.Now()
+	object, err := e.rs.GetHistoricalWhois(ctx, info.Ip, e.asOf)
+	if errors.Is(err, ripestat.ErrNoHistoricalRecord) {
+		e.lookupLog(info.Ip, "historical-whois", start).Debugf("no historical whois record at %s", e.asOf.Format(time.RFC3339))
+		return nil
+	}
+	if err != nil {
+		e.lookupLog(info.Ip, "historical-whois", start).Warnf("historical whois err: %v", err)
+		return err
+	}
+
+	info.HistoricalHolder = object.Holder
+	info.HistoricalOrg = object.Org
+	info.HistoricalObject = object.Type + " " + object.Key
+	return nil
+}
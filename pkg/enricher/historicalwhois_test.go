@@ -0,0 +1,92 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+// historicalVersions lists an inetnum that changed holder in 2015 and the route object of the prefix.
+const historicalVersions = `{"resource": "193.0.6.139", "num_versions": 3, "versions": [
+	{"version": 1, "from_time": "2001-09-21T22:08:01", "to_time": "2015-03-01T10:00:00", "type": "inetnum", "key": "193.0.0.0 - 193.0.7.255"},
+	{"version": 2, "from_time": "2015-03-01T10:00:00", "to_time": "", "type": "inetnum", "key": "193.0.0.0 - 193.0.7.255"},
+	{"version": 1, "from_time": "2001-09-21T22:08:01", "to_time": "", "type": "route", "key": "193.0.0.0/21AS3333"}
+], "objects": []}`
+
+// historicalObject is version 1 of the inetnum.
+const historicalObject = `{"resource": "193.0.6.139", "versions": [], "objects": [
+	{"version": 1, "type": "route", "key": "193.0.0.0/21AS3333", "attributes": [{"attribute": "descr", "value": "RIPE-NCC"}]},
+	{"version": 1, "type": "inetnum", "key": "193.0.0.0 - 193.0.7.255", "attributes": [
+		{"attribute": "inetnum", "value": "193.0.0.0 - 193.0.7.255"},
+		{"attribute": "netname", "value": "RIPE-NCC-OLD"},
+		{"attribute": "descr", "value": "RIPE Network Coordination Centre"},
+		{"attribute": "org", "value": "ORG-RIEN1-RIPE"},
+		{"attribute": "netname", "value": "SECOND-NETNAME"}
+	]}
+]}`
+
+func TestHistoricalWhois(t *testing.T) {
+	tests := []struct {
+		name      string
+		asOf      time.Time
+		responses []ripestattest.Response
+		want      [3]string
+		wantErr   bool
+	}{
+		{
+			"registered",
+			time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+			[]ripestattest.Response{ripestattest.JSON(historicalVersions), ripestattest.JSON(historicalObject)},
+			[3]string{"RIPE-NCC-OLD", "ORG-RIEN1-RIPE", "inetnum 193.0.0.0 - 193.0.7.255"},
+			false,
+		},
+		{
+			// not an error, the resource wasn't registered yet
+			"before the first version",
+			time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC),
+			[]ripestattest.Response{ripestattest.JSON(historicalVersions)},
+			[3]string{},
+			false,
+		},
+		{
+			"version without object",
+			time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+			[]ripestattest.Response{ripestattest.JSON(historicalVersions), ripestattest.JSON(`{"versions": [], "objects": []}`)},
+			[3]string{},
+			false,
+		},
+		{
+			"lookup failed",
+			time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC),
+			[]ripestattest.Response{ripestattest.Error(http.StatusBadRequest, "bad request")},
+			[3]string{},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Handle("historical-whois", "193.0.6.139", tt.responses...)
+			e := newTestEnricher(server, WithHistoricalWhois(tt.asOf))
+
+			got := e.EnrichIP(context.Background(), "193.0.6.139")
+			if historical := [3]string{got.HistoricalHolder, got.HistoricalOrg, got.HistoricalObject}; historical != tt.want {
+				t.Errorf("historical holder, org and object %q, want %q", historical, tt.want)
+			}
+			if _, failed := got.Errors["HistoricalWhois"]; failed != tt.wantErr {
+				t.Errorf("errors %v, want a HistoricalWhois error %v", got.Errors, tt.wantErr)
+			}
+			server.AssertRequests(t, "historical-whois", "193.0.6.139", len(tt.responses))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"time"
+
+	"nuclei-parse-enrich/pkg/irr"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithIRR fills in the prefix, ASN and holder from the route objects in an Internet Routing
+// Registry when RipeSTAT doesn't know them.
+func WithIRR(c *irr.Client) Option {
+	return func(e *Enricher) {
+		e.irr = c
+	}
+}
+
+// enrichFromIRR fills the unknown prefix, ASN and holder of info from the most specific IRR route object.
+func (e *Enricher) enrichFromIRR(ctx context.Context, info *types.EnrichInfo) error {
+	start := time.Now()
+	route, err := e.irr.LookupRoute(ctx, info.Ip)
+	if err != nil {
+		e.lookupLog(info.Ip, "irr-whois", start).Warnf("irr err: %v", err)
+		return err
+	}
+
+	if info.Prefix == "unknown" && route.Route != "" {
+		info.Prefix = route.Route
+	}
+	if info.Asn == "unknown" && route.Origin != "" {
+		origin, err := e.normalizeASN(route.Origin)
+		if err != nil {
+			e.lookupLog(info.Ip, "irr-whois", start).Debugf("ignoring irr origin: %v", err)
+		}
+		info.Asn = origin
+	}
+	if info.Holder == "unknown" {
+		if route.Descr != "" {
+			info.Holder = route.Descr
+		} else if route.MntBy != "" {
+			info.Holder = route.MntBy
+		}
+	}
+
+	return nil
+}
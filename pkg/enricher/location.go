@@ -0,0 +1,125 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"nuclei-parse-enrich/pkg/country"
+	"nuclei-parse-enrich/pkg/geofeed"
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// sanitizeLocation cleans up the country and city found by the sources before they reach the
+// writers: the country becomes an uppercase ISO 3166-1 code (or one of the user-assigned codes
+// the registries use) and CountryName its name, placeholders such as "?" become "unknown".
+func sanitizeLocation(info *types.EnrichInfo) {
+	info.Country = sanitizeCountry(info.Country)
+	info.CountryName = country.Name(info.Country)
+	info.City = sanitizeCity(info.City)
+}
+
+// sanitizeCountry returns the normalized country code of value, or "unknown" when value is no
+// known country code.
+func sanitizeCountry(value string) string {
+	if code := country.Normalize(cleanText(value)); code != "" {
+		return code
+	}
+	return "unknown"
+}
+
+// sanitizeCity returns value as valid UTF-8 with its whitespace collapsed, or "unknown" for
+// placeholders.
+func sanitizeCity(value string) string {
+	city := cleanText(value)
+	switch strings.ToLower(city) {
+	case "", "?", "-", "n/a", "unknown":
+		return "unknown"
+	}
+	return city
+}
+
+// cleanText returns s as valid UTF-8 without control characters and with its whitespace
+// collapsed. Bytes that are not valid UTF-8 are decoded as Latin-1, the encoding the malformed
+// values come in.
+func cleanText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if r == utf8.RuneError && size == 1 {
+			r = rune(s[0])
+		}
+		s = s[size:]
+
+		if unicode.IsControl(r) || r == utf8.RuneError {
+			r = ' '
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// WithGeofeed overrides the RipeSTAT city and country with the operator published geolocation
+// of f whenever one of its prefixes covers the IP address.
+func WithGeofeed(f *geofeed.Feed) Option {
+	return func(e *Enricher) {
+		e.geofeed = f
+	}
+}
+
+func (e *Enricher) enrichLocationFromPrefix(ctx context.Context, ipAddr string, prefix string) (ripestat.ResourceLocation, error) {
+	location := ripestat.ResourceLocation{
+		City:    "unknown",
+		Country: "unknown",
+	}
+
+	if prefix == "unknown" {
+		return location, nil
+	}
+
+	start := time.Now()
+	geolocation, err := e.rs.GetGeolocationData(ctx, prefix)
+	if err != nil {
+		e.lookupLog(ipAddr, "maxmind-geo-lite", start).Warnf("geolocation err: %v", err)
+		return location, err
+	}
+
+	if len(geolocation.LocatedResources) == 0 {
+		return location, nil
+	}
+
+	if len(geolocation.LocatedResources[0].Locations) == 0 {
+		return location, nil
+	}
+
+	return geolocation.LocatedResources[0].Locations[0], nil
+}
+
+func (e *Enricher) enrichFromGeofeed(info *types.EnrichInfo) {
+	entry, found := e.geofeed.Lookup(info.Ip)
+	if !found {
+		return
+	}
+
+	if entry.Country != "" {
+		info.Country = entry.Country
+	}
+
+	if entry.City != "" {
+		info.City = entry.City
+	}
+
+	// the coordinates belong to the location the geofeed overrides
+	info.Latitude, info.Longitude = 0, 0
+	info.GeoSource = "geofeed"
+}
@@ -0,0 +1,99 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"testing"
+	"unicode/utf8"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestSanitizeCountry(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"NL", "NL"},
+		{" nl\n", "NL"},
+		{"Nl", "NL"},
+		// user-assigned codes the registries use
+		{"eu", "EU"},
+		{"AP", "AP"},
+		{"XK", "XK"},
+		{"ZZ", "unknown"},
+		{"?", "unknown"},
+		{"", "unknown"},
+		{"  ", "unknown"},
+		// not ISO 3166-1, the United Kingdom is GB
+		{"UK", "unknown"},
+		{"NLD", "unknown"},
+		{"N\x00L", "unknown"},
+		{"\xff", "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeCountry(tt.value); got != tt.want {
+			t.Errorf("sanitizeCountry(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeCity(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Amsterdam", "Amsterdam"},
+		{"  Amsterdam ", "Amsterdam"},
+		{"Den\tHaag", "Den Haag"},
+		{"New  York\r\n", "New York"},
+		{"São Paulo", "São Paulo"},
+		// Latin-1 instead of UTF-8
+		{"S\xe3o Paulo", "São Paulo"},
+		{"Z\xfcrich", "Zürich"},
+		{"Ams\x00terdam", "Ams terdam"},
+		{"?", "unknown"},
+		{"-", "unknown"},
+		{"N/A", "unknown"},
+		{"Unknown", "unknown"},
+		{"", "unknown"},
+		{" \t ", "unknown"},
+	}
+
+	for _, tt := range tests {
+		got := sanitizeCity(tt.value)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("sanitizeCity(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeLocation(t *testing.T) {
+	tests := []struct {
+		name                  string
+		country, city         string
+		wantCountry, wantName string
+		wantCity              string
+	}{
+		{"clean", "NL", "Amsterdam", "NL", "Netherlands", "Amsterdam"},
+		{"messy", " nl ", " Z\xfcrich\n", "NL", "Netherlands", "Zürich"},
+		{"user-assigned", "eu", "", "EU", "European Union", "unknown"},
+		{"placeholders", "?", "?", "unknown", "", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := types.EnrichInfo{Country: tt.country, City: tt.city, CountryName: "stale"}
+			sanitizeLocation(&info)
+			if info.Country != tt.wantCountry || info.CountryName != tt.wantName || info.City != tt.wantCity {
+				t.Errorf("sanitizeLocation = %q, %q, %q, want %q, %q, %q",
+					info.Country, info.CountryName, info.City, tt.wantCountry, tt.wantName, tt.wantCity)
+			}
+		})
+	}
+}
@@ -7,190 +7,808 @@ package enricher
  */
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"net/mail"
-	"regexp"
+	"net/netip"
 	"strings"
-
+	"time"
+
+	"nuclei-parse-enrich/pkg/annotate"
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/bogon"
+	"nuclei-parse-enrich/pkg/cache"
+	"nuclei-parse-enrich/pkg/cloud"
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/country"
+	"nuclei-parse-enrich/pkg/csirt"
+	"nuclei-parse-enrich/pkg/cymru"
+	"nuclei-parse-enrich/pkg/geofeed"
+	"nuclei-parse-enrich/pkg/irr"
+	"nuclei-parse-enrich/pkg/peeringdb"
+	"nuclei-parse-enrich/pkg/radar"
+	"nuclei-parse-enrich/pkg/rdns"
 	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/rir"
+	"nuclei-parse-enrich/pkg/tlscert"
 	"nuclei-parse-enrich/pkg/types"
+	"nuclei-parse-enrich/pkg/version"
 
-	"github.com/likexian/whois"
 	"github.com/sirupsen/logrus"
 )
 
-var whoisRegexp = regexp.MustCompile("[a-zA-Z\\d.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?(?:\\.[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?)*\\.?[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?(?:\\.[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?)*")
+// RipeStatSourceApp identifies the requests to RipeSTAT, unless WithSourceApp sets another one.
+//
+// Deprecated: use ripestat.DefaultSourceApp.
+const RipeStatSourceApp = ripestat.DefaultSourceApp
 
-const RipeStatSourceApp = "AS50559-DIVD_NL"
+// IPEnricher enriches single IP addresses, as Enricher does. Code depending on it rather than on
+// Enricher can be tested with the enrichertest.FakeEnricher.
+type IPEnricher interface {
+	EnrichIP(ctx context.Context, ipAddr string) types.EnrichInfo
+}
 
 type Enricher struct {
-	rs *ripestat.Client
+	rs      *ripestat.Client
+	workers int
+	// jobBuffer and resultBuffer size the channels of EnrichIPs, see WithChannelBuffers
+	jobBuffer    int
+	resultBuffer int
+	whoisSem     chan struct{}
+	// whoisTimeout bounds every whois lookup, zero means no limit besides the context
+	whoisTimeout time.Duration
+	// perIPTimeout bounds the enrichment of a single IP address, zero means no limit
+	perIPTimeout time.Duration
+	noWhois      bool
+	// rirs and whoisRIRs limit the enrichment and the whois lookups to the IP addresses of these
+	// RIRs, nil doesn't limit them
+	rirs      map[string]bool
+	whoisRIRs map[string]bool
+	// whoisServers are asked in turn when a whois lookup is refused or finds nothing
+	whoisServers []string
+	// whoisBreaker skips whois lookups during whois outages, nil disables it
+	whoisBreaker *whoisBreaker
+
+	classifier       *contact.Classifier
+	roleContactsOnly bool
+
+	annotator *annotate.Annotator
+	radar     *radar.Client
+	geofeed   *geofeed.Feed
+	cymru     *cymru.Client
+	irr       *irr.Client
+	log       logrus.FieldLogger
+	progress  func(Progress)
+	onResult  func(types.EnrichInfo)
+	cache     *cache.Cache
+	rdns      *rdns.Hinter
+	tlscert   *tlscert.Client
+	cloud     *cloud.Ranges
+	// whois does the whois lookups, the connections of the default client are limited to maxResponseSize
+	whois           WhoisClient
+	maxResponseSize int64
+	registryHandles bool
+	registration    bool
+	coveringPrefix  bool
+	// prefixAbuse shares the abuse contact lookups of the IP addresses of a prefix, nil disables it
+	prefixAbuse *prefixAbuseCache
+	// asnCache holds the prefixes found by LookupASN
+	asnCache *asnCache
+	// asnClassifier classifies the AS of every IP address, nil disables it
+	asnClassifier *asn.Classifier
+	// rejectPrivateASN drops private AS numbers, which are only used inside networks
+	rejectPrivateASN bool
+	abuseTo          bool
+	// adaptiveMin is the number of IP addresses enriched concurrently at first when the number
+	// adapts to rate limiting, up to the number of workers, zero disables adapting
+	adaptiveMin int
+	// unknownPlaceholder keeps "unknown" in the fields that were not determined, like records were written before
+	unknownPlaceholder bool
+	// asOf is the date the historical holder is looked up for, zero disables the lookup
+	asOf time.Time
+	// nationalCERTs adds the national CERT of the country to the abuse contacts, nil disables it
+	nationalCERTs *csirt.Routing
+	// peeringdb records where the AS of every IP address peers, nil disables it
+	peeringdb *peeringdb.Client
+	// fallback fills in what RipeSTAT failed to look up, in order
+	fallback []FallbackProvider
+	// ripeStatHints records the query time of the RipeSTAT data and caches for as long as RipeSTAT allows
+	ripeStatHints bool
+	// mappedAsIPv6 looks up IPv6 addresses embedding an IPv4 address as IPv6, see CanonicalIPAs
+	mappedAsIPv6 bool
+}
+
+// Option configures optional behaviour of an Enricher.
+type Option func(*Enricher)
+
+// WithWorkers sets the number of IP addresses EnrichIPs enriches concurrently.
+func WithWorkers(n int) Option {
+	return func(e *Enricher) {
+		e.workers = n
+	}
+}
+
+// WithAdaptiveConcurrency makes EnrichIPs start enriching lower IP addresses concurrently, ramping
+// up to the number of workers while RipeSTAT doesn't rate limit and backing off when it does.
+func WithAdaptiveConcurrency(lower int) Option {
+	return func(e *Enricher) {
+		e.adaptiveMin = lower
+	}
+}
+
+// WithLogger makes the enricher and its RipeSTAT client log to l instead of the standard logger.
+func WithLogger(l logrus.FieldLogger) Option {
+	return func(e *Enricher) {
+		e.log = l
+		e.rs.Logger = l
+	}
+}
+
+// WithProgress calls fn every time EnrichIPs finished an IP address. The calls are never
+// concurrent, but fn should return quickly as it holds up the collection of results.
+func WithProgress(fn func(Progress)) Option {
+	return func(e *Enricher) {
+		e.progress = fn
+	}
+}
+
+// WithResultHook calls fn with every result of EnrichIPs as soon as it is available. fn is
+// called from one goroutine at a time.
+func WithResultHook(fn func(types.EnrichInfo)) Option {
+	return func(e *Enricher) {
+		e.onResult = fn
+	}
+}
+
+// WithCache looks up IP addresses in c before enriching them, and stores complete enrichments in it.
+func WithCache(c *cache.Cache) Option {
+	return func(e *Enricher) {
+		e.cache = c
+	}
+}
+
+// WithChannelBuffers sizes the channels EnrichIPs hands IP addresses to its workers and collects
+// their results through. By default the jobs are handed over unbuffered and up to one result
+// per worker is buffered; a larger result buffer keeps workers busy while the result hook or
+// progress callback is slow. Zero keeps the default.
+func WithChannelBuffers(jobs, results int) Option {
+	return func(e *Enricher) {
+		if jobs > 0 {
+			e.jobBuffer = jobs
+		}
+		if results > 0 {
+			e.resultBuffer = results
+		}
+	}
+}
+
+// WithSourceApp identifies the requests to RipeSTAT with sourceApp instead of ripestat.DefaultSourceApp.
+// An invalid sourceApp (see ripestat.ValidateSourceApp) is ignored with a warning.
+func WithSourceApp(sourceApp string) Option {
+	return func(e *Enricher) {
+		e.rs.SourceApp = sourceApp
+	}
+}
+
+// WithRipeStatTimeout bounds every single RipeSTAT request to d.
+func WithRipeStatTimeout(d time.Duration) Option {
+	return func(e *Enricher) {
+		e.rs.Timeout = d
+	}
+}
+
+// WithRipeStatBaseURL sends the RipeSTAT data calls to baseURL instead of ripestat.DATA_URL, e.g.
+// a mirror or a proxy. The data call name and "/data.json" are appended to its path.
+func WithRipeStatBaseURL(baseURL string) Option {
+	return func(e *Enricher) {
+		e.rs.BaseURL = baseURL
+	}
+}
+
+// WithRipeStatHTTPClient sends the RipeSTAT requests with c instead of http.DefaultClient, e.g.
+// a client replaying recorded responses, see vcr.Recorder.
+func WithRipeStatHTTPClient(c *http.Client) Option {
+	return func(e *Enricher) {
+		e.rs.HTTPClient = c
+	}
+}
+
+// WithMaxResponseSize bounds the size of a single RipeSTAT or whois response to n bytes, larger
+// responses fail the lookup. The default is ripestat.DefaultMaxResponseSize.
+func WithMaxResponseSize(n int64) Option {
+	return func(e *Enricher) {
+		e.rs.MaxResponseSize = n
+		e.maxResponseSize = n
+	}
+}
+
+// WithSlowQueryThreshold logs a warning for every RipeSTAT call taking longer than d.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(e *Enricher) {
+		e.rs.SlowThreshold = d
+	}
+}
+
+// WithPerIPTimeout abandons the enrichment of a single IP address after d, the lookups not done by
+// then leave their fields unknown and a "Timeout" error is recorded. Other IP addresses are not
+// affected.
+func WithPerIPTimeout(d time.Duration) Option {
+	return func(e *Enricher) {
+		e.perIPTimeout = d
+	}
+}
+
+// WithAnnotator tags every enriched IP address with the matching labels of a.
+func WithAnnotator(a *annotate.Annotator) Option {
+	return func(e *Enricher) {
+		e.annotator = a
+	}
+}
+
+// WithUnknownPlaceholder writes "unknown" in the fields that could not be determined, instead of
+// leaving them empty, for consumers of the records written by earlier versions.
+func WithUnknownPlaceholder() Option {
+	return func(e *Enricher) {
+		e.unknownPlaceholder = true
+	}
 }
 
-func NewEnricher() *Enricher {
-	return &Enricher{
-		rs: ripestat.NewRipeStatClient(RipeStatSourceApp, 10),
+// WithASNCategories records the category of the AS of every IP address according to c: transit,
+// eyeball, content, enterprise or unknown.
+func WithASNCategories(c *asn.Classifier) Option {
+	return func(e *Enricher) {
+		e.asnClassifier = c
+	}
+}
+
+// WithRejectPrivateASNs treats private AS numbers (RFC 6996) found by any source as unknown and
+// records why. Reserved AS numbers such as AS0 are always treated as unknown.
+func WithRejectPrivateASNs() Option {
+	return func(e *Enricher) {
+		e.rejectPrivateASN = true
+	}
+}
+
+// WithRipeStatHints records the time RipeSTAT queried the data of every IP address, the oldest of
+// its data calls, and caches the enrichment for as long as the RipeSTAT responses may be cached
+// instead of the ttl of the cache. Responses without Cache-Control leave the ttl of the cache.
+func WithRipeStatHints() Option {
+	return func(e *Enricher) {
+		e.ripeStatHints = true
+	}
+}
+
+// WithCloudRanges records the cloud provider and region of every IP address covered by the
+// published ranges of r.
+func WithCloudRanges(r *cloud.Ranges) Option {
+	return func(e *Enricher) {
+		e.cloud = r
+	}
+}
+
+func NewEnricher(opts ...Option) *Enricher {
+	e := &Enricher{
+		rs:       ripestat.NewRipeStatClient(ripestat.DefaultSourceApp, 10),
+		workers:  DefaultWorkers,
+		whoisSem: make(chan struct{}, MaxConcurrentWhoisLookups),
+
+		whoisBreaker: newWhoisBreaker(DefaultWhoisBreakerThreshold, DefaultWhoisBreakerCooldown),
+
+		classifier: contact.NewClassifier(),
+		asnCache:   newASNCache(),
+		log:        logrus.StandardLogger(),
 		// is: ipinfo.NewIpInfoClient(),
 	}
+	e.rs.UserAgent = version.UserAgent()
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if err := ripestat.ValidateSourceApp(e.rs.SourceApp); err != nil {
+		e.log.Warnf("enricher: %v, using %q", err, ripestat.DefaultSourceApp)
+		e.rs.SourceApp = ripestat.DefaultSourceApp
+	}
+
+	if e.whois == nil {
+		e.whois = newDefaultWhoisClient(e.maxResponseSize)
+	}
+
+	return e
 }
 
-func (e *Enricher) EnrichIP(ipAddr string) types.EnrichInfo {
-	ret := types.EnrichInfo{
-		Ip: ipAddr,
+// ErrInvalidIP is recorded for values that are not an IP address, see ValidateIP.
+var ErrInvalidIP = errors.New("invalid IP address")
+
+// MaxInvalidExamples is the number of invalid values a summary lists, so they can be found in the input.
+const MaxInvalidExamples = 5
+
+// ValidateIP returns an error wrapping ErrInvalidIP when ipAddr is not an IP address, such as an
+// empty value, a host name or "N/A".
+func ValidateIP(ipAddr string) error {
+	if _, err := netip.ParseAddr(strings.Trim(ipAddr, "[]")); err != nil {
+		return fmt.Errorf("%w %q", ErrInvalidIP, ipAddr)
+	}
+	return nil
+}
+
+// EnrichIP enriches a single IP address. Lookups that are aborted because ctx is done leave
+// their fields unknown. The Ip field holds the canonical form of ipAddr, see CanonicalIPAs, and
+// IpRaw the address as given when that differs, with IpMapping when it was an IPv6 address embedding
+// the IPv4 address looked up. Values that are not an IP address are not looked
+// up at all, their record has all fields unknown and an Ip error wrapping ErrInvalidIP.
+func (e *Enricher) EnrichIP(ctx context.Context, ipAddr string) types.EnrichInfo {
+	if err := ValidateIP(ipAddr); err != nil {
+		e.log.Debugf("not enriching %v", err)
+		ret := invalidIPInfo(ipAddr, err)
+		if !e.unknownPlaceholder {
+			clearUnknown(&ret)
+		}
+		return ret
+	}
+
+	rawIPAddr := ipAddr
+	ipAddr = CanonicalIPAs(ipAddr, e.mappedAsIPv6)
+
+	var ret types.EnrichInfo
+	cached := false
+	if e.cache != nil {
+		ret, cached = e.cache.Get(ipAddr)
+		if cached && e.annotator != nil {
+			ret.Tags = e.annotator.Tags(ipAddr)
+		}
+	}
+
+	if !cached {
+		ipCtx := ctx
+		if e.perIPTimeout > 0 {
+			var cancel context.CancelFunc
+			ipCtx, cancel = context.WithTimeout(ctx, e.perIPTimeout)
+			defer cancel()
+		}
+
+		var hints *ripestat.Hints
+		if e.ripeStatHints {
+			hints = &ripestat.Hints{}
+			ipCtx = ripestat.ContextWithHints(ipCtx, hints)
+		}
+
+		ret = e.enrichIP(ipCtx, ipAddr)
+		if ipCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			addError(&ret, "Timeout", fmt.Errorf("enrichment abandoned after %v", e.perIPTimeout))
+			e.log.WithField("ip", ipAddr).Warnf("enrichment abandoned after %v", e.perIPTimeout)
+		}
+		if hints != nil {
+			if queryTime := hints.QueryTime(); !queryTime.IsZero() {
+				ret.QueryTime = queryTime.Format(time.RFC3339)
+			}
+		}
+
+		if e.cache != nil && len(ret.Errors) == 0 && ctx.Err() == nil {
+			e.cacheResult(ret, hints)
+		}
 	}
 
-	ret.Abuse, ret.AbuseSource = e.enrichAbuseFromIP(ipAddr)
-	ret.Prefix, ret.Asn = e.enrichPrefixAndASNFromIP(ipAddr)
-	ret.Holder = e.enrichHolderFromASN(ret.Asn)
-	ret.City, ret.Country = e.enrichCityAndCountryFromPrefix(ret.Prefix)
+	if rawIPAddr != ipAddr {
+		ret.IpRaw = rawIPAddr
+		ret.IpMapping = ipMapping(rawIPAddr, e.mappedAsIPv6)
+	}
+	if !e.unknownPlaceholder {
+		clearUnknown(&ret)
+	}
 
 	return ret
 }
 
-func (e *Enricher) enrichAbuseFromIP(ipAddr string) (foundMailAddresses string, abuseSource string) {
-	foundMailAddresses = "unknown"
-	abuseSource = "RipeSTAT"
+// clearUnknown empties the fields left "unknown", so consumers can't take the placeholder for data.
+// Why a field could not be determined is recorded in the Errors of the record.
+func clearUnknown(info *types.EnrichInfo) {
+	for _, field := range []*string{
+		&info.Abuse, &info.Prefix, &info.Asn, &info.Holder, &info.HolderName, &info.HolderCountry,
+		&info.Country, &info.City, &info.ASNCategory,
+	} {
+		if *field == "unknown" {
+			*field = ""
+		}
+	}
+}
 
-	rsEmailAddresses, err := e.rs.GetAbuseContacts(ipAddr)
-	if err != nil {
-		logrus.Warnf("abuse rsEmailAddresses err: %v", err)
-		return foundMailAddresses, abuseSource
+// InvalidIPInfo returns the record of a value that is not an IP address with its fields empty and
+// an Ip error wrapping ErrInvalidIP, for writing the findings of such values without enrichment.
+func InvalidIPInfo(ipAddr string) types.EnrichInfo {
+	ret := invalidIPInfo(ipAddr, ValidateIP(ipAddr))
+	clearUnknown(&ret)
+	return ret
+}
+
+// invalidIPInfo returns the record of a value that is not an IP address.
+func invalidIPInfo(ipAddr string, err error) types.EnrichInfo {
+	ret := types.EnrichInfo{
+		Ip:          ipAddr,
+		AbuseSource: types.AbuseSourceError,
+		EnrichedAt:  time.Now().UTC().Format(time.RFC3339),
+	}
+	ret.Abuse, ret.Prefix, ret.Asn, ret.Holder = "unknown", "unknown", "unknown", "unknown"
+	ret.Country, ret.City = "unknown", "unknown"
+	addError(&ret, "Ip", err)
+	return ret
+}
+
+func (e *Enricher) enrichIP(ctx context.Context, ipAddr string) types.EnrichInfo {
+	ret := types.EnrichInfo{
+		Ip:         ipAddr,
+		EnrichedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if addr, err := netip.ParseAddr(ipAddr); err == nil && bogon.IsBogon(addr) {
+		// there is nothing to look up for private and reserved addresses
+		ret.Abuse, ret.AbuseSource = "unknown", types.AbuseSourceSkippedPrivate
+		ret.Prefix, ret.Asn, ret.Holder = "unknown", "unknown", "unknown"
+		ret.Country, ret.City = "unknown", "unknown"
+		ret.HolderName = "unknown"
+		if e.annotator != nil {
+			ret.Tags = e.annotator.Tags(ipAddr)
+		}
+		return ret
+	}
+
+	if e.rirs != nil || e.whoisRIRs != nil {
+		if addr, err := netip.ParseAddr(ipAddr); err == nil {
+			ret.RIR = rir.Of(addr)
+		}
+	}
+	if !inRIRs(e.rirs, ipAddr) {
+		ret.Abuse, ret.AbuseSource = "unknown", types.AbuseSourceSkippedRIR
+		ret.Prefix, ret.Asn, ret.Holder = "unknown", "unknown", "unknown"
+		ret.Country, ret.City = "unknown", "unknown"
+		ret.HolderName = "unknown"
+		if e.annotator != nil {
+			ret.Tags = e.annotator.Tags(ipAddr)
+		}
+		return ret
+	}
+
+	var err error
+	// unavailable collects the fields left unknown because RipeSTAT was in maintenance
+	var unavailable []*string
+
+	ret.Prefix, ret.Asn, err = e.enrichPrefixAndASNFromIP(ctx, ipAddr)
+	addError(&ret, "Prefix", err)
+	if errors.Is(err, ripestat.ErrSourceUnavailable) {
+		// the holder and location are looked up by the prefix and ASN
+		unavailable = append(unavailable, &ret.Prefix, &ret.Asn, &ret.Holder, &ret.City, &ret.Country)
+	}
+	ret.Asn, err = e.normalizeASN(ret.Asn)
+	addError(&ret, "Asn", err)
+	if ret.Prefix != "unknown" && ret.Asn == "unknown" && ret.Errors["Prefix"] == "" && ret.Errors["Asn"] == "" {
+		// an allocated but unannounced prefix, the origin it was last announced by is the next best
+		ret.Asn, err = e.enrichASNFromRoutingStatus(ctx, ipAddr, ret.Prefix)
+		addError(&ret, "RoutingStatus", err)
+		if ret.Asn != "unknown" {
+			ret.AsnSource = "routing-status"
+		}
+	}
+	if e.coveringPrefix && ret.Prefix != "unknown" && ret.Errors["Prefix"] == "" {
+		ret.CoveringPrefix, ret.CoveringAsn, err = e.enrichCoveringPrefix(ctx, ipAddr, ret.Prefix)
+		addError(&ret, "CoveringPrefix", err)
+	}
+	// the prefix goes first, the abuse contacts can be looked up per prefix
+	ret.Abuse, ret.AbuseSource, err = e.enrichAbuseFromIP(ctx, ipAddr, ret.Prefix)
+	addError(&ret, "Abuse", err)
+	if errors.Is(err, ripestat.ErrSourceUnavailable) {
+		unavailable = append(unavailable, &ret.Abuse)
+	}
+	ret.Abuse, ret.AbuseSource, ret.AbuseContacts = e.classifyAbuseContacts(ret.Abuse, ret.AbuseSource)
+	ret.AbuseAddresses = ret.AbuseList()
+	if e.registryHandles || e.registration {
+		// both are read from the same registry objects
+		records, err := e.registryObjects(ctx, ipAddr)
+		if e.registryHandles {
+			ret.AbuseHandle, ret.OrgHandle = findWhoisValue(records, abuseHandleKeys), findWhoisValue(records, orgHandleKeys)
+			addError(&ret, "Handles", err)
+		}
+		if e.registration {
+			ret.RegistrationDate, ret.AllocationStatus = registrationOf(records)
+			addError(&ret, "Registration", err)
+		}
+	}
+	ret.Holder, err = e.enrichHolderFromASN(ctx, ipAddr, ret.Asn)
+	addError(&ret, "Holder", err)
+	if errors.Is(err, ripestat.ErrSourceUnavailable) {
+		unavailable = append(unavailable, &ret.Holder)
+	}
+	if ret.Prefix != "unknown" && ret.Asn == "unknown" && ret.Holder == "unknown" {
+		// without any origin the organisation registered for the prefix is the holder
+		ret.Holder, err = e.enrichHolderFromWhois(ctx, ipAddr, ret.Prefix)
+		addError(&ret, "WhoisHolder", err)
+		if ret.Holder != "unknown" {
+			ret.HolderSource = "whois"
+		}
+	}
+
+	if e.cymru != nil {
+		ret.WhoisAsn, ret.AsnDiscrepancy, err = e.crossCheckASN(ctx, ipAddr, ret.Asn)
+		addError(&ret, "WhoisAsn", err)
+	}
+	if e.irr != nil && (ret.Prefix == "unknown" || ret.Asn == "unknown" || ret.Holder == "unknown") {
+		err = e.enrichFromIRR(ctx, &ret)
+		addError(&ret, "IRR", err)
+	}
+	if e.asnClassifier != nil {
+		ret.ASNCategory = e.asnClassifier.Category(ret.Asn)
+	}
+
+	location, err := e.enrichLocationFromPrefix(ctx, ipAddr, ret.Prefix)
+	// the other sources only fill in a country RipeSTAT doesn't know, so its placeholders must be unknown
+	ret.City, ret.Country = sanitizeCity(location.City), sanitizeCountry(location.Country)
+	ret.Latitude, ret.Longitude = float64(location.Latitude), float64(location.Longitude)
+	addError(&ret, "Geolocation", err)
+	if errors.Is(err, ripestat.ErrSourceUnavailable) {
+		unavailable = append(unavailable, &ret.City, &ret.Country)
+	}
+	ret.GeoSource = "RipeSTAT"
+
+	if e.geofeed != nil {
+		e.enrichFromGeofeed(&ret)
+	}
+
+	if e.radar != nil {
+		addError(&ret, "Radar", e.enrichFromRadar(ctx, &ret))
+	}
+
+	if len(e.fallback) > 0 {
+		addError(&ret, "Fallback", e.enrichFromFallback(ctx, &ret))
+	}
+
+	if e.peeringdb != nil && ret.Asn != "unknown" {
+		addError(&ret, "PeeringDB", e.enrichFromPeeringDB(ctx, &ret))
+	}
+
+	sanitizeLocation(&ret)
+
+	// the national CERT is routed by the country, so its contact goes last
+	if e.nationalCERTs != nil {
+		e.addNationalCERT(&ret)
+	}
+	if e.abuseTo && len(ret.AbuseAddresses) > 0 {
+		var invalid []string
+		ret.AbuseTo, invalid = contact.AddressList(ret.AbuseAddresses)
+		for _, address := range invalid {
+			e.log.WithField("ip", ipAddr).Debugf("leaving invalid abuse address %q out of AbuseTo", address)
+		}
+	}
+
+	if e.rdns != nil {
+		e.enrichFromReverseDNS(ctx, &ret)
+	}
+
+	if !e.asOf.IsZero() {
+		addError(&ret, "HistoricalWhois", e.enrichFromHistoricalWhois(ctx, &ret))
+	}
+
+	if e.cloud != nil {
+		if rng, found := e.cloud.Lookup(ipAddr); found {
+			ret.CloudProvider, ret.CloudRegion = rng.Provider, rng.Region
+		}
+	}
+
+	if e.tlscert != nil {
+		e.enrichFromTLSCertificate(ctx, &ret)
 	}
 
-	if len(rsEmailAddresses) == 1 {
-		mailAddress, err := mail.ParseAddress(rsEmailAddresses[0])
-		if err != nil {
-			logrus.Warnf("abuse foundMailAddresses err: %v", err)
+	if e.annotator != nil {
+		ret.Tags = e.annotator.Tags(ipAddr)
+	}
+
+	// the other sources may have filled some of the fields RipeSTAT couldn't
+	for _, field := range unavailable {
+		if *field == "unknown" {
+			*field = types.SourceUnavailable
 		}
+	}
+	ret.HolderName, ret.HolderCountry = splitHolder(ret.Holder)
 
-		return mailAddress.Address, abuseSource
+	return ret
+}
+
+func (e *Enricher) enrichAbuseFromIP(ctx context.Context, ipAddr string, prefix string) (foundMailAddresses string, abuseSource string, err error) {
+	foundMailAddresses = "unknown"
+	abuseSource = types.AbuseSourceRipeSTAT
+
+	start := time.Now()
+	rsEmailAddresses, err := e.ripeStatAbuseContacts(ctx, ipAddr, prefix)
+	if err != nil {
+		e.lookupLog(ipAddr, "abuse-contact-finder", start).Warnf("abuse rsEmailAddresses err: %v", err)
+		if errors.Is(err, ripestat.ErrSourceUnavailable) {
+			return foundMailAddresses, types.AbuseSourceUnavailable, err
+		}
+		return foundMailAddresses, types.AbuseSourceError, err
 	}
 
-	if len(rsEmailAddresses) > 1 {
+	if len(rsEmailAddresses) > 0 {
 		var cleanMailAddresses []string
 
-		for _, mailAddress := range rsEmailAddresses {
-			mailAddress, err := mail.ParseAddress(mailAddress)
+		for _, rsEmailAddress := range rsEmailAddresses {
+			mailAddress, err := mail.ParseAddress(rsEmailAddress)
 			if err != nil {
-				logrus.Warnf("abuse foundMailAddresses err: %v", err)
+				e.log.Warnf("abuse foundMailAddresses err: %v", err)
+				continue
 			}
 			cleanMailAddresses = append(cleanMailAddresses, mailAddress.Address)
 		}
 
-		return strings.Join(cleanMailAddresses, ";"), abuseSource
+		if len(cleanMailAddresses) > 0 {
+			return strings.Join(cleanMailAddresses, ";"), abuseSource, nil
+		}
+	}
+
+	if e.noWhois {
+		return foundMailAddresses, types.AbuseSourceNone, nil
+	}
+	if !inRIRs(e.whoisRIRs, ipAddr) {
+		return foundMailAddresses, types.AbuseSourceSkippedRIR, nil
 	}
 
 	// Fallback to whois
-	contactsFromWhois := e.whoisEnrichmentIP(ipAddr)
+	contactsFromWhois, err := e.whoisEnrichmentIP(ctx, ipAddr)
+	if err != nil {
+		return foundMailAddresses, types.AbuseSourceError, err
+	}
 	if len(contactsFromWhois) > 0 {
-		return strings.Join(contactsFromWhois, ";"), "whois"
+		return strings.Join(contactsFromWhois, ";"), types.AbuseSourceWhois, nil
+	}
+
+	return foundMailAddresses, types.AbuseSourceNone, nil
+}
+
+// cacheResult stores ret in the cache, for as long as the RipeSTAT responses may be cached when
+// hints has their max-age, see WithRipeStatHints.
+func (e *Enricher) cacheResult(ret types.EnrichInfo, hints *ripestat.Hints) {
+	if hints == nil {
+		e.cache.Put(ret)
+		return
+	}
+
+	maxAge, found := hints.MaxAge()
+	if !found {
+		e.cache.Put(ret)
+		return
+	}
+	// a max-age of zero allows no caching, PutWithTTL skips it
+	e.cache.PutWithTTL(ret, maxAge)
+}
+
+// addError records err as the reason field could not be determined.
+func addError(info *types.EnrichInfo, field string, err error) {
+	if err == nil {
+		return
 	}
 
-	return foundMailAddresses, abuseSource
+	if info.Errors == nil {
+		info.Errors = make(map[string]string)
+	}
+	info.Errors[field] = err.Error()
 }
 
-func (e *Enricher) enrichPrefixAndASNFromIP(ipAddr string) (string, string) {
+// lookupLog returns a logger for the outcome of a single lookup of ipAddr.
+func (e *Enricher) lookupLog(ipAddr string, dataCall string, start time.Time) logrus.FieldLogger {
+	return e.log.WithFields(logrus.Fields{
+		"ip":        ipAddr,
+		"data_call": dataCall,
+		"duration":  time.Since(start).String(),
+	})
+}
+
+func (e *Enricher) enrichPrefixAndASNFromIP(ctx context.Context, ipAddr string) (string, string, error) {
 	prefix := "unknown"
 	asn := "unknown"
 
-	netInfo, err := e.rs.GetNetworkInfo(ipAddr)
+	start := time.Now()
+	netInfo, err := e.rs.GetNetworkInfo(ctx, ipAddr)
 	if err != nil {
-		logrus.Warnf("network info err: %v", err)
-		return prefix, asn
+		e.lookupLog(ipAddr, "network-info", start).Warnf("network info err: %v", err)
+		return prefix, asn, err
 	}
 
 	if len(netInfo.ASNs) == 0 {
-		return netInfo.Prefix, asn
+		return netInfo.Prefix, asn, nil
 	}
 
-	return netInfo.Prefix, netInfo.ASNs[0]
+	return netInfo.Prefix, netInfo.ASNs[0], nil
 }
 
-func (e *Enricher) enrichHolderFromASN(asn string) string {
-	holder := "unknown"
-
-	if asn == "unknown" {
-		return holder
+// normalizeASN returns the canonical form of an AS number found by a source, see asn.Normalize,
+// or "unknown" with the reason when it is no AS number or one that is rejected.
+func (e *Enricher) normalizeASN(value string) (string, error) {
+	if value == "unknown" {
+		return value, nil
 	}
 
-	asOverview, err := e.rs.GetASOverview(asn)
+	normalized, err := asn.Normalize(value, e.rejectPrivateASN)
 	if err != nil {
-		logrus.Warnf("holder err: %v", err)
-		return holder
+		return "unknown", err
 	}
-
-	return asOverview.Holder
+	return normalized, nil
 }
 
-func (e *Enricher) enrichCityAndCountryFromPrefix(prefix string) (string, string) {
-	city := "unknown"
-	country := "unknown"
+func (e *Enricher) enrichHolderFromASN(ctx context.Context, ipAddr string, asn string) (string, error) {
+	holder := "unknown"
 
-	if prefix == "unknown" {
-		return city, country
+	if asn == "unknown" {
+		return holder, nil
 	}
 
-	geolocation, err := e.rs.GetGeolocationData(prefix)
+	start := time.Now()
+	asOverview, err := e.rs.GetASOverview(ctx, asn)
 	if err != nil {
-		logrus.Warnf("geolocation err: %v", err)
-		return city, country
+		e.lookupLog(ipAddr, "as-overview", start).Warnf("holder err: %v", err)
+		return holder, err
 	}
 
-	if len(geolocation.LocatedResources) == 0 {
-		return city, country
-	}
+	return asOverview.Holder, nil
+}
 
-	if len(geolocation.LocatedResources[0].Locations) == 0 {
-		return city, country
+// enrichASNFromRoutingStatus returns the origin AS that last announced prefix, for prefixes
+// network-info returns without ASN because they are not announced now.
+func (e *Enricher) enrichASNFromRoutingStatus(ctx context.Context, ipAddr string, prefix string) (string, error) {
+	start := time.Now()
+	status, err := e.rs.GetRoutingStatus(ctx, prefix)
+	if err != nil {
+		e.lookupLog(ipAddr, "routing-status", start).Warnf("last seen origin err: %v", err)
+		return "unknown", err
 	}
 
-	return geolocation.LocatedResources[0].Locations[0].City, geolocation.LocatedResources[0].Locations[0].Country
+	if status.LastSeen.Origin == "" {
+		e.lookupLog(ipAddr, "routing-status", start).Debugf("prefix %s was never seen announced", prefix)
+		return "unknown", nil
+	}
+	return e.normalizeASN(string(status.LastSeen.Origin))
 }
 
-func (e *Enricher) whoisEnrichmentIP(ipAddr string) []string {
-	logrus.Debug("enricher: ripestat has no abuse mails for us, executing whoisEnrichment on IP address: ", ipAddr)
+// holderKeys are the attributes holding the name of the organisation in the objects of the RIRs,
+// lower cased, the most descriptive first
+var holderKeys = []string{"org-name", "orgname", "owner", "descr", "netname"}
 
-	whoisInfo, err := whois.Whois(ipAddr)
-	if err != nil || whoisInfo == "" {
-		logrus.Debug("enricher: whoisEnrichment - could not get whois info for ", ipAddr)
-		return []string{}
+// enrichHolderFromWhois returns the organisation registered for prefix, for prefixes no origin AS
+// is known for.
+func (e *Enricher) enrichHolderFromWhois(ctx context.Context, ipAddr string, prefix string) (string, error) {
+	start := time.Now()
+	whoisData, err := e.rs.GetWhois(ctx, prefix)
+	if err != nil {
+		e.lookupLog(ipAddr, "whois", start).Warnf("prefix holder err: %v", err)
+		return "unknown", err
 	}
 
-	foundMailAddresses := whoisRegexp.FindAllString(whoisInfo, -1)
-
-	switch len(foundMailAddresses) {
-	case 0:
-		logrus.Debug("enricher: whoisEnrichment - could not find any abuse emails for ", ipAddr)
-		// TODO: fall back to ipinfo. Whois is not always available
-		return []string{}
-	case 1:
-		// Spare some allocations and a sort if there's only one address found
-		mail, err := mail.ParseAddress(foundMailAddresses[0])
-		if err != nil {
-			logrus.Debug("enricher: whoisEnrichment - could not parse email address for ", ipAddr)
-			return []string{}
+	for _, key := range holderKeys {
+		if holder := findWhoisValue(whoisData.Records, []string{key}); holder != "" {
+			return holder, nil
 		}
-
-		return []string{strings.ToLower(mail.Address)}
 	}
+	return "unknown", nil
+}
 
-	// lower and sort unique
-	var uniqueMailAddresses = make(map[string]struct{}, len(foundMailAddresses))
-	for _, v := range foundMailAddresses {
-		mail, err := mail.ParseAddress(v)
-
-		if err != nil {
-			logrus.Debug("enricher: whoisEnrichment - could not parse email address for ", ipAddr)
-			continue
-		}
-
-		uniqueMailAddresses[strings.ToLower(mail.Address)] = struct{}{}
+// splitHolder splits an as-overview holder such as "EXAMPLE-AS Example Networks B.V., NL" into the
+// name of the organisation and the code of its registration country. Holders without a known
+// country code after the last comma, and the placeholders, are returned as name.
+func splitHolder(holder string) (string, string) {
+	name, suffix, found := cutLast(holder, ",")
+	if !found {
+		return strings.TrimSpace(holder), ""
 	}
 
-	abuseEmails := make([]string, 0, len(uniqueMailAddresses))
-
-	for k := range uniqueMailAddresses {
-		abuseEmails = append(abuseEmails, k)
+	suffix = strings.TrimSpace(suffix)
+	code := country.Normalize(suffix)
+	if len(suffix) != 2 || code == "" || strings.TrimSpace(name) == "" {
+		return strings.TrimSpace(holder), ""
 	}
+	return strings.TrimSpace(name), code
+}
 
-	return abuseEmails
+func cutLast(s, sep string) (string, string, bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
 }
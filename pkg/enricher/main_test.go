@@ -0,0 +1,166 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/cache"
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/ripestattest"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newTestServer returns a fake RipeSTAT answering the data calls of an enrichment without whois,
+// the same for every IP address.
+func newTestServer(t *testing.T) *ripestattest.Server {
+	server := ripestattest.NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+	return server
+}
+
+// newTestEnricher returns an enricher without whois requesting server, logging nowhere.
+func newTestEnricher(server *ripestattest.Server, opts ...Option) *Enricher {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	opts = append([]Option{WithRipeStatBaseURL(server.BaseURL()), WithoutWhois(), WithLogger(logger)}, opts...)
+	return NewEnricher(opts...)
+}
+
+func TestSourceApp(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"default", nil, ripestat.DefaultSourceApp},
+		{"set", []Option{WithSourceApp("ACME-scanner")}, "ACME-scanner"},
+		{"invalid", []Option{WithSourceApp("ACME scanner")}, ripestat.DefaultSourceApp},
+		{"empty", []Option{WithSourceApp("")}, ripestat.DefaultSourceApp},
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEnricher(append(tt.opts, WithLogger(logger))...)
+			if e.rs.SourceApp != tt.want {
+				t.Errorf("sourceapp %q, want %q", e.rs.SourceApp, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerIPTimeout(t *testing.T) {
+	server := newTestServer(t)
+	slow := ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`)
+	slow.Latency = 5 * time.Second
+	server.Handle("abuse-contact-finder", "193.0.6.140", slow)
+	e := newTestEnricher(server, WithPerIPTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	results, _, err := e.EnrichIPs(context.Background(), []string{"193.0.6.139", "193.0.6.140"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("enrichment took %v, the slow lookup wasn't cut off", elapsed)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	for _, result := range results {
+		switch result.Ip {
+		case "193.0.6.139":
+			if len(result.Errors) != 0 || result.Abuse != "abuse@ripe.net" {
+				t.Errorf("the other IP got %+v, want it unaffected", result)
+			}
+		case "193.0.6.140":
+			if result.Errors["Timeout"] == "" || result.Abuse != "" {
+				t.Errorf("got %+v, want a Timeout error and no abuse contact", result)
+			}
+			// what was found before the timeout is kept
+			if result.Asn != "3333" || result.Prefix != "193.0.0.0/21" {
+				t.Errorf("got ASN %q and prefix %q, want those found before the timeout", result.Asn, result.Prefix)
+			}
+		}
+	}
+}
+
+func TestRipeStatHintsCacheTTL(t *testing.T) {
+	maxAge := func(response ripestattest.Response, cacheControl string) ripestattest.Response {
+		response.Header = http.Header{"Cache-Control": {cacheControl}}
+		return response
+	}
+	networkInfo := ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21", "query_time": "2024-05-01T12:00:00"}`)
+	abuse := ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"], "query_time": "2024-05-01T11:00:00"}`)
+
+	tests := []struct {
+		name        string
+		networkInfo ripestattest.Response
+		abuse       ripestattest.Response
+		opts        []Option
+		// ttl is the ttl of the cache entry, zero for the ttl of the cache
+		ttl    time.Duration
+		cached bool
+	}{
+		{"without Cache-Control", networkInfo, abuse, []Option{WithRipeStatHints()}, 0, true},
+		// the shortest max-age of the responses counts
+		{"max-age", maxAge(networkInfo, "max-age=3600"), maxAge(abuse, "public, max-age=300"), []Option{WithRipeStatHints()}, 5 * time.Minute, true},
+		{"no-cache", maxAge(networkInfo, "max-age=3600"), maxAge(abuse, "no-cache"), []Option{WithRipeStatHints()}, 0, false},
+		{"without hints", maxAge(networkInfo, "max-age=3600"), maxAge(abuse, "public, max-age=300"), nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Handle("network-info", "193.0.6.139", tt.networkInfo)
+			server.Handle("abuse-contact-finder", "193.0.6.139", tt.abuse)
+			path := filepath.Join(t.TempDir(), "cache.json")
+			c, err := cache.Open(path, time.Hour, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := newTestEnricher(server, append(tt.opts, WithCache(c))...).EnrichIP(context.Background(), "193.0.6.139")
+			if wantQueryTime := tt.opts != nil; (got.QueryTime == "2024-05-01T11:00:00Z") != wantQueryTime {
+				t.Errorf("query time %q, want the oldest one of the responses: %v", got.QueryTime, wantQueryTime)
+			}
+			if err := c.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			var saved struct {
+				Entries map[string]struct {
+					TTL time.Duration `json:"ttl"`
+				} `json:"entries"`
+			}
+			if data, err := os.ReadFile(path); err == nil {
+				if err := json.Unmarshal(data, &saved); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entry, cached := saved.Entries["193.0.6.139"]
+			if cached != tt.cached || entry.TTL != tt.ttl {
+				t.Errorf("cached %v with ttl %v, want cached %v with ttl %v", cached, entry.TTL, tt.cached, tt.ttl)
+			}
+		})
+	}
+}
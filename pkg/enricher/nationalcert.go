@@ -0,0 +1,47 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"strings"
+
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/csirt"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithNationalCERTs adds the abuse address r routes the country of every IP address to, the
+// national CERT, to its abuse contacts. The contacts found are kept.
+func WithNationalCERTs(r *csirt.Routing) Option {
+	return func(e *Enricher) {
+		e.nationalCERTs = r
+	}
+}
+
+// addNationalCERT adds the national CERT of the country of info to its abuse contacts, unless it
+// is one of them already.
+func (e *Enricher) addNationalCERT(info *types.EnrichInfo) {
+	address, found := e.nationalCERTs.Contact(info.Country)
+	if !found {
+		return
+	}
+	info.NationalCERT = address
+
+	for _, existing := range info.AbuseAddresses {
+		if strings.EqualFold(existing, address) {
+			return
+		}
+	}
+
+	info.AbuseAddresses = append(info.AbuseAddresses, address)
+	info.AbuseContacts = append(info.AbuseContacts, types.AbuseContact{
+		Email:  address,
+		Kind:   contact.KindRole,
+		Source: types.AbuseSourceNationalCERT,
+	})
+	info.Abuse = strings.Join(info.AbuseAddresses, ";")
+}
@@ -0,0 +1,59 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/csirt"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestNationalCERTs(t *testing.T) {
+	certs := csirt.NewRouting()
+	for country, address := range map[string]string{"NL": "cert@ncsc.example", "DE": "certbund@bsi.example"} {
+		if err := certs.Add(country, address); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := newTestServer(t)
+	server.Handle("network-info", "2001:67c:2e8::1", ripestattest.JSON(`{"asns": ["3333"], "prefix": "2001:67c:2e8::/48"}`))
+	server.Handle("maxmind-geo-lite", "2001:67c:2e8::/48", ripestattest.JSON(`{"located_resources": [{"resource": "2001:67c:2e8::/48", "locations": [{"country": "de", "city": "Berlin"}]}]}`))
+	server.Handle("network-info", "80.201.0.1", ripestattest.JSON(`{"asns": ["5432"], "prefix": "80.200.0.0/15"}`))
+	server.Handle("maxmind-geo-lite", "80.200.0.0/15", ripestattest.JSON(`{"located_resources": [{"resource": "80.200.0.0/15", "locations": [{"country": "BE", "city": "Brussels"}]}]}`))
+	server.Handle("abuse-contact-finder", "193.0.6.140", ripestattest.JSON(`{"abuse_contacts": ["CERT@ncsc.example"]}`))
+	e := newTestEnricher(server, WithNationalCERTs(certs))
+
+	tests := []struct {
+		ipAddr    string
+		wantCERT  string
+		wantAbuse string
+	}{
+		{"193.0.6.139", "cert@ncsc.example", "abuse@ripe.net;cert@ncsc.example"},
+		// routed by the country the IP address is located in, not the one of its holder
+		{"2001:67c:2e8::1", "certbund@bsi.example", "abuse@ripe.net;certbund@bsi.example"},
+		{"80.201.0.1", "", "abuse@ripe.net"},
+		// the CERT is the abuse contact already
+		{"193.0.6.140", "cert@ncsc.example", "CERT@ncsc.example"},
+	}
+	for _, tt := range tests {
+		got := e.EnrichIP(context.Background(), tt.ipAddr)
+		if got.NationalCERT != tt.wantCERT || got.Abuse != tt.wantAbuse {
+			t.Errorf("%s in %s: national CERT %q and abuse %q, want %q and %q", tt.ipAddr, got.Country, got.NationalCERT, got.Abuse, tt.wantCERT, tt.wantAbuse)
+		}
+		if tt.wantCERT == "" || !strings.EqualFold(tt.wantAbuse, tt.wantCERT) {
+			continue
+		}
+		if len(got.AbuseContacts) != 1 || got.AbuseContacts[0].Source != types.AbuseSourceRipeSTAT {
+			t.Errorf("%s: contacts %+v, want the contact of RipeSTAT only", tt.ipAddr, got.AbuseContacts)
+		}
+	}
+}
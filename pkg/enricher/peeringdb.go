@@ -0,0 +1,42 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"time"
+
+	"nuclei-parse-enrich/pkg/peeringdb"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithPeeringDB records the number of internet exchanges and the facilities the AS of every IP
+// address is present at according to PeeringDB. Every AS is looked up once.
+func WithPeeringDB(c *peeringdb.Client) Option {
+	return func(e *Enricher) {
+		e.peeringdb = c
+	}
+}
+
+func (e *Enricher) enrichFromPeeringDB(ctx context.Context, info *types.EnrichInfo) error {
+	start := time.Now()
+	presence, err := e.peeringdb.GetPresence(ctx, info.Asn)
+	if err != nil {
+		e.lookupLog(info.Ip, "peeringdb", start).Warnf("peeringdb err: %v", err)
+		return err
+	}
+
+	info.IXPCount = len(presence.IXPs)
+	for _, facility := range presence.Facilities {
+		info.Facilities = append(info.Facilities, types.Facility{
+			Name:    facility.Name,
+			City:    facility.City,
+			Country: facility.Country,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/peeringdb"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// TestPeeringDB records where the AS of the IP address peers according to PeeringDB.
+func TestPeeringDB(t *testing.T) {
+	if testing.Short() {
+		t.Skip("PeeringDB requests are rate limited to one per 1.5s")
+	}
+
+	var requests int32
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path + "?" + r.URL.RawQuery {
+		case "/netixlan?asn=3333":
+			_, _ = io.WriteString(w, `{"data": [{"ix_id": 26}, {"ix_id": 18}, {"ix_id": 26}]}`)
+		case "/netfac?local_asn=3333":
+			_, _ = io.WriteString(w, `{"data": [{"fac_id": 74, "name": "Equinix AM3", "city": "Amsterdam", "country": "NL"},
+				{"fac_id": 18, "name": "Digital Realty AMS1", "city": "Amsterdam", "country": "NL"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	client := peeringdb.NewPeeringDBClient("test-key")
+	client.BaseURL = api.URL + "/"
+
+	e := newTestEnricher(newTestServer(t), WithPeeringDB(client))
+	want := []types.Facility{
+		{Name: "Digital Realty AMS1", City: "Amsterdam", Country: "NL"},
+		{Name: "Equinix AM3", City: "Amsterdam", Country: "NL"},
+	}
+	// the IP addresses of an AS share its lookup
+	for _, ipAddr := range []string{"193.0.6.139", "193.0.6.140"} {
+		got := e.EnrichIP(context.Background(), ipAddr)
+		if got.IXPCount != 2 || !reflect.DeepEqual(got.Facilities, want) || got.Errors["PeeringDB"] != "" {
+			t.Errorf("%s: %d exchanges and facilities %+v (error %q), want 2 and %+v", ipAddr, got.IXPCount, got.Facilities, got.Errors["PeeringDB"], want)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("%d PeeringDB requests, want 2", n)
+	}
+
+	// an unknown AS is not looked up
+	server := newTestServer(t)
+	server.Handle("network-info", "193.0.6.141", ripestattest.JSON(`{"asns": [], "prefix": ""}`))
+	got := newTestEnricher(server, WithPeeringDB(client)).EnrichIP(context.Background(), "193.0.6.141")
+	if got.IXPCount != 0 || got.Facilities != nil || got.Errors["PeeringDB"] != "" {
+		t.Errorf("unknown AS: %d exchanges and facilities %+v (errors %v)", got.IXPCount, got.Facilities, got.Errors)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("%d PeeringDB requests after an unknown AS, want 2", n)
+	}
+}
@@ -0,0 +1,95 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"sync"
+)
+
+// prefixAbuseCache holds the RipeSTAT abuse contacts per prefix, so the IP addresses of a prefix
+// share a single lookup. Concurrent lookups of a prefix wait for the first one, failed lookups
+// are not kept.
+type prefixAbuseCache struct {
+	mu      sync.Mutex
+	entries map[string]*prefixAbuseEntry
+}
+
+type prefixAbuseEntry struct {
+	done     chan struct{}
+	contacts []string
+	err      error
+}
+
+func newPrefixAbuseCache() *prefixAbuseCache {
+	return &prefixAbuseCache{entries: make(map[string]*prefixAbuseEntry)}
+}
+
+// get returns the abuse contacts of prefix, looking them up with lookup unless another IP
+// address of the prefix did or is doing so. It reports whether the contacts came from the cache.
+func (c *prefixAbuseCache) get(ctx context.Context, prefix string, lookup func() ([]string, error)) ([]string, bool, error) {
+	c.mu.Lock()
+	entry, found := c.entries[prefix]
+	if !found {
+		entry = &prefixAbuseEntry{done: make(chan struct{})}
+		c.entries[prefix] = entry
+	}
+	c.mu.Unlock()
+
+	if found {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case <-entry.done:
+			return entry.contacts, true, entry.err
+		}
+	}
+
+	entry.contacts, entry.err = lookup()
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.entries, prefix)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+
+	return entry.contacts, false, entry.err
+}
+
+// ripeStatAbuseContacts returns the abuse contacts RipeSTAT has for ipAddr. With per-prefix
+// lookups they are looked up once for prefix, the IP address itself is looked up when its prefix
+// is unknown or the lookup of the prefix failed.
+func (e *Enricher) ripeStatAbuseContacts(ctx context.Context, ipAddr string, prefix string) ([]string, error) {
+	if e.prefixAbuse == nil || prefix == "" || prefix == "unknown" {
+		return e.rs.GetAbuseContacts(ctx, ipAddr)
+	}
+
+	contacts, cached, err := e.prefixAbuse.get(ctx, prefix, func() ([]string, error) {
+		return e.rs.GetAbuseContacts(ctx, prefix)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		e.log.WithField("ip", ipAddr).Debugf("enricher: abuse contacts of prefix %s failed, looking up the IP address: %v", prefix, err)
+		return e.rs.GetAbuseContacts(ctx, ipAddr)
+	}
+
+	if cached {
+		e.log.WithField("ip", ipAddr).Debugf("enricher: using the abuse contacts of prefix %s", prefix)
+	}
+	return contacts, nil
+}
+
+// WithPrefixAbuseContacts looks up the RipeSTAT abuse contacts once per announced prefix and uses
+// them for all IP addresses of the prefix, which saves most lookups for dense batches. IP
+// addresses without known prefix are looked up themselves.
+func WithPrefixAbuseContacts() Option {
+	return func(e *Enricher) {
+		e.prefixAbuse = newPrefixAbuseCache()
+	}
+}
@@ -0,0 +1,60 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+func TestPrefixAbuseContacts(t *testing.T) {
+	server := newTestServer(t)
+	e := newTestEnricher(server, WithPrefixAbuseContacts(), WithWorkers(2))
+
+	results, _, err := e.EnrichIPs(context.Background(), []string{"193.0.6.139", "193.0.6.140"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Abuse != "abuse@ripe.net" {
+			t.Errorf("abuse of %s %q, want the contact of the prefix", result.Ip, result.Abuse)
+		}
+	}
+	// both IP addresses share the lookup of their prefix, concurrent or not
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.0.0/21", 1)
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.6.139", 0)
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.6.140", 0)
+
+	without := newTestServer(t)
+	newTestEnricher(without).EnrichIP(context.Background(), "193.0.6.139")
+	newTestEnricher(without).EnrichIP(context.Background(), "193.0.6.140")
+	without.AssertRequests(t, "abuse-contact-finder", "193.0.0.0/21", 0)
+	without.AssertRequests(t, "abuse-contact-finder", "193.0.6.139", 1)
+	without.AssertRequests(t, "abuse-contact-finder", "193.0.6.140", 1)
+}
+
+func TestPrefixAbuseContactsFailed(t *testing.T) {
+	server := newTestServer(t)
+	server.Handle("abuse-contact-finder", "193.0.0.0/21",
+		ripestattest.Error(http.StatusBadRequest, "bad request"), ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	e := newTestEnricher(server, WithPrefixAbuseContacts())
+
+	// the IP address is looked up itself, and the failed lookup of the prefix is not kept
+	if got := e.EnrichIP(context.Background(), "193.0.6.139"); got.Abuse != "abuse@ripe.net" {
+		t.Errorf("abuse %q after the prefix failed, want the contact of the IP address", got.Abuse)
+	}
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.6.139", 1)
+
+	e.EnrichIP(context.Background(), "193.0.6.140")
+	e.EnrichIP(context.Background(), "193.0.6.141")
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.0.0/21", 2)
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.6.140", 0)
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.6.141", 0)
+}
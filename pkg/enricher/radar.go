@@ -0,0 +1,54 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"time"
+
+	"nuclei-parse-enrich/pkg/radar"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithRadar cross-checks the AS of every IP address with Cloudflare Radar. Radar fills in
+// the holder and country when RipeSTAT has none and classifies the network type of the AS.
+func WithRadar(c *radar.Client) Option {
+	return func(e *Enricher) {
+		e.radar = c
+	}
+}
+
+func (e *Enricher) enrichFromRadar(ctx context.Context, info *types.EnrichInfo) error {
+	if info.Asn == "unknown" {
+		return nil
+	}
+
+	start := time.Now()
+	asInfo, err := e.radar.GetASN(ctx, info.Asn)
+	if err != nil {
+		e.lookupLog(info.Ip, "radar-asn", start).Warnf("radar asn err: %v", err)
+		return err
+	}
+
+	if info.Holder == "unknown" && asInfo.OrgName != "" {
+		info.Holder = asInfo.OrgName
+	}
+
+	if info.Country == "unknown" && asInfo.Country != "" {
+		info.Country = asInfo.Country
+	}
+
+	start = time.Now()
+	networkType, err := e.radar.GetNetworkType(ctx, asInfo)
+	if err != nil {
+		e.lookupLog(info.Ip, "radar-asn-rel", start).Warnf("radar network type err: %v", err)
+		return err
+	}
+
+	info.NetworkType = networkType
+	return nil
+}
@@ -0,0 +1,64 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/radar"
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+// TestRadar fills in the holder and country RipeSTAT doesn't know from Cloudflare Radar.
+func TestRadar(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/entities/asns/3333":
+			_, _ = io.WriteString(w, `{"success": true, "result": {"asn": {"asn": 3333, "orgName": "RIPE NCC", "country": "NL"}}}`)
+		case "/entities/asns/3333/rel":
+			_, _ = io.WriteString(w, `{"success": true, "result": {"rels": [{"asn1": 3333, "asn2": 1299, "rel": "C2P"}]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	client := radar.NewRadarClient("test-token")
+	client.BaseURL = api.URL + "/"
+
+	tests := []struct {
+		name            string
+		holder, country ripestattest.Response
+		wantHolder      string
+		wantCountry     string
+	}{
+		{"unknown to RipeSTAT", ripestattest.Error(404, "not found"), ripestattest.JSON(`{"located_resources": []}`), "RIPE NCC", "NL"},
+		{"known to RipeSTAT", ripestattest.JSON(`{"holder": "RIPE-NCC-AS"}`),
+			ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "DE", "city": "Berlin"}]}]}`),
+			"RIPE-NCC-AS", "DE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t)
+			server.Handle("as-overview", "", tt.holder)
+			server.Handle("maxmind-geo-lite", "", tt.country)
+			e := newTestEnricher(server, WithRadar(client))
+
+			got := e.EnrichIP(context.Background(), "193.0.6.139")
+			if got.Holder != tt.wantHolder || got.Country != tt.wantCountry || got.NetworkType != radar.NetworkTypeOther {
+				t.Errorf("holder %q, country %q and network type %q, want %q, %q and %q",
+					got.Holder, got.Country, got.NetworkType, tt.wantHolder, tt.wantCountry, radar.NetworkTypeOther)
+			}
+			if got.Errors["Radar"] != "" {
+				t.Errorf("radar error %q", got.Errors["Radar"])
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+
+	"nuclei-parse-enrich/pkg/rdns"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithReverseDNS resolves the PTR record of every IP address and derives a hosting
+// provider hint from it with h.
+func WithReverseDNS(h *rdns.Hinter) Option {
+	return func(e *Enricher) {
+		e.rdns = h
+	}
+}
+
+func (e *Enricher) enrichFromReverseDNS(ctx context.Context, info *types.EnrichInfo) {
+	ptr, err := e.rdns.LookupPTR(ctx, info.Ip)
+	if err != nil {
+		e.log.Debugf("enricher: no PTR record for %s: %v", info.Ip, err)
+		return
+	}
+
+	info.Ptr = ptr
+	info.ProviderHint = e.rdns.Hint(ptr)
+}
@@ -0,0 +1,49 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/rdns"
+)
+
+// ptrResolver answers every PTR lookup with its name.
+type ptrResolver string
+
+func (r ptrResolver) LookupAddr(context.Context, string) ([]string, error) {
+	if r == "" {
+		return nil, errors.New("no such host")
+	}
+	return []string{string(r)}, nil
+}
+
+func TestReverseDNS(t *testing.T) {
+	tests := []struct {
+		ptr      ptrResolver
+		wantPtr  string
+		wantHint string
+	}{
+		{"ec2-52-94-236-248.compute-1.amazonaws.com.", "ec2-52-94-236-248.compute-1.amazonaws.com", "Amazon Web Services"},
+		{"www.ripe.net.", "www.ripe.net", ""},
+		{"", "", ""},
+	}
+
+	server := newTestServer(t)
+	for _, tt := range tests {
+		h := rdns.NewHinter()
+		h.SetResolver(tt.ptr)
+		e := newTestEnricher(server, WithReverseDNS(h))
+
+		got := e.EnrichIP(context.Background(), "193.0.6.139")
+		if got.Ptr != tt.wantPtr || got.ProviderHint != tt.wantHint {
+			t.Errorf("ptr %q and provider hint %q, want %q and %q", got.Ptr, got.ProviderHint, tt.wantPtr, tt.wantHint)
+		}
+	}
+}
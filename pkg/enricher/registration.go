@@ -0,0 +1,114 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+)
+
+// WithRegistryHandles records the abuse-c handle and organisation id of every IP address from the
+// registry objects returned by the RipeSTAT whois data call, to reference in correspondence.
+func WithRegistryHandles() Option {
+	return func(e *Enricher) {
+		e.registryHandles = true
+	}
+}
+
+// WithRegistration records the registration date and allocation status (ALLOCATED, ASSIGNED or
+// LEGACY) of the address block of every IP address from the registry objects returned by the
+// RipeSTAT whois data call. They are left empty when the registry doesn't list them.
+func WithRegistration() Option {
+	return func(e *Enricher) {
+		e.registration = true
+	}
+}
+
+// abuseHandleKeys and orgHandleKeys are the attributes holding the abuse contact handle and the
+// organisation id in the objects of the RIRs, lower cased
+var (
+	abuseHandleKeys = []string{"abuse-c", "orgabusehandle"}
+	orgHandleKeys   = []string{"org", "orgid", "owner-id"}
+)
+
+// registryObjects returns the records of the registry objects of ipAddr, the most specific first.
+func (e *Enricher) registryObjects(ctx context.Context, ipAddr string) ([][]ripestat.WhoisKeyValue, error) {
+	start := time.Now()
+	whoisData, err := e.rs.GetWhois(ctx, ipAddr)
+	if err != nil {
+		e.lookupLog(ipAddr, "whois", start).Warnf("registry objects err: %v", err)
+		return nil, err
+	}
+	return whoisData.Records, nil
+}
+
+// registrationDateKeys and allocationStatusKeys are the attributes holding the registration date and
+// the status of an address block in the objects of the RIRs, lower cased
+var (
+	registrationDateKeys = []string{"created", "regdate"}
+	allocationStatusKeys = []string{"status", "nettype"}
+)
+
+// registrationDateLayouts are the formats of the registration dates of the RIRs
+var registrationDateLayouts = []string{time.RFC3339, "2006-01-02", "20060102"}
+
+// registrationOf returns the registration date, as YYYY-MM-DD, and the allocation status of the
+// most specific address block in records with a status. The status is ALLOCATED, ASSIGNED or
+// LEGACY, or the status as registered in upper case when it is none of these. Values that are
+// missing or can't be parsed are left empty.
+func registrationOf(records [][]ripestat.WhoisKeyValue) (string, string) {
+	for _, record := range records {
+		status := findWhoisValue([][]ripestat.WhoisKeyValue{record}, allocationStatusKeys)
+		if status == "" {
+			continue
+		}
+		return parseRegistrationDate(findWhoisValue([][]ripestat.WhoisKeyValue{record}, registrationDateKeys)), normalizeAllocationStatus(status)
+	}
+	return "", ""
+}
+
+func parseRegistrationDate(value string) string {
+	for _, layout := range registrationDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format("2006-01-02")
+		}
+	}
+	return ""
+}
+
+// normalizeAllocationStatus maps the statuses of the RIRs, e.g. "ALLOCATED PA", "ASSIGNED PI",
+// "Direct Allocation" or "Reassigned", to ALLOCATED, ASSIGNED or LEGACY.
+func normalizeAllocationStatus(status string) string {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	switch {
+	case strings.Contains(status, "LEGACY"):
+		return "LEGACY"
+	case strings.Contains(status, "ALLOCAT"):
+		return "ALLOCATED"
+	case strings.Contains(status, "ASSIGN"):
+		return "ASSIGNED"
+	}
+	return status
+}
+
+// findWhoisValue returns the first value of any of keys in records, the records of the most
+// specific object come first.
+func findWhoisValue(records [][]ripestat.WhoisKeyValue, keys []string) string {
+	for _, record := range records {
+		for _, kv := range record {
+			for _, key := range keys {
+				if strings.EqualFold(kv.Key, key) && strings.TrimSpace(kv.Value) != "" {
+					return strings.TrimSpace(kv.Value)
+				}
+			}
+		}
+	}
+	return ""
+}
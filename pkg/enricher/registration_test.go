@@ -0,0 +1,82 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+func TestRegistration(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		ipAddr     string
+		wantDate   string
+		wantStatus string
+		wantAbuseC string
+		wantOrg    string
+	}{
+		// the most specific object with a status counts
+		{"ripe.json", "193.0.6.139", "2003-03-17", "ASSIGNED", "OPS4-RIPE", "ORG-RIEN1-RIPE"},
+		{"arin.json", "8.8.8.8", "2014-03-14", "ALLOCATED", "ABUSE5250-ARIN", "GOGL"},
+		// without status, handles or organisation they are left empty
+		{"bare.json", "193.0.6.140", "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", "ripestat-whois", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := newTestServer(t)
+			server.Handle("whois", tt.ipAddr, ripestattest.JSON(string(fixture)))
+
+			got := newTestEnricher(server, WithRegistration(), WithRegistryHandles()).EnrichIP(context.Background(), tt.ipAddr)
+			if got.RegistrationDate != tt.wantDate || got.AllocationStatus != tt.wantStatus {
+				t.Errorf("registered %q as %q, want %q as %q", got.RegistrationDate, got.AllocationStatus, tt.wantDate, tt.wantStatus)
+			}
+			if got.AbuseHandle != tt.wantAbuseC || got.OrgHandle != tt.wantOrg {
+				t.Errorf("handles %q and %q, want %q and %q", got.AbuseHandle, got.OrgHandle, tt.wantAbuseC, tt.wantOrg)
+			}
+			if len(got.Errors) > 0 {
+				t.Errorf("errors %v", got.Errors)
+			}
+
+			// the registry objects are only requested when asked for
+			plain := newTestServer(t)
+			plain.Handle("whois", tt.ipAddr, ripestattest.JSON(string(fixture)))
+			got = newTestEnricher(plain).EnrichIP(context.Background(), tt.ipAddr)
+			if got.RegistrationDate != "" || got.AllocationStatus != "" || got.AbuseHandle != "" || got.OrgHandle != "" {
+				t.Errorf("registration %+v without the options", got)
+			}
+			plain.AssertRequests(t, "whois", tt.ipAddr, 0)
+		})
+	}
+}
+
+func TestNormalizeAllocationStatus(t *testing.T) {
+	for status, want := range map[string]string{
+		"ALLOCATED PA":       "ALLOCATED",
+		"ALLOCATED-BY-RIR":   "ALLOCATED",
+		"Direct Allocation":  "ALLOCATED",
+		"ASSIGNED PI":        "ASSIGNED",
+		"Reassigned":         "ASSIGNED",
+		"legacy":             "LEGACY",
+		" sub-allocated pa ": "ALLOCATED",
+		"AGGREGATED-BY-LIR":  "AGGREGATED-BY-LIR",
+		"":                   "",
+	} {
+		if got := normalizeAllocationStatus(status); got != want {
+			t.Errorf("normalizeAllocationStatus(%q) = %q, want %q", status, got, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/netip"
+
+	"nuclei-parse-enrich/pkg/rir"
+)
+
+// WithRIRs limits the enrichment to the IP addresses the IANA delegated to one of rirs, see
+// rir.Of. The other IP addresses are not looked up, their record only has the RIR and the
+// abuse source skipped-rir. Names that are not a RIR are ignored.
+func WithRIRs(rirs ...string) Option {
+	return func(e *Enricher) {
+		e.rirs = rirSet(rirs)
+	}
+}
+
+// WithWhoisRIRs limits the whois fallback for abuse contacts to the IP addresses the IANA
+// delegated to one of rirs, see rir.Of. The other IP addresses RipeSTAT has no abuse contacts for
+// get the abuse source skipped-rir. Names that are not a RIR are ignored.
+func WithWhoisRIRs(rirs ...string) Option {
+	return func(e *Enricher) {
+		e.whoisRIRs = rirSet(rirs)
+	}
+}
+
+func rirSet(rirs []string) map[string]bool {
+	set := make(map[string]bool, len(rirs))
+	for _, value := range rirs {
+		if name, err := rir.Normalize(value); err == nil {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// inRIRs reports whether ipAddr was delegated to one of rirs, a nil set holds every address.
+func inRIRs(rirs map[string]bool, ipAddr string) bool {
+	if rirs == nil {
+		return true
+	}
+	addr, err := netip.ParseAddr(ipAddr)
+	return err == nil && rirs[rir.Of(addr)]
+}
@@ -0,0 +1,70 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+	"nuclei-parse-enrich/pkg/whoistest"
+)
+
+func TestRIRScoping(t *testing.T) {
+	server := newTestServer(t)
+	e := newTestEnricher(server, WithRIRs("ripe-ncc", "LACNIC", "not a RIR"))
+
+	tests := []struct {
+		ipAddr       string
+		wantRIR      string
+		wantSource   string
+		wantEnriched bool
+	}{
+		{"193.0.6.139", "RIPE", types.AbuseSourceRipeSTAT, true},
+		{"200.160.2.3", "LACNIC", types.AbuseSourceRipeSTAT, true},
+		{"8.8.8.8", "ARIN", types.AbuseSourceSkippedRIR, false},
+		{"2001:500::1", "ARIN", types.AbuseSourceSkippedRIR, false},
+		// private addresses are skipped for being private
+		{"10.0.0.1", "", types.AbuseSourceSkippedPrivate, false},
+	}
+	for _, tt := range tests {
+		got := e.EnrichIP(context.Background(), tt.ipAddr)
+		if got.RIR != tt.wantRIR || got.AbuseSource != tt.wantSource || (got.Asn == "3333") != tt.wantEnriched {
+			t.Errorf("%s: RIR %q, abuse source %q and ASN %q, want %q, %q and enriched: %v",
+				tt.ipAddr, got.RIR, got.AbuseSource, got.Asn, tt.wantRIR, tt.wantSource, tt.wantEnriched)
+		}
+		if !tt.wantEnriched {
+			server.AssertRequests(t, "network-info", tt.ipAddr, 0)
+			server.AssertRequests(t, "abuse-contact-finder", tt.ipAddr, 0)
+		}
+	}
+
+	// without scoping the RIR is not recorded
+	if got := newTestEnricher(server).EnrichIP(context.Background(), "8.8.8.8"); got.RIR != "" || got.AbuseSource != types.AbuseSourceRipeSTAT {
+		t.Errorf("unscoped: RIR %q and abuse source %q", got.RIR, got.AbuseSource)
+	}
+}
+
+func TestWhoisRIRScoping(t *testing.T) {
+	inetnum := readWhois(t, "ripe-inetnum.txt")
+	client := whoistest.NewClient()
+	client.Handle("193.0.6.139", "", whoistest.Text(inetnum))
+	client.Handle("8.8.8.8", "", whoistest.Text(inetnum))
+	e := newWhoisEnricher(t, client, WithWhoisRIRs("arin"))
+
+	// RipeSTAT is asked for every IP address, whois only for those of ARIN
+	got := e.EnrichIP(context.Background(), "193.0.6.139")
+	if got.RIR != "RIPE" || got.AbuseSource != types.AbuseSourceSkippedRIR || got.Abuse != "" || got.Asn != "3333" {
+		t.Errorf("193.0.6.139: RIR %q, abuse %q from %q and ASN %q, want RIPE without whois", got.RIR, got.Abuse, got.AbuseSource, got.Asn)
+	}
+	got = e.EnrichIP(context.Background(), "8.8.8.8")
+	if got.RIR != "ARIN" || got.AbuseSource != types.AbuseSourceWhois || got.Abuse != "abuse@ripe.net;ops@ripe.net" {
+		t.Errorf("8.8.8.8: RIR %q and abuse %q from %q, want ARIN with whois", got.RIR, got.Abuse, got.AbuseSource)
+	}
+	client.AssertLookups(t, "193.0.6.139", 0)
+	client.AssertLookups(t, "8.8.8.8", 1)
+}
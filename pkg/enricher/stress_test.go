@@ -0,0 +1,233 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/cache"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The stress suite is left out by -short, run it with the race detector:
+//
+//	go test -race ./pkg/enricher -run Stress
+
+const (
+	stressPrefixes   = 16
+	stressHosts      = 16
+	stressGoroutines = 300
+)
+
+// stressIP returns the IP address of host in prefix, of 193.0.<prefix>.0/24.
+func stressIP(prefix, host int) string {
+	return fmt.Sprintf("193.0.%d.%d", prefix, host+1)
+}
+
+// stressFails reports whether the network-info of the IP address fails, leaving an error on it.
+func stressFails(prefix, host int) bool {
+	return host == 7
+}
+
+// stressServer returns a fake RipeSTAT with latency, failing lookups, prefixes without abuse
+// contacts of their own and a few rate limited responses.
+func stressServer(t *testing.T) *ripestattest.Server {
+	server := ripestattest.NewServer()
+	t.Cleanup(server.Close)
+
+	for prefix := 0; prefix < stressPrefixes; prefix++ {
+		for host := 0; host < stressHosts; host++ {
+			response := ripestattest.JSON(fmt.Sprintf(`{"asns": ["3333"], "prefix": "193.0.%d.0/24"}`, prefix))
+			if stressFails(prefix, host) {
+				response = ripestattest.Error(400, "bad request")
+			}
+			response.Latency = time.Duration(rand.Intn(5)) * time.Millisecond
+			server.Handle("network-info", stressIP(prefix, host), response)
+		}
+
+		contacts := ripestattest.JSON(fmt.Sprintf(`{"abuse_contacts": ["abuse-%d@example.net"]}`, prefix))
+		if prefix%4 == 3 {
+			// the IP addresses fall back to their own abuse contacts
+			contacts = ripestattest.Error(404, "not found")
+		}
+		contacts.Latency = 20 * time.Millisecond
+		server.Handle("abuse-contact-finder", fmt.Sprintf("193.0.%d.0/24", prefix), contacts)
+	}
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/16", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+	for prefix := 0; prefix < 4; prefix++ {
+		server.Handle("maxmind-geo-lite", stressIP(prefix, 0),
+			ripestattest.RateLimited(),
+			ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/16", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+	}
+	return server
+}
+
+// checkStressResult fails t when result isn't the enrichment of the IP address of host in prefix.
+func checkStressResult(t *testing.T, result types.EnrichInfo, prefix, host int) {
+	t.Helper()
+	if stressFails(prefix, host) {
+		if result.Errors["Prefix"] == "" {
+			t.Errorf("%s enriched without the network-info error: %+v", result.Ip, result.Errors)
+		}
+		return
+	}
+
+	abuse := fmt.Sprintf("abuse-%d@example.net", prefix)
+	if prefix%4 == 3 {
+		abuse = "abuse@ripe.net"
+	}
+	if result.Abuse != abuse || result.Prefix != fmt.Sprintf("193.0.%d.0/24", prefix) || result.Country != "NL" || len(result.Errors) > 0 {
+		t.Errorf("%s enriched with abuse %s, prefix %s, country %s and errors %v, want %s", result.Ip, result.Abuse, result.Prefix, result.Country, result.Errors, abuse)
+	}
+}
+
+// TestStressEnrichIPs enriches overlapping IP addresses from hundreds of goroutines with one
+// enricher, sharing the cache, the abuse contacts per prefix and the RipeSTAT client, with an
+// adaptive limit per batch.
+func TestStressEnrichIPs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress suite")
+	}
+
+	server := stressServer(t)
+	c, err := cache.Open(filepath.Join(t.TempDir(), "cache.json"), time.Hour, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	e := NewEnricher(
+		WithRipeStatBaseURL(server.BaseURL()),
+		WithoutWhois(),
+		WithWorkers(8),
+		WithAdaptiveConcurrency(2),
+		WithPrefixAbuseContacts(),
+		WithCache(c),
+		WithLogger(logger),
+	)
+
+	type position struct{ prefix, host int }
+	positions := make(map[string]position)
+	var all []string
+	for prefix := 0; prefix < stressPrefixes; prefix++ {
+		for host := 0; host < stressHosts; host++ {
+			positions[stressIP(prefix, host)] = position{prefix, host}
+			all = append(all, stressIP(prefix, host))
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		enriched int
+	)
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+
+			if g%3 == 0 {
+				ipAddr := all[r.Intn(len(all))]
+				result := e.EnrichIP(context.Background(), ipAddr)
+				if result.Ip != ipAddr {
+					t.Errorf("EnrichIP(%s) returned %s", ipAddr, result.Ip)
+				}
+				checkStressResult(t, result, positions[ipAddr].prefix, positions[ipAddr].host)
+				mu.Lock()
+				enriched++
+				mu.Unlock()
+				return
+			}
+
+			// an overlapping window of the IP addresses, in random order
+			start := r.Intn(len(all))
+			batch := make([]string, 1+r.Intn(64))
+			for i := range batch {
+				batch[i] = all[(start+i)%len(all)]
+			}
+			r.Shuffle(len(batch), func(i, j int) { batch[i], batch[j] = batch[j], batch[i] })
+
+			results, summary, err := e.EnrichIPs(context.Background(), batch)
+			if err != nil {
+				t.Errorf("EnrichIPs: %v", err)
+				return
+			}
+
+			// every IP address comes back once
+			got := make([]string, 0, len(results))
+			failed := 0
+			for _, result := range results {
+				got = append(got, result.Ip)
+				checkStressResult(t, result, positions[result.Ip].prefix, positions[result.Ip].host)
+				if stressFails(positions[result.Ip].prefix, positions[result.Ip].host) {
+					failed++
+				}
+			}
+			want := append([]string(nil), batch...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("EnrichIPs returned %v, want %v", got, want)
+			}
+			if summary.Total != len(batch) || summary.Enriched != len(batch) || summary.Failed != failed {
+				t.Errorf("summary = %+v, want %d enriched of which %d failed", summary, len(batch), failed)
+			}
+
+			mu.Lock()
+			enriched += len(batch)
+			mu.Unlock()
+		}(g)
+	}
+	wg.Wait()
+
+	// every enrichment looks in the cache, and every miss is looked up
+	hits, misses := c.Stats()
+	if hits+misses != enriched {
+		t.Errorf("%d cache hits and %d misses, want %d in total", hits, misses, enriched)
+	}
+	server.AssertRequests(t, "network-info", "", misses)
+
+	// the abuse contacts of a prefix are looked up once, unless the lookup fails
+	for prefix := 0; prefix < stressPrefixes; prefix++ {
+		resource := fmt.Sprintf("193.0.%d.0/24", prefix)
+		if got := server.Requests("abuse-contact-finder", resource); prefix%4 != 3 && got != 1 {
+			t.Errorf("abuse contacts of %s looked up %d times, want once", resource, got)
+		}
+	}
+	server.AssertNoUnexpected(t)
+
+	// all but the failed IP addresses are served from the cache from now on
+	hitsBefore, missesBefore := c.Stats()
+	results, summary, err := e.EnrichIPs(context.Background(), all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(all) {
+		t.Fatalf("EnrichIPs returned %d results, want %d", len(results), len(all))
+	}
+	hits, misses = c.Stats()
+	if hits-hitsBefore != len(all)-stressPrefixes || misses-missesBefore != stressPrefixes {
+		t.Errorf("%d cache hits and %d misses, want %d and %d", hits-hitsBefore, misses-missesBefore, len(all)-stressPrefixes, stressPrefixes)
+	}
+	if summary.CacheHits != hits-hitsBefore || summary.CacheMisses != misses-missesBefore {
+		t.Errorf("summary counts %d cache hits and %d misses, want %d and %d", summary.CacheHits, summary.CacheMisses, hits-hitsBefore, misses-missesBefore)
+	}
+}
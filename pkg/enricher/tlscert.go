@@ -0,0 +1,44 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"time"
+
+	"nuclei-parse-enrich/pkg/tlscert"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// WithTLSCertificates records the certificate of the HTTPS target registered with c for every IP
+// address that has one.
+func WithTLSCertificates(c *tlscert.Client) Option {
+	return func(e *Enricher) {
+		e.tlscert = c
+	}
+}
+
+func (e *Enricher) enrichFromTLSCertificate(ctx context.Context, info *types.EnrichInfo) {
+	target, found := e.tlscert.Target(info.Ip)
+	if !found {
+		return
+	}
+
+	start := time.Now()
+	cert, err := e.tlscert.Fetch(ctx, info.Ip, target)
+	if err != nil {
+		e.lookupLog(info.Ip, "tls", start).Warnf("tls certificate err: %v", err)
+		addError(info, "Cert", err)
+		return
+	}
+
+	info.CertIssuer = cert.Issuer
+	info.CertSubject = cert.SubjectCN
+	info.CertNames = cert.SANs
+	info.CertNotAfter = cert.NotAfter.Format(time.RFC3339)
+	addError(info, "CertVerify", cert.VerifyErr)
+}
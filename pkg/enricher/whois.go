@@ -0,0 +1,324 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/likexian/whois"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+)
+
+// WhoisClient does the whois lookups of the enricher, see WithWhoisClient. Lookup returns the raw
+// response for resource, an IP address or domain, from server, or from the server of the registry
+// of resource when server is empty.
+type WhoisClient interface {
+	Lookup(ctx context.Context, resource, server string) (string, error)
+}
+
+// WithWhoisClient replaces the whois client, e.g. by a whoistest.Client in tests. The enricher
+// still applies the whois timeout, concurrency limit and circuit breaker; the maximum response
+// size only applies to the default client.
+func WithWhoisClient(c WhoisClient) Option {
+	return func(e *Enricher) {
+		e.whois = c
+	}
+}
+
+// defaultWhoisClient is the WhoisClient of the likexian/whois package, its connections are limited
+// to the maximum response size.
+type defaultWhoisClient struct {
+	client *whois.Client
+}
+
+func newDefaultWhoisClient(maxResponseSize int64) *defaultWhoisClient {
+	client := whois.NewClient()
+	client.SetDialer(&limitedDialer{
+		dialer: &net.Dialer{Timeout: whoisDialTimeout},
+		limit:  maxResponseSize,
+	})
+	return &defaultWhoisClient{client}
+}
+
+// Lookup ignores ctx, the package can't abandon a lookup; the enricher stops waiting for it instead.
+func (c *defaultWhoisClient) Lookup(_ context.Context, resource, server string) (string, error) {
+	if server == "" {
+		return c.client.Whois(resource)
+	}
+	return c.client.Whois(resource, server)
+}
+
+// whoisDialTimeout matches the connect timeout of the whois package
+const whoisDialTimeout = 30 * time.Second
+
+// ErrWhoisResponseTooLarge is returned by whois lookups with a response larger than the maximum
+// response size, see WithMaxResponseSize.
+var ErrWhoisResponseTooLarge = errors.New("whois response too large")
+
+// limitedDialer dials whois connections that fail once more than limit bytes are read, as the
+// whois package reads responses until the server closes the connection.
+type limitedDialer struct {
+	dialer *net.Dialer
+	limit  int64
+}
+
+func (d *limitedDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := d.dialer.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := d.limit
+	if limit <= 0 {
+		limit = ripestat.DefaultMaxResponseSize
+	}
+	return &limitedConn{Conn: conn, limit: limit, remaining: limit}, nil
+}
+
+type limitedConn struct {
+	net.Conn
+	limit     int64
+	remaining int64
+}
+
+func (c *limitedConn) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		// a single byte more tells a response of exactly limit bytes from a larger one
+		var probe [1]byte
+		if n, err := c.Conn.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w, exceeds %d bytes", ErrWhoisResponseTooLarge, c.limit)
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.Conn.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// Defaults of the whois circuit breaker, see WithWhoisCircuitBreaker.
+const (
+	DefaultWhoisBreakerThreshold = 5
+	DefaultWhoisBreakerCooldown  = 5 * time.Minute
+)
+
+// ErrWhoisCircuitOpen is returned by whois lookups skipped because the preceding lookups timed out.
+var ErrWhoisCircuitOpen = errors.New("whois skipped, the preceding lookups timed out")
+
+// whoisBreaker skips whois lookups for cooldown once threshold lookups in a row timed out, as
+// during a whois outage every lookup would hold up its IP address for the full timeout. After
+// the cooldown lookups are tried again, a single timeout opens the breaker again and a lookup
+// that doesn't time out closes it.
+type whoisBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	timeouts  int
+	openUntil time.Time
+}
+
+func newWhoisBreaker(threshold int, cooldown time.Duration) *whoisBreaker {
+	return &whoisBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a whois lookup may be done.
+func (b *whoisBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.now().Before(b.openUntil)
+}
+
+// record registers the outcome of a whois lookup and reports whether it opened the breaker.
+func (b *whoisBreaker) record(timedOut bool) bool {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !timedOut {
+		b.timeouts = 0
+		return false
+	}
+
+	b.timeouts++
+	now := b.now()
+	if b.timeouts < b.threshold || now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// whoisRateLimited and whoisNoMatch match the responses of whois servers refusing a lookup
+// because of the query rate, and of servers without a match for the query.
+var (
+	whoisRateLimited = regexp.MustCompile(`(?i)rate limit|limit exceeded|too many (queries|requests)|excessive query|query rate|access denied|temporarily denied`)
+	whoisNoMatch     = regexp.MustCompile(`(?i)no match|no entries found|no data found|object not found|not found in database`)
+)
+
+// whoisRefusal returns "rate limit" or "no match" when the whois response says so, and an empty
+// string otherwise.
+func whoisRefusal(response string) string {
+	switch {
+	case whoisRateLimited.MatchString(response):
+		return "rate limit"
+	case whoisNoMatch.MatchString(response):
+		return "no match"
+	}
+	return ""
+}
+
+// Whois servers are quick to block clients hammering them, so only a few lookups run at once
+const MaxConcurrentWhoisLookups = 2
+
+// WithoutWhois disables the whois fallback for abuse contacts, for environments where outbound
+// whois is blocked. Abuse contacts then only come from RipeSTAT.
+func WithoutWhois() Option {
+	return func(e *Enricher) {
+		e.noWhois = true
+	}
+}
+
+// WithWhoisCircuitBreaker skips whois lookups for cooldown once threshold lookups in a row timed
+// out, the abuse contacts of the IP addresses are then left unknown. By default the breaker opens
+// after DefaultWhoisBreakerThreshold timeouts for DefaultWhoisBreakerCooldown, a threshold of zero
+// disables it.
+func WithWhoisCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(e *Enricher) {
+		if threshold <= 0 {
+			e.whoisBreaker = nil
+			return
+		}
+		e.whoisBreaker = newWhoisBreaker(threshold, cooldown)
+	}
+}
+
+// WithWhoisServers asks servers in turn when a whois server answers that it rate limits the
+// lookups or has no match for the IP address, e.g. the whois servers of the RIRs. Without servers
+// the whois lookup is not retried.
+func WithWhoisServers(servers ...string) Option {
+	return func(e *Enricher) {
+		e.whoisServers = servers
+	}
+}
+
+// WithWhoisTimeout bounds every single whois lookup to d.
+func WithWhoisTimeout(d time.Duration) Option {
+	return func(e *Enricher) {
+		e.whoisTimeout = d
+	}
+}
+
+func (e *Enricher) whoisEnrichmentIP(ctx context.Context, ipAddr string) ([]string, error) {
+	e.log.Debug("enricher: ripestat has no abuse mails for us, executing whoisEnrichment on IP address: ", ipAddr)
+
+	whoisInfo, err := e.whoisWithContext(ctx, ipAddr)
+	if err != nil {
+		e.log.Debug("enricher: whoisEnrichment - could not get whois info for ", ipAddr)
+		return nil, fmt.Errorf("whois: %v", err)
+	}
+
+	// a server refusing or not knowing the IP address won't answer differently when asked again,
+	// unless a referred server in the same response did know it
+	for _, server := range e.whoisServers {
+		refusal := whoisRefusal(whoisInfo)
+		if refusal == "" || len(extractWhoisEmails(whoisInfo)) > 0 {
+			break
+		}
+
+		e.log.Debugf("enricher: whois for %s returned %s, retrying with %s", ipAddr, refusal, server)
+		retryInfo, err := e.whoisWithContext(ctx, ipAddr, server)
+		if err != nil {
+			e.log.Debugf("enricher: whois for %s with %s failed: %v", ipAddr, server, err)
+			continue
+		}
+		whoisInfo = retryInfo
+	}
+
+	abuseEmails := extractWhoisEmails(whoisInfo)
+	if len(abuseEmails) == 0 {
+		e.log.Debug("enricher: whoisEnrichment - could not find any abuse emails for ", ipAddr)
+		// TODO: fall back to ipinfo. Whois is not always available
+	}
+
+	return abuseEmails, nil
+}
+
+// whoisWithContext performs a whois lookup of an IP address or domain that is abandoned as soon
+// as ctx is done.
+func (e *Enricher) whoisWithContext(ctx context.Context, query string, server ...string) (string, error) {
+	type whoisResult struct {
+		info string
+		err  error
+	}
+
+	if !e.whoisBreaker.allow() {
+		return "", ErrWhoisCircuitOpen
+	}
+
+	parent := ctx
+	if e.whoisTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.whoisTimeout)
+		defer cancel()
+	}
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case e.whoisSem <- struct{}{}:
+	}
+
+	resultCh := make(chan whoisResult, 1)
+	go func() {
+		defer func() { <-e.whoisSem }()
+
+		lookupServer := ""
+		if len(server) > 0 {
+			lookupServer = server[0]
+		}
+		info, err := e.whois.Lookup(ctx, query, lookupServer)
+		resultCh <- whoisResult{info, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// only the whois timeout counts, not the run or the IP address running out of time
+		if parent.Err() == nil {
+			e.recordWhoisTimeout(true)
+		}
+		return "", ctx.Err()
+	case result := <-resultCh:
+		var netErr net.Error
+		e.recordWhoisTimeout(errors.As(result.err, &netErr) && netErr.Timeout())
+		return result.info, result.err
+	}
+}
+
+func (e *Enricher) recordWhoisTimeout(timedOut bool) {
+	if e.whoisBreaker.record(timedOut) {
+		e.log.Warnf("enricher: %d whois lookups in a row timed out, skipping whois for %v", e.whoisBreaker.threshold, e.whoisBreaker.cooldown)
+	}
+}
@@ -0,0 +1,252 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+	"nuclei-parse-enrich/pkg/whoistest"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newWhoisEnricher returns an enricher looking up whois with client, against a fake RipeSTAT
+// without abuse contacts for the IP addresses, so the enricher falls back to whois.
+func newWhoisEnricher(t *testing.T, client *whoistest.Client, opts ...Option) *Enricher {
+	server := ripestattest.NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": []}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	opts = append([]Option{WithRipeStatBaseURL(server.BaseURL()), WithWhoisClient(client), WithLogger(logger)}, opts...)
+	return NewEnricher(opts...)
+}
+
+// readWhois returns the whois response of testdata/whois/name.
+func readWhois(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "whois", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWhoisAbuse(t *testing.T) {
+	inetnum := readWhois(t, "ripe-inetnum.txt")
+	tests := []struct {
+		name        string
+		responses   []whoistest.Response
+		abuse       string
+		abuseSource string
+		err         string
+	}{
+		{"found", []whoistest.Response{whoistest.Text(inetnum)}, "abuse@ripe.net;ops@ripe.net", types.AbuseSourceWhois, ""},
+		{"no addresses", []whoistest.Response{whoistest.Text("inetnum: 193.0.0.0 - 193.0.7.255\n")}, "unknown", types.AbuseSourceNone, ""},
+		{"failed", []whoistest.Response{whoistest.Error(errors.New("connection refused"))}, "unknown", types.AbuseSourceError, "whois: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := whoistest.NewClient()
+			client.Handle("193.0.6.139", "", tt.responses...)
+			e := newWhoisEnricher(t, client, WithUnknownPlaceholder())
+
+			got := e.EnrichIP(context.Background(), "193.0.6.139")
+			if got.Abuse != tt.abuse || got.AbuseSource != tt.abuseSource || got.Errors["Abuse"] != tt.err {
+				t.Errorf("abuse %q from %s with error %q, want %q from %s with error %q",
+					got.Abuse, got.AbuseSource, got.Errors["Abuse"], tt.abuse, tt.abuseSource, tt.err)
+			}
+			client.AssertLookups(t, "193.0.6.139", 1)
+			client.AssertNoUnexpected(t)
+		})
+	}
+}
+
+func TestWhoisFallbackServers(t *testing.T) {
+	client := whoistest.NewClient()
+	client.Handle("193.0.6.139", "", whoistest.Text("%ERROR:201: access denied\n"))
+	client.Handle("193.0.6.139", "whois.arin.net", whoistest.Text("No match found for n + 193.0.6.139.\n"))
+	client.Handle("193.0.6.139", "whois.ripe.net", whoistest.Text(readWhois(t, "ripe-inetnum.txt")))
+	e := newWhoisEnricher(t, client, WithWhoisServers("whois.arin.net", "whois.ripe.net", "whois.apnic.net"))
+
+	got := e.EnrichIP(context.Background(), "193.0.6.139")
+	if got.Abuse != "abuse@ripe.net;ops@ripe.net" || got.AbuseSource != types.AbuseSourceWhois {
+		t.Errorf("abuse %q from %s, want the addresses of whois.ripe.net", got.Abuse, got.AbuseSource)
+	}
+	// the servers after the one answering are not asked
+	client.AssertLookups(t, "193.0.6.139", 3)
+	client.AssertNoUnexpected(t)
+}
+
+func TestWhoisNotNeeded(t *testing.T) {
+	client := whoistest.NewClient()
+	e := newWhoisEnricher(t, client)
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": []}`))
+	e.rs.BaseURL = server.BaseURL()
+
+	if got := e.EnrichIP(context.Background(), "193.0.6.139"); got.AbuseSource != types.AbuseSourceRipeSTAT {
+		t.Errorf("abuse from %s, want RipeSTAT", got.AbuseSource)
+	}
+	if lookups := client.TotalLookups(); lookups > 0 {
+		t.Errorf("%d whois lookups with abuse contacts from RipeSTAT", lookups)
+	}
+}
+
+func TestWhoisCircuitBreaker(t *testing.T) {
+	client := whoistest.NewClient()
+	client.Handle("", "", whoistest.Timeout(time.Millisecond))
+	e := newWhoisEnricher(t, client, WithWhoisCircuitBreaker(2, time.Hour))
+
+	for _, ipAddr := range []string{"193.0.6.139", "193.0.6.140"} {
+		if got := e.EnrichIP(context.Background(), ipAddr); !strings.Contains(got.Errors["Abuse"], "timeout") {
+			t.Errorf("%s abuse error %q, want the timeout", ipAddr, got.Errors["Abuse"])
+		}
+	}
+	// two timeouts in a row open the breaker
+	got := e.EnrichIP(context.Background(), "193.0.6.141")
+	if got.Errors["Abuse"] != "whois: "+ErrWhoisCircuitOpen.Error() {
+		t.Errorf("abuse error %q with the breaker open", got.Errors["Abuse"])
+	}
+	if lookups := client.TotalLookups(); lookups != 2 {
+		t.Errorf("%d whois lookups, want 2", lookups)
+	}
+}
+
+func TestWhoisTimeout(t *testing.T) {
+	client := whoistest.NewClient()
+	client.Handle("193.0.6.139", "", whoistest.Text("abuse-mailbox: abuse@ripe.net\n"))
+	client.Delay = time.Second
+	e := newWhoisEnricher(t, client, WithWhoisTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	got := e.EnrichIP(context.Background(), "193.0.6.139")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("enrichment took %v, the whois lookup should be abandoned", elapsed)
+	}
+	if got.AbuseSource != types.AbuseSourceError || got.Errors["Abuse"] != "whois: "+context.DeadlineExceeded.Error() {
+		t.Errorf("abuse from %s with error %q, want the whois deadline", got.AbuseSource, got.Errors["Abuse"])
+	}
+}
+
+// TestLimitedConn reads whois responses up to the maximum response size, from a server that
+// closes the connection after the response and from one that keeps streaming.
+func TestLimitedConn(t *testing.T) {
+	const limit = 1024
+
+	tests := []struct {
+		name string
+		// size is the number of bytes sent before closing the connection, -1 streams until the
+		// reader gives up
+		size    int
+		tooLong bool
+	}{
+		{"empty", 0, false},
+		{"below the limit", limit - 1, false},
+		{"at the limit", limit, false},
+		{"over the limit", limit + 1, true},
+		{"streaming", -1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			go func() {
+				defer server.Close()
+				if tt.size >= 0 {
+					_, _ = server.Write([]byte(strings.Repeat("a", tt.size)))
+					return
+				}
+				chunk := []byte(strings.Repeat("a", 100))
+				for {
+					if _, err := server.Write(chunk); err != nil {
+						return
+					}
+				}
+			}()
+
+			conn := &limitedConn{Conn: client, limit: limit, remaining: limit}
+			defer conn.Close()
+			response, err := io.ReadAll(conn)
+			if tt.tooLong {
+				if !errors.Is(err, ErrWhoisResponseTooLarge) {
+					t.Errorf("reading error = %v, want ErrWhoisResponseTooLarge", err)
+				}
+				if len(response) > limit {
+					t.Errorf("read %d bytes, at most %d expected", len(response), limit)
+				}
+				return
+			}
+			if err != nil || len(response) != tt.size {
+				t.Errorf("read %d bytes, %v, want %d bytes", len(response), err, tt.size)
+			}
+		})
+	}
+}
+
+// TestLimitedDialer dials connections limited to the maximum response size, the default size
+// without one.
+func TestLimitedDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte(strings.Repeat("a", 2048)))
+			}()
+		}
+	}()
+
+	tests := []struct {
+		limit   int64
+		tooLong bool
+	}{
+		{1024, true},
+		{2048, false},
+		{0, false},
+	}
+
+	for _, tt := range tests {
+		dialer := &limitedDialer{dialer: &net.Dialer{Timeout: time.Second}, limit: tt.limit}
+		conn, err := dialer.Dial("tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = io.ReadAll(conn)
+		conn.Close()
+		if errors.Is(err, ErrWhoisResponseTooLarge) != tt.tooLong {
+			t.Errorf("reading with limit %d: error = %v, too large %v expected", tt.limit, err, tt.tooLong)
+		}
+	}
+}
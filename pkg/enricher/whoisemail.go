@@ -0,0 +1,59 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var whoisRegexp = regexp.MustCompile("[a-zA-Z\\d.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?(?:\\.[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?)*\\.?[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?(?:\\.[a-zA-Z\\d](?:[a-zA-Z\\d-]{0,61}[a-zA-Z\\d])?)*")
+
+// Whois servers are not to be trusted to send sane responses, the email extraction only looks at
+// the start of overly long responses and at a limited number of addresses.
+const (
+	maxWhoisResponseSize = 1 << 20
+	maxWhoisEmails       = 100
+)
+
+// extractWhoisEmails returns the unique, lower cased and sorted email addresses in a whois response.
+func extractWhoisEmails(whoisInfo string) []string {
+	if len(whoisInfo) > maxWhoisResponseSize {
+		whoisInfo = whoisInfo[:maxWhoisResponseSize]
+	}
+
+	foundMailAddresses := whoisRegexp.FindAllString(whoisInfo, maxWhoisEmails)
+
+	uniqueMailAddresses := make(map[string]struct{}, len(foundMailAddresses))
+	for _, foundMailAddress := range foundMailAddresses {
+		mailAddress, err := mail.ParseAddress(trimWhoisQuotes(foundMailAddress))
+		if err != nil {
+			continue
+		}
+		uniqueMailAddresses[strings.ToLower(mailAddress.Address)] = struct{}{}
+	}
+
+	abuseEmails := make([]string, 0, len(uniqueMailAddresses))
+	for mailAddress := range uniqueMailAddresses {
+		abuseEmails = append(abuseEmails, mailAddress)
+	}
+	sort.Strings(abuseEmails)
+
+	return abuseEmails
+}
+
+// trimWhoisQuotes drops the opening quotes of a quoted address. Quotes are valid in the local
+// part, so the match of the RIPE abuse contact remark includes the opening quote:
+//
+//	% Abuse contact for '193.0.0.0 - 193.0.7.255' is 'abuse@ripe.net'
+//
+// The closing quote isn't valid in a domain and never part of the match.
+func trimWhoisQuotes(mailAddress string) string {
+	return strings.TrimLeft(mailAddress, "'`")
+}
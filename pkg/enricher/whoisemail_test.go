@@ -0,0 +1,101 @@
+package enricher
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExtractWhoisEmails(t *testing.T) {
+	tests := []struct {
+		name      string
+		whoisInfo string
+		want      []string
+	}{
+		{"none", "inetnum: 193.0.0.0 - 193.0.7.255\n", []string{}},
+		{"lower cased and unique", "abuse-mailbox: Abuse@RIPE.net\ne-mail: abuse@ripe.net\n", []string{"abuse@ripe.net"}},
+		{"sorted", "e-mail: noc@example.net\nabuse-mailbox: abuse@example.net\n", []string{"abuse@example.net", "noc@example.net"}},
+		{"in angle brackets", "remarks: Abuse <abuse@example.net>\n", []string{"abuse@example.net"}},
+		{"quoted", "% Abuse contact for '193.0.0.0 - 193.0.7.255' is 'abuse@ripe.net'\n", []string{"abuse@ripe.net"}},
+		{"quote in the local part", "e-mail: o'brien@example.net\n", []string{"o'brien@example.net"}},
+		{"backquoted", "remarks: mail `abuse@example.net' for abuse\n", []string{"abuse@example.net"}},
+		{"quoted twice", "remarks: ''abuse@example.net''\n", []string{"abuse@example.net"}},
+		{"quote as local part", "remarks: '@example.net'\n", []string{}},
+		{"quoted and unquoted", "abuse-mailbox: abuse@ripe.net\n% Abuse contact is 'abuse@ripe.net'\n", []string{"abuse@ripe.net"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractWhoisEmails(tt.whoisInfo); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractWhoisEmails = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractWhoisEmailsPathological extracts the email addresses of responses over the maximum
+// size built to be slow to scan. The extraction time has to grow linearly with the size of the
+// response, comparing sizes keeps the check independent of the speed of the machine and of the
+// race detector.
+func TestExtractWhoisEmailsPathological(t *testing.T) {
+	responses := map[string]func(size int) string{
+		"distinct addresses": func(size int) string {
+			var distinct strings.Builder
+			for i := 0; distinct.Len() < size; i++ {
+				fmt.Fprintf(&distinct, "e-mail: abuse%d@example.net\n", i)
+			}
+			return distinct.String()
+		},
+		"long local part": func(size int) string { return strings.Repeat("a", size) + "@" },
+		"at signs":        func(size int) string { return strings.Repeat("a@", size/2) },
+		"long domain":     func(size int) string { return "a@" + strings.Repeat("a.", size/2) },
+		"long label":      func(size int) string { return "a@" + strings.Repeat("a", size) },
+	}
+
+	// the fastest of a few runs, to smooth out scheduling noise
+	extract := func(whoisInfo string) time.Duration {
+		fastest := time.Duration(math.MaxInt64)
+		for i := 0; i < 3; i++ {
+			start := time.Now()
+			extractWhoisEmails(whoisInfo)
+			if elapsed := time.Since(start); elapsed < fastest {
+				fastest = elapsed
+			}
+		}
+		return fastest
+	}
+
+	for name, response := range responses {
+		t.Run(name, func(t *testing.T) {
+			if emails := extractWhoisEmails(response(2 * maxWhoisResponseSize)); len(emails) > maxWhoisEmails {
+				t.Errorf("extracted %d email addresses, at most %d expected", len(emails), maxWhoisEmails)
+			}
+			if testing.Short() {
+				return
+			}
+
+			small := extract(response(maxWhoisResponseSize / 8))
+			large := extract(response(maxWhoisResponseSize / 2))
+			// linear is a ratio of 4, quadratic 16; below a few milliseconds the ratio is noise
+			if large > 5*time.Millisecond && large > 8*small {
+				t.Errorf("extracting 4 times the input took %v instead of %v, not linear", large, small)
+			}
+			// over the maximum size only the start is scanned, twice the input of large
+			whoisInfo := response(4 * maxWhoisResponseSize)
+			start := time.Now()
+			extractWhoisEmails(whoisInfo)
+			if huge := time.Since(start); huge > 5*time.Millisecond && huge > 4*large {
+				t.Errorf("extracting over the maximum size took %v instead of %v", huge, large)
+			}
+		})
+	}
+}
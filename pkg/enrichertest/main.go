@@ -0,0 +1,214 @@
+package enrichertest
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/country"
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// EnrichedAt is the enrichment time of the records of a FakeEnricher, unless changed.
+var EnrichedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// fakeCountries are the countries of the derived records
+var fakeCountries = []string{"NL", "DE", "US", "GB", "FR", "JP", "BR", "ZA"}
+
+// FakeEnricher is a deterministic enricher.IPEnricher for testing code using the enricher without
+// network. Every IP address gets the same record on every run: the record set with Set, or else one
+// derived from a hash of the address, with an ASN in the private range, the /24 (IPv4) or /48 (IPv6)
+// around the address as prefix, a holder, an abuse address under example.com and a country:
+//
+//	fake := enrichertest.NewFakeEnricher()
+//	fake.Set("193.0.6.139", types.EnrichInfo{Asn: "3333", Holder: "RIPE-NCC-AS"})
+//	fake.Fail("192.0.2.1", "Abuse", errors.New("whois: i/o timeout"))
+//	srv := server.NewServer(ctx, fake, 4)
+//
+// Values that are not an IP address get an Ip error wrapping enricher.ErrInvalidIP, like the real
+// enricher.
+type FakeEnricher struct {
+	// Latency delays every enrichment
+	Latency time.Duration
+	// EnrichedAt is the enrichment time of every record
+	EnrichedAt time.Time
+
+	mu      sync.Mutex
+	records map[string]types.EnrichInfo
+	errors  map[string]map[string]string
+	delays  map[string]time.Duration
+	calls   map[string]int
+}
+
+func NewFakeEnricher() *FakeEnricher {
+	return &FakeEnricher{
+		EnrichedAt: EnrichedAt,
+		records:    make(map[string]types.EnrichInfo),
+		errors:     make(map[string]map[string]string),
+		delays:     make(map[string]time.Duration),
+		calls:      make(map[string]int),
+	}
+}
+
+// Set makes info the record of ipAddr, instead of the derived one. Its Ip is filled in.
+func (f *FakeEnricher) Set(ipAddr string, info types.EnrichInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.records[enricher.CanonicalIP(ipAddr)] = info
+}
+
+// Fail records err for field in the Errors of the record of ipAddr, as the enricher does for a
+// failed lookup, e.g. "Abuse" or "Prefix". The field is left empty in the record.
+func (f *FakeEnricher) Fail(ipAddr, field string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ipAddr = enricher.CanonicalIP(ipAddr)
+	if f.errors[ipAddr] == nil {
+		f.errors[ipAddr] = make(map[string]string)
+	}
+	f.errors[ipAddr][field] = err.Error()
+}
+
+// Delay delays the enrichment of ipAddr by d, on top of Latency.
+func (f *FakeEnricher) Delay(ipAddr string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delays[enricher.CanonicalIP(ipAddr)] = d
+}
+
+// Calls returns the number of enrichments of ipAddr so far.
+func (f *FakeEnricher) Calls(ipAddr string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[enricher.CanonicalIP(ipAddr)]
+}
+
+// EnrichIP implements enricher.IPEnricher. When ctx is done before the delay of ipAddr has passed
+// the record is returned without enrichment, with a Timeout error.
+func (f *FakeEnricher) EnrichIP(ctx context.Context, ipAddr string) types.EnrichInfo {
+	rawIPAddr := ipAddr
+	ipAddr = enricher.CanonicalIP(ipAddr)
+
+	f.mu.Lock()
+	f.calls[ipAddr]++
+	info, found := f.records[ipAddr]
+	failures := f.errors[ipAddr]
+	delay := f.Latency + f.delays[ipAddr]
+	f.mu.Unlock()
+
+	enrichedAt := f.EnrichedAt.UTC().Format(time.RFC3339)
+	if err := enricher.ValidateIP(rawIPAddr); err != nil {
+		return types.EnrichInfo{
+			Ip:          rawIPAddr,
+			AbuseSource: types.AbuseSourceError,
+			EnrichedAt:  enrichedAt,
+			Errors:      map[string]string{"Ip": err.Error()},
+		}
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return types.EnrichInfo{
+				Ip:          ipAddr,
+				AbuseSource: types.AbuseSourceError,
+				EnrichedAt:  enrichedAt,
+				Errors:      map[string]string{"Timeout": ctx.Err().Error()},
+			}
+		case <-timer.C:
+		}
+	}
+
+	if !found {
+		info = derive(ipAddr)
+	}
+	info.Ip = ipAddr
+	if rawIPAddr != ipAddr {
+		info.IpRaw = rawIPAddr
+	}
+	if info.EnrichedAt == "" {
+		info.EnrichedAt = enrichedAt
+	}
+
+	if len(failures) > 0 {
+		errors := make(map[string]string, len(info.Errors)+len(failures))
+		for field, message := range info.Errors {
+			errors[field] = message
+		}
+		for field, message := range failures {
+			errors[field] = message
+			clearField(&info, field)
+		}
+		info.Errors = errors
+	}
+	return info
+}
+
+// derive returns the record of ipAddr derived from its hash.
+func derive(ipAddr string) types.EnrichInfo {
+	addr, _ := netip.ParseAddr(ipAddr)
+	addr = addr.Unmap()
+
+	hash := fnv.New32a()
+	hash.Write([]byte(addr.String()))
+	sum := hash.Sum32()
+
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.Prefix(bits)
+
+	// 64512-65534 is the 16 bit private ASN range
+	asn := strconv.Itoa(64512 + int(sum%1023))
+	countryCode := fakeCountries[int(sum/1023)%len(fakeCountries)]
+	abuse := fmt.Sprintf("abuse@as%s.example.com", asn)
+
+	return types.EnrichInfo{
+		Abuse:          abuse,
+		AbuseSource:    types.AbuseSourceRipeSTAT,
+		AbuseAddresses: []string{abuse},
+		AbuseContacts:  []types.AbuseContact{{Email: abuse, Kind: contact.KindRole, Source: types.AbuseSourceRipeSTAT}},
+		Prefix:         prefix.String(),
+		Asn:            asn,
+		Holder:         fmt.Sprintf("FAKE-AS%s Fake Network %s, %s", asn, asn, countryCode),
+		HolderName:     fmt.Sprintf("FAKE-AS%s Fake Network %s", asn, asn),
+		HolderCountry:  countryCode,
+		Country:        countryCode,
+		CountryName:    country.Name(countryCode),
+		GeoSource:      "RipeSTAT",
+	}
+}
+
+// clearField empties the field of info named as in its Errors.
+func clearField(info *types.EnrichInfo, field string) {
+	switch strings.ToLower(field) {
+	case "abuse":
+		info.Abuse, info.AbuseAddresses, info.AbuseContacts = "", nil, nil
+		info.AbuseSource = types.AbuseSourceError
+	case "prefix":
+		info.Prefix, info.Asn = "", ""
+	case "asn":
+		info.Asn = ""
+	case "holder":
+		info.Holder, info.HolderName, info.HolderCountry = "", "", ""
+	case "geolocation":
+		info.Country, info.CountryName, info.City = "", "", ""
+	}
+}
@@ -0,0 +1,176 @@
+package enrichpb
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative enrichinfo.proto
+
+import (
+	"reflect"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// Unmapped returns the JSON names of the fields of types.EnrichInfo without a field of the same
+// name in the EnrichInfo message, which ToProto leaves out. It is empty as long as
+// enrichinfo.proto is kept up to date.
+func Unmapped() []string {
+	fields := (&EnrichInfo{}).ProtoReflect().Descriptor().Fields()
+	t := reflect.TypeOf(types.EnrichInfo{})
+
+	var unmapped []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if fields.ByName(protoreflect.Name(name)) == nil {
+			unmapped = append(unmapped, name)
+		}
+	}
+	return unmapped
+}
+
+// ToProto returns info as the EnrichInfo message of enrichinfo.proto, with the current
+// types.SchemaVersion.
+func ToProto(info types.EnrichInfo) *EnrichInfo {
+	m := &EnrichInfo{
+		SchemaVersion:    types.SchemaVersion,
+		Ip:               info.Ip,
+		IpRaw:            info.IpRaw,
+		IpMapping:        info.IpMapping,
+		AbuseSource:      info.AbuseSource,
+		Abuse:            info.Abuse,
+		AbuseAddresses:   info.AbuseAddresses,
+		AbuseTo:          info.AbuseTo,
+		NationalCert:     info.NationalCERT,
+		AbuseHandle:      info.AbuseHandle,
+		OrgHandle:        info.OrgHandle,
+		RegistrationDate: info.RegistrationDate,
+		AllocationStatus: info.AllocationStatus,
+		Rir:              info.RIR,
+		Prefix:           info.Prefix,
+		CoveringPrefix:   info.CoveringPrefix,
+		CoveringAsn:      info.CoveringAsn,
+		Asn:              info.Asn,
+		AsnSource:        info.AsnSource,
+		WhoisAsn:         info.WhoisAsn,
+		AsnCategory:      info.ASNCategory,
+		IxpCount:         int64(info.IXPCount),
+		AsnDiscrepancy:   info.AsnDiscrepancy,
+		Holder:           info.Holder,
+		HolderSource:     info.HolderSource,
+		HolderName:       info.HolderName,
+		HolderCountry:    info.HolderCountry,
+		Country:          info.Country,
+		CountryName:      info.CountryName,
+		City:             info.City,
+		Latitude:         info.Latitude,
+		Longitude:        info.Longitude,
+		GeoSource:        info.GeoSource,
+		EnrichedAt:       info.EnrichedAt,
+		QueryTime:        info.QueryTime,
+		NetworkType:      info.NetworkType,
+		Ptr:              info.Ptr,
+		ProviderHint:     info.ProviderHint,
+		CloudProvider:    info.CloudProvider,
+		CloudRegion:      info.CloudRegion,
+		HistoricalHolder: info.HistoricalHolder,
+		HistoricalOrg:    info.HistoricalOrg,
+		HistoricalObject: info.HistoricalObject,
+		CertIssuer:       info.CertIssuer,
+		CertSubject:      info.CertSubject,
+		CertNames:        info.CertNames,
+		CertNotAfter:     info.CertNotAfter,
+		Tags:             info.Tags,
+		Errors:           info.Errors,
+	}
+	for _, contact := range info.AbuseContacts {
+		m.AbuseContacts = append(m.AbuseContacts, &AbuseContact{
+			Email:  contact.Email,
+			Kind:   contact.Kind,
+			Source: contact.Source,
+		})
+	}
+	for _, facility := range info.Facilities {
+		m.Facilities = append(m.Facilities, &Facility{
+			Name:    facility.Name,
+			City:    facility.City,
+			Country: facility.Country,
+		})
+	}
+	return m
+}
+
+// FromProto returns the types.EnrichInfo of an EnrichInfo message. Fields unknown to this
+// version, e.g. added by a later one, are left out.
+func FromProto(m *EnrichInfo) types.EnrichInfo {
+	info := types.EnrichInfo{
+		Ip:               m.GetIp(),
+		IpRaw:            m.GetIpRaw(),
+		IpMapping:        m.GetIpMapping(),
+		AbuseSource:      m.GetAbuseSource(),
+		Abuse:            m.GetAbuse(),
+		AbuseAddresses:   m.GetAbuseAddresses(),
+		AbuseTo:          m.GetAbuseTo(),
+		NationalCERT:     m.GetNationalCert(),
+		AbuseHandle:      m.GetAbuseHandle(),
+		OrgHandle:        m.GetOrgHandle(),
+		RegistrationDate: m.GetRegistrationDate(),
+		AllocationStatus: m.GetAllocationStatus(),
+		RIR:              m.GetRir(),
+		Prefix:           m.GetPrefix(),
+		CoveringPrefix:   m.GetCoveringPrefix(),
+		CoveringAsn:      m.GetCoveringAsn(),
+		Asn:              m.GetAsn(),
+		AsnSource:        m.GetAsnSource(),
+		WhoisAsn:         m.GetWhoisAsn(),
+		ASNCategory:      m.GetAsnCategory(),
+		IXPCount:         int(m.GetIxpCount()),
+		AsnDiscrepancy:   m.GetAsnDiscrepancy(),
+		Holder:           m.GetHolder(),
+		HolderSource:     m.GetHolderSource(),
+		HolderName:       m.GetHolderName(),
+		HolderCountry:    m.GetHolderCountry(),
+		Country:          m.GetCountry(),
+		CountryName:      m.GetCountryName(),
+		City:             m.GetCity(),
+		Latitude:         m.GetLatitude(),
+		Longitude:        m.GetLongitude(),
+		GeoSource:        m.GetGeoSource(),
+		EnrichedAt:       m.GetEnrichedAt(),
+		QueryTime:        m.GetQueryTime(),
+		NetworkType:      m.GetNetworkType(),
+		Ptr:              m.GetPtr(),
+		ProviderHint:     m.GetProviderHint(),
+		CloudProvider:    m.GetCloudProvider(),
+		CloudRegion:      m.GetCloudRegion(),
+		HistoricalHolder: m.GetHistoricalHolder(),
+		HistoricalOrg:    m.GetHistoricalOrg(),
+		HistoricalObject: m.GetHistoricalObject(),
+		CertIssuer:       m.GetCertIssuer(),
+		CertSubject:      m.GetCertSubject(),
+		CertNames:        m.GetCertNames(),
+		CertNotAfter:     m.GetCertNotAfter(),
+		Tags:             m.GetTags(),
+		Errors:           m.GetErrors(),
+	}
+	for _, contact := range m.GetAbuseContacts() {
+		info.AbuseContacts = append(info.AbuseContacts, types.AbuseContact{
+			Email:  contact.GetEmail(),
+			Kind:   contact.GetKind(),
+			Source: contact.GetSource(),
+		})
+	}
+	for _, facility := range m.GetFacilities() {
+		info.Facilities = append(info.Facilities, types.Facility{
+			Name:    facility.GetName(),
+			City:    facility.GetCity(),
+			Country: facility.GetCountry(),
+		})
+	}
+	return info
+}
@@ -0,0 +1,122 @@
+package enrichpb
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// filled returns an EnrichInfo with every field set, so a field missing from the conversion
+// shows up as a difference
+func filled(t *testing.T) types.EnrichInfo {
+	var info types.EnrichInfo
+	v := reflect.ValueOf(&info).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		name := v.Type().Field(i).Name
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(name + "-value")
+		case reflect.Int:
+			f.SetInt(int64(i + 1))
+		case reflect.Float64:
+			f.SetFloat(float64(i) + 0.5)
+		case reflect.Bool:
+			f.SetBool(true)
+		case reflect.Slice:
+			elem := f.Type().Elem()
+			values := reflect.MakeSlice(f.Type(), 2, 2)
+			for j := 0; j < 2; j++ {
+				switch elem.Kind() {
+				case reflect.String:
+					values.Index(j).SetString(fmt.Sprintf("%s-%d", name, j))
+				case reflect.Struct:
+					for k := 0; k < elem.NumField(); k++ {
+						values.Index(j).Field(k).SetString(fmt.Sprintf("%s-%d-%s", name, j, elem.Field(k).Name))
+					}
+				default:
+					t.Fatalf("no test value for %s", name)
+				}
+			}
+			f.Set(values)
+		case reflect.Map:
+			f.Set(reflect.ValueOf(map[string]string{"Abuse": "not found", "Holder": "timeout"}))
+		default:
+			t.Fatalf("no test value for %s", name)
+		}
+	}
+	return info
+}
+
+func TestUnmapped(t *testing.T) {
+	if unmapped := Unmapped(); len(unmapped) > 0 {
+		t.Errorf("fields of types.EnrichInfo missing from enrichinfo.proto: %v", unmapped)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	info := filled(t)
+
+	data, err := proto.Marshal(ToProto(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m EnrichInfo
+	if err := proto.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.GetSchemaVersion() != types.SchemaVersion {
+		t.Errorf("schema_version = %d, want %d", m.GetSchemaVersion(), types.SchemaVersion)
+	}
+	if got := FromProto(&m); !reflect.DeepEqual(got, info) {
+		t.Errorf("round trip changed the enrichment\ngot  %+v\nwant %+v", got, info)
+	}
+}
+
+func TestRoundTripEmpty(t *testing.T) {
+	data, err := proto.Marshal(ToProto(types.EnrichInfo{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m EnrichInfo
+	if err := proto.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if got := FromProto(&m); !reflect.DeepEqual(got, types.EnrichInfo{}) {
+		t.Errorf("round trip of an empty enrichment gave %+v", got)
+	}
+}
+
+func TestDelimited(t *testing.T) {
+	infos := []types.EnrichInfo{filled(t), {Ip: "192.0.2.1", AbuseSource: types.AbuseSourceRipeSTAT}}
+
+	var buf bytes.Buffer
+	for _, info := range infos {
+		if _, err := protodelim.MarshalTo(&buf, ToProto(info)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, want := range infos {
+		var m EnrichInfo
+		if err := protodelim.UnmarshalFrom(&buf, &m); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if got := FromProto(&m); !reflect.DeepEqual(got, want) {
+			t.Errorf("message %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package geofeed
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultMaxFeedSize bounds a downloaded geofeed, unless Feed.MaxFeedSize sets another size.
+const DefaultMaxFeedSize = 64 << 20
+
+// Entry is a single row of an RFC 8805 geofeed.
+type Entry struct {
+	Prefix     netip.Prefix
+	Country    string
+	Region     string
+	City       string
+	PostalCode string
+}
+
+// LoadStats reports how many rows of a feed were loaded and how many were skipped as malformed.
+type LoadStats struct {
+	Loaded  int
+	Skipped int
+}
+
+// Feed holds the entries of one or more geofeeds, indexed for longest-prefix matching.
+type Feed struct {
+	// entries by prefix length, separately for IPv4 and IPv6
+	v4 [33]map[netip.Prefix]Entry
+	v6 [129]map[netip.Prefix]Entry
+
+	// MaxFeedSize bounds a downloaded geofeed in bytes, zero means DefaultMaxFeedSize
+	MaxFeedSize int64
+
+	httpClient *http.Client
+}
+
+func NewFeed() *Feed {
+	return &Feed{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Load reads a geofeed from source, which can either be an http(s) URL or a local file. A
+// downloaded geofeed larger than MaxFeedSize is an error, nothing of it is loaded.
+func (f *Feed) Load(source string) (LoadStats, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := f.httpClient.Get(source)
+		if err != nil {
+			return LoadStats{}, fmt.Errorf("geofeed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return LoadStats{}, fmt.Errorf("geofeed: fetching %q returned status %d", source, resp.StatusCode)
+		}
+
+		limit := f.MaxFeedSize
+		if limit <= 0 {
+			limit = DefaultMaxFeedSize
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			return LoadStats{}, fmt.Errorf("geofeed: %v", err)
+		}
+		if int64(len(data)) > limit {
+			return LoadStats{}, fmt.Errorf("geofeed: %q is larger than %d bytes", source, limit)
+		}
+
+		return f.Read(bytes.NewReader(data))
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return LoadStats{}, fmt.Errorf("geofeed: %v", err)
+	}
+	defer file.Close()
+
+	return f.Read(file)
+}
+
+// Read parses a geofeed in the RFC 8805 CSV format: prefix, country, region, city and postal code.
+// Comments and empty lines are ignored, malformed rows are skipped and counted.
+func (f *Feed) Read(r io.Reader) (LoadStats, error) {
+	stats := LoadStats{}
+
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				stats.Skipped++
+				continue
+			}
+			return stats, fmt.Errorf("geofeed: %v", err)
+		}
+
+		entry, ok := parseRecord(record)
+		if !ok {
+			stats.Skipped++
+			continue
+		}
+
+		f.add(entry)
+		stats.Loaded++
+	}
+
+	return stats, nil
+}
+
+// Lookup returns the entry with the longest prefix covering ipAddr.
+func (f *Feed) Lookup(ipAddr string) (Entry, bool) {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return Entry{}, false
+	}
+	addr = addr.Unmap().WithZone("")
+
+	if addr.Is4() {
+		return lookup(f.v4[:], addr)
+	}
+	return lookup(f.v6[:], addr)
+}
+
+func lookup(byLength []map[netip.Prefix]Entry, addr netip.Addr) (Entry, bool) {
+	for bits := len(byLength) - 1; bits >= 0; bits-- {
+		if byLength[bits] == nil {
+			continue
+		}
+
+		prefix, err := addr.Prefix(bits)
+		if err != nil {
+			continue
+		}
+
+		if entry, ok := byLength[bits][prefix]; ok {
+			return entry, true
+		}
+	}
+
+	return Entry{}, false
+}
+
+func (f *Feed) add(entry Entry) {
+	byLength := f.v6[:]
+	if entry.Prefix.Addr().Is4() {
+		byLength = f.v4[:]
+	}
+
+	bits := entry.Prefix.Bits()
+	if byLength[bits] == nil {
+		byLength[bits] = make(map[netip.Prefix]Entry)
+	}
+	byLength[bits][entry.Prefix] = entry
+}
+
+func parseRecord(record []string) (Entry, bool) {
+	if len(record) < 2 {
+		return Entry{}, false
+	}
+
+	for i := range record {
+		record[i] = strings.TrimSpace(record[i])
+	}
+
+	prefix, err := netip.ParsePrefix(record[0])
+	if err != nil {
+		return Entry{}, false
+	}
+	if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	}
+
+	// RFC 8805 allows an empty country, but any value given must be an alpha-2 code
+	country := strings.ToUpper(record[1])
+	if country != "" && (len(country) != 2 || !isAlpha(country)) {
+		return Entry{}, false
+	}
+
+	entry := Entry{
+		Prefix:  prefix.Masked(),
+		Country: country,
+	}
+
+	if len(record) > 2 {
+		entry.Region = record[2]
+	}
+	if len(record) > 3 {
+		entry.City = record[3]
+	}
+	if len(record) > 4 {
+		entry.PostalCode = record[4]
+	}
+
+	return entry, true
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
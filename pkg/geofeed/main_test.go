@@ -0,0 +1,169 @@
+package geofeed
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestFeed returns a feed loaded from testdata/geofeed.csv.
+func newTestFeed(t *testing.T) *Feed {
+	f := NewFeed()
+	stats, err := f.Load(filepath.Join("testdata", "geofeed.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (LoadStats{Loaded: 9, Skipped: 6}) {
+		t.Errorf("Load = %+v, want 9 loaded and 6 skipped", stats)
+	}
+	return f
+}
+
+func TestLookup(t *testing.T) {
+	f := newTestFeed(t)
+
+	entry := func(prefix, country, region, city, postalCode string) Entry {
+		return Entry{Prefix: netip.MustParsePrefix(prefix), Country: country, Region: region, City: city, PostalCode: postalCode}
+	}
+	tests := []struct {
+		ipAddr string
+		want   Entry
+		found  bool
+	}{
+		// the most specific of the overlapping prefixes wins
+		{"193.0.6.139", entry("193.0.6.0/24", "DE", "DE-BE", "Berlin", "10115"), true},
+		{"193.0.7.1", entry("193.0.0.0/21", "NL", "NL-NH", "Amsterdam", "1098 XG"), true},
+		{"193.0.8.1", entry("193.0.0.0/16", "NL", "NL-NH", "Amsterdam", ""), true},
+		{"2001:67c:2e8:22::c100:68b", entry("2001:67c:2e8:22::/64", "GB", "GB-ENG", "London", ""), true},
+		{"[2001:67c:2e8:23::1]", entry("2001:67c:2e8::/48", "NL", "", "", ""), true},
+		{"2001:67c:2e8:22::1%eth0", entry("2001:67c:2e8:22::/64", "GB", "GB-ENG", "London", ""), true},
+		// IPv4-mapped prefixes and addresses are IPv4
+		{"192.0.2.1", entry("192.0.2.0/24", "US", "US-CA", "", ""), true},
+		{"::ffff:193.0.6.139", entry("193.0.6.0/24", "DE", "DE-BE", "Berlin", "10115"), true},
+		// an empty country is allowed
+		{"10.1.2.3", entry("10.0.0.0/8", "", "", "", ""), true},
+		// trimmed, upper cased and masked
+		{"198.51.100.200", entry("198.51.100.0/24", "NL", "NL-ZH", "Rotterdam", ""), true},
+		// rows after a malformed one are loaded
+		{"203.0.113.1", entry("203.0.113.0/24", "AU", "AU-NSW", "Sydney", "2000"), true},
+		// misses, including the prefixes of malformed rows
+		{"193.1.0.1", Entry{}, false},
+		{"194.0.0.1", Entry{}, false},
+		{"197.0.0.1", Entry{}, false},
+		{"2001:67c:2e9::1", Entry{}, false},
+		{"not an IP", Entry{}, false},
+	}
+
+	for _, tt := range tests {
+		got, found := f.Lookup(tt.ipAddr)
+		if found != tt.found || got != tt.want {
+			t.Errorf("Lookup(%s) = %+v, %v, want %+v, %v", tt.ipAddr, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		record []string
+		want   Entry
+		ok     bool
+	}{
+		{[]string{"193.0.0.0/21", "NL"}, Entry{Prefix: netip.MustParsePrefix("193.0.0.0/21"), Country: "NL"}, true},
+		{[]string{"193.0.0.0/21", "nl", "NL-NH", "Amsterdam", "1098 XG", "extra"}, Entry{Prefix: netip.MustParsePrefix("193.0.0.0/21"), Country: "NL", Region: "NL-NH", City: "Amsterdam", PostalCode: "1098 XG"}, true},
+		{[]string{" 2001:db8::1/32 ", ""}, Entry{Prefix: netip.MustParsePrefix("2001:db8::/32")}, true},
+		{[]string{"::ffff:0:0/96", "NL"}, Entry{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Country: "NL"}, true},
+		{[]string{"193.0.0.0/21"}, Entry{}, false},
+		{[]string{"193.0.0.1", "NL"}, Entry{}, false},
+		{[]string{"193.0.0.0/33", "NL"}, Entry{}, false},
+		{[]string{"193.0.0.0/21", "N"}, Entry{}, false},
+		{[]string{"193.0.0.0/21", "NLD"}, Entry{}, false},
+		{[]string{"193.0.0.0/21", "N1"}, Entry{}, false},
+		{[]string{"193.0.0.0/21", "ÑL"}, Entry{}, false},
+	}
+
+	for _, tt := range tests {
+		record := append([]string(nil), tt.record...)
+		got, ok := parseRecord(record)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseRecord(%q) = %+v, %v, want %+v, %v", tt.record, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	f := NewFeed()
+	stats, err := f.Read(strings.NewReader("193.0.0.0/16,NL\n193.0.0.0/21,NL\n"))
+	if err != nil || stats != (LoadStats{Loaded: 2}) {
+		t.Fatalf("Read = %+v, %v", stats, err)
+	}
+	// a later feed adds to the entries and replaces those of the same prefix
+	stats, err = f.Read(strings.NewReader("193.0.0.0/21,BE\nbad,NL\n193.0.6.0/24,DE\n"))
+	if err != nil || stats != (LoadStats{Loaded: 2, Skipped: 1}) {
+		t.Fatalf("Read = %+v, %v", stats, err)
+	}
+
+	for ipAddr, want := range map[string]string{"193.0.6.139": "DE", "193.0.7.1": "BE", "193.0.8.1": "NL"} {
+		if got, _ := f.Lookup(ipAddr); got.Country != want {
+			t.Errorf("Lookup(%s) country %q, want %q", ipAddr, got.Country, want)
+		}
+	}
+}
+
+func TestLoadHTTP(t *testing.T) {
+	feed, err := os.ReadFile(filepath.Join("testdata", "geofeed.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/geofeed.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(feed)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		maxFeedSize int64
+		want        LoadStats
+		wantErr     bool
+	}{
+		{"default size", "/geofeed.csv", 0, LoadStats{Loaded: 9, Skipped: 6}, false},
+		{"at the maximum size", "/geofeed.csv", int64(len(feed)), LoadStats{Loaded: 9, Skipped: 6}, false},
+		{"over the maximum size", "/geofeed.csv", int64(len(feed)) - 1, LoadStats{}, true},
+		{"not found", "/missing.csv", 0, LoadStats{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFeed()
+			f.MaxFeedSize = tt.maxFeedSize
+			stats, err := f.Load(server.URL + tt.path)
+			if (err != nil) != tt.wantErr || stats != tt.want {
+				t.Fatalf("Load = %+v, %v, want %+v, error %v", stats, err, tt.want, tt.wantErr)
+			}
+			// nothing of a failed feed is loaded
+			if _, found := f.Lookup("193.0.6.139"); found == tt.wantErr {
+				t.Errorf("Lookup found %v after loading with error %v", found, err)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := NewFeed().Load(filepath.Join("testdata", "missing.csv")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
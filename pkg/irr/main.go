@@ -0,0 +1,157 @@
+package irr
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/likexian/whois"
+)
+
+const WhoisServer = "whois.radb.net"
+
+// Route is a route or route6 object from an Internet Routing Registry.
+type Route struct {
+	Route  string
+	Origin string
+	Descr  string
+	MntBy  string
+	Source string
+}
+
+type Client struct {
+	Server string
+	whois  *whois.Client
+}
+
+func NewIRRClient() *Client {
+	return &Client{
+		Server: WhoisServer,
+		whois:  whois.NewClient(),
+	}
+}
+
+// LookupRoute queries the IRR whois server for the route objects covering ipAddr and returns the
+// most specific one.
+func (c *Client) LookupRoute(ctx context.Context, ipAddr string) (Route, error) {
+	type whoisResult struct {
+		info string
+		err  error
+	}
+
+	resultCh := make(chan whoisResult, 1)
+	go func() {
+		info, err := c.whois.Whois("-T route,route6 "+ipAddr, c.Server)
+		resultCh <- whoisResult{info, err}
+	}()
+
+	var info string
+	select {
+	case <-ctx.Done():
+		return Route{}, ctx.Err()
+	case result := <-resultCh:
+		if result.err != nil {
+			return Route{}, fmt.Errorf("irr: %v", result.err)
+		}
+		info = result.info
+	}
+
+	route, found := MostSpecific(ParseRoutes(info))
+	if !found {
+		return Route{}, fmt.Errorf("irr: no route object for %s", ipAddr)
+	}
+	return route, nil
+}
+
+// ParseRoutes parses the route and route6 objects of an RPSL whois response, like:
+//
+//	route:      193.0.0.0/21
+//	descr:      RIPE-NCC
+//	origin:     AS3333
+//	mnt-by:     RIPE-NCC-MNT
+//	source:     RIPE
+//
+// Objects are separated by blank lines, only the first value of an attribute is kept. The origin
+// is returned without the AS prefix.
+func ParseRoutes(data string) []Route {
+	var routes []Route
+	var current *Route
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) == "" {
+			current = nil
+			continue
+		}
+		if strings.HasPrefix(line, "%") || strings.HasPrefix(line, "#") ||
+			strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "+") {
+			// comments and continuation lines
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "route" || key == "route6" {
+			routes = append(routes, Route{Route: value})
+			current = &routes[len(routes)-1]
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		switch key {
+		case "origin":
+			setOnce(&current.Origin, strings.TrimPrefix(strings.ToUpper(value), "AS"))
+		case "descr":
+			setOnce(&current.Descr, value)
+		case "mnt-by":
+			setOnce(&current.MntBy, value)
+		case "source":
+			setOnce(&current.Source, value)
+		}
+	}
+
+	return routes
+}
+
+func setOnce(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
+
+// MostSpecific returns the route with the longest prefix.
+func MostSpecific(routes []Route) (Route, bool) {
+	best := -1
+	bestBits := -1
+	for i, route := range routes {
+		prefix, err := netip.ParsePrefix(route.Route)
+		if err != nil {
+			continue
+		}
+		if prefix.Bits() > bestBits {
+			best, bestBits = i, prefix.Bits()
+		}
+	}
+
+	if best < 0 {
+		return Route{}, false
+	}
+	return routes[best], true
+}
@@ -0,0 +1,65 @@
+package irr
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+// radbResponse is a response of whois.radb.net to "-T route,route6 193.0.6.139"
+const radbResponse = `% Information related to '193.0.0.0/21AS3333'
+
+route:          193.0.0.0/21
+descr:          RIPE-NCC
+                Amsterdam
+origin:         AS3333
+mnt-by:         RIPE-NCC-MNT
+mnt-by:         RIPE-NCC-HM-MNT
+created:        2008-09-10T14:27:31Z
+source:         RIPE
+remarks:        ****************************
++               * a continuation
+descr:          a second description
+
+route:          193.0.0.0/16
+origin:         as3333
+descr:          RIPE NCC covering route
+source:         RADB
+
+route6:         2001:67c:2e8::/48
+descr:          RIPE-NCC
+origin:         AS3333
+mnt-by:         RIPE-NCC-MNT
+source:         RIPE
+`
+
+func TestParseRoutes(t *testing.T) {
+	want := []Route{
+		{Route: "193.0.0.0/21", Origin: "3333", Descr: "RIPE-NCC", MntBy: "RIPE-NCC-MNT", Source: "RIPE"},
+		{Route: "193.0.0.0/16", Origin: "3333", Descr: "RIPE NCC covering route", Source: "RADB"},
+		{Route: "2001:67c:2e8::/48", Origin: "3333", Descr: "RIPE-NCC", MntBy: "RIPE-NCC-MNT", Source: "RIPE"},
+	}
+	if got := ParseRoutes(radbResponse); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRoutes = %+v, want %+v", got, want)
+	}
+
+	// attributes outside a route object are ignored
+	if got := ParseRoutes("% No entries found\n\norigin: AS64496\n"); len(got) != 0 {
+		t.Errorf("ParseRoutes without routes = %+v", got)
+	}
+}
+
+func TestMostSpecific(t *testing.T) {
+	route, found := MostSpecific([]Route{{Route: "193.0.0.0/16"}, {Route: "not a prefix"}, {Route: "193.0.0.0/21"}})
+	if !found || route.Route != "193.0.0.0/21" {
+		t.Errorf("MostSpecific = %+v, %v, want the /21", route, found)
+	}
+	if _, found := MostSpecific([]Route{{Route: "not a prefix"}}); found {
+		t.Error("MostSpecific found a route without a valid prefix")
+	}
+}
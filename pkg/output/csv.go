@@ -0,0 +1,57 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+var csvHeader = []string{
+	"ip", "template-id", "name", "severity", "host", "matched-at", "timestamp",
+	"abuse", "abuse-source", "prefix", "asn", "holder", "holder-name", "holder-country", "country", "country-name", "city", "errors",
+}
+
+// RenderCSV writes the merge results as CSV with a header row, one row per finding. Lists are
+// joined with a semicolon, the errors are written as field: message.
+func RenderCSV(w io.Writer, results []types.MergeResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		row := []string{
+			result.EnrichInfo.Ip, result.TemplateId, result.Info.Name, result.Info.Severity, result.Host, result.MatchedAt, result.Timestamp,
+			result.Abuse, result.AbuseSource, result.Prefix, result.Asn, result.Holder, result.HolderName, result.HolderCountry, result.Country, result.CountryName, result.City,
+			joinErrors(result.Errors),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func joinErrors(errs map[string]string) string {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for i, field := range fields {
+		fields[i] = field + ": " + errs[field]
+	}
+	return strings.Join(fields, "; ")
+}
@@ -0,0 +1,70 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// abuseCases are enrichments with 0, 1 and N abuse contacts, in the current form and in the
+// ";" joined form of the records written before AbuseAddresses
+var abuseCases = []struct {
+	name string
+	info types.EnrichInfo
+	// joined is the abuse column of the CSV outputs
+	joined string
+	emails []string
+}{
+	{"no contacts", types.EnrichInfo{}, "", nil},
+	{"one contact", types.EnrichInfo{Abuse: "abuse@ripe.net", AbuseAddresses: []string{"abuse@ripe.net"}}, "abuse@ripe.net", []string{"abuse@ripe.net"}},
+	{
+		"several contacts",
+		types.EnrichInfo{Abuse: "noc@ripe.net;abuse@ripe.net", AbuseAddresses: []string{"noc@ripe.net", "abuse@ripe.net"}},
+		"noc@ripe.net;abuse@ripe.net",
+		[]string{"abuse@ripe.net", "noc@ripe.net"},
+	},
+	{"legacy one contact", types.EnrichInfo{Abuse: "abuse@ripe.net"}, "abuse@ripe.net", []string{"abuse@ripe.net"}},
+	{"legacy several contacts", types.EnrichInfo{Abuse: "noc@ripe.net;abuse@ripe.net"}, "noc@ripe.net;abuse@ripe.net", []string{"abuse@ripe.net", "noc@ripe.net"}},
+}
+
+func TestRenderCSVAbuse(t *testing.T) {
+	for _, tt := range abuseCases {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			info.Ip, info.Holder = "193.0.6.139", "RIPE-NCC"
+			result := types.MergeResult{EnrichInfo: info}
+			result.TemplateId = "test"
+
+			var buf bytes.Buffer
+			if err := RenderCSV(&buf, []types.MergeResult{result}); err != nil {
+				t.Fatal(err)
+			}
+			rows, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 2 {
+				t.Fatalf("got %d rows, want a header and one finding", len(rows))
+			}
+
+			column := map[string]string{}
+			for i, name := range rows[0] {
+				column[name] = rows[1][i]
+			}
+			if column["abuse"] != tt.joined {
+				t.Errorf("abuse = %q, want %q", column["abuse"], tt.joined)
+			}
+			if column["ip"] != "193.0.6.139" || column["holder"] != "RIPE-NCC" {
+				t.Errorf("row = %q, columns out of place", rows[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+const (
+	DefaultElasticsearchIndex     = "nuclei-enrichment"
+	DefaultElasticsearchBatchSize = 500
+)
+
+// elasticsearchMapping maps the EnrichInfo fields, identifiers are keywords so they can be used in
+// aggregations, invalid IPs are kept but not indexed as ip.
+const elasticsearchMapping = `{
+  "mappings": {
+    "properties": {
+      "schema_version": {"type": "integer"},
+      "ip": {"type": "ip", "ignore_malformed": true},
+      "ip_raw": {"type": "keyword"},
+      "ip_mapping": {"type": "keyword"},
+      "abuse_source": {"type": "keyword"},
+      "abuse": {"type": "keyword"},
+      "abuse_addresses": {"type": "keyword"},
+      "abuse_contacts": {"properties": {"email": {"type": "keyword"}, "kind": {"type": "keyword"}, "source": {"type": "keyword"}}},
+      "prefix": {"type": "keyword"},
+      "covering_prefix": {"type": "keyword"},
+      "covering_asn": {"type": "keyword"},
+      "asn": {"type": "keyword"},
+      "asn_source": {"type": "keyword"},
+      "whois_asn": {"type": "keyword"},
+      "asn_category": {"type": "keyword"},
+      "ixp_count": {"type": "integer"},
+      "facilities": {"properties": {"name": {"type": "keyword"}, "city": {"type": "keyword"}, "country": {"type": "keyword"}}},
+      "asn_discrepancy": {"type": "boolean"},
+      "holder": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+      "holder_name": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+      "holder_country": {"type": "keyword"},
+      "holder_source": {"type": "keyword"},
+      "country": {"type": "keyword"},
+      "country_name": {"type": "keyword"},
+      "city": {"type": "keyword"},
+      "latitude": {"type": "float"},
+      "longitude": {"type": "float"},
+      "geo_source": {"type": "keyword"},
+      "enriched_at": {"type": "date"},
+      "query_time": {"type": "date"},
+      "network_type": {"type": "keyword"},
+      "ptr": {"type": "keyword"},
+      "provider_hint": {"type": "keyword"},
+      "cloud_provider": {"type": "keyword"},
+      "abuse_handle": {"type": "keyword"},
+      "registration_date": {"type": "date", "format": "yyyy-MM-dd"},
+      "allocation_status": {"type": "keyword"},
+      "rir": {"type": "keyword"},
+      "abuse_to": {"type": "keyword"},
+      "national_cert": {"type": "keyword"},
+      "org_handle": {"type": "keyword"},
+      "cloud_region": {"type": "keyword"},
+      "historical_holder": {"type": "keyword"},
+      "historical_org": {"type": "keyword"},
+      "historical_object": {"type": "keyword"},
+      "cert_issuer": {"type": "keyword"},
+      "cert_subject": {"type": "keyword"},
+      "cert_names": {"type": "keyword"},
+      "cert_not_after": {"type": "date"},
+      "tags": {"type": "keyword"},
+      "errors": {"type": "object", "dynamic": true}
+    }
+  }
+}`
+
+// elasticsearchMappingBody returns elasticsearchMapping, with the field names of schema version 1
+// when opts.LegacyFieldNames is set.
+func elasticsearchMappingBody(opts types.MarshalOptions) string {
+	if !opts.LegacyFieldNames {
+		return elasticsearchMapping
+	}
+
+	var mapping struct {
+		Mappings struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(elasticsearchMapping), &mapping); err != nil {
+		panic(fmt.Sprintf("elasticsearch: mapping: %v", err))
+	}
+	mapping.Mappings.Properties = legacyProperties(mapping.Mappings.Properties)
+
+	data, _ := json.Marshal(mapping)
+	return string(data)
+}
+
+func legacyProperties(properties map[string]interface{}) map[string]interface{} {
+	renamed := make(map[string]interface{}, len(properties))
+	for name, value := range properties {
+		if name == "schema_version" {
+			continue
+		}
+		if legacy, found := types.LegacyFieldName(name); found {
+			name = legacy
+		}
+		if field, ok := value.(map[string]interface{}); ok {
+			if nested, ok := field["properties"].(map[string]interface{}); ok {
+				field["properties"] = legacyProperties(nested)
+			}
+		}
+		renamed[name] = value
+	}
+	return renamed
+}
+
+// IndexStats describes the outcome of indexing enrichment results.
+type IndexStats struct {
+	Indexed int
+	Failed  int
+}
+
+// ElasticsearchSink bulk-indexes enrichment results into an Elasticsearch or OpenSearch index.
+// Documents are keyed by IP, so indexing the same results twice updates them.
+type ElasticsearchSink struct {
+	URL        string
+	Index      string
+	BatchSize  int
+	MaxRetries int
+	// APIKey is sent as "Authorization: ApiKey ..." when set
+	APIKey string
+	// MarshalOptions is the layout the records are indexed in, the mapping follows it
+	MarshalOptions types.MarshalOptions
+	HTTPClient     *http.Client
+	Logger         logrus.FieldLogger
+}
+
+func NewElasticsearchSink(url, index string) *ElasticsearchSink {
+	if index == "" {
+		index = DefaultElasticsearchIndex
+	}
+
+	return &ElasticsearchSink{
+		URL:        strings.TrimRight(url, "/"),
+		Index:      index,
+		BatchSize:  DefaultElasticsearchBatchSize,
+		MaxRetries: 3,
+		HTTPClient: http.DefaultClient,
+		Logger:     logrus.StandardLogger(),
+	}
+}
+
+// EnsureIndex creates the index with the enrichment mapping, unless it already exists.
+func (s *ElasticsearchSink) EnsureIndex(ctx context.Context) error {
+	resp, err := s.do(ctx, http.MethodHead, "/"+s.Index, "", nil)
+	if err != nil {
+		return fmt.Errorf("elasticsearch: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("elasticsearch: checking index %s: unexpected status %s", s.Index, resp.Status)
+	}
+
+	resp, err = s.do(ctx, http.MethodPut, "/"+s.Index, "application/json", strings.NewReader(elasticsearchMappingBody(s.MarshalOptions)))
+	if err != nil {
+		return fmt.Errorf("elasticsearch: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("elasticsearch: creating index %s: %s: %s", s.Index, resp.Status, body)
+	}
+
+	return nil
+}
+
+// Write indexes records in batches. Documents rejected by the index are logged and counted as
+// failed rather than aborting the remaining batches, an error is only returned when a whole batch
+// could not be delivered.
+func (s *ElasticsearchSink) Write(ctx context.Context, records []types.EnrichInfo) (IndexStats, error) {
+	var stats IndexStats
+
+	batchSize := s.BatchSize
+	if batchSize < 1 {
+		batchSize = DefaultElasticsearchBatchSize
+	}
+
+	for start := 0; start < len(records); start += batchSize {
+		end := start + batchSize
+		if end > len(records) {
+			end = len(records)
+		}
+
+		indexed, failed, err := s.writeBatch(ctx, records[start:end])
+		stats.Indexed += indexed
+		stats.Failed += failed
+		if err != nil {
+			stats.Failed += len(records) - end
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Id     string `json:"_id"`
+		Status int    `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (s *ElasticsearchSink) writeBatch(ctx context.Context, batch []types.EnrichInfo) (int, int, error) {
+	indexed, failed := 0, 0
+	backoff := 500 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		body, err := bulkBody(s.Index, batch, s.MarshalOptions)
+		if err != nil {
+			return indexed, failed + len(batch), fmt.Errorf("elasticsearch: %v", err)
+		}
+
+		result, retryable, err := s.sendBulk(ctx, body)
+		if err != nil {
+			if !retryable || attempt >= s.MaxRetries {
+				return indexed, failed + len(batch), fmt.Errorf("elasticsearch: %v", err)
+			}
+		} else {
+			// only documents rejected because the cluster is overloaded are retried
+			var retry []types.EnrichInfo
+			for i, item := range result.Items {
+				for _, status := range item {
+					switch {
+					case status.Status >= 200 && status.Status < 300:
+						indexed++
+					case status.Status == http.StatusTooManyRequests && attempt < s.MaxRetries && i < len(batch):
+						retry = append(retry, batch[i])
+					default:
+						failed++
+						s.Logger.WithField("ip", status.Id).Warnf("elasticsearch: document rejected: %s: %s", status.Error.Type, status.Error.Reason)
+					}
+				}
+			}
+			if len(retry) == 0 {
+				return indexed, failed, nil
+			}
+			batch = retry
+		}
+
+		s.Logger.Debugf("elasticsearch: retrying bulk request of %d documents in %v", len(batch), backoff)
+		select {
+		case <-ctx.Done():
+			return indexed, failed + len(batch), ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *ElasticsearchSink) sendBulk(ctx context.Context, body []byte) (bulkResponse, bool, error) {
+	var result bulkResponse
+
+	resp, err := s.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", bytes.NewReader(body))
+	if err != nil {
+		return result, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return result, true, fmt.Errorf("bulk request: unexpected status %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return result, false, fmt.Errorf("bulk request: %s: %s", resp.Status, msg)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, false, fmt.Errorf("bulk response: %v", err)
+	}
+
+	return result, false, nil
+}
+
+func (s *ElasticsearchSink) do(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.URL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+s.APIKey)
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(req)
+}
+
+// bulkBody builds the newline delimited bulk request indexing records into index, in the layout of opts.
+func bulkBody(index string, records []types.EnrichInfo, opts types.MarshalOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, record := range records {
+		action := map[string]map[string]string{
+			"index": {"_index": index, "_id": record.Ip},
+		}
+		if err := enc.Encode(action); err != nil {
+			return nil, err
+		}
+		if err := enc.Encode(opts.EnrichInfo(record)); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,169 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// bulkServer is a fake _bulk endpoint answering the requests with responses in turn. A response
+// is a status code, or the statuses of the documents in the request when it is 200.
+type bulkServer struct {
+	mu        sync.Mutex
+	responses []bulkReply
+	// ids holds the document IDs of every bulk request
+	ids [][]string
+}
+
+type bulkReply struct {
+	status int
+	items  []int
+}
+
+func (b *bulkServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" || r.Header.Get("Authorization") != "ApiKey secret" {
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+		return
+	}
+
+	// the body alternates action and document lines
+	var ids []string
+	scanner := bufio.NewScanner(r.Body)
+	for line := 0; scanner.Scan(); line++ {
+		if line%2 == 0 {
+			var action map[string]map[string]string
+			if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || action["index"]["_index"] != "enrichment" {
+				http.Error(w, fmt.Sprintf("bad action line %q", scanner.Text()), http.StatusBadRequest)
+				return
+			}
+			ids = append(ids, action["index"]["_id"])
+			continue
+		}
+		var doc map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil || doc["ip"] != ids[len(ids)-1] {
+			http.Error(w, fmt.Sprintf("bad document line %q", scanner.Text()), http.StatusBadRequest)
+			return
+		}
+	}
+	b.ids = append(b.ids, ids)
+
+	if len(b.responses) == 0 {
+		http.Error(w, "no more responses", http.StatusInternalServerError)
+		return
+	}
+	reply := b.responses[0]
+	b.responses = b.responses[1:]
+	if reply.status != http.StatusOK {
+		http.Error(w, `{"error": "unavailable"}`, reply.status)
+		return
+	}
+
+	var items []string
+	for i, status := range reply.items {
+		items = append(items, fmt.Sprintf(`{"index": {"_id": %q, "status": %d, "error": {"type": "mapper_parsing_exception", "reason": "failed"}}}`, ids[i], status))
+	}
+	_, _ = io.WriteString(w, `{"errors": true, "items": [`+strings.Join(items, ",")+`]}`)
+}
+
+func newBulkSink(t *testing.T, responses ...bulkReply) (*ElasticsearchSink, *bulkServer) {
+	bulk := &bulkServer{responses: responses}
+	server := httptest.NewServer(bulk)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	sink := NewElasticsearchSink(server.URL+"/", "enrichment")
+	sink.APIKey = "secret"
+	sink.Logger = logger
+	return sink, bulk
+}
+
+var bulkRecords = []types.EnrichInfo{
+	{Ip: "193.0.6.139", Asn: "3333"},
+	{Ip: "193.0.6.140", Asn: "3333"},
+	{Ip: "193.0.6.141", Asn: "3333"},
+}
+
+func TestElasticsearchWriteRetries(t *testing.T) {
+	// the first request fails as a whole, then one document is rejected as the cluster is
+	// overloaded and another one for good
+	sink, bulk := newBulkSink(t,
+		bulkReply{status: http.StatusServiceUnavailable},
+		bulkReply{status: http.StatusOK, items: []int{201, 429, 400}},
+		bulkReply{status: http.StatusOK, items: []int{200}},
+	)
+
+	stats, err := sink.Write(context.Background(), bulkRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (IndexStats{Indexed: 2, Failed: 1}) {
+		t.Errorf("stats = %+v, want 2 indexed and 1 failed", stats)
+	}
+
+	want := [][]string{
+		{"193.0.6.139", "193.0.6.140", "193.0.6.141"},
+		{"193.0.6.139", "193.0.6.140", "193.0.6.141"},
+		{"193.0.6.140"},
+	}
+	if fmt.Sprint(bulk.ids) != fmt.Sprint(want) {
+		t.Errorf("bulk requests indexed %v, want %v", bulk.ids, want)
+	}
+}
+
+func TestElasticsearchWriteBatches(t *testing.T) {
+	sink, bulk := newBulkSink(t,
+		bulkReply{status: http.StatusOK, items: []int{201, 201}},
+		bulkReply{status: http.StatusOK, items: []int{201}},
+	)
+	sink.BatchSize = 2
+
+	stats, err := sink.Write(context.Background(), bulkRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (IndexStats{Indexed: 3}) {
+		t.Errorf("stats = %+v, want 3 indexed", stats)
+	}
+	if len(bulk.ids) != 2 || len(bulk.ids[0]) != 2 || len(bulk.ids[1]) != 1 {
+		t.Errorf("bulk requests indexed %v, want batches of 2 and 1", bulk.ids)
+	}
+}
+
+func TestElasticsearchWriteError(t *testing.T) {
+	// a rejected request isn't retried and fails the remaining batches too
+	sink, bulk := newBulkSink(t, bulkReply{status: http.StatusBadRequest})
+	sink.BatchSize = 2
+
+	stats, err := sink.Write(context.Background(), bulkRecords)
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request") {
+		t.Errorf("Write returned %v, want the status of the bulk request", err)
+	}
+	if stats != (IndexStats{Failed: 3}) {
+		t.Errorf("stats = %+v, want 3 failed", stats)
+	}
+	if len(bulk.ids) != 1 {
+		t.Errorf("sent %d bulk requests, want 1", len(bulk.ids))
+	}
+}
@@ -0,0 +1,68 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// FailedEnrichments returns the enrichments with unresolved key fields, see types.EnrichInfo.Unresolved,
+// ordered by IP address.
+func FailedEnrichments(infos []types.EnrichInfo) []types.EnrichInfo {
+	var failed []types.EnrichInfo
+	for _, info := range infos {
+		if len(info.Unresolved()) > 0 {
+			failed = append(failed, info)
+		}
+	}
+
+	sort.SliceStable(failed, func(i, j int) bool {
+		return compareIP(failed[i].Ip, failed[j].Ip) < 0
+	})
+	return failed
+}
+
+// RenderFailedIPs writes the IP addresses of the failed enrichments one per line, the format the
+// --file input reads, so they can be enriched again. With reasons every IP address is followed by
+// a tab and its unresolved fields with the error of the lookup, when there was one.
+func RenderFailedIPs(w io.Writer, infos []types.EnrichInfo, withReasons bool) error {
+	bw := bufio.NewWriter(w)
+	for _, info := range FailedEnrichments(infos) {
+		line := info.Ip
+		if withReasons {
+			line += "\t" + failureReasons(info)
+		}
+		if _, err := fmt.Fprintln(bw, line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// failureReasons lists the unresolved fields followed by all errors of info, e.g.
+// "unresolved: Abuse, Holder; Abuse: whois: i/o timeout".
+func failureReasons(info types.EnrichInfo) string {
+	reasons := []string{"unresolved: " + strings.Join(info.Unresolved(), ", ")}
+
+	fields := make([]string, 0, len(info.Errors))
+	for field := range info.Errors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		// the errors are written on a single line
+		reasons = append(reasons, field+": "+strings.Join(strings.Fields(info.Errors[field]), " "))
+	}
+
+	return strings.Join(reasons, "; ")
+}
@@ -0,0 +1,63 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// failedInfos holds a complete enrichment, a private IP address and two failed enrichments, out of order.
+var failedInfos = []types.EnrichInfo{
+	{Ip: "193.0.6.140", Abuse: "unknown", Prefix: "193.0.0.0/21", Asn: "3333", Holder: "unknown", Country: "NL",
+		Errors: map[string]string{"Abuse": "whois: i/o\ntimeout", "Holder": "as-overview: status 500"}},
+	{Ip: "193.0.6.139", Abuse: "abuse@ripe.net", Prefix: "193.0.0.0/21", Asn: "3333", Holder: "RIPE-NCC", Country: "NL"},
+	{Ip: "10.0.0.1", AbuseSource: types.AbuseSourceSkippedPrivate},
+	{Ip: "2001:67c:2e8::1", Abuse: types.SourceUnavailable, Prefix: "2001:67c:2e8::/48", Asn: "3333", Holder: "RIPE-NCC"},
+}
+
+func TestFailedEnrichments(t *testing.T) {
+	failed := FailedEnrichments(failedInfos)
+	if len(failed) != 2 || failed[0].Ip != "193.0.6.140" || failed[1].Ip != "2001:67c:2e8::1" {
+		t.Errorf("FailedEnrichments = %+v, want the IPv4 and IPv6 failures in order", failed)
+	}
+	if failed := FailedEnrichments(failedInfos[1:3]); len(failed) != 0 {
+		t.Errorf("FailedEnrichments of complete and private enrichments = %+v", failed)
+	}
+}
+
+func TestRenderFailedIPs(t *testing.T) {
+	tests := []struct {
+		name        string
+		withReasons bool
+		want        string
+	}{
+		{"without reasons", false, "193.0.6.140\n2001:67c:2e8::1\n"},
+		// the errors are sorted by field and written on the line of their IP address
+		{"with reasons", true, "193.0.6.140\tunresolved: Abuse, Holder; Abuse: whois: i/o timeout; Holder: as-overview: status 500\n" +
+			"2001:67c:2e8::1\tunresolved: Abuse, Country\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := RenderFailedIPs(&buf, failedInfos, tt.withReasons); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("RenderFailedIPs = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := RenderFailedIPs(&buf, failedInfos[1:2], true); err != nil || buf.Len() != 0 {
+		t.Errorf("RenderFailedIPs without failures wrote %q, %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,79 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatJSONL   = "jsonl"
+	FormatCSV     = "csv"
+	FormatHTML    = "html"
+	FormatGeoJSON = "geojson"
+	// FormatSTIX writes the enrichments as a STIX 2.1 bundle, see RenderSTIX
+	FormatSTIX = "stix"
+	// FormatMISP writes the enrichments as a MISP event, see RenderMISP
+	FormatMISP = "misp"
+	// FormatPrefixes writes one CSV row per prefix, holder and abuse contacts, see RenderPrefixCSV
+	FormatPrefixes = "prefixes"
+	// FormatFailed lists the IP addresses that failed enrichment, see RenderFailedIPs
+	FormatFailed = "failed"
+	// FormatLogfmt writes one line of key=value pairs per finding, see RenderLogfmt
+	FormatLogfmt = "logfmt"
+	// FormatProtobuf writes the enrichments as length delimited protobuf messages, see RenderProtobuf
+	FormatProtobuf = "protobuf"
+)
+
+// formatsByExt maps file extensions to the format they imply
+var formatsByExt = map[string]string{
+	".json":    FormatJSON,
+	".jsonl":   FormatJSONL,
+	".ndjson":  FormatJSONL,
+	".csv":     FormatCSV,
+	".html":    FormatHTML,
+	".htm":     FormatHTML,
+	".geojson": FormatGeoJSON,
+	".logfmt":  FormatLogfmt,
+	".binpb":   FormatProtobuf,
+}
+
+// Target is a file the merge results are written to in Format.
+type Target struct {
+	Format string
+	Path   string
+}
+
+func isFormat(s string) bool {
+	switch s {
+	case FormatJSON, FormatJSONL, FormatCSV, FormatHTML, FormatGeoJSON, FormatSTIX, FormatMISP, FormatPrefixes, FormatFailed, FormatLogfmt, FormatProtobuf:
+		return true
+	}
+	return false
+}
+
+// ParseTarget parses an output of the form [format:]path. Without a format prefix the format is
+// inferred from the extension of path, paths with another extension are written as JSON.
+func ParseTarget(s string) (Target, error) {
+	if format, path, found := strings.Cut(s, ":"); found && isFormat(strings.ToLower(format)) {
+		if path == "" {
+			return Target{}, fmt.Errorf("output %q has no path", s)
+		}
+		return Target{Format: strings.ToLower(format), Path: path}, nil
+	}
+
+	if s == "" {
+		return Target{}, fmt.Errorf("empty output path")
+	}
+	if format, found := formatsByExt[strings.ToLower(filepath.Ext(s))]; found {
+		return Target{Format: format, Path: s}, nil
+	}
+	return Target{Format: FormatJSON, Path: s}, nil
+}
@@ -0,0 +1,94 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"nuclei-parse-enrich/pkg/country"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONPoint struct {
+	Type string `json:"type"`
+	// longitude first, as GeoJSON (RFC 7946) orders positions
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// GeoJSONOptions configures RenderGeoJSON.
+type GeoJSONOptions struct {
+	// CountryFallback places records without coordinates at a reference point of their country,
+	// see country.Point, instead of leaving them out
+	CountryFallback bool
+}
+
+// RenderGeoJSON writes the merge results as a GeoJSON FeatureCollection of points, one per finding,
+// with the IP, ASN, holder, country and severity as properties. It returns the number of records
+// left out because they have no location.
+func RenderGeoJSON(w io.Writer, results []types.MergeResult, opts GeoJSONOptions) (int, error) {
+	collection := geoJSONFeatureCollection{
+		Type:     "FeatureCollection",
+		Features: []geoJSONFeature{},
+	}
+	omitted := 0
+
+	for _, result := range results {
+		lat, lon := result.Latitude, result.Longitude
+		approximate := false
+
+		if lat == 0 && lon == 0 {
+			var found bool
+			if opts.CountryFallback {
+				lat, lon, found = country.Point(result.Country)
+			}
+			if !found {
+				omitted++
+				continue
+			}
+			approximate = true
+		}
+
+		collection.Features = append(collection.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{lon, lat},
+			},
+			Properties: map[string]interface{}{
+				"ip":          result.EnrichInfo.Ip,
+				"asn":         result.Asn,
+				"holder":      result.Holder,
+				"holder_name": result.HolderName,
+				"country":     result.Country,
+				"city":        result.City,
+				"severity":    result.Info.Severity,
+				"template_id": result.TemplateId,
+				"approximate": approximate,
+			},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(collection); err != nil {
+		return omitted, fmt.Errorf("error writing GeoJSON: %v", err)
+	}
+
+	return omitted, nil
+}
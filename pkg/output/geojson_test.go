@@ -0,0 +1,101 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/country"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// geoResults are findings with coordinates, with only a known country and without any location.
+func geoResults() []types.MergeResult {
+	located := types.MergeResult{EnrichInfo: types.EnrichInfo{
+		Ip: "193.0.6.139", Asn: "3333", Holder: "RIPE-NCC-AS", Country: "NL", City: "Amsterdam", Latitude: 52.37, Longitude: 4.89,
+	}}
+	located.TemplateId = "CVE-2021-44228"
+	located.Info.Severity = "critical"
+
+	countryOnly := types.MergeResult{EnrichInfo: types.EnrichInfo{Ip: "185.49.140.1", Asn: "49685", Country: "NL"}}
+	countryOnly.Info.Severity = "low"
+
+	unknown := types.MergeResult{EnrichInfo: types.EnrichInfo{Ip: "192.0.2.1"}}
+
+	return []types.MergeResult{located, countryOnly, unknown}
+}
+
+// geoFeatures renders results and decodes the features of the collection.
+func geoFeatures(t *testing.T, results []types.MergeResult, opts GeoJSONOptions) ([]geoJSONFeature, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	omitted, err := RenderGeoJSON(&buf, results, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var collection geoJSONFeatureCollection
+	if err := json.Unmarshal(buf.Bytes(), &collection); err != nil {
+		t.Fatalf("invalid GeoJSON %s: %v", buf.Bytes(), err)
+	}
+	if collection.Type != "FeatureCollection" || collection.Features == nil {
+		t.Fatalf("rendered %s, want a FeatureCollection", buf.Bytes())
+	}
+	for _, feature := range collection.Features {
+		if feature.Type != "Feature" || feature.Geometry.Type != "Point" {
+			t.Errorf("feature %+v isn't a Point Feature", feature)
+		}
+	}
+	return collection.Features, omitted
+}
+
+func TestRenderGeoJSON(t *testing.T) {
+	features, omitted := geoFeatures(t, geoResults(), GeoJSONOptions{})
+	if len(features) != 1 || omitted != 2 {
+		t.Fatalf("rendered %d features and omitted %d, want only the located one", len(features), omitted)
+	}
+
+	feature := features[0]
+	if feature.Geometry.Coordinates != [2]float64{4.89, 52.37} {
+		t.Errorf("coordinates %v, want longitude first", feature.Geometry.Coordinates)
+	}
+	want := map[string]interface{}{
+		"ip": "193.0.6.139", "asn": "3333", "holder": "RIPE-NCC-AS", "holder_name": "", "country": "NL", "city": "Amsterdam",
+		"severity": "critical", "template_id": "CVE-2021-44228", "approximate": false,
+	}
+	for key, value := range want {
+		if feature.Properties[key] != value {
+			t.Errorf("property %s = %v, want %v", key, feature.Properties[key], value)
+		}
+	}
+}
+
+func TestRenderGeoJSONCountryFallback(t *testing.T) {
+	features, omitted := geoFeatures(t, geoResults(), GeoJSONOptions{CountryFallback: true})
+	if len(features) != 2 || omitted != 1 {
+		t.Fatalf("rendered %d features and omitted %d, want the one without a country omitted", len(features), omitted)
+	}
+
+	lat, lon, _ := country.Point("NL")
+	fallback := features[1]
+	if fallback.Properties["ip"] != "185.49.140.1" || fallback.Properties["approximate"] != true ||
+		fallback.Geometry.Coordinates != [2]float64{lon, lat} {
+		t.Errorf("feature %+v, want an approximate point in NL", fallback)
+	}
+}
+
+func TestRenderGeoJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := RenderGeoJSON(&buf, nil, GeoJSONOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "{\"type\":\"FeatureCollection\",\"features\":[]}\n" {
+		t.Errorf("rendered %s, want an empty FeatureCollection", got)
+	}
+}
@@ -0,0 +1,98 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"sort"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// UnknownHolder is the Holder of the HolderReport of the records without known holder.
+const UnknownHolder = "unknown"
+
+// HolderReport rolls up the enrichments of a holder, to organise the notifications of a
+// disclosure campaign per organisation.
+type HolderReport struct {
+	Holder string
+	// ASNs, Prefixes and AbuseEmails are the unique values of the records of the holder, sorted
+	ASNs        []string
+	Prefixes    []string
+	AbuseEmails []string
+	// IPCount is the number of unique IP addresses of the holder
+	IPCount int
+}
+
+// ReportByHolder rolls the enrichments up per holder, ordered by the number of IP addresses, most
+// first, and then by holder. The records without known holder, RipeSTAT being in maintenance
+// included, are rolled up in the report of UnknownHolder.
+func ReportByHolder(infos []types.EnrichInfo) []HolderReport {
+	type rollup struct {
+		asns, prefixes, emails, ips map[string]struct{}
+	}
+
+	byHolder := make(map[string]*rollup)
+	for _, info := range infos {
+		holder := info.Holder
+		if holder == "" || holder == types.SourceUnavailable {
+			holder = UnknownHolder
+		}
+
+		r, found := byHolder[holder]
+		if !found {
+			r = &rollup{
+				asns:     make(map[string]struct{}),
+				prefixes: make(map[string]struct{}),
+				emails:   make(map[string]struct{}),
+				ips:      make(map[string]struct{}),
+			}
+			byHolder[holder] = r
+		}
+
+		r.ips[info.Ip] = struct{}{}
+		addKnown(r.asns, info.Asn)
+		addKnown(r.prefixes, info.Prefix)
+		for _, email := range info.AbuseList() {
+			addKnown(r.emails, email)
+		}
+	}
+
+	reports := make([]HolderReport, 0, len(byHolder))
+	for holder, r := range byHolder {
+		reports = append(reports, HolderReport{
+			Holder:      holder,
+			ASNs:        sortedKeys(r.asns),
+			Prefixes:    sortedKeys(r.prefixes),
+			AbuseEmails: sortedKeys(r.emails),
+			IPCount:     len(r.ips),
+		})
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].IPCount != reports[j].IPCount {
+			return reports[i].IPCount > reports[j].IPCount
+		}
+		return reports[i].Holder < reports[j].Holder
+	})
+	return reports
+}
+
+// addKnown adds value to set unless it is empty or a placeholder.
+func addKnown(set map[string]struct{}, value string) {
+	if value != "" && value != "unknown" && value != types.SourceUnavailable {
+		set[value] = struct{}{}
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
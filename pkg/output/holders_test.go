@@ -0,0 +1,56 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"reflect"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestReportByHolderAbuse(t *testing.T) {
+	for _, tt := range abuseCases {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			info.Ip, info.Asn, info.Prefix, info.Holder = "193.0.6.139", "3333", "193.0.0.0/21", "RIPE-NCC"
+
+			reports := ReportByHolder([]types.EnrichInfo{info})
+			want := []HolderReport{{
+				Holder:      "RIPE-NCC",
+				ASNs:        []string{"3333"},
+				Prefixes:    []string{"193.0.0.0/21"},
+				AbuseEmails: tt.emails,
+				IPCount:     1,
+			}}
+			if want[0].AbuseEmails == nil {
+				want[0].AbuseEmails = []string{}
+			}
+			if !reflect.DeepEqual(reports, want) {
+				t.Errorf("ReportByHolder = %+v, want %+v", reports, want)
+			}
+		})
+	}
+}
+
+func TestReportByHolderMergesContacts(t *testing.T) {
+	infos := []types.EnrichInfo{
+		{Ip: "193.0.6.139", Holder: "RIPE-NCC", AbuseAddresses: []string{"abuse@ripe.net"}},
+		{Ip: "193.0.6.140", Holder: "RIPE-NCC", Abuse: "noc@ripe.net;abuse@ripe.net"},
+		{Ip: "193.0.6.140", Holder: "RIPE-NCC", Abuse: "unknown"},
+		{Ip: "192.0.2.1", Abuse: "abuse@example.net"},
+		{Ip: "192.0.2.2", Holder: types.SourceUnavailable},
+	}
+
+	want := []HolderReport{
+		{Holder: "RIPE-NCC", ASNs: []string{}, Prefixes: []string{}, AbuseEmails: []string{"abuse@ripe.net", "noc@ripe.net"}, IPCount: 2},
+		{Holder: UnknownHolder, ASNs: []string{}, Prefixes: []string{}, AbuseEmails: []string{"abuse@example.net"}, IPCount: 2},
+	}
+	if reports := ReportByHolder(infos); !reflect.DeepEqual(reports, want) {
+		t.Errorf("ReportByHolder = %+v, want %+v", reports, want)
+	}
+}
@@ -0,0 +1,41 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"html/template"
+	"io"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Enriched findings</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 2px 6px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>Enriched findings</h1>
+<p>{{len .}} findings</p>
+<table>
+<tr><th>IP</th><th>Template</th><th>Severity</th><th>Matched at</th><th>Abuse</th><th>Prefix</th><th>ASN</th><th>Holder</th><th>Country</th><th>City</th></tr>
+{{range .}}<tr><td>{{.EnrichInfo.Ip}}</td><td>{{.TemplateId}}</td><td>{{.Info.Severity}}</td><td>{{.MatchedAt}}</td><td>{{.Abuse}}</td><td>{{.Prefix}}</td><td>{{.Asn}}</td><td>{{if .HolderName}}{{.HolderName}}{{else}}{{.Holder}}{{end}}</td><td>{{.CountryName}}</td><td>{{.City}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// RenderHTML writes the merge results as a self-contained HTML report with one table row per finding.
+func RenderHTML(w io.Writer, results []types.MergeResult) error {
+	return htmlReport.Execute(w, results)
+}
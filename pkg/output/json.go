@@ -0,0 +1,65 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// RenderJSONByIP writes the merge results as an indented JSON object keyed by IP address, the
+// last result of every IP address. The keys are ordered like SortEnrichments orders records, so
+// identical runs give identical bytes. The enrichment is written in the layout of opts.
+func RenderJSONByIP(w io.Writer, results []types.MergeResult, opts types.MarshalOptions) error {
+	byIP := make(map[string]types.MergeResult, len(results))
+	for _, result := range results {
+		byIP[result.EnrichInfo.Ip] = result
+	}
+
+	ipAddrs := make([]string, 0, len(byIP))
+	for ipAddr := range byIP {
+		ipAddrs = append(ipAddrs, ipAddr)
+	}
+	sort.Slice(ipAddrs, func(i, j int) bool {
+		return compareIP(ipAddrs[i], ipAddrs[j]) < 0
+	})
+
+	bw := bufio.NewWriter(w)
+	if len(ipAddrs) == 0 {
+		bw.WriteString("{}\n")
+		return bw.Flush()
+	}
+
+	bw.WriteString("{\n")
+	for i, ipAddr := range ipAddrs {
+		key, err := json.Marshal(ipAddr)
+		if err != nil {
+			return fmt.Errorf("error writing output: %v", err)
+		}
+		value, err := json.MarshalIndent(opts.MergeResult(byIP[ipAddr]), "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("error writing output: %v", err)
+		}
+
+		bw.WriteString("  ")
+		bw.Write(key)
+		bw.WriteString(": ")
+		bw.Write(value)
+		if i < len(ipAddrs)-1 {
+			bw.WriteString(",")
+		}
+		bw.WriteString("\n")
+	}
+	bw.WriteString("}\n")
+
+	return bw.Flush()
+}
@@ -0,0 +1,75 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// RenderLogfmt writes the merge results as logfmt, one line of key=value pairs per finding, for
+// log based systems such as Loki and Splunk:
+//
+//	ip=193.0.6.139 template_id=tech-detect severity=info asn=3333 holder="RIPE-NCC-AS Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL" country=NL abuse=abuse@ripe.net
+//
+// Empty fields are left out, lists are joined with a semicolon and the errors are written as
+// field: message. Values with spaces, quotes, = or control characters are quoted.
+func RenderLogfmt(w io.Writer, results []types.MergeResult) error {
+	writer := bufio.NewWriter(w)
+
+	for _, result := range results {
+		pairs := [][2]string{
+			{"ip", result.EnrichInfo.Ip},
+			{"template_id", result.TemplateId},
+			{"name", result.Info.Name},
+			{"severity", result.Info.Severity},
+			{"host", result.Host},
+			{"matched_at", result.MatchedAt},
+			{"timestamp", result.Timestamp},
+			{"prefix", result.Prefix},
+			{"asn", result.Asn},
+			{"holder", result.Holder},
+			{"country", result.Country},
+			{"city", result.City},
+			{"abuse", strings.Join(result.AbuseList(), ";")},
+			{"abuse_source", result.AbuseSource},
+			{"errors", joinErrors(result.Errors)},
+		}
+
+		first := true
+		for _, pair := range pairs {
+			if pair[1] == "" {
+				continue
+			}
+			if !first {
+				writer.WriteByte(' ')
+			}
+			first = false
+			writer.WriteString(pair[0])
+			writer.WriteByte('=')
+			writer.WriteString(logfmtValue(pair[1]))
+		}
+		writer.WriteByte('\n')
+	}
+
+	return writer.Flush()
+}
+
+// logfmtValue returns value quoted when it has characters that would end or break the pair.
+func logfmtValue(value string) string {
+	for _, r := range value {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
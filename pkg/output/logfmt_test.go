@@ -0,0 +1,69 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"193.0.6.139", "193.0.6.139"},
+		{"abuse@ripe.net;noc@ripe.net", "abuse@ripe.net;noc@ripe.net"},
+		{"São-Paulo", "São-Paulo"},
+		{"RIPE-NCC-AS Reseaux IP Europeens", `"RIPE-NCC-AS Reseaux IP Europeens"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`"`, `"\""`},
+		{"a=b", `"a=b"`},
+		{"=", `"="`},
+		{`C:\scans`, `"C:\\scans"`},
+		{"tab\there", `"tab\there"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"no\u00a0break", `"no\u00a0break"`},
+		{"bell\x07", `"bell\a"`},
+		{" ", `" "`},
+	}
+	for _, tt := range tests {
+		if got := logfmtValue(tt.value); got != tt.want {
+			t.Errorf("logfmtValue(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRenderLogfmt(t *testing.T) {
+	var result types.MergeResult
+	result.EnrichInfo = types.EnrichInfo{
+		Ip:             "193.0.6.139",
+		Asn:            "3333",
+		Holder:         `RIPE-NCC-AS "RIPE NCC", NL`,
+		Country:        "NL",
+		AbuseAddresses: []string{"abuse@ripe.net", "noc@ripe.net"},
+		AbuseSource:    types.AbuseSourceRipeSTAT,
+		Errors:         map[string]string{"Holder": "as-overview: timeout", "Geolocation": "no data"},
+	}
+	result.TemplateId = "tech-detect"
+	result.Info.Severity = "info"
+	result.MatchedAt = "https://193.0.6.139/?a=b"
+
+	var buf bytes.Buffer
+	if err := RenderLogfmt(&buf, []types.MergeResult{result, {}}); err != nil {
+		t.Fatal(err)
+	}
+	// empty values are left out, a result without any leaves an empty line
+	want := `ip=193.0.6.139 template_id=tech-detect severity=info matched_at="https://193.0.6.139/?a=b" asn=3333 ` +
+		`holder="RIPE-NCC-AS \"RIPE NCC\", NL" country=NL abuse=abuse@ripe.net;noc@ripe.net abuse_source=RipeSTAT ` +
+		`errors="Geolocation: no data; Holder: as-overview: timeout"` + "\n\n"
+	if buf.String() != want {
+		t.Errorf("RenderLogfmt =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,156 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+const (
+	// mispDistributionOrganisation limits a MISP event to the organisation importing it, it is up
+	// to the analyst to share it further
+	mispDistributionOrganisation = "0"
+	// mispDistributionInherit shares attributes and objects like their event
+	mispDistributionInherit = "5"
+)
+
+type mispExport struct {
+	Event mispEvent `json:"Event"`
+}
+
+type mispEvent struct {
+	UUID          string          `json:"uuid"`
+	Info          string          `json:"info"`
+	Date          string          `json:"date"`
+	Timestamp     string          `json:"timestamp"`
+	ThreatLevelID string          `json:"threat_level_id"`
+	Analysis      string          `json:"analysis"`
+	Distribution  string          `json:"distribution"`
+	Attribute     []mispAttribute `json:"Attribute"`
+	Object        []mispObject    `json:"Object"`
+}
+
+type mispAttribute struct {
+	UUID           string `json:"uuid"`
+	Type           string `json:"type"`
+	Category       string `json:"category"`
+	ObjectRelation string `json:"object_relation,omitempty"`
+	Value          string `json:"value"`
+	ToIDS          bool   `json:"to_ids"`
+	Comment        string `json:"comment,omitempty"`
+	Distribution   string `json:"distribution"`
+}
+
+type mispObject struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	MetaCategory string          `json:"meta-category"`
+	Comment      string          `json:"comment,omitempty"`
+	Distribution string          `json:"distribution"`
+	Attribute    []mispAttribute `json:"Attribute"`
+}
+
+// RenderMISP writes the enrichments as a MISP event in the JSON format MISP imports. Every IP
+// address is an ip-dst attribute commented with its ASN, holder and country, and a whois object
+// holds the IP address with its holder and abuse contacts. The attributes are not marked for IDS
+// use and the event is limited to the importing organisation, it is up to the analyst to change that.
+func RenderMISP(w io.Writer, infos []types.EnrichInfo, info string) error {
+	now := time.Now().UTC()
+
+	sorted := make([]types.EnrichInfo, len(infos))
+	copy(sorted, infos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareIP(sorted[i].Ip, sorted[j].Ip) < 0
+	})
+
+	event := mispEvent{
+		UUID:          randomUUID(),
+		Info:          info,
+		Date:          now.Format("2006-01-02"),
+		Timestamp:     fmt.Sprint(now.Unix()),
+		ThreatLevelID: "4",
+		Analysis:      "0",
+		Distribution:  mispDistributionOrganisation,
+		Attribute:     []mispAttribute{},
+		Object:        []mispObject{},
+	}
+	if event.Info == "" {
+		event.Info = "nuclei-parse-enrich " + event.Date
+	}
+
+	for _, enrichment := range sorted {
+		addr, err := netip.ParseAddr(strings.Trim(enrichment.Ip, "[]"))
+		if err != nil {
+			continue
+		}
+		ipAddr := addr.Unmap().WithZone("").String()
+		comment := mispComment(enrichment)
+
+		event.Attribute = append(event.Attribute, newMISPAttribute("ip-dst", "Network activity", "", ipAddr, comment))
+
+		object := mispObject{
+			UUID:         randomUUID(),
+			Name:         "whois",
+			MetaCategory: "network",
+			Comment:      comment,
+			Distribution: mispDistributionInherit,
+			Attribute: []mispAttribute{
+				newMISPAttribute("ip-src", "Network activity", "ip-address", ipAddr, ""),
+			},
+		}
+		if holder := knownHolderName(enrichment); holder != "" {
+			object.Attribute = append(object.Attribute, newMISPAttribute("whois-registrant-org", "Attribution", "registrant-org", holder, ""))
+		}
+		for _, address := range enrichment.AbuseList() {
+			object.Attribute = append(object.Attribute, newMISPAttribute("whois-registrant-email", "Attribution", "registrant-email", address, "abuse contact"))
+		}
+		event.Object = append(event.Object, object)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(mispExport{Event: event}); err != nil {
+		return fmt.Errorf("error writing MISP event: %v", err)
+	}
+	return nil
+}
+
+func newMISPAttribute(attributeType, category, relation, value, comment string) mispAttribute {
+	return mispAttribute{
+		UUID:           randomUUID(),
+		Type:           attributeType,
+		Category:       category,
+		ObjectRelation: relation,
+		Value:          value,
+		Comment:        comment,
+		Distribution:   mispDistributionInherit,
+	}
+}
+
+// mispComment summarizes the ASN, holder and country of an enrichment, e.g.
+// "AS3333, RIPE-NCC-AS Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL".
+func mispComment(info types.EnrichInfo) string {
+	var parts []string
+	if isKnown(info.Asn) {
+		parts = append(parts, "AS"+info.Asn)
+	}
+	if holder := knownHolderName(info); holder != "" {
+		parts = append(parts, holder)
+	}
+	if isKnown(info.Country) {
+		parts = append(parts, info.Country)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,105 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestRenderMISP(t *testing.T) {
+	infos := []types.EnrichInfo{
+		{Ip: "193.0.6.139", Asn: "3333", Holder: "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL",
+			HolderName: "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", Country: "NL",
+			Abuse: "abuse@ripe.net;noc@ripe.net", AbuseAddresses: []string{"abuse@ripe.net", "noc@ripe.net"}},
+		{Ip: "::ffff:192.0.2.1", Asn: "unknown", Holder: types.SourceUnavailable},
+		{Ip: "not an IP"},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderMISP(&buf, infos, "log4j scan"); err != nil {
+		t.Fatal(err)
+	}
+	var export mispExport
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.Bytes(), err)
+	}
+	event := export.Event
+
+	if event.Info != "log4j scan" || event.Distribution != "0" || event.ThreatLevelID != "4" || event.Analysis != "0" ||
+		event.Date != time.Now().UTC().Format("2006-01-02") || event.UUID == "" {
+		t.Errorf("event %+v", event)
+	}
+
+	// the attributes are sorted by IP address as given, IPv4 first, the mapped IPv4 address is
+	// written as such
+	type attribute struct{ Type, Category, Relation, Value, Comment string }
+	attributes := func(list []mispAttribute) []attribute {
+		var ret []attribute
+		for _, a := range list {
+			if a.UUID == "" || a.ToIDS || a.Distribution != "5" {
+				t.Errorf("attribute %+v should have a UUID, not be for IDS and inherit the distribution", a)
+			}
+			ret = append(ret, attribute{a.Type, a.Category, a.ObjectRelation, a.Value, a.Comment})
+		}
+		return ret
+	}
+	comment := "AS3333, RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL"
+	wantAttributes := []attribute{
+		{"ip-dst", "Network activity", "", "193.0.6.139", comment},
+		{"ip-dst", "Network activity", "", "192.0.2.1", ""},
+	}
+	if got := attributes(event.Attribute); !reflect.DeepEqual(got, wantAttributes) {
+		t.Errorf("attributes %+v, want %+v", got, wantAttributes)
+	}
+
+	if len(event.Object) != 2 {
+		t.Fatalf("wrote %d objects, want a whois object per IP address", len(event.Object))
+	}
+	for _, object := range event.Object {
+		if object.Name != "whois" || object.MetaCategory != "network" || object.Distribution != "5" {
+			t.Errorf("object %+v", object)
+		}
+	}
+	if got := attributes(event.Object[1].Attribute); !reflect.DeepEqual(got, []attribute{{"ip-src", "Network activity", "ip-address", "192.0.2.1", ""}}) {
+		t.Errorf("attributes of an unknown holder %+v", got)
+	}
+	wantWhois := []attribute{
+		{"ip-src", "Network activity", "ip-address", "193.0.6.139", ""},
+		{"whois-registrant-org", "Attribution", "registrant-org", "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", ""},
+		{"whois-registrant-email", "Attribution", "registrant-email", "abuse@ripe.net", "abuse contact"},
+		{"whois-registrant-email", "Attribution", "registrant-email", "noc@ripe.net", "abuse contact"},
+	}
+	if got := attributes(event.Object[0].Attribute); !reflect.DeepEqual(got, wantWhois) {
+		t.Errorf("whois attributes %+v, want %+v", got, wantWhois)
+	}
+	if event.Object[0].Comment != comment {
+		t.Errorf("whois comment %q, want %q", event.Object[0].Comment, comment)
+	}
+}
+
+func TestRenderMISPDefaultInfo(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderMISP(&buf, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	var export mispExport
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if want := "nuclei-parse-enrich " + export.Event.Date; export.Event.Info != want {
+		t.Errorf("info %q, want %q", export.Event.Info, want)
+	}
+	if export.Event.Attribute == nil || export.Event.Object == nil {
+		t.Errorf("an empty event must have empty lists, got %s", buf.Bytes())
+	}
+}
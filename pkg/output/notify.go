@@ -0,0 +1,342 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// The message formats of a Notifier
+const (
+	// NotifyAuto picks the format by the host of the webhook URL, plain text for unknown hosts
+	NotifyAuto  = "auto"
+	NotifySlack = "slack"
+	NotifyTeams = "teams"
+	// NotifyText posts {"text": ...}, which most chat webhooks accept
+	NotifyText = "text"
+)
+
+// NotifyTopCountries is the number of countries listed in a RunReport.
+const NotifyTopCountries = 5
+
+// severityOrder is the order the severities of nuclei are listed in, the most severe first
+var severityOrder = []string{"critical", "high", "medium", "low", "info", "unknown"}
+
+// CountryCount is the number of unique IP addresses of a country.
+type CountryCount struct {
+	Country string
+	IPs     int
+}
+
+// RunReport summarises a run for a notification.
+type RunReport struct {
+	// Findings counts the findings per severity, findings without severity count as unknown
+	Findings  map[string]int
+	UniqueIPs int
+	// UniqueASNs counts the known ASNs of the IP addresses
+	UniqueASNs int
+	// Failed is the number of IP addresses with unresolved key fields, see types.EnrichInfo.Unresolved
+	Failed int
+	// TopCountries are the countries with the most IP addresses, most first
+	TopCountries []CountryCount
+	// Outputs and FailedOutputs are the paths of the output files written and failed
+	Outputs       []string
+	FailedOutputs []string
+	Duration      time.Duration
+}
+
+// NewRunReport counts the findings of results and the IP addresses, ASNs and countries of infos.
+func NewRunReport(results []types.MergeResult, infos []types.EnrichInfo) RunReport {
+	report := RunReport{Findings: make(map[string]int)}
+	for _, result := range results {
+		severity := strings.ToLower(strings.TrimSpace(result.Info.Severity))
+		if severity == "" {
+			severity = "unknown"
+		}
+		report.Findings[severity]++
+	}
+
+	ips := make(map[string]bool)
+	asns := make(map[string]bool)
+	countries := make(map[string]int)
+	for _, info := range infos {
+		if ips[info.Ip] {
+			continue
+		}
+		ips[info.Ip] = true
+		if info.Asn != "" && info.Asn != "unknown" && info.Asn != types.SourceUnavailable {
+			asns[info.Asn] = true
+		}
+		if info.Country != "" && info.Country != "unknown" && info.Country != types.SourceUnavailable {
+			countries[info.Country]++
+		}
+		if len(info.Unresolved()) > 0 {
+			report.Failed++
+		}
+	}
+	report.UniqueIPs, report.UniqueASNs = len(ips), len(asns)
+
+	for country, count := range countries {
+		report.TopCountries = append(report.TopCountries, CountryCount{country, count})
+	}
+	sort.Slice(report.TopCountries, func(i, j int) bool {
+		if report.TopCountries[i].IPs != report.TopCountries[j].IPs {
+			return report.TopCountries[i].IPs > report.TopCountries[j].IPs
+		}
+		return report.TopCountries[i].Country < report.TopCountries[j].Country
+	})
+	if len(report.TopCountries) > NotifyTopCountries {
+		report.TopCountries = report.TopCountries[:NotifyTopCountries]
+	}
+	return report
+}
+
+// severities returns the counts of the findings as "critical: 2", in severityOrder and then the
+// other severities alphabetically.
+func (r RunReport) severities() []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, severity := range severityOrder {
+		seen[severity] = true
+		if r.Findings[severity] > 0 {
+			lines = append(lines, fmt.Sprintf("%s: %d", severity, r.Findings[severity]))
+		}
+	}
+
+	var others []string
+	for severity := range r.Findings {
+		if !seen[severity] {
+			others = append(others, severity)
+		}
+	}
+	sort.Strings(others)
+	for _, severity := range others {
+		lines = append(lines, fmt.Sprintf("%s: %d", severity, r.Findings[severity]))
+	}
+	return lines
+}
+
+func (r RunReport) countries() string {
+	var parts []string
+	for _, c := range r.TopCountries {
+		parts = append(parts, fmt.Sprintf("%s (%d)", c.Country, c.IPs))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Text returns the report as plain text, the fallback of the rich formats.
+func (r RunReport) Text() string {
+	var b strings.Builder
+	b.WriteString("nuclei-parse-enrich run finished")
+	if r.Duration > 0 {
+		fmt.Fprintf(&b, " in %v", r.Duration.Round(time.Second))
+	}
+	b.WriteString("\n")
+
+	findings := r.severities()
+	if len(findings) == 0 {
+		findings = []string{"none"}
+	}
+	fmt.Fprintf(&b, "Findings: %s\n", strings.Join(findings, ", "))
+	fmt.Fprintf(&b, "Unique IPs: %d, unique ASNs: %d, failed enrichments: %d\n", r.UniqueIPs, r.UniqueASNs, r.Failed)
+	if countries := r.countries(); countries != "" {
+		fmt.Fprintf(&b, "Top countries: %s\n", countries)
+	}
+	if len(r.Outputs) > 0 {
+		fmt.Fprintf(&b, "Outputs: %s\n", strings.Join(r.Outputs, ", "))
+	}
+	if len(r.FailedOutputs) > 0 {
+		fmt.Fprintf(&b, "Failed outputs: %s\n", strings.Join(r.FailedOutputs, ", "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Notifier posts a RunReport to a Slack or Microsoft Teams incoming webhook. A webhook rejecting
+// the rich message, e.g. a Slack compatible one without blocks, gets the plain text instead.
+type Notifier struct {
+	URL string
+	// Format is NotifyAuto, NotifySlack, NotifyTeams or NotifyText, empty means NotifyAuto
+	Format     string
+	HTTPClient *http.Client
+}
+
+func NewNotifier(url, format string) *Notifier {
+	return &Notifier{
+		URL:        url,
+		Format:     format,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NotifyFormat returns the format NotifyAuto picks for webhookURL.
+func NotifyFormat(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return NotifyText
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "hooks.slack.com":
+		return NotifySlack
+	case strings.HasSuffix(host, ".webhook.office.com"), strings.HasSuffix(host, ".logic.azure.com"):
+		return NotifyTeams
+	}
+	return NotifyText
+}
+
+// Notify posts report. When the webhook rejects the rich message with a 4xx status the plain text
+// is posted instead.
+func (n *Notifier) Notify(ctx context.Context, report RunReport) error {
+	format := n.Format
+	if format == "" || format == NotifyAuto {
+		format = NotifyFormat(n.URL)
+	}
+
+	text := map[string]interface{}{"text": report.Text()}
+	var rich map[string]interface{}
+	switch format {
+	case NotifySlack:
+		rich = slackMessage(report)
+	case NotifyTeams:
+		rich = teamsMessage(report)
+	}
+
+	if rich != nil {
+		status, err := n.post(ctx, rich)
+		if err == nil || status < 400 || status >= 500 {
+			return err
+		}
+	}
+	_, err := n.post(ctx, text)
+	return err
+}
+
+func (n *Notifier) post(ctx context.Context, message map[string]interface{}) (int, error) {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// slackMessage returns report in Slack Block Kit, with the plain text for notifications and
+// clients without blocks.
+func slackMessage(report RunReport) map[string]interface{} {
+	mrkdwn := func(text string) map[string]interface{} {
+		return map[string]interface{}{"type": "mrkdwn", "text": text}
+	}
+
+	findings := report.severities()
+	if len(findings) == 0 {
+		findings = []string{"none"}
+	}
+	fields := []interface{}{
+		mrkdwn("*Findings*\n" + strings.Join(findings, "\n")),
+		mrkdwn(fmt.Sprintf("*Unique IPs*\n%d", report.UniqueIPs)),
+		mrkdwn(fmt.Sprintf("*Unique ASNs*\n%d", report.UniqueASNs)),
+		mrkdwn(fmt.Sprintf("*Failed enrichments*\n%d", report.Failed)),
+	}
+	if countries := report.countries(); countries != "" {
+		fields = append(fields, mrkdwn("*Top countries*\n"+countries))
+	}
+
+	blocks := []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": "nuclei-parse-enrich run finished"},
+		},
+		map[string]interface{}{"type": "section", "fields": fields},
+	}
+	if outputs := outputsText(report, "`"); outputs != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type":     "context",
+			"elements": []interface{}{mrkdwn(outputs)},
+		})
+	}
+
+	return map[string]interface{}{"text": report.Text(), "blocks": blocks}
+}
+
+// teamsMessage returns report as an Adaptive Card, as Teams workflows and incoming webhooks accept.
+func teamsMessage(report RunReport) map[string]interface{} {
+	findings := report.severities()
+	if len(findings) == 0 {
+		findings = []string{"none"}
+	}
+	facts := []interface{}{
+		map[string]interface{}{"title": "Findings", "value": strings.Join(findings, ", ")},
+		map[string]interface{}{"title": "Unique IPs", "value": fmt.Sprint(report.UniqueIPs)},
+		map[string]interface{}{"title": "Unique ASNs", "value": fmt.Sprint(report.UniqueASNs)},
+		map[string]interface{}{"title": "Failed enrichments", "value": fmt.Sprint(report.Failed)},
+	}
+	if countries := report.countries(); countries != "" {
+		facts = append(facts, map[string]interface{}{"title": "Top countries", "value": countries})
+	}
+
+	body := []interface{}{
+		map[string]interface{}{"type": "TextBlock", "size": "Medium", "weight": "Bolder", "text": "nuclei-parse-enrich run finished"},
+		map[string]interface{}{"type": "FactSet", "facts": facts},
+	}
+	if outputs := outputsText(report, ""); outputs != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "wrap": true, "isSubtle": true, "text": outputs})
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"text": report.Text(),
+		"attachments": []interface{}{map[string]interface{}{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// outputsText lists the output files, every path between quote.
+func outputsText(report RunReport, quote string) string {
+	var parts []string
+	if len(report.Outputs) > 0 {
+		parts = append(parts, "Outputs: "+quote+strings.Join(report.Outputs, quote+", "+quote)+quote)
+	}
+	if len(report.FailedOutputs) > 0 {
+		parts = append(parts, "Failed outputs: "+quote+strings.Join(report.FailedOutputs, quote+", "+quote)+quote)
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,106 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// PrefixSampleIPs is the number of IP addresses listed per collapsed row.
+const PrefixSampleIPs = 5
+
+var prefixCSVHeader = []string{
+	"prefix", "asn", "holder", "abuse", "country", "ip-count", "sample-ips",
+}
+
+// PrefixGroup is the enrichment shared by the IP addresses of a prefix with the same holder and
+// abuse contacts.
+type PrefixGroup struct {
+	Prefix  string
+	Asn     string
+	Holder  string
+	Abuse   string
+	Country string
+	// IPCount is the number of IP addresses in the group, SampleIPs lists the lowest of them
+	IPCount   int
+	SampleIPs []string
+}
+
+// CollapseByPrefix groups the enrichments sharing prefix, holder and abuse contacts, ordered by
+// the number of IP addresses, most first, and then by prefix. Records without known prefix are
+// grouped by holder and abuse contacts.
+func CollapseByPrefix(infos []types.EnrichInfo) []PrefixGroup {
+	sorted := make([]types.EnrichInfo, len(infos))
+	copy(sorted, infos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareIP(sorted[i].Ip, sorted[j].Ip) < 0
+	})
+
+	byKey := make(map[[3]string]int)
+	var groups []PrefixGroup
+	for _, info := range sorted {
+		abuse := strings.Join(info.AbuseList(), ";")
+		if abuse == "" {
+			abuse = info.Abuse
+		}
+
+		key := [3]string{info.Prefix, info.Holder, abuse}
+		i, found := byKey[key]
+		if !found {
+			i = len(groups)
+			byKey[key] = i
+			groups = append(groups, PrefixGroup{
+				Prefix:  info.Prefix,
+				Asn:     info.Asn,
+				Holder:  info.Holder,
+				Abuse:   abuse,
+				Country: info.Country,
+			})
+		}
+
+		groups[i].IPCount++
+		if len(groups[i].SampleIPs) < PrefixSampleIPs {
+			groups[i].SampleIPs = append(groups[i].SampleIPs, info.Ip)
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].IPCount != groups[j].IPCount {
+			return groups[i].IPCount > groups[j].IPCount
+		}
+		return groups[i].Prefix < groups[j].Prefix
+	})
+	return groups
+}
+
+// RenderPrefixCSV writes the enrichments collapsed by prefix, see CollapseByPrefix, as CSV with a
+// header row. The sample IP addresses are joined with a semicolon.
+func RenderPrefixCSV(w io.Writer, infos []types.EnrichInfo) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(prefixCSVHeader); err != nil {
+		return err
+	}
+
+	for _, group := range CollapseByPrefix(infos) {
+		row := []string{
+			group.Prefix, group.Asn, group.Holder, group.Abuse, group.Country,
+			strconv.Itoa(group.IPCount), strings.Join(group.SampleIPs, ";"),
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
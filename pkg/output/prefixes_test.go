@@ -0,0 +1,82 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+func TestCollapseByPrefixAbuse(t *testing.T) {
+	for _, tt := range abuseCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var infos []types.EnrichInfo
+			for _, ipAddr := range []string{"193.0.6.140", "193.0.6.139"} {
+				info := tt.info
+				info.Ip, info.Prefix, info.Holder = ipAddr, "193.0.0.0/21", "RIPE-NCC"
+				infos = append(infos, info)
+			}
+
+			groups := CollapseByPrefix(infos)
+			want := []PrefixGroup{{
+				Prefix:    "193.0.0.0/21",
+				Holder:    "RIPE-NCC",
+				Abuse:     tt.joined,
+				IPCount:   2,
+				SampleIPs: []string{"193.0.6.139", "193.0.6.140"},
+			}}
+			if !reflect.DeepEqual(groups, want) {
+				t.Errorf("CollapseByPrefix = %+v, want %+v", groups, want)
+			}
+		})
+	}
+}
+
+func TestCollapseByPrefixLegacyMatchesAddresses(t *testing.T) {
+	infos := []types.EnrichInfo{
+		{Ip: "193.0.6.139", Prefix: "193.0.0.0/21", Abuse: "noc@ripe.net;abuse@ripe.net", AbuseAddresses: []string{"noc@ripe.net", "abuse@ripe.net"}},
+		{Ip: "193.0.6.140", Prefix: "193.0.0.0/21", Abuse: "noc@ripe.net; abuse@ripe.net"},
+		{Ip: "193.0.6.141", Prefix: "193.0.0.0/21", Abuse: "abuse@ripe.net"},
+	}
+
+	groups := CollapseByPrefix(infos)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want the IPs with the same contacts in one: %+v", len(groups), groups)
+	}
+	if groups[0].IPCount != 2 || groups[0].Abuse != "noc@ripe.net;abuse@ripe.net" {
+		t.Errorf("first group = %+v, want 2 IPs of noc@ripe.net;abuse@ripe.net", groups[0])
+	}
+}
+
+func TestRenderPrefixCSV(t *testing.T) {
+	infos := []types.EnrichInfo{
+		{Ip: "193.0.6.139", Prefix: "193.0.0.0/21", Asn: "3333", Holder: "RIPE-NCC", Country: "NL", AbuseAddresses: []string{"abuse@ripe.net", "noc@ripe.net"}},
+		{Ip: "193.0.6.140", Prefix: "193.0.0.0/21", Asn: "3333", Holder: "RIPE-NCC", Country: "NL", Abuse: "abuse@ripe.net;noc@ripe.net"},
+		{Ip: "192.0.2.1"},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderPrefixCSV(&buf, infos); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		prefixCSVHeader,
+		{"193.0.0.0/21", "3333", "RIPE-NCC", "abuse@ripe.net;noc@ripe.net", "NL", "2", "193.0.6.139;193.0.6.140"},
+		{"", "", "", "", "", "1", "192.0.2.1"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
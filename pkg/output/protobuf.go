@@ -0,0 +1,30 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	"nuclei-parse-enrich/pkg/enrichpb"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// RenderProtobuf writes the enrichments as a stream of EnrichInfo messages of enrichinfo.proto,
+// see enrichpb.ToProto, every message preceded by its length as varint. This is the delimited
+// format of the protobuf libraries, e.g. protodelim in Go and parseDelimitedFrom in Java.
+func RenderProtobuf(w io.Writer, infos []types.EnrichInfo) error {
+	writer := bufio.NewWriter(w)
+	for _, info := range infos {
+		if _, err := protodelim.MarshalTo(writer, enrichpb.ToProto(info)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
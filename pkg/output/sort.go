@@ -0,0 +1,192 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+const (
+	SortBySeverity = "severity"
+	SortByCountry  = "country"
+	SortByAsn      = "asn"
+	SortByIp       = "ip"
+	// SortByAbuseScore orders the records by how confident their abuse contacts are, see abuseScore
+	SortByAbuseScore = "abuse-score"
+)
+
+// severityRank orders nuclei severities from most to least severe
+var severityRank = map[string]int{
+	"critical": 0,
+	"high":     1,
+	"medium":   2,
+	"low":      3,
+	"info":     4,
+}
+
+// ParseSortKeys parses a comma separated list of sort keys, like "severity,country".
+func ParseSortKeys(s string) ([]string, error) {
+	var keys []string
+
+	for _, key := range strings.Split(s, ",") {
+		key = strings.ToLower(strings.TrimSpace(key))
+		switch key {
+		case SortBySeverity, SortByAbuseScore, SortByCountry, SortByAsn, SortByIp:
+			keys = append(keys, key)
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown sort key %q, expected one of %s, %s, %s, %s or %s", key, SortBySeverity, SortByAbuseScore, SortByCountry, SortByAsn, SortByIp)
+		}
+	}
+
+	return keys, nil
+}
+
+// SortMergeResults orders results by keys, most severe first for severity and most confident
+// abuse contacts first for abuse-score. Ties are broken by
+// IP address, template id, host and matched-at, so the order is deterministic.
+func SortMergeResults(results []types.MergeResult, keys []string) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+
+		for _, key := range keys {
+			if c := compareBy(key, a, b); c != 0 {
+				return c < 0
+			}
+		}
+
+		if c := compareIP(a.EnrichInfo.Ip, b.EnrichInfo.Ip); c != 0 {
+			return c < 0
+		}
+
+		if a.TemplateId != b.TemplateId {
+			return a.TemplateId < b.TemplateId
+		}
+		if a.Host != b.Host {
+			return a.Host < b.Host
+		}
+		return a.MatchedAt < b.MatchedAt
+	})
+}
+
+// SortEnrichments orders infos by IP address: numerically, IPv4 before IPv6 and values that are
+// not an IP address last, so the enrichments are written in the same order every run.
+func SortEnrichments(infos []types.EnrichInfo) {
+	sort.SliceStable(infos, func(i, j int) bool {
+		return compareIP(infos[i].Ip, infos[j].Ip) < 0
+	})
+}
+
+func compareBy(key string, a, b types.MergeResult) int {
+	switch key {
+	case SortBySeverity:
+		return compareInt(rankSeverity(a.Info.Severity), rankSeverity(b.Info.Severity))
+	case SortByAbuseScore:
+		return compareInt(abuseScore(b.EnrichInfo), abuseScore(a.EnrichInfo))
+	case SortByCountry:
+		return compareKnown(a.Country, b.Country)
+	case SortByAsn:
+		return compareASN(a.Asn, b.Asn)
+	case SortByIp:
+		return compareIP(a.EnrichInfo.Ip, b.EnrichInfo.Ip)
+	}
+	return 0
+}
+
+// abuseScore rates how confident the abuse contacts of info are to reach the holder of the IP
+// address: 3 for contacts from RipeSTAT, 2 from whois, 1 for other contacts such as the national
+// CERT alone and 0 without contacts.
+func abuseScore(info types.EnrichInfo) int {
+	if len(info.AbuseList()) == 0 {
+		return 0
+	}
+
+	score := 1
+	for _, source := range strings.Split(info.AbuseSource, ";") {
+		switch source {
+		case types.AbuseSourceRipeSTAT:
+			return 3
+		case types.AbuseSourceWhois:
+			score = 2
+		}
+	}
+	return score
+}
+
+func rankSeverity(severity string) int {
+	if rank, found := severityRank[strings.ToLower(severity)]; found {
+		return rank
+	}
+	return len(severityRank)
+}
+
+// compareKnown compares strings alphabetically, with empty and unknown values last.
+func compareKnown(a, b string) int {
+	aUnknown, bUnknown := a == "" || a == "unknown", b == "" || b == "unknown"
+	switch {
+	case aUnknown && bUnknown:
+		return 0
+	case aUnknown:
+		return 1
+	case bUnknown:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareASN(a, b string) int {
+	aNumber, aErr := asn.Parse(a)
+	bNumber, bErr := asn.Parse(b)
+	if aErr != nil || bErr != nil {
+		return compareKnown(a, b)
+	}
+
+	switch {
+	case aNumber < bNumber:
+		return -1
+	case aNumber > bNumber:
+		return 1
+	}
+	return 0
+}
+
+// compareIP compares IP addresses numerically, IPv4 before IPv6 and unparsable values last. The
+// notations of the same address, e.g. with and without brackets, are compared as strings.
+func compareIP(a, b string) int {
+	aAddr, aErr := netip.ParseAddr(strings.Trim(a, "[]"))
+	bAddr, bErr := netip.ParseAddr(strings.Trim(b, "[]"))
+
+	switch {
+	case aErr != nil && bErr != nil:
+		return strings.Compare(a, b)
+	case aErr != nil:
+		return 1
+	case bErr != nil:
+		return -1
+	}
+
+	if c := aAddr.Compare(bAddr); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,166 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// sortIPs are IP addresses in their sorted order: IPv4 numerically, then IPv6 numerically, then
+// the values that are not an IP address
+var sortIPs = []string{
+	"9.9.9.9",
+	"10.0.0.1",
+	"193.0.6.139",
+	"193.0.6.139",
+	"193.0.10.1",
+	"::1",
+	"::ffff:193.0.6.139",
+	"2001:67c:2e8:22::c100:68b",
+	"[2001:67c:2e8:22::c100:68b]",
+	"",
+	"not-an-ip",
+	"unknown",
+}
+
+func TestSortEnrichmentsDeterministic(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var want []byte
+	for i := 0; i < 100; i++ {
+		infos := make([]types.EnrichInfo, len(sortIPs))
+		for j, ipAddr := range sortIPs {
+			infos[j] = types.EnrichInfo{Ip: ipAddr, Asn: "3333"}
+		}
+		r.Shuffle(len(infos), func(i, j int) { infos[i], infos[j] = infos[j], infos[i] })
+
+		SortEnrichments(infos)
+		var ips []string
+		for _, info := range infos {
+			ips = append(ips, info.Ip)
+		}
+		if !reflect.DeepEqual(ips, sortIPs) {
+			t.Fatalf("sorted as %q, want %q", ips, sortIPs)
+		}
+
+		got, err := json.Marshal(infos)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want == nil {
+			want = got
+		} else if !bytes.Equal(got, want) {
+			t.Fatalf("shuffle %d sorted differently:\n%s\nwant\n%s", i, got, want)
+		}
+	}
+}
+
+func TestSortMergeResultsDeterministic(t *testing.T) {
+	var results []types.MergeResult
+	for _, ipAddr := range sortIPs {
+		for _, templateID := range []string{"b-template", "a-template"} {
+			for _, host := range []string{"https://example.com", "https://example.net"} {
+				var result types.MergeResult
+				result.EnrichInfo.Ip, result.Country, result.Asn = ipAddr, "NL", "3333"
+				result.TemplateId, result.Host = templateID, host
+				result.Info.Severity = "high"
+				results = append(results, result)
+			}
+		}
+	}
+
+	order := func(results []types.MergeResult) []string {
+		var lines []string
+		for _, result := range results {
+			lines = append(lines, result.EnrichInfo.Ip+" "+result.TemplateId+" "+result.Host)
+		}
+		return lines
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for _, keys := range [][]string{nil, {SortBySeverity}, {SortByAbuseScore}, {SortByCountry, SortByAsn}, {SortByIp}} {
+		var want []string
+		for i := 0; i < 100; i++ {
+			shuffled := append([]types.MergeResult(nil), results...)
+			r.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+			SortMergeResults(shuffled, keys)
+			got := order(shuffled)
+			if want == nil {
+				want = got
+			} else if !reflect.DeepEqual(got, want) {
+				t.Fatalf("sorting by %v, shuffle %d sorted as\n%q\nwant\n%q", keys, i, got, want)
+			}
+		}
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{"severity", []string{SortBySeverity}, false},
+		{"Severity, abuse-score", []string{SortBySeverity, SortByAbuseScore}, false},
+		{"country,asn,ip,", []string{SortByCountry, SortByAsn, SortByIp}, false},
+		{"abuse", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSortKeys(tt.s)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSortKeys(%q) = %q, %v, want %q", tt.s, got, err, tt.want)
+		}
+	}
+}
+
+func TestSortMergeResultsAbuseScore(t *testing.T) {
+	records := []struct {
+		ip, severity, abuse, source string
+	}{
+		{"193.0.6.1", "high", "", types.AbuseSourceNone},
+		{"193.0.6.2", "high", "abuse@example.net", types.AbuseSourceWhois},
+		{"193.0.6.3", "critical", "cert@ncsc.nl", types.AbuseSourceNone},
+		{"193.0.6.4", "high", "abuse@ripe.net", types.AbuseSourceRipeSTAT},
+		{"193.0.6.5", "critical", "abuse@ripe.net;abuse@example.net", types.AbuseSourceRipeSTAT + ";" + types.AbuseSourceWhois},
+		{"193.0.6.6", "high", "unknown", types.AbuseSourceError},
+		{"193.0.6.7", "high", "noc@example.net", types.AbuseSourceWhois},
+	}
+	var results []types.MergeResult
+	for _, record := range records {
+		var result types.MergeResult
+		result.EnrichInfo.Ip, result.Abuse, result.AbuseSource = record.ip, record.abuse, record.source
+		result.Info.Severity = record.severity
+		results = append(results, result)
+	}
+
+	tests := []struct {
+		keys []string
+		want []string
+	}{
+		// ties of the score by IP
+		{[]string{SortByAbuseScore}, []string{"193.0.6.4", "193.0.6.5", "193.0.6.2", "193.0.6.7", "193.0.6.3", "193.0.6.1", "193.0.6.6"}},
+		{[]string{SortBySeverity, SortByAbuseScore}, []string{"193.0.6.5", "193.0.6.3", "193.0.6.4", "193.0.6.2", "193.0.6.7", "193.0.6.1", "193.0.6.6"}},
+	}
+	for _, tt := range tests {
+		sorted := append([]types.MergeResult(nil), results...)
+		SortMergeResults(sorted, tt.keys)
+		var got []string
+		for _, result := range sorted {
+			got = append(got, result.EnrichInfo.Ip)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sorting by %v gave %q, want %q", tt.keys, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/country"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// stixNamespace is the namespace of the deterministic identifiers of STIX cyber-observable
+// objects, see section 2.9 of the STIX 2.1 specification
+var stixNamespace = [16]byte{0x00, 0xab, 0xed, 0xb4, 0xaa, 0x42, 0x46, 0x6c, 0x9c, 0x01, 0xfe, 0xd2, 0x33, 0x15, 0xa9, 0xb7}
+
+// stixTimestamp is the timestamp format of STIX, with millisecond precision
+const stixTimestamp = "2006-01-02T15:04:05.000Z"
+
+type stixBundle struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Objects []interface{} `json:"objects"`
+}
+
+type stixAddress struct {
+	Type        string `json:"type"`
+	SpecVersion string `json:"spec_version"`
+	ID          string `json:"id"`
+	Value       string `json:"value"`
+}
+
+type stixAutonomousSystem struct {
+	Type        string `json:"type"`
+	SpecVersion string `json:"spec_version"`
+	ID          string `json:"id"`
+	Number      uint32 `json:"number"`
+	Name        string `json:"name,omitempty"`
+}
+
+type stixLocation struct {
+	Type        string   `json:"type"`
+	SpecVersion string   `json:"spec_version"`
+	ID          string   `json:"id"`
+	Created     string   `json:"created"`
+	Modified    string   `json:"modified"`
+	Country     string   `json:"country,omitempty"`
+	City        string   `json:"city,omitempty"`
+	Latitude    *float64 `json:"latitude,omitempty"`
+	Longitude   *float64 `json:"longitude,omitempty"`
+}
+
+type stixRelationship struct {
+	Type             string `json:"type"`
+	SpecVersion      string `json:"spec_version"`
+	ID               string `json:"id"`
+	Created          string `json:"created"`
+	Modified         string `json:"modified"`
+	RelationshipType string `json:"relationship_type"`
+	SourceRef        string `json:"source_ref"`
+	TargetRef        string `json:"target_ref"`
+}
+
+// RenderSTIX writes the enrichments as a STIX 2.1 bundle: an ipv4-addr or ipv6-addr observable per
+// IP address, related to the autonomous-system announcing it ("belongs-to", named after the holder)
+// and to its location ("located-at"). Autonomous systems and locations shared by several addresses
+// are written once. Observables get the deterministic identifiers of the specification, so exports
+// of different runs refer to the same objects.
+func RenderSTIX(w io.Writer, infos []types.EnrichInfo) error {
+	now := time.Now().UTC().Format(stixTimestamp)
+
+	sorted := make([]types.EnrichInfo, len(infos))
+	copy(sorted, infos)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareIP(sorted[i].Ip, sorted[j].Ip) < 0
+	})
+
+	bundle := stixBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + randomUUID(),
+		Objects: []interface{}{},
+	}
+	written := make(map[string]bool)
+	add := func(id string, object interface{}) {
+		if !written[id] {
+			written[id] = true
+			bundle.Objects = append(bundle.Objects, object)
+		}
+	}
+	relate := func(relationshipType, source, target string) {
+		id := "relationship--" + stixID(relationshipType, source, target)
+		add(id, stixRelationship{
+			Type:             "relationship",
+			SpecVersion:      "2.1",
+			ID:               id,
+			Created:          now,
+			Modified:         now,
+			RelationshipType: relationshipType,
+			SourceRef:        source,
+			TargetRef:        target,
+		})
+	}
+
+	for _, info := range sorted {
+		addr, err := netip.ParseAddr(strings.Trim(info.Ip, "[]"))
+		if err != nil {
+			continue
+		}
+		addr = addr.Unmap().WithZone("")
+
+		addressType := "ipv6-addr"
+		if addr.Is4() {
+			addressType = "ipv4-addr"
+		}
+		addressID := addressType + "--" + stixID(map[string]string{"value": addr.String()})
+		add(addressID, stixAddress{Type: addressType, SpecVersion: "2.1", ID: addressID, Value: addr.String()})
+
+		if number, err := asn.Parse(info.Asn); err == nil {
+			asID := "autonomous-system--" + stixID(map[string]uint32{"number": number})
+			add(asID, stixAutonomousSystem{
+				Type:        "autonomous-system",
+				SpecVersion: "2.1",
+				ID:          asID,
+				Number:      number,
+				Name:        knownHolderName(info),
+			})
+			relate("belongs-to", addressID, asID)
+		}
+
+		if location, ok := stixLocationOf(info, now); ok {
+			add(location.ID, location)
+			relate("located-at", addressID, location.ID)
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(bundle); err != nil {
+		return fmt.Errorf("error writing STIX bundle: %v", err)
+	}
+	return nil
+}
+
+// knownHolderName returns the name of the holder without the country suffix, or nothing when unknown.
+func knownHolderName(info types.EnrichInfo) string {
+	name := info.HolderName
+	if name == "" {
+		name = info.Holder
+	}
+	if !isKnown(name) {
+		return ""
+	}
+	return name
+}
+
+// stixLocationOf returns the location of info, when its country or coordinates are known. The
+// identifier is derived from the location, so addresses at the same location share the object.
+func stixLocationOf(info types.EnrichInfo, now string) (stixLocation, bool) {
+	location := stixLocation{
+		Type:        "location",
+		SpecVersion: "2.1",
+		Created:     now,
+		Modified:    now,
+	}
+	location.Country = country.Normalize(info.Country)
+	if isKnown(info.City) {
+		location.City = info.City
+	}
+	if info.Latitude != 0 || info.Longitude != 0 {
+		latitude, longitude := info.Latitude, info.Longitude
+		location.Latitude, location.Longitude = &latitude, &longitude
+	}
+
+	// a location needs a country, region or coordinates
+	if location.Country == "" && location.Latitude == nil {
+		return stixLocation{}, false
+	}
+
+	location.ID = "location--" + stixID(location.Country, location.City, location.Latitude, location.Longitude)
+	return location, true
+}
+
+func isKnown(value string) bool {
+	switch value {
+	case "", "unknown", types.SourceUnavailable:
+		return false
+	}
+	return true
+}
+
+// stixID returns a version 5 UUID in the STIX namespace of the JSON encoding of values. For a
+// single map of the identifier contributing properties this is the identifier the specification
+// prescribes for observables.
+func stixID(values ...interface{}) string {
+	var value interface{} = values
+	if len(values) == 1 {
+		value = values[0]
+	}
+	data, _ := json.Marshal(value)
+
+	hash := sha1.New()
+	hash.Write(stixNamespace[:])
+	hash.Write(data)
+	sum := hash.Sum(nil)
+
+	var uuid [16]byte
+	copy(uuid[:], sum)
+	uuid[6] = uuid[6]&0x0f | 0x50
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return formatUUID(uuid)
+}
+
+// randomUUID returns a version 4 UUID.
+func randomUUID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return formatUUID(uuid)
+}
+
+func formatUUID(uuid [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
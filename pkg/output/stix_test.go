@@ -0,0 +1,134 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+var stixInfos = []types.EnrichInfo{
+	{Ip: "193.0.6.139", Asn: "3333", Holder: "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC), NL",
+		HolderName: "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", Country: "NL", City: "Amsterdam"},
+	{Ip: "2001:67c:2e8:22::c100:68b", Asn: "AS3333", Country: "nl", City: "Amsterdam"},
+	{Ip: "192.0.2.1", Asn: "unknown", Country: "unknown"},
+	{Ip: "not an IP"},
+}
+
+// stixObjects renders infos and decodes the objects of the bundle, by type.
+func stixObjects(t *testing.T, infos []types.EnrichInfo) (string, map[string][]map[string]interface{}) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := RenderSTIX(&buf, infos); err != nil {
+		t.Fatal(err)
+	}
+
+	var bundle struct {
+		Type    string                   `json:"type"`
+		ID      string                   `json:"id"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatalf("invalid JSON %s: %v", buf.Bytes(), err)
+	}
+	if bundle.Type != "bundle" {
+		t.Errorf("type %q, want a bundle", bundle.Type)
+	}
+
+	byType := make(map[string][]map[string]interface{})
+	for _, object := range bundle.Objects {
+		objectType, _ := object["type"].(string)
+		byType[objectType] = append(byType[objectType], object)
+	}
+	return bundle.ID, byType
+}
+
+var stixIDPattern = regexp.MustCompile(`^([a-z0-9-]+)--[0-9a-f]{8}-[0-9a-f]{4}-([45])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRenderSTIX(t *testing.T) {
+	bundleID, objects := stixObjects(t, stixInfos)
+	if m := stixIDPattern.FindStringSubmatch(bundleID); m == nil || m[1] != "bundle" || m[2] != "4" {
+		t.Errorf("bundle id %q, want a random UUID", bundleID)
+	}
+
+	ids := make(map[string]bool)
+	for objectType, list := range objects {
+		for _, object := range list {
+			id, _ := object["id"].(string)
+			// observables and the other objects here have deterministic identifiers
+			if m := stixIDPattern.FindStringSubmatch(id); m == nil || m[1] != objectType || m[2] != "5" {
+				t.Errorf("%s has id %q", objectType, id)
+			}
+			if object["spec_version"] != "2.1" {
+				t.Errorf("%s has spec_version %v", id, object["spec_version"])
+			}
+			if ids[id] {
+				t.Errorf("%s written twice", id)
+			}
+			ids[id] = true
+		}
+	}
+
+	count := func(objectType string) int { return len(objects[objectType]) }
+	if count("ipv4-addr") != 2 || count("ipv6-addr") != 1 || count("autonomous-system") != 1 || count("location") != 1 {
+		t.Errorf("wrote %d ipv4-addr, %d ipv6-addr, %d autonomous-system and %d location, want 2, 1, 1 and 1",
+			count("ipv4-addr"), count("ipv6-addr"), count("autonomous-system"), count("location"))
+	}
+	// both addresses of AS3333 belong to it and are located in Amsterdam
+	if count("relationship") != 4 {
+		t.Errorf("wrote %d relationships, want 4", count("relationship"))
+	}
+	for _, relationship := range objects["relationship"] {
+		source, _ := relationship["source_ref"].(string)
+		target, _ := relationship["target_ref"].(string)
+		if !ids[source] || !ids[target] {
+			t.Errorf("relationship %v refers to objects not in the bundle", relationship["id"])
+		}
+		switch relationship["relationship_type"] {
+		case "belongs-to":
+			if !strings.HasPrefix(target, "autonomous-system--") {
+				t.Errorf("belongs-to %s", target)
+			}
+		case "located-at":
+			if !strings.HasPrefix(target, "location--") {
+				t.Errorf("located-at %s", target)
+			}
+		default:
+			t.Errorf("unexpected relationship %v", relationship["relationship_type"])
+		}
+	}
+
+	as := objects["autonomous-system"][0]
+	if as["number"] != float64(3333) || as["name"] != "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)" {
+		t.Errorf("autonomous-system %v", as)
+	}
+	location := objects["location"][0]
+	if location["country"] != "NL" || location["city"] != "Amsterdam" {
+		t.Errorf("location %v", location)
+	}
+}
+
+func TestRenderSTIXDeterministicIDs(t *testing.T) {
+	// the identifier of an ipv4-addr is the one of the specification for its value
+	_, objects := stixObjects(t, []types.EnrichInfo{{Ip: "193.0.6.139"}})
+	if got, want := objects["ipv4-addr"][0]["id"], "ipv4-addr--"+stixID(map[string]string{"value": "193.0.6.139"}); got != want {
+		t.Errorf("ipv4-addr id %v, want %s", got, want)
+	}
+
+	// and doesn't depend on the other records of the export
+	_, all := stixObjects(t, stixInfos)
+	for _, address := range all["ipv4-addr"] {
+		if address["value"] == "193.0.6.139" && address["id"] != objects["ipv4-addr"][0]["id"] {
+			t.Errorf("ipv4-addr id %v in another export, want %v", address["id"], objects["ipv4-addr"][0]["id"])
+		}
+	}
+}
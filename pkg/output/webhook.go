@@ -0,0 +1,135 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DeliveryStats describes the outcome of delivering enrichment results to a webhook.
+type DeliveryStats struct {
+	Delivered int
+	Failed    int
+}
+
+// WebhookSink POSTs every enrichment result as JSON to a webhook. Deliveries failing with a
+// network error, 429 or 5xx are retried with the same Idempotency-Key header, so receivers can
+// drop the duplicates a retry of an already processed delivery causes.
+type WebhookSink struct {
+	URL        string
+	MaxRetries int
+	// MarshalOptions is the layout the results are posted in
+	MarshalOptions types.MarshalOptions
+	HTTPClient     *http.Client
+	Logger         logrus.FieldLogger
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL:        url,
+		MaxRetries: 3,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Logger:     logrus.StandardLogger(),
+	}
+}
+
+// IdempotencyKey returns the idempotency key of info, derived from its IP and enrichment time so
+// it is the same for every delivery attempt of the same result.
+func IdempotencyKey(info types.EnrichInfo) string {
+	sum := sha256.Sum256([]byte(info.Ip + "|" + info.EnrichedAt))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Write delivers records one by one. Failed deliveries are logged and counted, only a done ctx
+// stops the remaining deliveries.
+func (s *WebhookSink) Write(ctx context.Context, records []types.EnrichInfo) (DeliveryStats, error) {
+	var stats DeliveryStats
+
+	for _, record := range records {
+		if err := s.deliver(ctx, record); err != nil {
+			if ctx.Err() != nil {
+				stats.Failed += len(records) - stats.Delivered - stats.Failed
+				return stats, ctx.Err()
+			}
+			s.Logger.WithField("ip", record.Ip).Warnf("webhook: %v", err)
+			stats.Failed++
+			continue
+		}
+		stats.Delivered++
+	}
+
+	return stats, nil
+}
+
+func (s *WebhookSink) deliver(ctx context.Context, record types.EnrichInfo) error {
+	body, err := json.Marshal(s.MarshalOptions.EnrichInfo(record))
+	if err != nil {
+		return err
+	}
+	key := IdempotencyKey(record)
+
+	backoff := 500 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(ctx, body, key)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= s.MaxRetries {
+			return err
+		}
+
+		s.Logger.WithField("ip", record.Ip).Debugf("webhook: %v, retrying in %v", err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *WebhookSink) post(ctx context.Context, body []byte, key string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
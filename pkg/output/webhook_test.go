@@ -0,0 +1,115 @@
+package output
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// webhookReceiver is a webhook failing the first delivery of every record with status, recording
+// the idempotency keys of the deliveries by IP.
+type webhookReceiver struct {
+	mu     sync.Mutex
+	status int
+	keys   map[string][]string
+}
+
+func (h *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var record map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	ipAddr, _ := record["ip"].(string)
+	h.keys[ipAddr] = append(h.keys[ipAddr], r.Header.Get(IdempotencyKeyHeader))
+	if len(h.keys[ipAddr]) == 1 {
+		w.WriteHeader(h.status)
+	}
+}
+
+func newWebhookSink(t *testing.T, receiver *webhookReceiver) *WebhookSink {
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	sink := NewWebhookSink(server.URL)
+	sink.Logger = logger
+	return sink
+}
+
+func TestWebhookRetryIdempotencyKey(t *testing.T) {
+	receiver := &webhookReceiver{status: http.StatusServiceUnavailable, keys: make(map[string][]string)}
+	sink := newWebhookSink(t, receiver)
+
+	records := []types.EnrichInfo{
+		{Ip: "193.0.6.139", EnrichedAt: "2024-05-01T12:00:00Z"},
+		{Ip: "193.0.6.140", EnrichedAt: "2024-05-01T12:00:00Z"},
+	}
+	stats, err := sink.Write(context.Background(), records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (DeliveryStats{Delivered: 2}) {
+		t.Errorf("stats = %+v, want both delivered", stats)
+	}
+
+	for _, record := range records {
+		keys := receiver.keys[record.Ip]
+		if len(keys) != 2 {
+			t.Fatalf("%s delivered %d times, want a failed delivery and a retry", record.Ip, len(keys))
+		}
+		if keys[0] == "" || keys[0] != keys[1] || keys[0] != IdempotencyKey(record) {
+			t.Errorf("%s delivered with keys %q, want %s twice", record.Ip, keys, IdempotencyKey(record))
+		}
+	}
+	if receiver.keys["193.0.6.139"][0] == receiver.keys["193.0.6.140"][0] {
+		t.Error("different records share their idempotency key")
+	}
+}
+
+func TestWebhookNoRetry(t *testing.T) {
+	// the receiver rejecting a record isn't retried
+	receiver := &webhookReceiver{status: http.StatusBadRequest, keys: make(map[string][]string)}
+	sink := newWebhookSink(t, receiver)
+
+	stats, err := sink.Write(context.Background(), []types.EnrichInfo{{Ip: "193.0.6.139"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (DeliveryStats{Failed: 1}) || len(receiver.keys["193.0.6.139"]) != 1 {
+		t.Errorf("stats = %+v after %d deliveries, want a single failed one", stats, len(receiver.keys["193.0.6.139"]))
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	info := types.EnrichInfo{Ip: "193.0.6.139", EnrichedAt: "2024-05-01T12:00:00Z", Abuse: "abuse@ripe.net"}
+	updated := info
+	updated.Abuse = "noc@ripe.net"
+	if IdempotencyKey(info) != IdempotencyKey(updated) {
+		t.Error("the key depends on more than the IP and the enrichment time")
+	}
+	later := info
+	later.EnrichedAt = "2024-05-02T12:00:00Z"
+	if IdempotencyKey(info) == IdempotencyKey(later) {
+		t.Error("another enrichment of the IP has the same key")
+	}
+}
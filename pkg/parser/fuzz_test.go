@@ -0,0 +1,124 @@
+package parser
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// maxSeedSize leaves the huge corpus files out of the seed corpus, the fuzzer minimizes every new
+// interesting input and slows to a crawl on them
+const maxSeedSize = 64 << 10
+
+// addCorpusSeeds adds the files of testdata/corpus to the seed corpus of f.
+func addCorpusSeeds(f *testing.F) {
+	corpus, err := filepath.Glob(filepath.Join("testdata", "corpus", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range corpus {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if len(data) <= maxSeedSize {
+			f.Add(data)
+		}
+	}
+}
+
+// FuzzRecordReader reads records until the input ends or is malformed: every record comes either
+// without error or with the fields of an unexpected type left out, and reading always ends.
+func FuzzRecordReader(f *testing.F) {
+	addCorpusSeeds(f)
+	f.Add([]byte(`[`))
+	f.Add([]byte(`[{"ip": "193.0.6.139"},]`))
+	f.Add([]byte(`{"ip": ["193.0.6.139"]} null [] "x"`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, passthrough := range []bool{false, true} {
+			records := NewRecordReader(bytes.NewReader(data), passthrough)
+			// every record takes at least one byte of input
+			for n := 0; ; n++ {
+				if n > len(data)+1 {
+					t.Fatalf("read %d records from %d bytes", n, len(data))
+				}
+				record, err := records.Read()
+				if err == io.EOF {
+					break
+				}
+				var typeErr *json.UnmarshalTypeError
+				if err != nil && !errors.As(err, &typeErr) {
+					var syntaxErr *json.SyntaxError
+					if !errors.As(err, &syntaxErr) && err != io.ErrUnexpectedEOF {
+						t.Fatalf("record %d: unexpected error %T: %v", n, err, err)
+					}
+					break
+				}
+				checkRecordIPs(t, record.Ip, record.AdditionalIPs)
+			}
+		}
+	})
+}
+
+// FuzzNormalizeRecordIP derives the IP addresses of records with any ip and host field.
+func FuzzNormalizeRecordIP(f *testing.F) {
+	seeds := []struct{ ip, host string }{
+		{"193.0.6.139", "https://www.ripe.net"},
+		{"193.0.6.142,193.0.6.141, 193.0.6.142", "https://193.0.6.141"},
+		{"2001:067c:02e8:0022:0000:0000:c100:068b", "https://[2001:67c:2e8:22::c100:68b]:8443"},
+		{"", "[fe80::1%eth0]:80"},
+		{"::ffff:193.0.6.139", "http://[::ffff:193.0.6.139]/"},
+		{"ripe.net", "ripe.net:443"},
+		{",,", "http://[::1"},
+	}
+	for _, seed := range seeds {
+		f.Add(seed.ip, seed.host)
+	}
+
+	f.Fuzz(func(t *testing.T, ip, host string) {
+		normalized := types.NucleiJsonRecord{Ip: ip, Host: host}
+		NormalizeRecordIP(&normalized)
+		if normalized.Ip != ip {
+			if _, err := netip.ParseAddr(normalized.Ip); err != nil {
+				t.Fatalf("ip %q, host %q: derived invalid IP address %q", ip, host, normalized.Ip)
+			}
+		}
+		checkRecordIPs(t, normalized.Ip, normalized.AdditionalIPs)
+
+		// normalizing is idempotent
+		again := normalized
+		NormalizeRecordIP(&again)
+		if again.Ip != normalized.Ip {
+			t.Fatalf("ip %q, host %q: normalized again to %q instead of %q", ip, host, again.Ip, normalized.Ip)
+		}
+	})
+}
+
+// checkRecordIPs fails t when the additional IP addresses of a record are invalid or repeated.
+func checkRecordIPs(t *testing.T, ipAddr string, additional []string) {
+	t.Helper()
+	seen := map[string]bool{ipAddr: true}
+	for _, other := range additional {
+		if _, err := netip.ParseAddr(other); err != nil {
+			t.Fatalf("invalid additional IP address %q", other)
+		}
+		if seen[other] {
+			t.Fatalf("additional IP address %q repeated, primary %q", other, ipAddr)
+		}
+		seen[other] = true
+	}
+}
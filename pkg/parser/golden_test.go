@@ -0,0 +1,96 @@
+package parser
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/golden with the current output")
+
+// goldenResult is what the parser makes of a corpus file, written as the golden file.
+type goldenResult struct {
+	Records []types.NucleiJsonRecord `json:"records"`
+	// IPs are the unique IP addresses to enrich
+	IPs   []string `json:"ips"`
+	Stats IPStats  `json:"stats"`
+	Error string   `json:"error,omitempty"`
+}
+
+// parseFile parses the nuclei output in path like the command line tool does.
+func parseFile(t *testing.T, path string) goldenResult {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	p := (&Parser{}).NewParser(file)
+	p.Logger = logger
+
+	var result goldenResult
+	if err := p.ProcessNucleiScan(); err != nil {
+		result.Error = err.Error()
+	}
+	result.Records = p.ScanRecords
+	result.IPs, result.Stats = p.UniqueIPs()
+	return result
+}
+
+// TestGolden parses every file of testdata/corpus and compares the result to the file of the same
+// name in testdata/golden. Run with -update to write the golden files of new or changed corpus
+// files, and review the diff.
+func TestGolden(t *testing.T) {
+	corpus, err := filepath.Glob(filepath.Join("testdata", "corpus", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corpus) == 0 {
+		t.Fatal("no corpus files in testdata/corpus")
+	}
+
+	for _, path := range corpus {
+		name := filepath.Base(path)
+		t.Run(name, func(t *testing.T) {
+			got, err := json.MarshalIndent(parseFile(t, path), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "golden", strings.TrimSuffix(name, filepath.Ext(name))+".json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run go test ./pkg/parser -run TestGolden -update to create it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("parsing %s changed, compare with %s or run with -update when intended:\n%s", path, golden, got)
+			}
+		})
+	}
+}
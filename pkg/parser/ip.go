@@ -0,0 +1,153 @@
+package parser
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// NormalizeRecordIP derives the IP address of record from its ip and host fields. The ip field of
+// nuclei may hold a host name, or several comma separated addresses (interactsh templates), and
+// the host field a URL or host:port with the address. All IP addresses found are collected in
+// order of appearance: the primary, stored in Ip, is the address of the host field when the ip
+// field lists it or lists none, and the first address of the ip field otherwise. The others are
+// stored in AdditionalIPs. When no IP address is found the record is left as is, see RecordHostname.
+func NormalizeRecordIP(record *types.NucleiJsonRecord) {
+	var fromIP []string
+	for _, token := range strings.Split(record.Ip, ",") {
+		if addr, _ := parseAddrToken(token); addr.IsValid() {
+			fromIP = appendUnique(fromIP, addr.String())
+		}
+	}
+	hostAddr, _ := parseAddrToken(record.Host)
+
+	var addrs []string
+	switch {
+	case hostAddr.IsValid() && (len(fromIP) == 0 || contains(fromIP, hostAddr.String())):
+		addrs = appendUnique([]string{hostAddr.String()}, fromIP...)
+	case len(fromIP) > 0:
+		addrs = fromIP
+		if hostAddr.IsValid() {
+			addrs = appendUnique(addrs, hostAddr.String())
+		}
+	default:
+		return
+	}
+
+	// an address written differently is kept as written
+	if enricher.CanonicalIPAs(record.Ip, true) != addrs[0] {
+		record.Ip = addrs[0]
+	}
+	record.AdditionalIPs = nil
+	if len(addrs) > 1 {
+		record.AdditionalIPs = addrs[1:]
+	}
+}
+
+// RecordHostname returns the host name of a record without IP address, from its host field or
+// else its ip field, or nothing when the record has an IP address or neither field has a host name.
+func RecordHostname(record types.NucleiJsonRecord) string {
+	if addr, _ := parseAddrToken(record.Ip); addr.IsValid() {
+		return ""
+	}
+	for _, value := range []string{record.Host, record.Ip} {
+		if _, hostname := parseAddrToken(value); hostname != "" {
+			return hostname
+		}
+	}
+	return ""
+}
+
+// ResolveRecordHost looks up the IP addresses of the host name of record, see RecordHostname, with
+// resolver, net.DefaultResolver when nil. The lowest address, IPv4 before IPv6, is stored in Ip and
+// the others in AdditionalIPs. It returns whether the record was resolved.
+func ResolveRecordHost(ctx context.Context, resolver *net.Resolver, record *types.NucleiJsonRecord) (bool, error) {
+	hostname := RecordHostname(*record)
+	if hostname == "" {
+		return false, nil
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	found, err := resolver.LookupNetIP(ctx, "ip", hostname)
+	if err != nil {
+		return false, err
+	}
+	if len(found) == 0 {
+		return false, nil
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Unmap().Less(found[j].Unmap())
+	})
+	var addrs []string
+	for _, addr := range found {
+		addrs = appendUnique(addrs, addr.Unmap().String())
+	}
+
+	record.Ip = addrs[0]
+	record.AdditionalIPs = nil
+	if len(addrs) > 1 {
+		record.AdditionalIPs = addrs[1:]
+	}
+	return true, nil
+}
+
+// parseAddrToken returns the IP address of token, a bare or bracketed address, host:port or URL,
+// or else its host name.
+func parseAddrToken(token string) (netip.Addr, string) {
+	host := strings.TrimSpace(token)
+	if host == "" {
+		return netip.Addr{}, ""
+	}
+
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return netip.Addr{}, ""
+		}
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr, ""
+	}
+	if strings.ContainsAny(host, " ,/?#@") || !strings.Contains(host, ".") {
+		return netip.Addr{}, ""
+	}
+	return netip.Addr{}, strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+func appendUnique(values []string, add ...string) []string {
+	for _, value := range add {
+		if !contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
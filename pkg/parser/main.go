@@ -8,14 +8,20 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"net"
+	"net/netip"
+	"nuclei-parse-enrich/pkg/bogon"
 	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/output"
+	"nuclei-parse-enrich/pkg/scope"
 	"nuclei-parse-enrich/pkg/types"
 	"os"
-	"sync"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -23,10 +29,23 @@ import (
 type Parser struct {
 	*json.Decoder
 	*os.File
+	// Input is read for the scan records instead of File when set, InputName names it in the logs
+	Input        io.Reader
+	InputName    string
 	Enrichment   []types.EnrichInfo
 	SimpleIPs    []types.SimpleIPRecord
 	ScanRecords  []types.NucleiJsonRecord
 	MergeResults []types.MergeResult
+	// IPStats describes the IP addresses of the last EnrichScanRecords
+	IPStats IPStats
+	// Logger is used instead of the standard logger when set, and passed on to the enricher
+	Logger logrus.FieldLogger
+	// Passthrough keeps the original JSON object of every record, see DecodeRecord
+	Passthrough bool
+	// MarshalOptions is the layout the JSON outputs write the enrichment in
+	MarshalOptions types.MarshalOptions
+	// MappedAsIPv6 enriches IPv6 addresses embedding an IPv4 address as IPv6, see enricher.WithMappedAsIPv6
+	MappedAsIPv6 bool
 }
 
 func (p *Parser) NewSimpleParser(file *os.File) *Parser {
@@ -42,123 +61,412 @@ func (p *Parser) NewParser(file *os.File) *Parser {
 	}
 }
 
-func (p *Parser) ProcessSimpleScan() {
-	scanner := bufio.NewScanner(p.File)
+// NewReaderParser returns a parser of the scan records read from r, name names r in the logs.
+func NewReaderParser(r io.Reader, name string) *Parser {
+	return &Parser{
+		Decoder:   json.NewDecoder(r),
+		Input:     r,
+		InputName: name,
+	}
+}
+
+// input returns the reader of the scan records and its name.
+func (p *Parser) input() (io.Reader, string) {
+	if p.Input != nil {
+		return p.Input, p.InputName
+	}
+	return p.File, p.File.Name()
+}
+
+func (p *Parser) log() logrus.FieldLogger {
+	if p.Logger == nil {
+		return logrus.StandardLogger()
+	}
+	return p.Logger
+}
+
+func (p *Parser) ProcessSimpleScan() error {
+	input, _ := p.input()
+	scanner := bufio.NewScanner(input)
 	for scanner.Scan() {
-		if err := scanner.Err(); err != nil {
-			log.Fatal(err)
-		}
 		var record types.NucleiJsonRecord
 		record.Ip = scanner.Text()
 		p.ScanRecords = append(p.ScanRecords, record)
-		if err := scanner.Err(); err != nil {
-			log.Fatal(err)
-		}
 	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading ip file: %v", err)
+	}
+
+	return nil
 }
 
-func (p *Parser) ProcessNucleiScan() {
-	logrus.Debug("parser: ProcessNucleiScan - started parsing: ", p.File.Name())
+// ProcessNucleiScan decodes the nuclei JSON records, as JSON Lines or a JSON array, see
+// RecordReader. Records with fields of an unexpected type are kept with the fields that could be
+// decoded, malformed JSON stops the parsing with an error.
+func (p *Parser) ProcessNucleiScan() error {
+	input, name := p.input()
+	p.log().Debug("parser: ProcessNucleiScan - started parsing: ", name)
+	records := NewRecordReader(input, p.Passthrough)
 	for {
-		var record types.NucleiJsonRecord
-		err := p.Decode(&record)
+		record, err := records.Read()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			logrus.Debug(err)
+			if _, ok := err.(*json.UnmarshalTypeError); !ok {
+				return fmt.Errorf("error parsing record %d: %v", len(p.ScanRecords)+1, err)
+			}
+			p.log().Debug(err)
 		}
 		p.ScanRecords = append(p.ScanRecords, record)
 	}
 
-	logrus.Debug("parser: ProcessNucleiScan - ended parsing ", len(p.ScanRecords), " records")
+	p.log().Debug("parser: ProcessNucleiScan - ended parsing ", len(p.ScanRecords), " records")
+	return nil
 }
 
-func (p *Parser) EnrichScanRecords() {
+// DecodeRecord decodes the next nuclei record of decoder, with its IP address derived from the
+// ip and host fields, see NormalizeRecordIP. With passthrough the original JSON object is kept in
+// the Raw field of the record. Like json.Decoder.Decode it returns the record with the fields that
+// could be decoded along with a *json.UnmarshalTypeError.
+func DecodeRecord(decoder *json.Decoder, passthrough bool) (types.NucleiJsonRecord, error) {
+	var record types.NucleiJsonRecord
+	if !passthrough {
+		err := decoder.Decode(&record)
+		NormalizeRecordIP(&record)
+		return record, err
+	}
+
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return record, err
+	}
+	err := json.Unmarshal(raw, &record)
+	if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] == '{' {
+		record.Raw = raw
+	}
+	NormalizeRecordIP(&record)
+	return record, err
+}
+
+// ResolveHosts resolves the host names of the scan records without IP address, see
+// ResolveRecordHost, looking up every host name once. It returns the number of records resolved
+// and of host names that could not be resolved.
+func (p *Parser) ResolveHosts(ctx context.Context, resolver *net.Resolver) (resolved int, failed int) {
+	results := make(map[string]*types.NucleiJsonRecord)
+	for i := range p.ScanRecords {
+		record := &p.ScanRecords[i]
+		hostname := RecordHostname(*record)
+		if hostname == "" {
+			continue
+		}
+
+		result, seen := results[hostname]
+		if !seen {
+			ok, err := ResolveRecordHost(ctx, resolver, record)
+			if err != nil {
+				p.log().Debugf("resolving host name %s: %v", hostname, err)
+			}
+			if !ok {
+				failed++
+				results[hostname] = nil
+				continue
+			}
+			results[hostname] = record
+			resolved++
+			continue
+		}
+
+		if result != nil {
+			record.Ip, record.AdditionalIPs = result.Ip, result.AdditionalIPs
+			resolved++
+		}
+	}
+	return resolved, failed
+}
+
+// IPStats counts the IP addresses of the scan records by how UniqueIPs treated them. InvalidIPs
+// lists the first few values that are not an IP address.
+type IPStats struct {
+	Records    int
+	Empty      int
+	Unique     int
+	Bogon      int
+	Invalid    int
+	InvalidIPs []string
+	// Unmapped counts the unique IPv6 addresses embedding an IPv4 address enriched as that address,
+	// see enricher.CanonicalIPAs
+	Unmapped int
+}
+
+// ScopeStats counts the unique IP addresses dropped by ApplyScope.
+type ScopeStats struct {
+	Excluded    int
+	NotIncluded int
+}
+
+// ApplyScope drops the scan records of IP addresses that are out of scope, so they are never
+// enriched nor written.
+func (p *Parser) ApplyScope(s *scope.Scope) ScopeStats {
+	var stats ScopeStats
+	dropped := make(map[string]struct{})
+
+	kept := p.ScanRecords[:0]
+	for _, record := range p.ScanRecords {
+		ipAddr := enricher.CanonicalIPAs(record.Ip, p.MappedAsIPv6)
+		verdict := s.Check(ipAddr)
+		if verdict == scope.InScope {
+			kept = append(kept, record)
+			continue
+		}
+
+		if _, seen := dropped[ipAddr]; seen {
+			continue
+		}
+		dropped[ipAddr] = struct{}{}
+
+		if verdict == scope.Excluded {
+			stats.Excluded++
+		} else {
+			stats.NotIncluded++
+		}
+		p.log().Debugf("dropping out of scope IP address %s", record.Ip)
+	}
+	p.ScanRecords = kept
+
+	return stats
+}
+
+// UniqueIPs returns the unique IP addresses of the scan records in order of appearance, in their
+// canonical form so differently written IPv6 addresses are enriched once. Private and reserved
+// addresses are kept, the enricher marks them skipped-private without looking them up, and counted
+// as Bogon. Values that are not an IP address are left out and counted as invalid, their records
+// are written without enrichment by MergeScanEnrichment.
+func (p *Parser) UniqueIPs() ([]string, IPStats) {
+	stats := IPStats{Records: len(p.ScanRecords)}
 	uniqueIPAddresses := make(map[string]struct{})
+	var ipAddrs []string
 
 	for i, record := range p.ScanRecords {
 		if record.Ip == "" {
-			logrus.Warnf("scan record %d contains empty IP address, skipping: %+v", i, record)
+			p.log().Warnf("scan record %d contains empty IP address, not enriching it: %+v", i, record)
+			stats.Empty++
 			continue
 		}
 
 		if record.Ip[0] == '[' {
-			logrus.Debugf("scan record %d contains ipv6 address:: %+v", i, record)
+			p.log().Debugf("scan record %d contains ipv6 address:: %+v", i, record)
 		}
 
-		uniqueIPAddresses[record.Ip] = struct{}{}
+		ipAddr := enricher.CanonicalIPAs(record.Ip, p.MappedAsIPv6)
+		if _, seen := uniqueIPAddresses[ipAddr]; seen {
+			continue
+		}
+		uniqueIPAddresses[ipAddr] = struct{}{}
+		stats.Unique++
+		if unmapped(record.Ip, ipAddr) {
+			p.log().Debugf("enriching %s as IPv4 address %s", record.Ip, ipAddr)
+			stats.Unmapped++
+		}
+
+		addr, err := netip.ParseAddr(ipAddr)
+		if err != nil {
+			p.log().Debugf("not enriching invalid IP address %q", record.Ip)
+			stats.Invalid++
+			if len(stats.InvalidIPs) < enricher.MaxInvalidExamples {
+				stats.InvalidIPs = append(stats.InvalidIPs, record.Ip)
+			}
+			continue
+		}
+		if bogon.IsBogon(addr) {
+			p.log().Debugf("not looking up private or reserved IP address %s", record.Ip)
+			stats.Bogon++
+		}
+
+		ipAddrs = append(ipAddrs, ipAddr)
 	}
 
-	nucleiEnricher := enricher.NewEnricher()
+	return ipAddrs, stats
+}
 
-	limitCh := make(chan bool, 8)
-	resultCh := make(chan types.EnrichInfo, 3)
+// unmapped reports whether CanonicalIPAs turned the IPv6 address rawIPAddr into the IPv4 address ipAddr.
+func unmapped(rawIPAddr, ipAddr string) bool {
+	addr, err := netip.ParseAddr(ipAddr)
+	return err == nil && addr.Is4() && strings.Contains(rawIPAddr, ":")
+}
 
-	var wg sync.WaitGroup
+// EnrichScanRecords enriches the unique IP addresses of the scan records, except the ones already
+// in Enrichment (e.g. loaded from a checkpoint). When ctx is done before all IP addresses are
+// enriched, the partial enrichment is kept and the context error returned.
+func (p *Parser) EnrichScanRecords(ctx context.Context, opts ...enricher.Option) (enricher.Summary, error) {
+	ipAddrs, stats := p.UniqueIPs()
+	p.IPStats = stats
+	if stats.Bogon > 0 {
+		p.log().Infof("not looking up %d private or reserved IP addresses", stats.Bogon)
+	}
+	if stats.Unmapped > 0 {
+		p.log().Infof("enriching %d IPv6 addresses embedding an IPv4 address as IPv4", stats.Unmapped)
+	}
+	if stats.Invalid > 0 {
+		p.log().Warnf("not enriching %d invalid IP addresses, e.g. %q", stats.Invalid, stats.InvalidIPs)
+	}
 
-	go func() {
-		for enrichResult := range resultCh {
-			p.Enrichment = append(p.Enrichment, enrichResult)
-			wg.Done()
+	if len(p.Enrichment) > 0 {
+		enriched := make(map[string]struct{}, len(p.Enrichment))
+		for _, enrichment := range p.Enrichment {
+			enriched[enrichment.Ip] = struct{}{}
 		}
-	}()
 
-	for ipAddr := range uniqueIPAddresses {
-		logrus.Debug("enriching IP: ", ipAddr)
-		wg.Add(1)
-		ipAddr := ipAddr
-		limitCh <- true
-		go func() {
-			wg.Add(1) // gets marked as Done in resultCh loop
-			resultCh <- nucleiEnricher.EnrichIP(ipAddr)
-			<-limitCh
-			wg.Done()
-		}()
+		remaining := make([]string, 0, len(ipAddrs))
+		for _, ipAddr := range ipAddrs {
+			if _, found := enriched[ipAddr]; !found {
+				remaining = append(remaining, ipAddr)
+			}
+		}
+		p.log().Infof("skipping %d IP addresses enriched before", len(ipAddrs)-len(remaining))
+		ipAddrs = remaining
 	}
-	wg.Wait()
-	close(resultCh)
-	close(limitCh)
+
+	opts = append([]enricher.Option{enricher.WithLogger(p.log()), enricher.WithMappedAsIPv6(p.MappedAsIPv6)}, opts...)
+	nucleiEnricher := enricher.NewEnricher(opts...)
+
+	enrichment, summary, err := nucleiEnricher.EnrichIPs(ctx, ipAddrs)
+	p.Enrichment = append(p.Enrichment, enrichment...)
+	// the workers finish in any order
+	output.SortEnrichments(p.Enrichment)
+
+	return summary, err
 }
 
-func (p *Parser) MergeScanEnrichment() {
-	logrus.Debug("parser: MergeScanEnrichment - start")
+// MergeScanEnrichment pairs every scan record with the enrichment of its IP address. The records
+// of values that are not an IP address, including empty ones, get an empty enrichment with an Ip
+// error, see enricher.InvalidIPInfo, so their findings are written as well.
+func (p *Parser) MergeScanEnrichment() error {
+	p.log().Debug("parser: MergeScanEnrichment - start")
 	var mergeResult = types.MergeResult{}
 
-	if len(p.Enrichment) < 1 {
-		logrus.Debug("Length of ips in scan is ", len(p.ScanRecords))
-		logrus.Fatal("No enrichment info to merge")
-	}
-
 	for _, record := range p.ScanRecords {
+		ipAddr := enricher.CanonicalIPAs(record.Ip, p.MappedAsIPv6)
+		merged := false
 		for _, enrichment := range p.Enrichment {
-			if record.Ip == enrichment.Ip {
+			if ipAddr == enrichment.Ip {
 				mergeResult.EnrichInfo = enrichment
 				mergeResult.NucleiJsonRecord = record
 				p.MergeResults = append(p.MergeResults, mergeResult)
+				merged = true
 			}
 		}
+		if !merged && enricher.ValidateIP(record.Ip) != nil {
+			mergeResult.EnrichInfo = enricher.InvalidIPInfo(record.Ip)
+			mergeResult.NucleiJsonRecord = record
+			p.MergeResults = append(p.MergeResults, mergeResult)
+		}
 	}
 
-	logrus.Debug("parser: MergeScanEnrichment - merged ", len(p.MergeResults), " records")
+	if len(p.MergeResults) < 1 {
+		p.log().Debug("Length of ips in scan is ", len(p.ScanRecords))
+		return fmt.Errorf("no enrichment info to merge")
+	}
+
+	p.log().Debug("parser: MergeScanEnrichment - merged ", len(p.MergeResults), " records")
+	return nil
 }
 
+// WriteOutput writes the merge results as a JSON object keyed by IP address, see output.RenderJSONByIP.
 func (p *Parser) WriteOutput(outputFile *os.File) error {
+	if err := output.RenderJSONByIP(outputFile, p.MergeResults, p.MarshalOptions); err != nil {
+		return err
+	}
 
-	mergeResultsMap := make(map[string]types.MergeResult)
+	p.log().Debug("parser: WriteOutput - ended")
+	return nil
+}
 
-	for _, mergeResult := range p.MergeResults {
-		mergeResultsMap[mergeResult.NucleiJsonRecord.Ip] = mergeResult
-	}
+// WriteSortedOutput writes the merge results as a JSON array ordered by sortKeys, see output.SortMergeResults.
+func (p *Parser) WriteSortedOutput(outputFile *os.File, sortKeys []string) error {
 	encoder := json.NewEncoder(outputFile)
 	encoder.SetIndent("", "  ")
 
-	err := encoder.Encode(mergeResultsMap)
+	if err := encoder.Encode(p.MarshalOptions.MergeResults(p.sortedMergeResults(sortKeys))); err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+
+	p.log().Debug("parser: WriteSortedOutput - ended")
+	return nil
+}
+
+// WriteJSONLines writes the merge results ordered by sortKeys as one JSON object per line.
+func (p *Parser) WriteJSONLines(outputFile *os.File, sortKeys []string) error {
+	encoder := json.NewEncoder(outputFile)
+	for _, mergeResult := range p.sortedMergeResults(sortKeys) {
+		if err := encoder.Encode(p.MarshalOptions.MergeResult(mergeResult)); err != nil {
+			return fmt.Errorf("error writing output: %v", err)
+		}
+	}
+	return nil
+}
 
+// WriteCSV writes the merge results ordered by sortKeys as CSV, see output.RenderCSV.
+func (p *Parser) WriteCSV(outputFile *os.File, sortKeys []string) error {
+	return output.RenderCSV(outputFile, p.sortedMergeResults(sortKeys))
+}
+
+// WriteLogfmt writes the merge results ordered by sortKeys as logfmt lines, see output.RenderLogfmt.
+func (p *Parser) WriteLogfmt(outputFile *os.File, sortKeys []string) error {
+	return output.RenderLogfmt(outputFile, p.sortedMergeResults(sortKeys))
+}
+
+// WriteHTML writes the merge results ordered by sortKeys as an HTML report, see output.RenderHTML.
+func (p *Parser) WriteHTML(outputFile *os.File, sortKeys []string) error {
+	return output.RenderHTML(outputFile, p.sortedMergeResults(sortKeys))
+}
+
+// WriteFailedIPs writes the IP addresses whose enrichment failed, see output.RenderFailedIPs.
+func (p *Parser) WriteFailedIPs(outputFile *os.File, withReasons bool) error {
+	return output.RenderFailedIPs(outputFile, p.Enrichment, withReasons)
+}
+
+// WriteSTIX writes the enrichments as a STIX 2.1 bundle, see output.RenderSTIX.
+func (p *Parser) WriteSTIX(outputFile *os.File) error {
+	return output.RenderSTIX(outputFile, p.Enrichment)
+}
+
+// WriteMISP writes the enrichments as a MISP event named info, see output.RenderMISP.
+func (p *Parser) WriteMISP(outputFile *os.File, info string) error {
+	return output.RenderMISP(outputFile, p.Enrichment, info)
+}
+
+// WritePrefixCSV writes the enrichments collapsed by prefix, see output.RenderPrefixCSV.
+func (p *Parser) WritePrefixCSV(outputFile *os.File) error {
+	return output.RenderPrefixCSV(outputFile, p.Enrichment)
+}
+
+// WriteProtobuf writes the enrichments as delimited protobuf messages, see output.RenderProtobuf.
+func (p *Parser) WriteProtobuf(outputFile *os.File) error {
+	return output.RenderProtobuf(outputFile, p.Enrichment)
+}
+
+// sortedMergeResults returns a copy of the merge results ordered by sortKeys, or in the order
+// they were merged when there are none.
+func (p *Parser) sortedMergeResults(sortKeys []string) []types.MergeResult {
+	mergeResults := make([]types.MergeResult, len(p.MergeResults))
+	copy(mergeResults, p.MergeResults)
+	if len(sortKeys) > 0 {
+		output.SortMergeResults(mergeResults, sortKeys)
+	}
+	return mergeResults
+}
+
+// WriteGeoJSON writes the merge results as a GeoJSON FeatureCollection, see output.RenderGeoJSON.
+func (p *Parser) WriteGeoJSON(outputFile *os.File, opts output.GeoJSONOptions) error {
+	omitted, err := output.RenderGeoJSON(outputFile, p.MergeResults, opts)
 	if err != nil {
-		return fmt.Errorf("error writing output: %v", err)
+		return err
 	}
 
-	logrus.Debug("parser: WriteOutput - ended")
+	if omitted > 0 {
+		p.log().Infof("left %d records without a location out of the GeoJSON output", omitted)
+	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package parser
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"unicode"
+
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// RecordReader reads the nuclei records of a JSON Lines stream, as written by nuclei -jsonl, or of
+// a JSON array, as written by the -json-export of nuclei v2. The format is told by the first
+// character of the input, which is not read before the first record is.
+type RecordReader struct {
+	r           *bufio.Reader
+	decoder     *json.Decoder
+	passthrough bool
+	// inArray is set while reading the elements of a JSON array, arrayDone once it is closed
+	inArray   bool
+	arrayDone bool
+}
+
+// NewRecordReader returns a reader of the records of r, see DecodeRecord for passthrough.
+func NewRecordReader(r io.Reader, passthrough bool) *RecordReader {
+	return &RecordReader{r: bufio.NewReader(r), passthrough: passthrough}
+}
+
+// Read returns the next record, or io.EOF after the last one. Like DecodeRecord it returns the
+// record with the fields that could be decoded along with a *json.UnmarshalTypeError, other
+// errors mean the input is malformed and no more records can be read.
+func (rr *RecordReader) Read() (types.NucleiJsonRecord, error) {
+	if rr.decoder == nil {
+		if err := rr.start(); err != nil {
+			return types.NucleiJsonRecord{}, err
+		}
+	}
+
+	if rr.arrayDone {
+		return types.NucleiJsonRecord{}, io.EOF
+	}
+	if rr.inArray && !rr.decoder.More() {
+		// the closing bracket, anything after the array is ignored
+		if _, err := rr.decoder.Token(); err != nil {
+			return types.NucleiJsonRecord{}, err
+		}
+		rr.inArray, rr.arrayDone = false, true
+		return types.NucleiJsonRecord{}, io.EOF
+	}
+	return DecodeRecord(rr.decoder, rr.passthrough)
+}
+
+// start detects the format of the input and sets up the decoder.
+func (rr *RecordReader) start() error {
+	for {
+		c, _, err := rr.r.ReadRune()
+		if err == io.EOF {
+			rr.decoder = json.NewDecoder(rr.r)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if unicode.IsSpace(c) || c == '\uFEFF' {
+			continue
+		}
+		if err := rr.r.UnreadRune(); err != nil {
+			return err
+		}
+		break
+	}
+
+	rr.decoder = json.NewDecoder(rr.r)
+	if first, _ := rr.r.Peek(1); len(first) == 1 && first[0] == '[' {
+		if _, err := rr.decoder.Token(); err != nil {
+			return err
+		}
+		rr.inArray = true
+	}
+	return nil
+}
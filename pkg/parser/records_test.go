@@ -0,0 +1,76 @@
+package parser
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecordReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		ips   []string
+		// fails is set when reading ends with an error instead of io.EOF
+		fails bool
+	}{
+		{"empty", "", nil, false},
+		{"whitespace", " \n\t\n", nil, false},
+		{"empty array", "[]", nil, false},
+		{"json lines", `{"ip": "193.0.6.139"}` + "\n" + `{"ip": "193.0.6.140"}` + "\n", []string{"193.0.6.139", "193.0.6.140"}, false},
+		{"byte order mark", "\uFEFF" + `{"ip": "193.0.6.139"}`, []string{"193.0.6.139"}, false},
+		{"array", "\n [{\"ip\": \"193.0.6.139\"},\n {\"ip\": \"193.0.6.140\"}]\n", []string{"193.0.6.139", "193.0.6.140"}, false},
+		{"after the array", `[{"ip": "193.0.6.139"}] {"ip": "193.0.6.140"}`, []string{"193.0.6.139"}, false},
+		{"unclosed array", `[{"ip": "193.0.6.139"}`, []string{"193.0.6.139"}, true},
+		{"missing comma", `[{"ip": "193.0.6.139"} {"ip": "193.0.6.140"}]`, []string{"193.0.6.139"}, true},
+		{"type error", `{"ip": 193}` + "\n" + `{"ip": "193.0.6.140"}`, []string{"", "193.0.6.140"}, false},
+		{"type error in array", `[{"ip": "193.0.6.139", "matcher-status": "yes"}]`, []string{"193.0.6.139"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records := NewRecordReader(strings.NewReader(tt.input), false)
+			var ips []string
+			var err error
+			for {
+				record, readErr := records.Read()
+				if _, ok := readErr.(*json.UnmarshalTypeError); readErr != nil && !ok {
+					err = readErr
+					break
+				}
+				ips = append(ips, record.Ip)
+			}
+
+			if fails := err != io.EOF; fails != tt.fails {
+				t.Errorf("reading ended with %v, want an error: %v", err, tt.fails)
+			}
+			if !reflect.DeepEqual(ips, tt.ips) {
+				t.Errorf("read IPs %q, want %q", ips, tt.ips)
+			}
+		})
+	}
+}
+
+func TestRecordReaderPassthrough(t *testing.T) {
+	element := `{"ip": "193.0.6.139", "response": "kept as is"}`
+	records := NewRecordReader(strings.NewReader("["+element+"]"), true)
+
+	record, err := records.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(record.Raw) != element {
+		t.Errorf("Raw = %s, want the array element %s", record.Raw, element)
+	}
+	if _, err := records.Read(); err != io.EOF {
+		t.Errorf("second Read error = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,205 @@
+package peeringdb
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/ratelimit"
+)
+
+const (
+	API_URL = "https://www.peeringdb.com/api/"
+
+	// PeeringDB allows 20 anonymous requests per minute, and 40 with an API key
+	AnonymousRequestsPerMinute = 20
+	KeyedRequestsPerMinute     = 40
+
+	// DefaultMaxResponseSize bounds a single response, the largest networks are present at a few
+	// hundred exchanges and facilities
+	DefaultMaxResponseSize = 4 << 20
+)
+
+// Facility is a colocation facility a network is present at.
+type Facility struct {
+	ID      int    `json:"fac_id"`
+	Name    string `json:"name"`
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+// Presence is where a network peers: the internet exchanges and facilities it is present at.
+type Presence struct {
+	// IXPs are the ids of the exchanges, a network with several ports at an exchange counts once
+	IXPs       []int
+	Facilities []Facility
+}
+
+type Client struct {
+	// Key is the PeeringDB API key, anonymous requests are rate limited more strictly without
+	Key     string
+	BaseURL string
+	// MaxResponseSize bounds a response in bytes, zero means DefaultMaxResponseSize
+	MaxResponseSize int64
+
+	httpClient *http.Client
+	limiter    *ratelimit.Limiter
+
+	mu       sync.Mutex
+	presence map[string]*presenceEntry
+}
+
+type presenceEntry struct {
+	done     chan struct{}
+	presence Presence
+	err      error
+}
+
+func NewPeeringDBClient(key string) *Client {
+	perMinute := AnonymousRequestsPerMinute
+	if key != "" {
+		perMinute = KeyedRequestsPerMinute
+	}
+	return &Client{
+		Key:        key,
+		BaseURL:    API_URL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		limiter:    ratelimit.NewLimiter(time.Minute / time.Duration(perMinute)),
+		presence:   make(map[string]*presenceEntry),
+	}
+}
+
+// GetPresence returns the exchanges and facilities the network with AS number asNumber is present
+// at. The presence of every AS is looked up once, concurrent lookups of an AS wait for the first
+// one and failed lookups are not kept.
+func (c *Client) GetPresence(ctx context.Context, asNumber string) (Presence, error) {
+	number, err := asn.Parse(asNumber)
+	if err != nil {
+		return Presence{}, fmt.Errorf("peeringdb: %v", err)
+	}
+	key := asn.Format(number)
+
+	c.mu.Lock()
+	entry, found := c.presence[key]
+	if !found {
+		entry = &presenceEntry{done: make(chan struct{})}
+		c.presence[key] = entry
+	}
+	c.mu.Unlock()
+
+	if found {
+		select {
+		case <-ctx.Done():
+			return Presence{}, ctx.Err()
+		case <-entry.done:
+			return entry.presence, entry.err
+		}
+	}
+
+	entry.presence, entry.err = c.lookupPresence(ctx, key)
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.presence, key)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+
+	return entry.presence, entry.err
+}
+
+func (c *Client) lookupPresence(ctx context.Context, asNumber string) (Presence, error) {
+	var ixlans []struct {
+		IXID int `json:"ix_id"`
+	}
+	if err := c.get(ctx, "netixlan", url.Values{"asn": {asNumber}}, &ixlans); err != nil {
+		return Presence{}, err
+	}
+
+	var facilities []Facility
+	if err := c.get(ctx, "netfac", url.Values{"local_asn": {asNumber}}, &facilities); err != nil {
+		return Presence{}, err
+	}
+
+	var presence Presence
+	seen := make(map[int]bool)
+	for _, ixlan := range ixlans {
+		if !seen[ixlan.IXID] {
+			seen[ixlan.IXID] = true
+			presence.IXPs = append(presence.IXPs, ixlan.IXID)
+		}
+	}
+	sort.Ints(presence.IXPs)
+
+	sort.Slice(facilities, func(i, j int) bool {
+		return facilities[i].ID < facilities[j].ID
+	})
+	presence.Facilities = facilities
+
+	return presence, nil
+}
+
+func (c *Client) get(ctx context.Context, object string, query url.Values, out interface{}) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+object+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.Key != "" {
+		req.Header.Set("Authorization", "Api-Key "+c.Key)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("peeringdb: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("peeringdb: rate limited on %s, retry after %q", object, resp.Header.Get("Retry-After"))
+	}
+
+	limit := c.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return fmt.Errorf("peeringdb: %v", err)
+	}
+	if int64(len(body)) > limit {
+		return fmt.Errorf("peeringdb: %s response larger than %d bytes", object, limit)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("peeringdb: request %s failed (status %d)", object, resp.StatusCode)
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("peeringdb: failed to unmarshal %s: %v", object, err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("peeringdb: failed to unmarshal %s: %v", object, err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package peeringdb
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// peeringDBResponses are the responses of the fake PeeringDB API by path and query
+var peeringDBResponses = map[string]string{
+	// AS3333 has two ports at AMS-IX (ix 26), its facilities are listed out of order
+	"/netixlan?asn=3333": `{"data": [{"ix_id": 26, "asn": 3333}, {"ix_id": 18, "asn": 3333}, {"ix_id": 26, "asn": 3333}]}`,
+	"/netfac?local_asn=3333": `{"data": [
+		{"fac_id": 74, "name": "Equinix AM3 - Amsterdam, Science Park", "city": "Amsterdam", "country": "NL", "local_asn": 3333},
+		{"fac_id": 18, "name": "Digital Realty Amsterdam AMS1", "city": "Amsterdam", "country": "NL", "local_asn": 3333}]}`,
+	"/netixlan?asn=64496":     `{"data": []}`,
+	"/netfac?local_asn=64496": `{"data": []}`,
+	"/netixlan?asn=64497":     `{"data": [{"ix_id": 1}]}`,
+	"/netfac?local_asn=64497": `{"data": "not a list"}`,
+}
+
+// newPeeringDBServer returns a fake PeeringDB API serving peeringDBResponses to requests with key,
+// or anonymous ones when key is empty, the client requesting it and the number of requests by path
+// and query.
+func newPeeringDBServer(t *testing.T, key string) (*Client, func(string) int) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorization := r.Header.Get("Authorization"); (key == "" && authorization != "") || (key != "" && authorization != "Api-Key "+key) {
+			http.Error(w, `{"meta": {"error": "Invalid API key"}}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("asn") == "429" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		request := r.URL.Path + "?" + r.URL.RawQuery
+		mu.Lock()
+		requests[request]++
+		mu.Unlock()
+		body, found := peeringDBResponses[request]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewPeeringDBClient(key)
+	client.BaseURL = server.URL + "/"
+	client.limiter = nil
+	return client, func(request string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests[request]
+	}
+}
+
+func TestGetPresence(t *testing.T) {
+	client, requests := newPeeringDBServer(t, "test-key")
+
+	want := Presence{
+		IXPs: []int{18, 26},
+		Facilities: []Facility{
+			{ID: 18, Name: "Digital Realty Amsterdam AMS1", City: "Amsterdam", Country: "NL"},
+			{ID: 74, Name: "Equinix AM3 - Amsterdam, Science Park", City: "Amsterdam", Country: "NL"},
+		},
+	}
+	for _, asNumber := range []string{"3333", "AS3333", "as3333"} {
+		presence, err := client.GetPresence(context.Background(), asNumber)
+		if err != nil {
+			t.Fatalf("GetPresence(%s): %v", asNumber, err)
+		}
+		if !reflect.DeepEqual(presence, want) {
+			t.Errorf("GetPresence(%s) = %+v, want %+v", asNumber, presence, want)
+		}
+	}
+	// every AS is looked up once, however it is written
+	if n := requests("/netixlan?asn=3333"); n != 1 {
+		t.Errorf("requested the exchanges %d times", n)
+	}
+
+	presence, err := client.GetPresence(context.Background(), "64496")
+	if err != nil || len(presence.IXPs) != 0 || len(presence.Facilities) != 0 {
+		t.Errorf("GetPresence of an AS without presence = %+v, %v", presence, err)
+	}
+
+	if _, err := client.GetPresence(context.Background(), "429"); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("GetPresence when rate limited returned %v", err)
+	}
+	if _, err := client.GetPresence(context.Background(), "not an AS"); err == nil {
+		t.Error("GetPresence of an invalid AS number succeeded")
+	}
+
+	// failed lookups are not kept
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetPresence(context.Background(), "64497"); err == nil || !strings.Contains(err.Error(), "unmarshal netfac") {
+			t.Errorf("GetPresence of a malformed response returned %v", err)
+		}
+	}
+	if n := requests("/netfac?local_asn=64497"); n != 2 {
+		t.Errorf("requested a failed lookup %d times, want 2", n)
+	}
+}
+
+func TestGetPresenceKey(t *testing.T) {
+	client, _ := newPeeringDBServer(t, "test-key")
+	client.Key = "wrong-key"
+	if _, err := client.GetPresence(context.Background(), "3333"); err == nil || !strings.Contains(err.Error(), "status 401") {
+		t.Errorf("GetPresence with a wrong key returned %v", err)
+	}
+
+	// anonymous requests are sent without Authorization
+	anonymous, _ := newPeeringDBServer(t, "")
+	if _, err := anonymous.GetPresence(context.Background(), "3333"); err != nil {
+		t.Errorf("anonymous GetPresence: %v", err)
+	}
+}
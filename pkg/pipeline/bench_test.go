@@ -0,0 +1,165 @@
+package pipeline
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/output"
+	"nuclei-parse-enrich/pkg/parser"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// The benchmarks run without network against ripestattest, e.g.
+//
+//	go test ./pkg/pipeline -run XXX -bench . -benchmem
+//
+// Their numbers are only comparable on the same machine, they are meant to catch changes of an
+// order of magnitude.
+
+const (
+	benchRecords = 100000
+	benchIPs     = 1000
+)
+
+var benchWorkers = []int{1, 4, 8, 16}
+
+func BenchmarkParse(b *testing.B) {
+	records := nucleiRecords(benchRecords, benchIPs)
+	b.SetBytes(int64(len(records)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		scanParser, err := Parse(context.Background(), Config{Input: strings.NewReader(records), Logger: discardLogger()})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(scanParser.ScanRecords) != benchRecords {
+			b.Fatalf("parsed %d records, want %d", len(scanParser.ScanRecords), benchRecords)
+		}
+	}
+	b.ReportMetric(float64(benchRecords*b.N)/b.Elapsed().Seconds(), "records/s")
+}
+
+// BenchmarkRun enriches benchIPs IP addresses and writes them as JSON, at several worker counts.
+func BenchmarkRun(b *testing.B) {
+	server := newRipeStat(b)
+	records := nucleiRecords(benchIPs, benchIPs)
+
+	for _, workers := range benchWorkers {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				cfg := pipeConfig(b, server, "")
+				cfg.Input = strings.NewReader(records)
+				cfg.InputFormat = FormatNuclei
+				cfg.Workers = workers
+				cfg.Force = true
+				cfg.Outputs = []output.Target{{Format: output.FormatJSON, Path: filepath.Join(b.TempDir(), "output.json")}}
+				b.StartTimer()
+
+				summary, err := Run(context.Background(), cfg)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if summary.Enriched != benchIPs {
+					b.Fatalf("enriched %d IPs, want %d", summary.Enriched, benchIPs)
+				}
+			}
+			b.ReportMetric(float64(benchIPs*b.N)/b.Elapsed().Seconds(), "IPs/s")
+		})
+	}
+}
+
+// BenchmarkPipeline streams benchIPs records of as many IP addresses, at several worker counts.
+func BenchmarkPipeline(b *testing.B) {
+	server := newRipeStat(b)
+	records := nucleiRecords(benchIPs, benchIPs)
+	discard := SinkFunc(func(types.MergeResult) error { return nil })
+
+	for _, workers := range benchWorkers {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				cfg := pipeConfig(b, server, "")
+				cfg.Input = strings.NewReader(records)
+				cfg.InputFormat = FormatNuclei
+				cfg.Workers = workers
+				b.StartTimer()
+
+				summary, err := (&Pipeline{Config: cfg, Sink: discard}).Run(context.Background())
+				if err != nil {
+					b.Fatal(err)
+				}
+				if summary.Total != benchIPs {
+					b.Fatalf("wrote %d records, want %d", summary.Total, benchIPs)
+				}
+			}
+			b.ReportMetric(float64(benchIPs*b.N)/b.Elapsed().Seconds(), "records/s")
+		})
+	}
+}
+
+// BenchmarkWrite writes the enriched records of benchRecords findings in the main output formats.
+func BenchmarkWrite(b *testing.B) {
+	scanParser, err := Parse(context.Background(), Config{Input: strings.NewReader(nucleiRecords(benchRecords, benchIPs)), Logger: discardLogger()})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ipAddrs, _ := scanParser.UniqueIPs()
+	for _, ipAddr := range ipAddrs {
+		scanParser.Enrichment = append(scanParser.Enrichment, types.EnrichInfo{
+			Ip:          ipAddr,
+			AbuseSource: types.AbuseSourceRipeSTAT,
+			Abuse:       "abuse@ripe.net",
+			Prefix:      "193.0.0.0/21",
+			Asn:         "3333",
+			Holder:      "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)",
+			Country:     "NL",
+			City:        "Amsterdam",
+		})
+	}
+	if err := scanParser.MergeScanEnrichment(); err != nil {
+		b.Fatal(err)
+	}
+
+	writers := []struct {
+		format string
+		write  func(p *parser.Parser, f *os.File) error
+	}{
+		{output.FormatJSON, func(p *parser.Parser, f *os.File) error { return p.WriteOutput(f) }},
+		{output.FormatJSONL, func(p *parser.Parser, f *os.File) error { return p.WriteJSONLines(f, nil) }},
+		{output.FormatCSV, func(p *parser.Parser, f *os.File) error { return p.WriteCSV(f, nil) }},
+	}
+	for _, writer := range writers {
+		b.Run(writer.format, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				f, err := os.Create(filepath.Join(b.TempDir(), "output"))
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				if err := writer.write(scanParser, f); err != nil {
+					b.Fatal(err)
+				}
+				f.Close()
+			}
+			b.ReportMetric(float64(benchRecords*b.N)/b.Elapsed().Seconds(), "records/s")
+		})
+	}
+}
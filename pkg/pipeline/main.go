@@ -0,0 +1,721 @@
+package pipeline
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"nuclei-parse-enrich/pkg/annotate"
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/cache"
+	"nuclei-parse-enrich/pkg/checkpoint"
+	"nuclei-parse-enrich/pkg/cloud"
+	"nuclei-parse-enrich/pkg/contact"
+	"nuclei-parse-enrich/pkg/csirt"
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/geofeed"
+	"nuclei-parse-enrich/pkg/irr"
+	"nuclei-parse-enrich/pkg/output"
+	"nuclei-parse-enrich/pkg/parser"
+	"nuclei-parse-enrich/pkg/peeringdb"
+	"nuclei-parse-enrich/pkg/radar"
+	"nuclei-parse-enrich/pkg/rdns"
+	"nuclei-parse-enrich/pkg/scope"
+	"nuclei-parse-enrich/pkg/tlscert"
+	"nuclei-parse-enrich/pkg/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Format is the format of the input of a run.
+type Format int
+
+const (
+	// FormatNuclei is the JSON output of nuclei
+	FormatNuclei Format = iota
+	// FormatIPList is a file with one IP address per line
+	FormatIPList
+)
+
+// Config describes a run: parse the input, drop out-of-scope IP addresses, enrich the rest and
+// write the outputs. The zero value of every optional field disables the feature, sources are
+// passed as ready to use clients so a Config can be built without touching global state.
+type Config struct {
+	// Input holds the scan records, e.g. os.Stdin or an opened file. InputName names it in the
+	// logs, the name of the file when empty. Pipeline.Run interrupts a read waiting for more input
+	// when the run stops only for inputs with a SetReadDeadline method, like files and pipes
+	Input       io.Reader
+	InputName   string
+	InputFormat Format
+	// Scope drops the out-of-scope IP addresses before enrichment, nil keeps all
+	Scope *scope.Scope
+
+	// Workers is the number of IP addresses enriched concurrently, zero means enricher.DefaultWorkers
+	Workers int
+	// AdaptiveWorkers starts with this many concurrent IP addresses and adapts up to Workers to
+	// the RipeSTAT rate limiting when set
+	AdaptiveWorkers int
+	// JobBuffer and ResultBuffer size the channels of the worker pool, see enricher.WithChannelBuffers
+	JobBuffer    int
+	ResultBuffer int
+	// Timeout stops the enrichment, the IP addresses enriched by then are still written
+	Timeout         time.Duration
+	RipeStatTimeout time.Duration
+	// SourceApp identifies the requests to RipeSTAT, empty means ripestat.DefaultSourceApp
+	SourceApp string
+	// RipeStatURL replaces ripestat.DATA_URL when set, see enricher.WithRipeStatBaseURL
+	RipeStatURL string
+	// RipeStatHints records the query time of the RipeSTAT data and caches for as long as
+	// RipeSTAT allows, see enricher.WithRipeStatHints
+	RipeStatHints bool
+	WhoisTimeout  time.Duration
+	// WhoisBreakerThreshold and WhoisBreakerCooldown configure the whois circuit breaker, see
+	// enricher.WithWhoisCircuitBreaker. Zero means the default, a negative threshold disables it.
+	WhoisBreakerThreshold int
+	WhoisBreakerCooldown  time.Duration
+	// WhoisServers are asked in turn when a whois lookup is refused or finds nothing
+	WhoisServers []string
+	// MaxResponseSize bounds a single RipeSTAT or whois response in bytes, zero means the default
+	MaxResponseSize    int64
+	IPTimeout          time.Duration
+	SlowQueryThreshold time.Duration
+
+	NoWhois bool
+	// RIRs and WhoisRIRs limit the enrichment and the whois fallback to the IP addresses of these
+	// RIRs, see enricher.WithRIRs and enricher.WithWhoisRIRs
+	RIRs              []string
+	WhoisRIRs         []string
+	VerifyASN         bool
+	RejectPrivateASN  bool
+	RoleContactsOnly  bool
+	ContactClassifier *contact.Classifier
+	IRR               *irr.Client
+	ReverseDNS        *rdns.Hinter
+	RegistryHandles   bool
+	Registration      bool
+	CoveringPrefix    bool
+	AbuseTo           bool
+	PrefixAbuse       bool
+	// UnknownPlaceholder writes "unknown" in the fields that could not be determined instead of leaving them empty
+	UnknownPlaceholder bool
+	// AsOf looks up who held every IP address at this time in the RIPE database history when set
+	AsOf time.Time
+	// ASNClassifier records the category of the AS of every IP address when set
+	ASNClassifier *asn.Classifier
+	// NationalCERTs adds the national CERT of the country of every IP address to its abuse contacts when set
+	NationalCERTs *csirt.Routing
+	// CloudRanges is used as loaded, Run doesn't refresh it
+	CloudRanges *cloud.Ranges
+	// TLSCerts gets the HTTPS targets of the scan records registered before enrichment
+	TLSCerts  *tlscert.Client
+	Annotator *annotate.Annotator
+	Geofeed   *geofeed.Feed
+	Radar     *radar.Client
+	// Fallback fills in what RipeSTAT failed to look up, see enricher.WithFallbackProviders
+	Fallback []enricher.FallbackProvider
+	// PeeringDB records the exchanges and facilities of the AS of every IP address when set
+	PeeringDB *peeringdb.Client
+	// Cache is used but not closed by Run
+	Cache *cache.Cache
+
+	Checkpoint         string
+	CheckpointEvery    int
+	CheckpointInterval time.Duration
+	// CheckpointRemove removes the checkpoint once the enrichment completes
+	CheckpointRemove bool
+
+	// Progress is called with the progress of the enrichment
+	Progress func(enricher.Progress)
+
+	// Passthrough keeps every field of the nuclei records, the JSON outputs write the original
+	// records with the enrichment under types.PassthroughKey
+	Passthrough bool
+	// ResolveHosts looks up the IP addresses of the records with a host name but no IP address,
+	// see parser.ResolveRecordHost
+	ResolveHosts bool
+	// Outputs are the files the enriched records are written to, each in its own format. A run
+	// writing at least one of them succeeds, see Summary.FailedOutputs.
+	Outputs []output.Target
+	// Force overwrites existing output files
+	Force                  bool
+	SortKeys               []string
+	GeoJSONCountryFallback bool
+	// FailedReasons adds the unresolved fields and errors to the failed output
+	FailedReasons bool
+	// MISPEventInfo names the event of a MISP output, a name with the date when empty
+	MISPEventInfo       string
+	Webhook             string
+	Elasticsearch       string
+	ElasticsearchIndex  string
+	ElasticsearchAPIKey string
+	// Notify is a Slack or Microsoft Teams incoming webhook posted a summary of the run to
+	Notify string
+	// NotifyFormat is the message format of Notify, see output.NotifyAuto
+	NotifyFormat string
+
+	// LegacyFieldNames writes the enrichment with the field names of schema version 1, see
+	// types.MarshalOptions
+	LegacyFieldNames bool
+	// MappedAsIPv6 enriches IPv6 addresses embedding an IPv4 address as IPv6, see
+	// enricher.WithMappedAsIPv6
+	MappedAsIPv6 bool
+
+	// Logger is used instead of the standard logger when set
+	Logger logrus.FieldLogger
+}
+
+// Summary describes a finished run.
+type Summary struct {
+	enricher.Summary
+	// Parsed is the number of in-scope scan records
+	Parsed  int
+	IPStats parser.IPStats
+	// Outputs lists the files written
+	Outputs []string
+	// FailedOutputs lists the files that could not be written
+	FailedOutputs []string
+}
+
+// Stage is the part of a run an Error occurred in.
+type Stage int
+
+const (
+	StageConfig Stage = iota
+	StageInput
+	// StageEnrich errors mean the enrichment was cut short, the outputs hold the IP addresses
+	// enriched so far
+	StageEnrich
+	StageOutput
+)
+
+// Error is returned by Run, Stage tells which part of the run failed. The messages read like
+// "parsing input: ...".
+type Error struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (cfg *Config) marshalOptions() types.MarshalOptions {
+	return types.MarshalOptions{LegacyFieldNames: cfg.LegacyFieldNames}
+}
+
+func (cfg *Config) log() logrus.FieldLogger {
+	if cfg.Logger == nil {
+		return logrus.StandardLogger()
+	}
+	return cfg.Logger
+}
+
+// inputName returns the name of the input in the logs.
+func (cfg Config) inputName() string {
+	if cfg.InputName != "" {
+		return cfg.InputName
+	}
+	if named, ok := cfg.Input.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+	return "input"
+}
+
+// Parse reads the scan records of cfg.Input, resolves their host names when cfg.ResolveHosts is
+// set and drops the out-of-scope ones.
+func Parse(ctx context.Context, cfg Config) (*parser.Parser, error) {
+	if cfg.Input == nil {
+		return nil, &Error{StageConfig, errors.New("checking config: no input")}
+	}
+
+	var scanParser *parser.Parser
+	var err error
+	if cfg.InputFormat == FormatIPList {
+		scanParser = parser.NewReaderParser(cfg.Input, cfg.inputName())
+		scanParser.Logger = cfg.log()
+		scanParser.MarshalOptions = cfg.marshalOptions()
+		scanParser.MappedAsIPv6 = cfg.MappedAsIPv6
+		err = scanParser.ProcessSimpleScan()
+	} else {
+		scanParser = parser.NewReaderParser(cfg.Input, cfg.inputName())
+		scanParser.Logger = cfg.log()
+		scanParser.MarshalOptions = cfg.marshalOptions()
+		scanParser.MappedAsIPv6 = cfg.MappedAsIPv6
+		scanParser.Passthrough = cfg.Passthrough
+		err = scanParser.ProcessNucleiScan()
+	}
+	if err != nil {
+		return nil, &Error{StageInput, fmt.Errorf("parsing input: %v", err)}
+	}
+
+	if cfg.ResolveHosts {
+		resolved, failed := scanParser.ResolveHosts(ctx, nil)
+		cfg.log().Infof("Resolved the host names of %d records, %d host names could not be resolved", resolved, failed)
+	}
+
+	if cfg.Scope != nil {
+		stats := scanParser.ApplyScope(cfg.Scope)
+		cfg.log().Infof("Dropped %d excluded IPs and %d IPs outside the include list", stats.Excluded, stats.NotIncluded)
+	}
+
+	return scanParser, nil
+}
+
+// Run parses, enriches and writes as configured by cfg, like the command line tool does. When the
+// enrichment is cut short because ctx is done or cfg.Timeout passed, the IP addresses enriched so
+// far are still written and a StageEnrich Error is returned. Lookups that failed for some IP
+// addresses are not an error, see Summary.Failed.
+func Run(ctx context.Context, cfg Config) (Summary, error) {
+	var summary Summary
+	log := cfg.log()
+
+	if len(cfg.Outputs) == 0 {
+		return summary, &Error{StageConfig, errors.New("checking config: no output")}
+	}
+	if cfg.Checkpoint != "" && cfg.CheckpointEvery < 0 {
+		return summary, &Error{StageConfig, fmt.Errorf("checking config: invalid checkpoint interval of %d IPs", cfg.CheckpointEvery)}
+	}
+
+	scanParser, err := Parse(ctx, cfg)
+	if err != nil {
+		return summary, err
+	}
+	summary.Parsed = len(scanParser.ScanRecords)
+
+	enricherOptions := cfg.enricherOptions(scanParser)
+
+	var checkpointWriter *checkpoint.Writer
+	if cfg.Checkpoint != "" {
+		resumed, err := checkpoint.Load(cfg.Checkpoint)
+		if err != nil {
+			return summary, &Error{StageInput, fmt.Errorf("loading checkpoint: %v", err)}
+		}
+		if len(resumed) > 0 {
+			kept := resumable(scanParser, resumed)
+			log.Infof("Resuming from checkpoint %s with %d enriched IPs, %d of them in the input", cfg.Checkpoint, len(resumed), len(kept))
+			scanParser.Enrichment = append(scanParser.Enrichment, kept...)
+		}
+
+		checkpointWriter, err = checkpoint.Open(cfg.Checkpoint)
+		if err != nil {
+			return summary, &Error{StageOutput, fmt.Errorf("opening checkpoint: %v", err)}
+		}
+		if cfg.CheckpointEvery > 0 {
+			checkpointWriter.FlushEvery = cfg.CheckpointEvery
+		}
+		if cfg.CheckpointInterval > 0 {
+			checkpointWriter.FlushInterval = cfg.CheckpointInterval
+		}
+		enricherOptions = append(enricherOptions, enricher.WithResultHook(func(info types.EnrichInfo) {
+			// failed lookups are left out, so a resumed run retries them
+			if !complete(info) {
+				return
+			}
+			if err := checkpointWriter.Add(info); err != nil {
+				log.Warnf("Error writing checkpoint: %v", err)
+			}
+		}))
+	}
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	enrichSummary, enrichErr := scanParser.EnrichScanRecords(ctx, enricherOptions...)
+	summary.Summary = enrichSummary
+	summary.IPStats = scanParser.IPStats
+	if errors.Is(enrichErr, context.Canceled) {
+		log.Warnf("Enrichment interrupted, writing the %d IPs enriched so far", enrichSummary.Enriched)
+	} else if enrichErr != nil {
+		log.Warnf("Enrichment incomplete: %v", enrichErr)
+	}
+	logSummary(log, enrichSummary, cfg.Cache != nil)
+
+	if checkpointWriter != nil {
+		if err := checkpointWriter.Close(); err != nil {
+			log.Errorf("Error writing checkpoint: %v", err)
+		} else if cfg.CheckpointRemove && enrichErr == nil {
+			if err := os.Remove(cfg.Checkpoint); err != nil {
+				log.Warnf("Error removing checkpoint: %v", err)
+			}
+		}
+	}
+
+	if err := scanParser.MergeScanEnrichment(); err != nil && enrichErr == nil {
+		return summary, &Error{StageInput, fmt.Errorf("merging enrichment: %v", err)}
+	}
+
+	// every output gets written even when another one fails, the enrichment is costly to redo
+	var outputErr error
+	for _, target := range cfg.Outputs {
+		if err := writeOutput(scanParser, target, cfg); err != nil {
+			outputErr = fmt.Errorf("writing %s output %s: %v", target.Format, target.Path, err)
+			if len(cfg.Outputs) > 1 {
+				log.Errorf("Error %v", outputErr)
+			}
+			summary.FailedOutputs = append(summary.FailedOutputs, target.Path)
+			continue
+		}
+		log.Infof("Wrote %d records as %s to %s", len(scanParser.MergeResults), target.Format, target.Path)
+		summary.Outputs = append(summary.Outputs, target.Path)
+	}
+	if cfg.Notify != "" {
+		report := output.NewRunReport(scanParser.MergeResults, scanParser.Enrichment)
+		report.Outputs, report.FailedOutputs = summary.Outputs, summary.FailedOutputs
+		report.Duration = enrichSummary.Duration
+		notifyRun(log, report, cfg.Notify, cfg.NotifyFormat)
+	}
+	if len(summary.Outputs) == 0 {
+		if len(cfg.Outputs) > 1 {
+			outputErr = errors.New("writing output: none of the outputs could be written")
+		}
+		return summary, &Error{StageOutput, outputErr}
+	}
+
+	if cfg.Webhook != "" {
+		deliverEnrichment(log, scanParser.Enrichment, cfg.Webhook, cfg.marshalOptions())
+	}
+
+	if cfg.Elasticsearch != "" {
+		indexEnrichment(log, scanParser.Enrichment, cfg.Elasticsearch, cfg.ElasticsearchIndex, cfg.ElasticsearchAPIKey, cfg.marshalOptions())
+	}
+
+	if enrichErr != nil {
+		return summary, &Error{StageEnrich, enrichErr}
+	}
+	return summary, nil
+}
+
+// resumable returns the results of a checkpoint to resume scanParser with: the complete ones of IP
+// addresses in its scan records. A checkpoint of another input, or written by a version that
+// recorded failed lookups, must not add records to the output or keep IP addresses from being retried.
+func resumable(scanParser *parser.Parser, resumed []types.EnrichInfo) []types.EnrichInfo {
+	input := make(map[string]struct{}, len(scanParser.ScanRecords))
+	for _, record := range scanParser.ScanRecords {
+		input[enricher.CanonicalIPAs(record.Ip, scanParser.MappedAsIPv6)] = struct{}{}
+	}
+
+	var kept []types.EnrichInfo
+	for _, info := range resumed {
+		if _, found := input[info.Ip]; found && complete(info) {
+			kept = append(kept, info)
+		}
+	}
+	return kept
+}
+
+// complete reports whether no lookup of info failed, neither with an error nor because RipeSTAT
+// was unavailable.
+func complete(info types.EnrichInfo) bool {
+	if len(info.Errors) > 0 {
+		return false
+	}
+	for _, value := range []string{
+		info.Abuse, info.AbuseSource, info.Prefix, info.Asn, info.Holder, info.City, info.Country,
+	} {
+		if value == types.SourceUnavailable {
+			return false
+		}
+	}
+	return true
+}
+
+// enricherOptions translates cfg into enricher options, registering the HTTPS targets of the
+// records of scanParser when it is set.
+func (cfg *Config) enricherOptions(scanParser *parser.Parser) []enricher.Option {
+	var opts []enricher.Option
+
+	if cfg.Workers > 0 {
+		opts = append(opts, enricher.WithWorkers(cfg.Workers))
+	}
+	if cfg.AdaptiveWorkers > 0 {
+		opts = append(opts, enricher.WithAdaptiveConcurrency(cfg.AdaptiveWorkers))
+	}
+	if cfg.ContactClassifier != nil {
+		opts = append(opts, enricher.WithContactClassifier(cfg.ContactClassifier))
+	}
+	if cfg.SourceApp != "" {
+		opts = append(opts, enricher.WithSourceApp(cfg.SourceApp))
+	}
+	if cfg.RipeStatURL != "" {
+		opts = append(opts, enricher.WithRipeStatBaseURL(cfg.RipeStatURL))
+	}
+	if cfg.RipeStatTimeout > 0 {
+		opts = append(opts, enricher.WithRipeStatTimeout(cfg.RipeStatTimeout))
+	}
+	if cfg.SlowQueryThreshold > 0 {
+		opts = append(opts, enricher.WithSlowQueryThreshold(cfg.SlowQueryThreshold))
+	}
+	if cfg.IPTimeout > 0 {
+		opts = append(opts, enricher.WithPerIPTimeout(cfg.IPTimeout))
+	}
+	if cfg.WhoisTimeout > 0 {
+		opts = append(opts, enricher.WithWhoisTimeout(cfg.WhoisTimeout))
+	}
+	if cfg.WhoisBreakerThreshold != 0 || cfg.WhoisBreakerCooldown > 0 {
+		threshold, cooldown := cfg.WhoisBreakerThreshold, cfg.WhoisBreakerCooldown
+		if threshold == 0 {
+			threshold = enricher.DefaultWhoisBreakerThreshold
+		}
+		if cooldown <= 0 {
+			cooldown = enricher.DefaultWhoisBreakerCooldown
+		}
+		opts = append(opts, enricher.WithWhoisCircuitBreaker(threshold, cooldown))
+	}
+	if len(cfg.WhoisServers) > 0 {
+		opts = append(opts, enricher.WithWhoisServers(cfg.WhoisServers...))
+	}
+	if cfg.JobBuffer > 0 || cfg.ResultBuffer > 0 {
+		opts = append(opts, enricher.WithChannelBuffers(cfg.JobBuffer, cfg.ResultBuffer))
+	}
+	if cfg.MaxResponseSize > 0 {
+		opts = append(opts, enricher.WithMaxResponseSize(cfg.MaxResponseSize))
+	}
+	if cfg.RoleContactsOnly {
+		opts = append(opts, enricher.WithRoleContactsOnly())
+	}
+	if cfg.NoWhois {
+		opts = append(opts, enricher.WithoutWhois())
+	}
+	if len(cfg.RIRs) > 0 {
+		opts = append(opts, enricher.WithRIRs(cfg.RIRs...))
+	}
+	if len(cfg.WhoisRIRs) > 0 {
+		opts = append(opts, enricher.WithWhoisRIRs(cfg.WhoisRIRs...))
+	}
+	if cfg.IRR != nil {
+		opts = append(opts, enricher.WithIRR(cfg.IRR))
+	}
+	if cfg.RejectPrivateASN {
+		opts = append(opts, enricher.WithRejectPrivateASNs())
+	}
+	if cfg.VerifyASN {
+		opts = append(opts, enricher.WithASNCrossCheck())
+	}
+	if cfg.ReverseDNS != nil {
+		opts = append(opts, enricher.WithReverseDNS(cfg.ReverseDNS))
+	}
+	if cfg.PrefixAbuse {
+		opts = append(opts, enricher.WithPrefixAbuseContacts())
+	}
+	if cfg.UnknownPlaceholder {
+		opts = append(opts, enricher.WithUnknownPlaceholder())
+	}
+	if cfg.AbuseTo {
+		opts = append(opts, enricher.WithAbuseAddressList())
+	}
+	if cfg.RegistryHandles {
+		opts = append(opts, enricher.WithRegistryHandles())
+	}
+	if cfg.Registration {
+		opts = append(opts, enricher.WithRegistration())
+	}
+	if cfg.CoveringPrefix {
+		opts = append(opts, enricher.WithCoveringPrefix())
+	}
+	if cfg.RipeStatHints {
+		opts = append(opts, enricher.WithRipeStatHints())
+	}
+	if cfg.MappedAsIPv6 {
+		opts = append(opts, enricher.WithMappedAsIPv6(true))
+	}
+	if !cfg.AsOf.IsZero() {
+		opts = append(opts, enricher.WithHistoricalWhois(cfg.AsOf))
+	}
+	if cfg.ASNClassifier != nil {
+		opts = append(opts, enricher.WithASNCategories(cfg.ASNClassifier))
+	}
+	if cfg.NationalCERTs != nil {
+		opts = append(opts, enricher.WithNationalCERTs(cfg.NationalCERTs))
+	}
+	if cfg.CloudRanges != nil {
+		opts = append(opts, enricher.WithCloudRanges(cfg.CloudRanges))
+	}
+	if cfg.TLSCerts != nil && scanParser != nil {
+		targets := 0
+		for _, record := range scanParser.ScanRecords {
+			ipAddr := enricher.CanonicalIPAs(record.Ip, cfg.MappedAsIPv6)
+			if cfg.TLSCerts.AddTarget(ipAddr, record.MatchedAt) || cfg.TLSCerts.AddTarget(ipAddr, record.Host) {
+				targets++
+			}
+		}
+		cfg.log().Debugf("fetching TLS certificates of %d HTTPS findings", targets)
+	}
+	if cfg.TLSCerts != nil {
+		opts = append(opts, enricher.WithTLSCertificates(cfg.TLSCerts))
+	}
+	if cfg.Annotator != nil {
+		opts = append(opts, enricher.WithAnnotator(cfg.Annotator))
+	}
+	if cfg.Geofeed != nil {
+		opts = append(opts, enricher.WithGeofeed(cfg.Geofeed))
+	}
+	if cfg.Radar != nil {
+		opts = append(opts, enricher.WithRadar(cfg.Radar))
+	}
+	if len(cfg.Fallback) > 0 {
+		opts = append(opts, enricher.WithFallbackProviders(cfg.Fallback...))
+	}
+	if cfg.PeeringDB != nil {
+		opts = append(opts, enricher.WithPeeringDB(cfg.PeeringDB))
+	}
+	if cfg.Cache != nil {
+		opts = append(opts, enricher.WithCache(cfg.Cache))
+	}
+	if cfg.Progress != nil {
+		opts = append(opts, enricher.WithProgress(cfg.Progress))
+	}
+
+	return opts
+}
+
+func logSummary(log logrus.FieldLogger, summary enricher.Summary, cached bool) {
+	log.Infof("Enriched %d of %d IPs (%d failed) in %v with %d workers (effective concurrency %d)",
+		summary.Enriched, summary.Total, summary.Failed, summary.Duration.Round(time.Millisecond), summary.Workers, summary.Concurrency)
+
+	dataCalls := make([]string, 0, len(summary.Latencies))
+	for dataCall := range summary.Latencies {
+		dataCalls = append(dataCalls, dataCall)
+	}
+	sort.Strings(dataCalls)
+	for _, dataCall := range dataCalls {
+		latency := summary.Latencies[dataCall]
+		log.Infof("RipeSTAT %s: %d calls, mean %v, max %v, %d slow", dataCall, latency.Count,
+			latency.Mean().Round(time.Millisecond), latency.Max.Round(time.Millisecond), latency.Slow)
+	}
+
+	if cached {
+		log.Infof("Cache: %d hits, %d misses", summary.CacheHits, summary.CacheMisses)
+	}
+}
+
+// CreateOutputFile creates path and its parent directories. Unless force is set, an existing file
+// is not overwritten.
+func CreateOutputFile(path string, force bool) (*os.File, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flag |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(path, flag, 0o644)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	return file, err
+}
+
+func writeOutput(scanParser *parser.Parser, target output.Target, cfg Config) error {
+	file, err := CreateOutputFile(target.Path, cfg.Force)
+	if err != nil {
+		return err
+	}
+
+	switch target.Format {
+	case output.FormatJSONL:
+		err = scanParser.WriteJSONLines(file, cfg.SortKeys)
+	case output.FormatCSV:
+		err = scanParser.WriteCSV(file, cfg.SortKeys)
+	case output.FormatHTML:
+		err = scanParser.WriteHTML(file, cfg.SortKeys)
+	case output.FormatLogfmt:
+		err = scanParser.WriteLogfmt(file, cfg.SortKeys)
+	case output.FormatGeoJSON:
+		err = scanParser.WriteGeoJSON(file, output.GeoJSONOptions{CountryFallback: cfg.GeoJSONCountryFallback})
+	case output.FormatFailed:
+		err = scanParser.WriteFailedIPs(file, cfg.FailedReasons)
+	case output.FormatSTIX:
+		err = scanParser.WriteSTIX(file)
+	case output.FormatMISP:
+		err = scanParser.WriteMISP(file, cfg.MISPEventInfo)
+	case output.FormatPrefixes:
+		err = scanParser.WritePrefixCSV(file)
+	case output.FormatProtobuf:
+		err = scanParser.WriteProtobuf(file)
+	case output.FormatJSON:
+		if len(cfg.SortKeys) > 0 {
+			err = scanParser.WriteSortedOutput(file, cfg.SortKeys)
+		} else {
+			err = scanParser.WriteOutput(file)
+		}
+	default:
+		err = fmt.Errorf("unknown output format %q", target.Format)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// deliverEnrichment posts the enrichment results to a webhook. Like indexing, failures are logged
+// rather than fatal as the output file is already written.
+func deliverEnrichment(log logrus.FieldLogger, records []types.EnrichInfo, url string, opts types.MarshalOptions) {
+	sink := output.NewWebhookSink(url)
+	sink.MarshalOptions = opts
+	stats, err := sink.Write(context.Background(), records)
+	if err != nil {
+		log.Errorf("Error delivering enrichment: %v", err)
+	}
+	if stats.Failed > 0 {
+		log.Warnf("Delivered %d of %d records to the webhook, %d failed", stats.Delivered, len(records), stats.Failed)
+		return
+	}
+	log.Infof("Delivered %d records to the webhook", stats.Delivered)
+}
+
+// notifyRun posts the summary of the run to a chat webhook. Failures are logged, the notification
+// does not change the outcome of the run.
+func notifyRun(log logrus.FieldLogger, report output.RunReport, url, format string) {
+	if err := output.NewNotifier(url, format).Notify(context.Background(), report); err != nil {
+		log.Warnf("Error posting run notification: %v", err)
+		return
+	}
+	log.Infof("Posted run notification")
+}
+
+// indexEnrichment writes the enrichment results to Elasticsearch. The results are already written to
+// the output file, so indexing failures are logged rather than fatal.
+func indexEnrichment(log logrus.FieldLogger, records []types.EnrichInfo, url, index, apiKey string, opts types.MarshalOptions) {
+	sink := output.NewElasticsearchSink(url, index)
+	sink.APIKey = apiKey
+	sink.MarshalOptions = opts
+	sink.Logger = log
+
+	ctx := context.Background()
+	if err := sink.EnsureIndex(ctx); err != nil {
+		log.Errorf("Error indexing enrichment: %v", err)
+		return
+	}
+
+	stats, err := sink.Write(ctx, records)
+	if err != nil {
+		log.Errorf("Error indexing enrichment: %v", err)
+	}
+	if stats.Failed > 0 {
+		log.Warnf("Indexed %d of %d records into %s, %d failed", stats.Indexed, len(records), sink.Index, stats.Failed)
+		return
+	}
+	log.Infof("Indexed %d records into %s", stats.Indexed, sink.Index)
+}
@@ -0,0 +1,122 @@
+package pipeline
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/checkpoint"
+	"nuclei-parse-enrich/pkg/output"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// checkpointConfig returns the config of a run enriching input with server, checkpointing to path.
+func checkpointConfig(t *testing.T, server *ripestattest.Server, input, path string) Config {
+	cfg := pipeConfig(t, server, input)
+	cfg.Checkpoint = path
+	cfg.CheckpointEvery = 1
+	cfg.Force = true
+	cfg.Outputs = []output.Target{{Format: output.FormatPrefixes, Path: filepath.Join(t.TempDir(), "prefixes.csv")}}
+	return cfg
+}
+
+// TestRunCheckpointFailed resumes a run in which an IP address failed, which must be retried.
+func TestRunCheckpointFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	input := "193.0.6.139\n193.0.6.140\n"
+
+	server := newRipeStat(t)
+	server.Handle("network-info", "193.0.6.140", ripestattest.Error(400, "bad request"))
+	if _, err := Run(context.Background(), checkpointConfig(t, server, input, path)); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := checkpoint.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed) != 1 || resumed[0].Ip != "193.0.6.139" {
+		t.Fatalf("checkpointed %+v, want only 193.0.6.139", resumed)
+	}
+
+	server = newRipeStat(t)
+	summary, err := Run(context.Background(), checkpointConfig(t, server, input, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Enriched != 1 || summary.Failed != 0 {
+		t.Errorf("summary = %+v, want the failed IP enriched", summary)
+	}
+	server.AssertRequests(t, "network-info", "193.0.6.139", 0)
+	server.AssertRequests(t, "network-info", "193.0.6.140", 1)
+}
+
+// TestRunCheckpointSourceUnavailable leaves IP addresses RipeSTAT couldn't look up out of the checkpoint.
+func TestRunCheckpointSourceUnavailable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	server := newRipeStat(t)
+	server.Handle("network-info", "", ripestattest.Maintenance("RIPEstat is in maintenance"))
+	if _, err := Run(context.Background(), checkpointConfig(t, server, "193.0.6.139\n", path)); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := checkpoint.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed) != 0 {
+		t.Errorf("checkpointed %+v, want nothing", resumed)
+	}
+}
+
+// TestRunCheckpointOtherInput resumes with a checkpoint of another input, whose IP addresses must
+// not be written.
+func TestRunCheckpointOtherInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	writer, err := checkpoint.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range []types.EnrichInfo{
+		{Ip: "198.51.100.1", Prefix: "198.51.100.0/24", Asn: "64500", Holder: "OTHER-AS", Abuse: "abuse@example.net", Country: "NL"},
+		{Ip: "193.0.6.139", Prefix: "193.0.0.0/21", Asn: "3333", Holder: "RIPE-NCC-AS", Abuse: "abuse@ripe.net", Country: "NL"},
+	} {
+		if err := writer.Add(info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newRipeStat(t)
+	cfg := checkpointConfig(t, server, "193.0.6.139\n193.0.6.140\n", path)
+	summary, err := Run(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Enriched != 1 {
+		t.Errorf("enriched %d IPs, want only the one not in the checkpoint", summary.Enriched)
+	}
+	server.AssertRequests(t, "network-info", "193.0.6.139", 0)
+
+	written, err := os.ReadFile(cfg.Outputs[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(written), "198.51.100.0/24") {
+		t.Errorf("wrote the prefix of an IP of another input:\n%s", written)
+	}
+	if !strings.Contains(string(written), "193.0.0.0/21") {
+		t.Errorf("didn't write the prefix of the input:\n%s", written)
+	}
+}
@@ -0,0 +1,359 @@
+package pipeline
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/parser"
+	"nuclei-parse-enrich/pkg/scope"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// pipeResult is the enriched record of one input line, ready once done is closed.
+type pipeResult struct {
+	done       chan struct{}
+	record     types.MergeResult
+	skip       skipReason
+	enrichment *pipeEnrichment
+}
+
+type skipReason int
+
+const (
+	skipNone skipReason = iota
+	skipEmpty
+	skipInvalid
+	// skipOther is used for out-of-scope IP addresses
+	skipOther
+)
+
+// pipeEnrichment is the enrichment of one IP address, shared by all records of that IP address.
+type pipeEnrichment struct {
+	done chan struct{}
+	info types.EnrichInfo
+	// counted is set once the enrichment is counted in the summary
+	counted bool
+}
+
+// Sink receives the enriched records of a Pipeline, one at a time and in input order. An error
+// stops the pipeline.
+type Sink interface {
+	Write(record types.MergeResult) error
+}
+
+// SinkFunc is a Sink calling the function.
+type SinkFunc func(record types.MergeResult) error
+
+func (f SinkFunc) Write(record types.MergeResult) error {
+	return f(record)
+}
+
+// NewJSONLinesSink returns a sink writing every record to w as one JSON object per line, with a
+// single Write per record. The enrichment is written in the layout of opts.
+func NewJSONLinesSink(w io.Writer, opts types.MarshalOptions) Sink {
+	encoder := json.NewEncoder(w)
+	return SinkFunc(func(record types.MergeResult) error {
+		return encoder.Encode(opts.MergeResult(record))
+	})
+}
+
+// Pipeline streams the records of Config.Input through the parser and the enricher to Sink:
+// records are read one at a time, enriched Config.Workers IP addresses at a time and passed to
+// Sink as soon as they and the records before them are done. At most Buffer records are in
+// flight, so a slow sink slows down reading the input and memory use doesn't grow with the
+// input, except for the enrichments remembered to enrich every IP address once, see Remember.
+// Records of IP addresses out of scope are left out, like Run does. The outputs of Config are not
+// written.
+type Pipeline struct {
+	Config Config
+	Sink   Sink
+	// Buffer is the number of records in flight, Config.Workers when zero
+	Buffer int
+	// Remember is the number of enrichments of the last IP addresses seen kept to enrich every IP
+	// address once, zero keeps all. IP addresses seen again after they were forgotten are enriched
+	// again, from Config.Cache when set.
+	Remember int
+	// Enricher enriches the IP addresses instead of an enricher built from Config, e.g. an
+	// enrichertest.FakeEnricher. The enrichment settings of Config don't apply to it.
+	Enricher enricher.IPEnricher
+}
+
+// Pipe enriches the records of cfg.Input as they come in and writes every enriched record to w as
+// one JSON object per line, see Pipeline. Every IP address is enriched once per run.
+func Pipe(ctx context.Context, cfg Config, w io.Writer) (Summary, error) {
+	return (&Pipeline{Config: cfg, Sink: NewJSONLinesSink(w, cfg.marshalOptions())}).Run(ctx)
+}
+
+// Run streams the records of the input to the sink until the input ends or ctx is done.
+func (p *Pipeline) Run(ctx context.Context) (Summary, error) {
+	var summary Summary
+	cfg := p.Config
+	log := cfg.log()
+
+	if p.Sink == nil {
+		return summary, &Error{StageConfig, errors.New("checking config: no sink")}
+	}
+
+	if cfg.Input == nil {
+		return summary, &Error{StageConfig, errors.New("checking config: no input")}
+	}
+
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = enricher.DefaultWorkers
+	}
+	summary.Workers, summary.Concurrency = workers, workers
+
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	// stopping the run because the output can't be written is not an enrichment error
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	e := p.Enricher
+	if e == nil {
+		cfg.Progress = nil
+		e = enricher.NewEnricher(append(cfg.enricherOptions(nil), enricher.WithLogger(log))...)
+	}
+
+	start := time.Now()
+	var hitsBefore, missesBefore int
+	if cfg.Cache != nil {
+		hitsBefore, missesBefore = cfg.Cache.Stats()
+	}
+
+	buffer := p.Buffer
+	if buffer < 1 {
+		buffer = workers
+	}
+
+	// the queue holds the records in input order, its capacity bounds the records in flight
+	queue := make(chan *pipeResult, buffer)
+	slots := make(chan struct{}, workers)
+	enrichments := make(map[string]*pipeEnrichment)
+	// remembered lists the IP addresses in enrichments, the oldest first, when p.Remember is set
+	var remembered []string
+	// resolved holds the records resolved by host name, nil for host names that could not be resolved
+	resolved := make(map[string]*types.NucleiJsonRecord)
+
+	readErr := make(chan error, 1)
+	readDone := make(chan struct{})
+	go func() {
+		// a read waiting for more input on a pipe or terminal is interrupted when the run stops
+		select {
+		case <-runCtx.Done():
+			if deadline, ok := cfg.Input.(interface{ SetReadDeadline(time.Time) error }); ok {
+				_ = deadline.SetReadDeadline(time.Now())
+			}
+		case <-readDone:
+		}
+	}()
+	go func() {
+		defer close(queue)
+		defer close(readDone)
+		readErr <- readRecords(cfg, func(record types.NucleiJsonRecord) bool {
+			result := &pipeResult{done: make(chan struct{})}
+			select {
+			case <-runCtx.Done():
+				return false
+			case queue <- result:
+			}
+
+			if hostname := parser.RecordHostname(record); cfg.ResolveHosts && hostname != "" {
+				found, seen := resolved[hostname]
+				if !seen {
+					if ok, err := parser.ResolveRecordHost(runCtx, nil, &record); ok {
+						found = &types.NucleiJsonRecord{Ip: record.Ip, AdditionalIPs: record.AdditionalIPs}
+					} else if err != nil {
+						log.Debugf("pipe: resolving host name %s: %v", hostname, err)
+					}
+					resolved[hostname] = found
+				}
+				if found != nil {
+					record.Ip, record.AdditionalIPs = found.Ip, found.AdditionalIPs
+				}
+			}
+
+			ipAddr, ok := pipeIP(cfg.Scope, record.Ip, cfg.MappedAsIPv6)
+			if !ok {
+				if record.Ip == "" {
+					result.skip = skipEmpty
+					result.record.NucleiJsonRecord = record
+				} else if enricher.ValidateIP(record.Ip) != nil {
+					result.skip = skipInvalid
+					result.record.NucleiJsonRecord = record
+				} else {
+					result.skip = skipOther
+					log.Debugf("pipe: leaving out the record of IP address %q", record.Ip)
+				}
+				close(result.done)
+				return true
+			}
+			result.record.NucleiJsonRecord = record
+
+			enrichment, found := enrichments[ipAddr]
+			if !found {
+				enrichment = &pipeEnrichment{done: make(chan struct{})}
+				enrichments[ipAddr] = enrichment
+				if p.Remember > 0 {
+					remembered = append(remembered, ipAddr)
+					if len(remembered) > p.Remember {
+						// the records of the forgotten IP address still hold its enrichment
+						delete(enrichments, remembered[0])
+						remembered = remembered[1:]
+					}
+				}
+
+				if cfg.TLSCerts != nil {
+					if !cfg.TLSCerts.AddTarget(ipAddr, record.MatchedAt) {
+						cfg.TLSCerts.AddTarget(ipAddr, record.Host)
+					}
+				}
+
+				select {
+				case <-runCtx.Done():
+					// the result is queued already, the records waiting for it are not written
+					close(enrichment.done)
+					close(result.done)
+					return false
+				case slots <- struct{}{}:
+				}
+				go func() {
+					enrichment.info = e.EnrichIP(runCtx, ipAddr)
+					<-slots
+					close(enrichment.done)
+				}()
+			}
+
+			result.enrichment = enrichment
+			go func() {
+				<-enrichment.done
+				result.record.EnrichInfo = enrichment.info
+				close(result.done)
+			}()
+			return true
+		})
+	}()
+
+	var writeErr error
+	for result := range queue {
+		<-result.done
+		summary.IPStats.Records++
+		switch result.skip {
+		case skipEmpty:
+			summary.IPStats.Empty++
+		case skipInvalid:
+			summary.IPStats.Invalid++
+			if len(summary.IPStats.InvalidIPs) < enricher.MaxInvalidExamples {
+				summary.IPStats.InvalidIPs = append(summary.IPStats.InvalidIPs, result.record.NucleiJsonRecord.Ip)
+			}
+		case skipOther:
+			// out-of-scope records are counted as skipped, like the private ones
+			summary.IPStats.Bogon++
+			continue
+		}
+		if writeErr != nil || runCtx.Err() != nil {
+			// after ctx is done the enrichments are incomplete
+			continue
+		}
+
+		summary.Total++
+		if result.enrichment == nil {
+			// the records of empty and invalid IP addresses are written without enrichment
+			result.record.EnrichInfo = enricher.InvalidIPInfo(result.record.NucleiJsonRecord.Ip)
+		} else if !result.enrichment.counted {
+			result.enrichment.counted = true
+			summary.Enriched++
+			if len(result.record.EnrichInfo.Errors) > 0 {
+				summary.Failed++
+			}
+			if result.record.EnrichInfo.AbuseSource == types.AbuseSourceSkippedPrivate {
+				summary.IPStats.Bogon++
+			}
+		}
+
+		if writeErr = p.Sink.Write(result.record); writeErr != nil {
+			stop()
+		}
+	}
+
+	summary.Parsed = summary.Total
+	summary.Duration = time.Since(start)
+	if cfg.Cache != nil {
+		hits, misses := cfg.Cache.Stats()
+		summary.CacheHits, summary.CacheMisses = hits-hitsBefore, misses-missesBefore
+	}
+	log.Infof("Enriched %d records with %d unique IPs (%d failed) in %v", summary.Total, summary.Enriched, summary.Failed, summary.Duration.Round(time.Millisecond))
+	if summary.IPStats.Invalid > 0 {
+		log.Warnf("Wrote %d records with an invalid IP address without enrichment, e.g. %q", summary.IPStats.Invalid, summary.IPStats.InvalidIPs)
+	}
+
+	if writeErr != nil {
+		return summary, &Error{StageOutput, fmt.Errorf("writing output: %v", writeErr)}
+	}
+	if err := <-readErr; err != nil && runCtx.Err() == nil {
+		return summary, &Error{StageInput, fmt.Errorf("parsing input: %v", err)}
+	}
+	if err := ctx.Err(); err != nil {
+		return summary, &Error{StageEnrich, err}
+	}
+	return summary, nil
+}
+
+// readRecords calls fn with every record of cfg.Input until fn returns false.
+func readRecords(cfg Config, fn func(types.NucleiJsonRecord) bool) error {
+	if cfg.InputFormat == FormatIPList {
+		scanner := bufio.NewScanner(cfg.Input)
+		for scanner.Scan() {
+			if !fn(types.NucleiJsonRecord{Ip: scanner.Text()}) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	records := parser.NewRecordReader(cfg.Input, cfg.Passthrough)
+	for n := 1; ; n++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*json.UnmarshalTypeError); !ok {
+				return fmt.Errorf("error parsing record %d: %v", n, err)
+			}
+		}
+		if !fn(record) {
+			return nil
+		}
+	}
+}
+
+// pipeIP returns the canonical form of ipAddr when it is an in-scope IP address. Private and
+// reserved addresses are returned as well, the enricher marks them skipped-private.
+func pipeIP(s *scope.Scope, ipAddr string, mappedAsIPv6 bool) (string, bool) {
+	ipAddr = enricher.CanonicalIPAs(ipAddr, mappedAsIPv6)
+	if _, err := netip.ParseAddr(ipAddr); err != nil {
+		return "", false
+	}
+	if s != nil && s.Check(ipAddr) != scope.InScope {
+		return "", false
+	}
+	return ipAddr, true
+}
@@ -0,0 +1,255 @@
+package pipeline
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/enrichertest"
+	"nuclei-parse-enrich/pkg/ripestattest"
+	"nuclei-parse-enrich/pkg/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// discardLogger returns a logger for runs whose logs don't matter.
+func discardLogger() logrus.FieldLogger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// newRipeStat returns a fake RipeSTAT answering the data calls of an enrichment without whois.
+func newRipeStat(t testing.TB) *ripestattest.Server {
+	server := ripestattest.NewServer()
+	t.Cleanup(server.Close)
+	server.Handle("network-info", "", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+	server.Handle("abuse-contact-finder", "", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("as-overview", "", ripestattest.JSON(`{"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)"}`))
+	server.Handle("maxmind-geo-lite", "", ripestattest.JSON(`{"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam"}]}]}`))
+	return server
+}
+
+// pipeConfig returns the config of a run enriching input with server, without whois.
+func pipeConfig(t testing.TB, server *ripestattest.Server, input string) Config {
+	return Config{
+		Input:       strings.NewReader(input),
+		InputFormat: FormatIPList,
+		RipeStatURL: server.BaseURL(),
+		NoWhois:     true,
+		Logger:      discardLogger(),
+	}
+}
+
+// runWithin fails t when run doesn't return within d.
+func runWithin(t *testing.T, d time.Duration, run func() (Summary, error)) (Summary, error) {
+	t.Helper()
+	type returned struct {
+		summary Summary
+		err     error
+	}
+	done := make(chan returned, 1)
+	go func() {
+		summary, err := run()
+		done <- returned{summary, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.summary, r.err
+	case <-time.After(d):
+		t.Fatalf("run didn't return within %v", d)
+		return Summary{}, nil
+	}
+}
+
+func TestPipe(t *testing.T) {
+	server := newRipeStat(t)
+	cfg := pipeConfig(t, server, "193.0.6.139\n193.0.6.139\n\nnot-an-ip\n10.0.0.1\n")
+
+	var out bytes.Buffer
+	summary, err := Pipe(context.Background(), cfg, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := bytes.Count(out.Bytes(), []byte("\n")); lines != 5 {
+		t.Errorf("wrote %d records, want 5:\n%s", lines, out.String())
+	}
+	if summary.Total != 5 || summary.Enriched != 2 || summary.IPStats.Empty != 1 || summary.IPStats.Invalid != 1 || summary.IPStats.Bogon != 1 {
+		t.Errorf("summary = %+v, want 5 records, 2 enriched, 1 empty, 1 invalid and 1 private", summary)
+	}
+	// every IP address is enriched once
+	server.AssertRequests(t, "network-info", "193.0.6.139", 1)
+	server.AssertNoUnexpected(t)
+}
+
+// TestPipeCancelledWithBusyWorkers stops a run while the reader waits for a worker, which must
+// not leave the writer waiting for a record that is never enriched.
+func TestPipeCancelledWithBusyWorkers(t *testing.T) {
+	server := newRipeStat(t)
+	server.Latency = time.Second
+	cfg := pipeConfig(t, server, "193.0.6.139\n193.0.6.140\n193.0.6.141\n")
+	cfg.Workers = 1
+	cfg.Timeout = 300 * time.Millisecond
+
+	var out bytes.Buffer
+	_, err := runWithin(t, 5*time.Second, func() (Summary, error) {
+		return Pipe(context.Background(), cfg, &out)
+	})
+
+	var runErr *Error
+	if !errors.As(err, &runErr) || runErr.Stage != StageEnrich || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Pipe error = %v, want the deadline as an enrichment error", err)
+	}
+	if out.Len() > 0 {
+		t.Errorf("wrote the incomplete enrichments:\n%s", out.String())
+	}
+}
+
+// nucleiRecords returns n nuclei JSONL records of ips IP addresses, in turn.
+func nucleiRecords(n, ips int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `{"ip": "193.0.%d.%d", "host": "https://example.com/%d", "template-id": "test", "info": {"severity": "high"}}`+"\n", i%ips/250, i%ips%250+1, i)
+	}
+	return b.String()
+}
+
+func TestPipelineSlowSink(t *testing.T) {
+	const records, ips, buffer = 2000, 100, 8
+	server := newRipeStat(t)
+	cfg := pipeConfig(t, server, nucleiRecords(records, ips))
+	cfg.InputFormat = FormatNuclei
+	cfg.Workers = 4
+
+	var written []string
+	sink := SinkFunc(func(record types.MergeResult) error {
+		// the reader doesn't get further ahead of the sink than the records in flight
+		if enriched := server.Requests("network-info", ""); enriched > len(written)+buffer+1 {
+			return fmt.Errorf("enriched %d IPs before writing record %d", enriched, len(written))
+		}
+		written = append(written, record.Host)
+		if len(written) < 20 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		return nil
+	})
+
+	pipe := &Pipeline{Config: cfg, Sink: sink, Buffer: buffer, Remember: ips}
+	summary, err := pipe.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != records || summary.Total != records {
+		t.Fatalf("wrote %d records, summary %d, want %d", len(written), summary.Total, records)
+	}
+	for i, host := range written {
+		if want := fmt.Sprintf("https://example.com/%d", i); host != want {
+			t.Fatalf("record %d is %s, want %s", i, host, want)
+		}
+	}
+	// the IP addresses come back before they are forgotten, so every one is enriched once
+	server.AssertRequests(t, "network-info", "", ips)
+}
+
+func TestPipelineEnricher(t *testing.T) {
+	fake := enrichertest.NewFakeEnricher()
+	fake.Set("193.0.6.139", types.EnrichInfo{Abuse: "abuse@ripe.net", Asn: "3333", Holder: "RIPE-NCC-AS"})
+	fake.Fail("192.0.2.1", "Abuse", errors.New("whois: i/o timeout"))
+	cfg := Config{
+		Input:       strings.NewReader("193.0.6.139\n192.0.2.1\n193.0.6.139\nnot-an-ip\n"),
+		InputFormat: FormatIPList,
+		Logger:      discardLogger(),
+	}
+
+	var written []types.MergeResult
+	pipe := &Pipeline{Config: cfg, Enricher: fake, Sink: SinkFunc(func(record types.MergeResult) error {
+		written = append(written, record)
+		return nil
+	})}
+	summary, err := pipe.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(written) != 4 || summary.Total != 4 || summary.Enriched != 2 || summary.Failed != 1 || summary.IPStats.Invalid != 1 {
+		t.Fatalf("wrote %d records, summary = %+v, want 4 records of 2 IPs of which 1 failed", len(written), summary)
+	}
+	for _, i := range []int{0, 2} {
+		if info := written[i].EnrichInfo; info.Ip != "193.0.6.139" || info.Abuse != "abuse@ripe.net" || info.Asn != "3333" {
+			t.Errorf("record %d enriched as %+v, want the record set on the fake", i, info)
+		}
+	}
+	if written[1].EnrichInfo.Errors["Abuse"] == "" {
+		t.Errorf("record 1 enriched without the failure of the fake: %+v", written[1].EnrichInfo)
+	}
+	// every IP address is enriched once, the invalid one not at all
+	for ipAddr, want := range map[string]int{"193.0.6.139": 1, "192.0.2.1": 1, "not-an-ip": 0} {
+		if calls := fake.Calls(ipAddr); calls != want {
+			t.Errorf("%s enriched %d times, want %d", ipAddr, calls, want)
+		}
+	}
+}
+
+func TestPipelineCancelled(t *testing.T) {
+	t.Run("busy workers", func(t *testing.T) {
+		server := newRipeStat(t)
+		server.Latency = time.Second
+		cfg := pipeConfig(t, server, "193.0.6.139\n193.0.6.140\n193.0.6.141\n")
+		cfg.Workers = 1
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(200*time.Millisecond, cancel)
+		pipe := &Pipeline{Config: cfg, Sink: SinkFunc(func(types.MergeResult) error { return nil })}
+		_, err := runWithin(t, 5*time.Second, func() (Summary, error) { return pipe.Run(ctx) })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run error = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("waiting for input", func(t *testing.T) {
+		server := newRipeStat(t)
+		input, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer input.Close()
+		defer w.Close()
+		// the input stays open, like stdin of a scan still running
+		if _, err := io.WriteString(w, "193.0.6.139\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := pipeConfig(t, server, "")
+		cfg.Input = input
+		written := make(chan string, 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		pipe := &Pipeline{Config: cfg, Sink: SinkFunc(func(record types.MergeResult) error {
+			written <- record.NucleiJsonRecord.Ip
+			cancel()
+			return nil
+		})}
+
+		_, err = runWithin(t, 5*time.Second, func() (Summary, error) { return pipe.Run(ctx) })
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Run error = %v, want context.Canceled", err)
+		}
+		if ip := <-written; ip != "193.0.6.139" {
+			t.Errorf("wrote %q before stopping", ip)
+		}
+	})
+}
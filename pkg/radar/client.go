@@ -0,0 +1,142 @@
+package radar
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"nuclei-parse-enrich/pkg/asn"
+	"nuclei-parse-enrich/pkg/ratelimit"
+)
+
+const (
+	API_URL = "https://api.cloudflare.com/client/v4/radar/"
+
+	// The Cloudflare API allows 1200 requests per 5 minutes per user
+	DefaultRequestsPerSecond = 4
+)
+
+const (
+	NetworkTypeEyeball = "eyeball"
+	NetworkTypeTransit = "transit"
+	NetworkTypeOther   = "other"
+)
+
+type Client struct {
+	Token   string
+	BaseURL string
+
+	httpClient *http.Client
+	limiter    *ratelimit.Limiter
+}
+
+func NewRadarClient(token string) *Client {
+	return &Client{
+		Token:      token,
+		BaseURL:    API_URL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		limiter:    ratelimit.NewLimiterPerSecond(DefaultRequestsPerSecond),
+	}
+}
+
+func (c *Client) GetASN(ctx context.Context, asn string) (ASN, error) {
+	resp := ASNBase{}
+	if err := c.get(ctx, "entities/asns/"+url.PathEscape(trimASN(asn)), &resp.ResponseBase, &resp); err != nil {
+		return ASN{}, err
+	}
+	return resp.Result.ASN, nil
+}
+
+func (c *Client) GetASNRelationships(ctx context.Context, asn string) ([]ASNRelationship, error) {
+	resp := ASNRelationshipsBase{}
+	if err := c.get(ctx, "entities/asns/"+url.PathEscape(trimASN(asn))+"/rel", &resp.ResponseBase, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Result.Rels, nil
+}
+
+// GetNetworkType classifies an AS as eyeball network when Radar estimates it has end users,
+// as transit network when it provides transit to customer networks, and as other otherwise.
+func (c *Client) GetNetworkType(ctx context.Context, info ASN) (string, error) {
+	if info.EstimatedUsers.EstimatedUsers > 0 {
+		return NetworkTypeEyeball, nil
+	}
+
+	rels, err := c.GetASNRelationships(ctx, strconv.Itoa(info.ASN))
+	if err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels {
+		if (rel.ASN1 == info.ASN && rel.Rel == "P2C") || (rel.ASN2 == info.ASN && rel.Rel == "C2P") {
+			return NetworkTypeTransit, nil
+		}
+	}
+
+	return NetworkTypeOther, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, base *ResponseBase, out interface{}) error {
+	if c.Token == "" {
+		return fmt.Errorf("radar: no API token configured")
+	}
+
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path+"?format=json", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("radar: rate limited on %q, retry after %q", path, resp.Header.Get("Retry-After"))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("radar: failed to unmarshal %q (status %d): %v", path, resp.StatusCode, err)
+	}
+
+	if !base.Success {
+		var messages []string
+		for _, e := range base.Errors {
+			messages = append(messages, e.Message)
+		}
+		return fmt.Errorf("radar: request %q failed (status %d): %s", path, resp.StatusCode, strings.Join(messages, "; "))
+	}
+
+	return nil
+}
+
+func trimASN(s string) string {
+	number, err := asn.Parse(s)
+	if err != nil {
+		return strings.TrimPrefix(strings.ToUpper(s), "AS")
+	}
+	return asn.Format(number)
+}
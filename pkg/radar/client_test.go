@@ -0,0 +1,106 @@
+package radar
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// radarResponses are the responses of the fake Radar API by path
+var radarResponses = map[string]string{
+	"/entities/asns/3333": `{"success": true, "errors": [], "result": {"asn": {"asn": 3333, "name": "RIPE-NCC-AS",
+		"orgName": "Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", "country": "NL", "estimatedUsers": {"estimatedUsers": 0}}}}`,
+	"/entities/asns/3333/rel": `{"success": true, "errors": [], "result": {"rels": [{"asn1": 3333, "asn2": 1299, "rel": "C2P"}]}}`,
+	"/entities/asns/1136": `{"success": true, "errors": [], "result": {"asn": {"asn": 1136, "name": "KPN",
+		"orgName": "KPN B.V.", "country": "NL", "estimatedUsers": {"estimatedUsers": 3500000}}}}`,
+	"/entities/asns/1299": `{"success": true, "errors": [], "result": {"asn": {"asn": 1299, "name": "TWELVE99",
+		"orgName": "Arelion Sweden AB", "country": "SE", "estimatedUsers": {"estimatedUsers": 0}}}}`,
+	"/entities/asns/1299/rel": `{"success": true, "errors": [], "result": {"rels": [{"asn1": 1299, "asn2": 3333, "rel": "P2C"}]}}`,
+	"/entities/asns/64500":    `{"success": false, "errors": [{"code": 1000, "message": "ASN not found"}], "result": {}}`,
+}
+
+// newRadarServer returns a fake Radar API serving radarResponses, and the client requesting it.
+func newRadarServer(t *testing.T) (*httptest.Server, *Client) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" || r.URL.Query().Get("format") != "json" {
+			http.Error(w, `{"success": false, "errors": [{"code": 10000, "message": "Authentication error"}]}`, http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/entities/asns/429" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, found := radarResponses[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewRadarClient("test-token")
+	client.BaseURL = server.URL + "/"
+	client.limiter = nil
+	return server, client
+}
+
+func TestGetASN(t *testing.T) {
+	_, client := newRadarServer(t)
+
+	for _, asn := range []string{"3333", "AS3333", "as3333"} {
+		info, err := client.GetASN(context.Background(), asn)
+		if err != nil {
+			t.Fatalf("GetASN(%s): %v", asn, err)
+		}
+		if info.ASN != 3333 || info.OrgName != "Reseaux IP Europeens Network Coordination Centre (RIPE NCC)" || info.Country != "NL" {
+			t.Errorf("GetASN(%s) = %+v", asn, info)
+		}
+	}
+
+	if _, err := client.GetASN(context.Background(), "64500"); err == nil || !strings.Contains(err.Error(), "ASN not found") {
+		t.Errorf("GetASN of an unknown AS returned %v, want the error of Radar", err)
+	}
+	if _, err := client.GetASN(context.Background(), "429"); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("GetASN when rate limited returned %v", err)
+	}
+
+	client.Token = ""
+	if _, err := client.GetASN(context.Background(), "3333"); err == nil {
+		t.Error("GetASN without a token succeeded")
+	}
+}
+
+func TestGetNetworkType(t *testing.T) {
+	_, client := newRadarServer(t)
+
+	tests := []struct {
+		asn  string
+		want string
+	}{
+		// estimated users make an eyeball network without looking at the relationships
+		{"1136", NetworkTypeEyeball},
+		{"1299", NetworkTypeTransit},
+		// a customer of its provider only
+		{"3333", NetworkTypeOther},
+	}
+	for _, tt := range tests {
+		info, err := client.GetASN(context.Background(), tt.asn)
+		if err != nil {
+			t.Fatalf("GetASN(%s): %v", tt.asn, err)
+		}
+		got, err := client.GetNetworkType(context.Background(), info)
+		if err != nil || got != tt.want {
+			t.Errorf("GetNetworkType(AS%s) = %q, %v, want %q", tt.asn, got, err, tt.want)
+		}
+	}
+}
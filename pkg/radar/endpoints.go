@@ -0,0 +1,59 @@
+package radar
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+type ResponseBase struct {
+	Success bool            `json:"success"`
+	Errors  []ResponseError `json:"errors"`
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type ASNBase struct {
+	ResponseBase
+	Result struct {
+		ASN ASN `json:"asn"`
+	} `json:"result"`
+}
+
+type ASN struct {
+	ASN            int            `json:"asn"`
+	Name           string         `json:"name"`
+	NameLong       string         `json:"nameLong"`
+	OrgName        string         `json:"orgName"`
+	Country        string         `json:"country"`
+	CountryName    string         `json:"countryName"`
+	Website        string         `json:"website"`
+	Source         string         `json:"source"`
+	EstimatedUsers EstimatedUsers `json:"estimatedUsers"`
+}
+
+type EstimatedUsers struct {
+	EstimatedUsers int `json:"estimatedUsers"`
+}
+
+type ASNRelationshipsBase struct {
+	ResponseBase
+	Result struct {
+		Rels []ASNRelationship `json:"rels"`
+	} `json:"result"`
+}
+
+// ASNRelationship describes the relationship of asn1 towards asn2: P2C (provider to customer),
+// C2P (customer to provider) or P2P (peers).
+type ASNRelationship struct {
+	ASN1        int    `json:"asn1"`
+	ASN1Name    string `json:"asn1_name"`
+	ASN1Country string `json:"asn1_country"`
+	ASN2        int    `json:"asn2"`
+	ASN2Name    string `json:"asn2_name"`
+	ASN2Country string `json:"asn2_country"`
+	Rel         string `json:"rel"`
+}
@@ -0,0 +1,66 @@
+package ratelimit
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter spaces out calls so that at most one call per interval is let through.
+// A nil Limiter or a zero interval does not limit at all.
+type Limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func NewLimiter(interval time.Duration) *Limiter {
+	return &Limiter{
+		interval: interval,
+	}
+}
+
+// NewLimiterPerSecond returns a Limiter letting through at most n calls per second.
+func NewLimiterPerSecond(n float64) *Limiter {
+	if n <= 0 {
+		return NewLimiter(0)
+	}
+	return NewLimiter(time.Duration(float64(time.Second) / n))
+}
+
+// Wait blocks until the caller is allowed to proceed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.interval <= 0 {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
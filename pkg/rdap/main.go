@@ -0,0 +1,228 @@
+package rdap
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// BOOTSTRAP_URL redirects every query to the RDAP server of the registry holding the resource
+	BOOTSTRAP_URL = "https://rdap.org/"
+
+	// DefaultMaxResponseSize bounds a single response, IP network objects are a few kB
+	DefaultMaxResponseSize = 1 << 20
+)
+
+// Network is the IP network registered for an IP address, according to its registry.
+type Network struct {
+	Handle  string
+	Name    string
+	Prefix  string
+	Country string
+	// Holder is the name of the registrant of the network, or the name of the network without one
+	Holder string
+	// Asn is the origin AS of the network, only some registries (ARIN) list it
+	Asn string
+}
+
+type Client struct {
+	BaseURL string
+	// MaxResponseSize bounds a response in bytes, zero means DefaultMaxResponseSize
+	MaxResponseSize int64
+
+	httpClient *http.Client
+}
+
+func NewRDAPClient() *Client {
+	return &Client{
+		BaseURL:    BOOTSTRAP_URL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// LookupIP returns the network registered for ipAddr.
+func (c *Client) LookupIP(ctx context.Context, ipAddr string) (Network, error) {
+	requestURL := strings.TrimSuffix(c.BaseURL, "/") + "/ip/" + url.PathEscape(ipAddr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return Network{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Network{}, fmt.Errorf("rdap: %v", err)
+	}
+	defer resp.Body.Close()
+
+	limit := c.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return Network{}, fmt.Errorf("rdap: %v", err)
+	}
+	if int64(len(body)) > limit {
+		return Network{}, fmt.Errorf("rdap: response for %s larger than %d bytes", ipAddr, limit)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return Network{}, fmt.Errorf("rdap: no network registered for %s", ipAddr)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Network{}, fmt.Errorf("rdap: lookup of %s returned status %d", ipAddr, resp.StatusCode)
+	}
+
+	return ParseNetwork(body)
+}
+
+type ipNetwork struct {
+	Handle       string   `json:"handle"`
+	Name         string   `json:"name"`
+	Country      string   `json:"country"`
+	StartAddress string   `json:"startAddress"`
+	EndAddress   string   `json:"endAddress"`
+	Entities     []entity `json:"entities"`
+	Cidrs        []struct {
+		V4Prefix string `json:"v4prefix"`
+		V6Prefix string `json:"v6prefix"`
+		Length   int    `json:"length"`
+	} `json:"cidr0_cidrs"`
+	OriginAutnums []json.Number `json:"arin_originas0_originautnums"`
+}
+
+type entity struct {
+	Roles      []string        `json:"roles"`
+	VCardArray json.RawMessage `json:"vcardArray"`
+}
+
+// ParseNetwork parses an RDAP IP network object (RFC 9083), with the cidr0 and arin_originas0
+// extensions when present.
+func ParseNetwork(data []byte) (Network, error) {
+	var object ipNetwork
+	if err := json.Unmarshal(data, &object); err != nil {
+		return Network{}, fmt.Errorf("rdap: failed to unmarshal network: %v", err)
+	}
+
+	network := Network{
+		Handle:  object.Handle,
+		Name:    object.Name,
+		Country: object.Country,
+		Holder:  object.Name,
+	}
+
+	for _, cidr := range object.Cidrs {
+		prefix := cidr.V4Prefix
+		if prefix == "" {
+			prefix = cidr.V6Prefix
+		}
+		if prefix != "" {
+			network.Prefix = prefix + "/" + strconv.Itoa(cidr.Length)
+			break
+		}
+	}
+	if network.Prefix == "" {
+		network.Prefix = rangePrefix(object.StartAddress, object.EndAddress)
+	}
+
+	if len(object.OriginAutnums) > 0 {
+		network.Asn = object.OriginAutnums[0].String()
+	}
+
+	for _, e := range object.Entities {
+		if !hasRole(e.Roles, "registrant") {
+			continue
+		}
+		if name := vcardName(e.VCardArray); name != "" {
+			network.Holder = name
+			break
+		}
+	}
+
+	return network, nil
+}
+
+// rangePrefix returns the prefix spanning exactly start to end, or nothing for other ranges.
+func rangePrefix(start, end string) string {
+	first, err := netip.ParseAddr(start)
+	if err != nil {
+		return ""
+	}
+	last, err := netip.ParseAddr(end)
+	if err != nil {
+		return ""
+	}
+
+	for bits := 0; bits <= first.BitLen(); bits++ {
+		prefix, err := first.Prefix(bits)
+		if err != nil || prefix.Addr() != first || !prefix.Contains(last) {
+			continue
+		}
+		if lastOf(prefix) == last {
+			return prefix.String()
+		}
+	}
+	return ""
+}
+
+// lastOf returns the last address of prefix.
+func lastOf(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().As16()
+	offset := 0
+	if prefix.Addr().Is4() {
+		offset = 96
+	}
+	for bit := offset + prefix.Bits(); bit < 128; bit++ {
+		addr[bit/8] |= 1 << (7 - bit%8)
+	}
+	last := netip.AddrFrom16(addr)
+	if prefix.Addr().Is4() {
+		return last.Unmap()
+	}
+	return last
+}
+
+// vcardName returns the formatted name (fn) of a jCard (RFC 7095).
+func vcardName(raw json.RawMessage) string {
+	var card []json.RawMessage
+	if err := json.Unmarshal(raw, &card); err != nil || len(card) < 2 {
+		return ""
+	}
+	var properties [][]interface{}
+	if err := json.Unmarshal(card[1], &properties); err != nil {
+		return ""
+	}
+	for _, property := range properties {
+		if len(property) < 4 {
+			continue
+		}
+		if name, _ := property[0].(string); name == "fn" {
+			value, _ := property[3].(string)
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package rdns
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// DefaultSuffixes maps well known PTR domain suffixes to the hosting provider using them.
+var DefaultSuffixes = map[string]string{
+	"amazonaws.com":            "Amazon Web Services",
+	"bc.googleusercontent.com": "Google Cloud",
+	"cloudapp.azure.com":       "Microsoft Azure",
+	"cloudapp.net":             "Microsoft Azure",
+	"digitalocean.com":         "DigitalOcean",
+	"linodeusercontent.com":    "Linode",
+	"members.linode.com":       "Linode",
+	"your-server.de":           "Hetzner",
+	"clients.your-server.de":   "Hetzner",
+	"hetzner.com":              "Hetzner",
+	"ip-ovh.net":               "OVH",
+	"ovh.net":                  "OVH",
+	"vultrusercontent.com":     "Vultr",
+	"vultr.com":                "Vultr",
+	"contaboserver.net":        "Contabo",
+	"scaleway.com":             "Scaleway",
+	"poneytelecom.eu":          "Scaleway",
+	"oraclecloud.com":          "Oracle Cloud",
+	"akamaitechnologies.com":   "Akamai",
+	"cloudflare.com":           "Cloudflare",
+	"fastly.net":               "Fastly",
+	"leaseweb.net":             "Leaseweb",
+	"leaseweb.com":             "Leaseweb",
+	"transip.net":              "TransIP",
+	"ionos.com":                "IONOS",
+	"alibabacloud.com":         "Alibaba Cloud",
+	"aliyun.com":               "Alibaba Cloud",
+	"tencentcloud.com":         "Tencent Cloud",
+}
+
+// Hinter derives a hosting provider hint from the PTR record of an IP address by matching the
+// longest known domain suffix.
+type Hinter struct {
+	suffixes map[string]string
+	resolver Resolver
+}
+
+// Resolver looks up the PTR names of an IP address, like net.Resolver.
+type Resolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// NewHinter returns a Hinter knowing DefaultSuffixes.
+func NewHinter() *Hinter {
+	h := &Hinter{
+		suffixes: make(map[string]string, len(DefaultSuffixes)),
+		resolver: net.DefaultResolver,
+	}
+
+	for suffix, provider := range DefaultSuffixes {
+		h.AddSuffix(suffix, provider)
+	}
+
+	return h
+}
+
+// SetResolver looks up the PTR records with r instead of net.DefaultResolver, e.g. a stub in tests.
+func (h *Hinter) SetResolver(r Resolver) {
+	h.resolver = r
+}
+
+// AddSuffix makes PTR names ending in suffix hint at provider, overriding a known suffix.
+func (h *Hinter) AddSuffix(suffix, provider string) {
+	h.suffixes[normalize(suffix)] = provider
+}
+
+// LookupPTR returns the first PTR name of ipAddr, without the trailing dot.
+func (h *Hinter) LookupPTR(ctx context.Context, ipAddr string) (string, error) {
+	names, err := h.resolver.LookupAddr(ctx, strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", nil
+	}
+
+	return strings.TrimSuffix(names[0], "."), nil
+}
+
+// Hint returns the provider of the longest suffix matching ptr, or an empty string.
+func (h *Hinter) Hint(ptr string) string {
+	name := normalize(ptr)
+
+	for name != "" {
+		if provider, found := h.suffixes[name]; found {
+			return provider
+		}
+
+		dot := strings.Index(name, ".")
+		if dot < 0 {
+			break
+		}
+		name = name[dot+1:]
+	}
+
+	return ""
+}
+
+func normalize(name string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(name)), ".")
+}
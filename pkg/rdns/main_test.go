@@ -0,0 +1,76 @@
+package rdns
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubResolver returns the PTR names of the IP addresses it holds, and fails for the others.
+type stubResolver map[string][]string
+
+func (r stubResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	names, found := r[addr]
+	if !found {
+		return nil, errors.New("no such host")
+	}
+	return names, nil
+}
+
+func TestProviderHint(t *testing.T) {
+	h := NewHinter()
+	h.AddSuffix("Example.NET.", "Example Hosting")
+	h.SetResolver(stubResolver{
+		"52.94.236.248":  {"ec2-52-94-236-248.compute-1.amazonaws.com."},
+		"34.102.136.180": {"180.136.102.34.bc.googleusercontent.com."},
+		"78.46.1.2":      {"static.2.1.46.78.clients.your-server.de."},
+		"51.75.1.2":      {"ip2.ip-51-75-1.EU.IP-OVH.NET."},
+		"2001:db8::1":    {"host.sub.example.net."},
+		"193.0.6.139":    {"www.ripe.net."},
+		"198.51.100.1":   {"amazonaws.com.example.org."},
+		"198.51.100.2":   {"notamazonaws.com."},
+		"203.0.113.1":    {},
+		"203.0.113.2":    {"first.ovh.net.", "second.amazonaws.com."},
+	})
+
+	tests := []struct {
+		ipAddr   string
+		wantPtr  string
+		wantHint string
+		wantErr  bool
+	}{
+		{"52.94.236.248", "ec2-52-94-236-248.compute-1.amazonaws.com", "Amazon Web Services", false},
+		// the longest suffix wins
+		{"34.102.136.180", "180.136.102.34.bc.googleusercontent.com", "Google Cloud", false},
+		{"78.46.1.2", "static.2.1.46.78.clients.your-server.de", "Hetzner", false},
+		// suffixes match regardless of case
+		{"51.75.1.2", "ip2.ip-51-75-1.EU.IP-OVH.NET", "OVH", false},
+		{"[2001:db8::1]", "host.sub.example.net", "Example Hosting", false},
+		{"193.0.6.139", "www.ripe.net", "", false},
+		// suffixes only match whole labels at the end
+		{"198.51.100.1", "amazonaws.com.example.org", "", false},
+		{"198.51.100.2", "notamazonaws.com", "", false},
+		{"203.0.113.1", "", "", false},
+		// the first name counts
+		{"203.0.113.2", "first.ovh.net", "OVH", false},
+		{"192.0.2.1", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ipAddr, func(t *testing.T) {
+			ptr, err := h.LookupPTR(context.Background(), tt.ipAddr)
+			if (err != nil) != tt.wantErr || ptr != tt.wantPtr {
+				t.Fatalf("LookupPTR = %q, %v, want %q", ptr, err, tt.wantPtr)
+			}
+			if hint := h.Hint(ptr); hint != tt.wantHint {
+				t.Errorf("Hint(%q) = %q, want %q", ptr, hint, tt.wantHint)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BatchError holds the errors of the resources a batch lookup could not resolve. The results of
+// all other resources are still returned alongside it.
+type BatchError struct {
+	DataCall string
+	Errors   map[string]error
+}
+
+func (e *BatchError) Error() string {
+	resources := make([]string, 0, len(e.Errors))
+	for resource := range e.Errors {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	messages := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		messages = append(messages, fmt.Sprintf("%s: %v", resource, e.Errors[resource]))
+	}
+
+	return fmt.Sprintf("%s failed for %d resources: %s", e.DataCall, len(e.Errors), strings.Join(messages, "; "))
+}
+
+// GetNetworkInfoBatch returns the network info of every IP address in ipAddrs it could resolve,
+// and a *BatchError for the ones it could not.
+func (c *Client) GetNetworkInfoBatch(ctx context.Context, ipAddrs []string) (map[string]NetworkInfo, error) {
+	return batch(ctx, c, "network-info", ipAddrs, c.GetNetworkInfo)
+}
+
+// GetAbuseContactsBatch returns the abuse contacts of every IP address in ipAddrs it could
+// resolve, and a *BatchError for the ones it could not.
+func (c *Client) GetAbuseContactsBatch(ctx context.Context, ipAddrs []string) (map[string][]string, error) {
+	return batch(ctx, c, "abuse-contact-finder", ipAddrs, c.GetAbuseContacts)
+}
+
+// batch looks up every unique resource with get. network-info and abuse-contact-finder only take
+// a single resource per request, so duplicates are dropped and the remaining requests run
+// concurrently, bounded by the request limit of the client.
+func batch[T any](ctx context.Context, c *Client, dataCall string, resources []string, get func(context.Context, string) (T, error)) (map[string]T, error) {
+	results := make(map[string]T, len(resources))
+	batchErr := &BatchError{
+		DataCall: dataCall,
+		Errors:   make(map[string]error),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	seen := make(map[string]struct{}, len(resources))
+	for _, resource := range resources {
+		if _, found := seen[resource]; found {
+			continue
+		}
+		seen[resource] = struct{}{}
+
+		wg.Add(1)
+		go func(resource string) {
+			defer wg.Done()
+
+			result, err := get(ctx, resource)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				batchErr.Errors[resource] = err
+				return
+			}
+			results[resource] = result
+		}(resource)
+	}
+	wg.Wait()
+
+	if len(batchErr.Errors) > 0 {
+		return results, batchErr
+	}
+
+	return results, nil
+}
@@ -0,0 +1,105 @@
+package ripestat_test
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/ripestattest"
+)
+
+// recordURLs makes server record the URL of every request, sorted as the batches request concurrently.
+func recordURLs(server *ripestattest.Server) func() []string {
+	var mu sync.Mutex
+	var urls []string
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		urls = append(urls, r.URL.String())
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	})
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		sorted := append([]string(nil), urls...)
+		sort.Strings(sorted)
+		return sorted
+	}
+}
+
+func TestGetNetworkInfoBatch(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "193.0.6.139", ripestattest.JSON(networkInfo))
+	server.Handle("network-info", "2001:67c:2e8:22::c100:68b", ripestattest.JSON(`{"asns": ["3333"], "prefix": "2001:67c:2e8::/48"}`))
+	server.Handle("network-info", "192.0.2.1", ripestattest.Error(http.StatusBadRequest, "bad request"))
+	urls := recordURLs(server)
+
+	var logs bytes.Buffer
+	infos, err := newClient(server, 0, &logs).GetNetworkInfoBatch(context.Background(),
+		[]string{"193.0.6.139", "2001:67c:2e8:22::c100:68b", "193.0.6.139", "192.0.2.1"})
+
+	// one request per unique IP address, the data calls take a single resource
+	wantURLs := []string{
+		"/data/network-info/data.json?resource=192.0.2.1&sourceapp=nuclei-parse-enrich",
+		"/data/network-info/data.json?resource=193.0.6.139&sourceapp=nuclei-parse-enrich",
+		"/data/network-info/data.json?resource=2001%3A67c%3A2e8%3A22%3A%3Ac100%3A68b&sourceapp=nuclei-parse-enrich",
+	}
+	if got := urls(); !reflect.DeepEqual(got, wantURLs) {
+		t.Errorf("requested %q, want %q", got, wantURLs)
+	}
+
+	prefixes := make(map[string]string)
+	for ipAddr, info := range infos {
+		prefixes[ipAddr] = info.Prefix
+	}
+	wantPrefixes := map[string]string{"193.0.6.139": "193.0.0.0/21", "2001:67c:2e8:22::c100:68b": "2001:67c:2e8::/48"}
+	if !reflect.DeepEqual(prefixes, wantPrefixes) {
+		t.Errorf("prefixes %v, want %v", prefixes, wantPrefixes)
+	}
+
+	var batchErr *ripestat.BatchError
+	if !errors.As(err, &batchErr) || batchErr.DataCall != "network-info" || len(batchErr.Errors) != 1 || batchErr.Errors["192.0.2.1"] == nil {
+		t.Errorf("GetNetworkInfoBatch error = %v, want a BatchError for 192.0.2.1", err)
+	}
+}
+
+func TestGetAbuseContactsBatch(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("abuse-contact-finder", "193.0.6.139", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+	server.Handle("abuse-contact-finder", "185.49.140.1", ripestattest.JSON(`{"abuse_contacts": ["abuse@divd.nl", "cert@divd.nl"]}`))
+	urls := recordURLs(server)
+
+	var logs bytes.Buffer
+	contacts, err := newClient(server, 0, &logs).GetAbuseContactsBatch(context.Background(), []string{"193.0.6.139", "185.49.140.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantURLs := []string{
+		"/data/abuse-contact-finder/data.json?resource=185.49.140.1&sourceapp=nuclei-parse-enrich",
+		"/data/abuse-contact-finder/data.json?resource=193.0.6.139&sourceapp=nuclei-parse-enrich",
+	}
+	if got := urls(); !reflect.DeepEqual(got, wantURLs) {
+		t.Errorf("requested %q, want %q", got, wantURLs)
+	}
+	want := map[string][]string{"193.0.6.139": {"abuse@ripe.net"}, "185.49.140.1": {"abuse@divd.nl", "cert@divd.nl"}}
+	if !reflect.DeepEqual(contacts, want) {
+		t.Errorf("GetAbuseContactsBatch = %v, want %v", contacts, want)
+	}
+}
@@ -1,99 +1,611 @@
 package ripestat
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	asnum "nuclei-parse-enrich/pkg/asn"
+
+	"github.com/sirupsen/logrus"
 )
 
 const (
 	DATA_URL = "https://stat.ripe.net/data/"
+
+	// RipeSTAT asks its users to keep the number of parallel requests low
+	DefaultMaxConcurrentRequests = 8
+
+	// DefaultMaxResponseSize bounds the body of a single response, the largest regular responses
+	// (maxmind-geo-lite of large prefixes) are well below it
+	DefaultMaxResponseSize = 4 << 20
+
+	// DefaultSourceApp identifies the requests of the enricher, unless another sourceapp is set, and
+	// is used by NewRipeStatClient instead of an invalid sourceapp
+	DefaultSourceApp = "AS50559-DIVD_NL"
+
+	// DefaultUnavailableCooldown is how long requests fail fast after RipeSTAT announced maintenance
+	DefaultUnavailableCooldown = time.Minute
 )
 
+// ErrSourceUnavailable is wrapped by the errors of requests RipeSTAT can't serve for now, see UnavailableError.
+var ErrSourceUnavailable = errors.New("RipeSTAT unavailable")
+
 type Client struct {
 	SourceApp  string
 	MaxRetries int
+	Logger     logrus.FieldLogger
+	// Timeout bounds every single request, zero means no limit besides the context of the caller
+	Timeout time.Duration
+	// SlowThreshold logs a warning for every call taking longer, zero disables the warning
+	SlowThreshold time.Duration
+	// UserAgent is sent with every request when set
+	UserAgent string
+	// HTTPClient sends the requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+	// MaxResponseSize bounds the body of a response in bytes, zero means DefaultMaxResponseSize
+	MaxResponseSize int64
+	// UnavailableCooldown is how long requests fail with ErrSourceUnavailable without being sent
+	// after a response announced maintenance, zero means DefaultUnavailableCooldown
+	UnavailableCooldown time.Duration
+	// BaseURL is the URL the data calls are relative to, DATA_URL when empty
+	BaseURL string
+
+	latencies latencies
+	// rateLimited counts the responses with status 429
+	rateLimitedMu sync.Mutex
+	rateLimited   int64
+	// unavailableUntil is the end of the cooldown after the last maintenance response
+	unavailableMu    sync.Mutex
+	unavailableUntil time.Time
+	unavailableMsg   string
+	// warned holds the schema and redirect warnings logged before
+	warned sync.Map
+	// limits the number of requests in flight, regardless of the number of callers
+	requestSem chan struct{}
 }
 
+// NewRipeStatClient returns a client identifying itself with sourceApp, see ValidateSourceApp.
+// An invalid sourceApp is replaced by DefaultSourceApp with a warning.
 func NewRipeStatClient(sourceApp string, maxRetries int) *Client {
-	return &Client{
+	c := &Client{
 		SourceApp:  sourceApp,
 		MaxRetries: maxRetries,
+		Logger:     logrus.StandardLogger(),
+		requestSem: make(chan struct{}, DefaultMaxConcurrentRequests),
+	}
+	if err := ValidateSourceApp(sourceApp); err != nil {
+		c.Logger.Warnf("ripestat: %v, using %q", err, DefaultSourceApp)
+		c.SourceApp = DefaultSourceApp
+	}
+	return c
+}
+
+// sourceAppRegexp matches the identifiers RipeSTAT asks for as sourceapp, e.g. a company name or
+// AS number followed by an application name. It is stricter than RipeSTAT, so the sourceapp can be
+// sent as is and reads well in the logs of RipeSTAT.
+var sourceAppRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// ValidateSourceApp checks that sourceApp is a non-empty identifier of at most 64 letters, digits,
+// dots, dashes and underscores, starting with a letter or digit.
+func ValidateSourceApp(sourceApp string) error {
+	if sourceApp == "" {
+		return errors.New("empty sourceapp")
 	}
+	if !sourceAppRegexp.MatchString(sourceApp) {
+		return fmt.Errorf("invalid sourceapp %q, expected up to 64 letters, digits, '.', '-' or '_'", sourceApp)
+	}
+	return nil
 }
 
-func (c *Client) GetAbuseContacts(ipAddr string) ([]string, error) {
-	data, err := c.send("abuse-contact-finder", ipAddr)
+// ValidateBaseURL checks that baseURL is an absolute http or https URL, see Client.BaseURL.
+func ValidateBaseURL(baseURL string) error {
+	u, err := url.Parse(baseURL)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return ConvertAbuseContactsData(data)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid base URL %q, expected an http or https URL", baseURL)
+	}
+	return nil
+}
+
+// MaxConcurrentRequests returns the maximum number of requests the client has in flight at once.
+func (c *Client) MaxConcurrentRequests() int {
+	return cap(c.requestSem)
+}
+
+// RateLimited returns the number of responses RipeSTAT rate limited (status 429) so far, retried
+// requests included.
+func (c *Client) RateLimited() int64 {
+	c.rateLimitedMu.Lock()
+	defer c.rateLimitedMu.Unlock()
+	return c.rateLimited
 }
 
-func (c *Client) GetNetworkInfo(ipAddr string) (NetworkInfo, error) {
-	data, err := c.send("network-info", ipAddr)
+// Meta is the envelope of a data call response, everything but its data.
+type Meta struct {
+	DataCall       string `json:"data_call_name"`
+	DataCallStatus string `json:"data_call_status"`
+	Status         string `json:"status"`
+	StatusCode     int    `json:"status_code"`
+	Cached         bool   `json:"cached"`
+	QueryID        string `json:"query_id"`
+	ProcessTime    int    `json:"process_time"`
+	ServerID       string `json:"server_id"`
+	BuildVersion   string `json:"build_version"`
+	Version        string `json:"version"`
+	Time           string `json:"time"`
+	// Messages are the texts of the info and warning messages of the response
+	Messages []string `json:"-"`
+	// SchemaWarnings describe where the response deviates from the layout the client expects
+	SchemaWarnings []string `json:"-"`
+	// QueryTime is the time RipeSTAT queried the data, zero when the data doesn't tell
+	QueryTime time.Time `json:"-"`
+}
+
+// Get requests dataCall (e.g. "network-info") for resource and decodes the data of the response
+// into out, which can be nil to only get the envelope. It is used by the typed methods, and goes
+// through the same retries, limits and error mapping for data calls they don't cover.
+func (c *Client) Get(ctx context.Context, dataCall, resource string, out interface{}) (*Meta, error) {
+	return c.getWithParams(ctx, dataCall, resource, nil, out)
+}
+
+// getWithParams is Get with additional query parameters for the data call.
+func (c *Client) getWithParams(ctx context.Context, dataCall, resource string, params url.Values, out interface{}) (*Meta, error) {
+	body, err := c.sendWithParams(ctx, dataCall, resource, params)
 	if err != nil {
+		return nil, err
+	}
+
+	meta, err := decodeResponse(body, out)
+	var noData *NoDataError
+	if errors.As(err, &noData) {
+		noData.DataCall = dataCall
+	}
+	if meta != nil {
+		hintsFrom(ctx).addQueryTime(meta.QueryTime)
+		for _, warning := range meta.SchemaWarnings {
+			c.warnOnce(warning)
+		}
+	}
+	return meta, err
+}
+
+// warnOnce logs warning the first time it occurs, a changed layout affects every response.
+func (c *Client) warnOnce(warning string) {
+	if _, warned := c.warned.LoadOrStore(warning, struct{}{}); !warned {
+		c.Logger.Warnf("ripestat: %s", warning)
+	}
+}
+
+func (c *Client) GetAbuseContacts(ctx context.Context, ipAddr string) ([]string, error) {
+	var data AbuseContactFinder
+	if _, err := c.Get(ctx, "abuse-contact-finder", ipAddr, &data); err != nil {
+		return nil, err
+	}
+	return data.AbuseContacts, nil
+}
+
+func (c *Client) GetNetworkInfo(ctx context.Context, ipAddr string) (NetworkInfo, error) {
+	var data NetworkInfo
+	if _, err := c.Get(ctx, "network-info", ipAddr, &data); err != nil {
 		return NetworkInfo{}, err
 	}
-	return ConvertNetworkInfoData(data)
+	return data, nil
 }
 
-func (c *Client) GetASOverview(asn string) (ASOverview, error) {
-	data, err := c.send("as-overview", asn)
-	if err != nil {
+func (c *Client) GetASOverview(ctx context.Context, asn string) (ASOverview, error) {
+	var data ASOverview
+	if _, err := c.Get(ctx, "as-overview", asResource(asn), &data); err != nil {
 		return ASOverview{}, err
 	}
-	return ConvertASOverviewData(data)
+	return data, nil
 }
 
-func (c *Client) GetGeolocationData(prefix string) (MaxmindGeoLite, error) {
-	data, err := c.send("maxmind-geo-lite", prefix)
-	if err != nil {
+func (c *Client) GetGeolocationData(ctx context.Context, prefix string) (MaxmindGeoLite, error) {
+	var data MaxmindGeoLite
+	if _, err := c.Get(ctx, "maxmind-geo-lite", prefix, &data); err != nil {
 		return MaxmindGeoLite{}, err
 	}
-	return ConvertGeolocationData(data)
+	return data, nil
+}
+
+// GetRoutingStatus returns the routing status of resource, a prefix or IP address, including the
+// origin AS of its last announcement.
+func (c *Client) GetRoutingStatus(ctx context.Context, resource string) (RoutingStatus, error) {
+	var data RoutingStatus
+	if _, err := c.Get(ctx, "routing-status", resource, &data); err != nil {
+		return RoutingStatus{}, err
+	}
+	return data, nil
+}
+
+// GetWhois returns the registry objects of resource from the authoritative RIR, e.g. the inetnum
+// or NetRange of an IP address.
+func (c *Client) GetWhois(ctx context.Context, resource string) (WhoisData, error) {
+	var data WhoisData
+	if _, err := c.Get(ctx, "whois", resource, &data); err != nil {
+		return WhoisData{}, err
+	}
+	return data, nil
+}
+
+// GetASNNeighbours returns the neighbours of an AS by relationship. It is not part of the default
+// enrichment, large transit networks have thousands of neighbours.
+func (c *Client) GetASNNeighbours(ctx context.Context, asn string) (ASNNeighbours, error) {
+	var data ASNNeighboursData
+	if _, err := c.Get(ctx, "asn-neighbours", asResource(asn), &data); err != nil {
+		return ASNNeighbours{}, err
+	}
+	return groupNeighbours(data), nil
+}
+
+// GetHistoricalWhois returns the RIPE database object of resource (an IP address, prefix or AS
+// number) as it was registered at t. It returns ErrNoHistoricalRecord when no version of the
+// object was registered at t, e.g. for resources outside the RIPE region.
+func (c *Client) GetHistoricalWhois(ctx context.Context, resource string, t time.Time) (HistoricalWhois, error) {
+	var versions HistoricalWhoisData
+	if _, err := c.Get(ctx, "historical-whois", resource, &versions); err != nil {
+		return HistoricalWhois{}, err
+	}
+
+	version, found := versionAt(versions.Versions, t)
+	if !found {
+		return HistoricalWhois{}, ErrNoHistoricalRecord
+	}
+
+	var data HistoricalWhoisData
+	params := url.Values{"version": {strconv.Itoa(version.Version)}}
+	if _, err := c.getWithParams(ctx, "historical-whois", resource, params, &data); err != nil {
+		return HistoricalWhois{}, err
+	}
+	return historicalObject(data, version)
+}
+
+// asResource returns AS numbers in the form the data calls accept, other values unchanged.
+func asResource(s string) string {
+	number, err := asnum.Parse(s)
+	if err != nil {
+		return s
+	}
+	return asnum.Format(number)
 }
 
-func (c *Client) send(endpoint, resource string) ([]byte, error) {
+// sendWithParams requests the data call endpoint for resource with the additional query
+// parameters, retrying failures that may be temporary, and returns the response body.
+func (c *Client) sendWithParams(ctx context.Context, endpoint, resource string, params url.Values) ([]byte, error) {
+	defer c.observe(endpoint, resource, time.Now())
+
+	if err := c.checkAvailable(endpoint); err != nil {
+		return nil, err
+	}
+
 	if c.MaxRetries < 0 {
 		return nil, fmt.Errorf("invalid MaxRetries, expected positive integer")
 	} else if c.MaxRetries == 0 {
-		return c.sendRequest(endpoint, resource)
+		return c.sendRequest(ctx, endpoint, resource, params)
 	}
 
 	lastTimeout := 1000 * time.Millisecond
+	var lastErr error
 	for i := 0; i < c.MaxRetries; i++ {
-		result, err := c.sendRequest(endpoint, resource)
+		result, err := c.sendRequest(ctx, endpoint, resource, params)
 		if err == nil {
 			return result, err
 		}
-		fmt.Printf("got error %v, sleeping %v\n", err, lastTimeout)
-		time.Sleep(lastTimeout)
+		lastErr = err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.Temporary() && statusErr.StatusCode >= 400 {
+			// a client error doesn't go away by retrying
+			return nil, err
+		}
+		var tooLargeErr *ResponseTooLargeError
+		if errors.As(err, &tooLargeErr) {
+			return nil, err
+		}
+		if errors.Is(err, ErrSourceUnavailable) {
+			// retrying during the cooldown fails without sending a request
+			return nil, err
+		}
+		c.Logger.WithFields(logrus.Fields{
+			"data_call": endpoint,
+			"resource":  resource,
+		}).Debugf("got error %v, sleeping %v", err, lastTimeout)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lastTimeout):
+		}
 		jitter := time.Duration(rand.Intn(1000)) * time.Millisecond
 		lastTimeout += lastTimeout + jitter
 	}
 
-	return nil, fmt.Errorf("MaxRetries (%d) exceeded for endpoint %q and resource %q", c.MaxRetries, endpoint, resource)
+	return nil, fmt.Errorf("MaxRetries (%d) exceeded for endpoint %q and resource %q: %w", c.MaxRetries, endpoint, resource, lastErr)
 }
 
-func (c *Client) sendRequest(endpoint, resource string) ([]byte, error) {
-	endpoint = url.QueryEscape(endpoint)
-	resource = url.QueryEscape(resource)
-	url := DATA_URL + endpoint + "/data.json?resource=" + resource + "&sourceapp=" + c.SourceApp
+func (c *Client) sendRequest(ctx context.Context, endpoint, resource string, params url.Values) ([]byte, error) {
+	requestURL, err := c.requestURL(endpoint, resource, params)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case c.requestSem <- struct{}{}:
+	}
+	defer func() { <-c.requestSem }()
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
 
-	resp, err := http.Get(url)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	limit := c.MaxResponseSize
+	if limit <= 0 {
+		limit = DefaultMaxResponseSize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
 	if err != nil {
 		return nil, err
 	}
+	if int64(len(body)) > limit {
+		return nil, &ResponseTooLargeError{DataCall: endpoint, Limit: limit}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.rateLimitedMu.Lock()
+		c.rateLimited++
+		c.rateLimitedMu.Unlock()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{DataCall: endpoint, StatusCode: resp.StatusCode, Body: bodySnippet(body)}
+	}
+	if resp.Request != nil && resp.Request.URL.Path != req.URL.Path {
+		c.warnOnce(fmt.Sprintf("%s: redirected to %s", endpoint, resp.Request.URL.Path))
+	}
+	// maintenance pages and redirects to HTML are served with 200 as well
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, &StatusError{DataCall: endpoint, StatusCode: resp.StatusCode, Body: bodySnippet(body)}
+	}
+	// as are maintenance announcements in the regular envelope
+	if message, maintenance := maintenanceMessage(body); maintenance {
+		return nil, c.markUnavailable(endpoint, message)
+	}
 
+	if maxAge, found := parseMaxAge(resp.Header.Get("Cache-Control")); found {
+		hintsFrom(ctx).addMaxAge(maxAge)
+	}
 	return body, nil
 }
+
+// checkAvailable returns an UnavailableError during the cooldown after a maintenance response.
+func (c *Client) checkAvailable(endpoint string) error {
+	c.unavailableMu.Lock()
+	defer c.unavailableMu.Unlock()
+
+	if time.Now().Before(c.unavailableUntil) {
+		return &UnavailableError{DataCall: endpoint, Message: c.unavailableMsg}
+	}
+	return nil
+}
+
+// markUnavailable starts the cooldown after a maintenance response and returns its error.
+func (c *Client) markUnavailable(endpoint, message string) error {
+	cooldown := c.UnavailableCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultUnavailableCooldown
+	}
+
+	c.unavailableMu.Lock()
+	started := !time.Now().Before(c.unavailableUntil)
+	c.unavailableUntil = time.Now().Add(cooldown)
+	c.unavailableMsg = message
+	c.unavailableMu.Unlock()
+
+	if started {
+		c.Logger.WithField("data_call", endpoint).Warnf("RipeSTAT is unavailable (%s), failing requests for %v", message, cooldown)
+	}
+	return &UnavailableError{DataCall: endpoint, Message: message}
+}
+
+// maintenanceMessage reports whether body announces RipeSTAT is in maintenance, either by its
+// status or by an empty data object with a message saying so, and returns the announcement.
+func maintenanceMessage(body []byte) (string, bool) {
+	var envelope struct {
+		Status         string          `json:"status"`
+		DataCallStatus string          `json:"data_call_status"`
+		Messages       json.RawMessage `json:"messages"`
+		Data           json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		// the converters report the malformed response
+		return "", false
+	}
+
+	messages := envelopeMessages(envelope.Messages)
+	message := strings.Join(messages, "; ")
+	if message == "" {
+		message = "maintenance"
+	}
+
+	if strings.EqualFold(envelope.Status, "maintenance") || strings.HasPrefix(strings.ToLower(envelope.DataCallStatus), "maintenance") {
+		return message, true
+	}
+
+	switch string(bytes.TrimSpace(envelope.Data)) {
+	case "", "null", "{}", "[]":
+	default:
+		return "", false
+	}
+	for _, m := range messages {
+		lower := strings.ToLower(m)
+		if strings.Contains(lower, "maintenance") || strings.Contains(lower, "temporarily unavailable") {
+			return message, true
+		}
+	}
+	return "", false
+}
+
+// envelopeMessages returns the texts of the messages of a response, which RipeSTAT sends as
+// [level, text] pairs.
+func envelopeMessages(raw json.RawMessage) []string {
+	var pairs [][]string
+	if err := json.Unmarshal(raw, &pairs); err == nil {
+		var messages []string
+		for _, pair := range pairs {
+			if len(pair) > 0 {
+				messages = append(messages, pair[len(pair)-1])
+			}
+		}
+		return messages
+	}
+
+	var messages []string
+	_ = json.Unmarshal(raw, &messages)
+	return messages
+}
+
+// UnavailableError is returned for a response announcing RipeSTAT is in maintenance, which is
+// served with status 200, and for the requests not sent during the cooldown after it.
+type UnavailableError struct {
+	DataCall string
+	Message  string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: %v: %s", e.DataCall, ErrSourceUnavailable, e.Message)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrSourceUnavailable
+}
+
+// Temporary reports whether the request may succeed when retried, which it may after the maintenance.
+func (e *UnavailableError) Temporary() bool {
+	return true
+}
+
+// ErrNoData is wrapped by the errors of data calls answering with null or empty data, see NoDataError.
+var ErrNoData = errors.New("no data")
+
+// NoDataError is returned for a response with status 200 without data, which a single data call
+// may serve while the others answer. Unlike an UnavailableError it doesn't start a cooldown.
+type NoDataError struct {
+	DataCall string
+}
+
+func (e *NoDataError) Error() string {
+	return fmt.Sprintf("no data from ripestat/%s", e.DataCall)
+}
+
+func (e *NoDataError) Unwrap() error {
+	return ErrNoData
+}
+
+// StatusError is returned for a response with a status other than 2xx, or without a JSON body.
+// Body holds the start of the response body.
+type StatusError struct {
+	DataCall   string
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.StatusCode >= 200 && e.StatusCode <= 299 {
+		return fmt.Sprintf("%s returned status %d without JSON: %q", e.DataCall, e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("%s returned status %d: %q", e.DataCall, e.StatusCode, e.Body)
+}
+
+// ResponseTooLargeError is returned for a response with a body larger than Client.MaxResponseSize.
+type ResponseTooLargeError struct {
+	DataCall string
+	Limit    int64
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("%s response too large, exceeds %d bytes", e.DataCall, e.Limit)
+}
+
+// Temporary reports whether the request may succeed when retried.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// maxSnippetLength bounds the body quoted in a StatusError
+const maxSnippetLength = 200
+
+// bodySnippet returns the start of body with its whitespace collapsed.
+func bodySnippet(body []byte) string {
+	if len(body) > 4*maxSnippetLength {
+		body = body[:4*maxSnippetLength]
+	}
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if len(snippet) > maxSnippetLength {
+		snippet = snippet[:maxSnippetLength] + "..."
+	}
+	return snippet
+}
+
+// requestURL returns the URL of a data call for resource, relative to BaseURL. The resource,
+// sourceapp and params are encoded in the query string, so IPv6 addresses and prefixes are
+// passed unaltered.
+func (c *Client) requestURL(endpoint, resource string, params url.Values) (string, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DATA_URL
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %v", base, err)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + endpoint + "/data.json"
+	u.RawPath = ""
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("resource", resource)
+	query.Set("sourceapp", c.SourceApp)
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
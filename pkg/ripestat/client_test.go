@@ -0,0 +1,362 @@
+package ripestat_test
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/ripestattest"
+
+	"github.com/sirupsen/logrus"
+)
+
+const networkInfo = `{"asns": ["3333"], "prefix": "193.0.0.0/21"}`
+
+// newClient returns a client of server with maxRetries, logging to logs.
+func newClient(server *ripestattest.Server, maxRetries int, logs *bytes.Buffer) *ripestat.Client {
+	client := ripestat.NewRipeStatClient("nuclei-parse-enrich", maxRetries)
+	client.BaseURL = server.BaseURL()
+	logger := logrus.New()
+	logger.SetOutput(logs)
+	client.Logger = logger
+	return client
+}
+
+func TestStatusHandling(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []ripestattest.Response
+		// status is the StatusCode of the StatusError the lookup fails with, zero when it succeeds
+		status int
+	}{
+		{"ok", []ripestattest.Response{ripestattest.JSON(networkInfo)}, 0},
+		{"rate limited", []ripestattest.Response{ripestattest.RateLimited()}, http.StatusTooManyRequests},
+		{"server error", []ripestattest.Response{ripestattest.Error(http.StatusInternalServerError, "internal error")}, http.StatusInternalServerError},
+		{"not found", []ripestattest.Response{ripestattest.Error(http.StatusNotFound, "not found")}, http.StatusNotFound},
+		{"empty 200", []ripestattest.Response{{Body: " "}}, http.StatusOK},
+		{"html 200", []ripestattest.Response{{Body: "<html>maintenance</html>"}}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ripestattest.NewServer()
+			defer server.Close()
+			server.Handle("network-info", "193.0.6.139", tt.responses...)
+
+			var logs bytes.Buffer
+			info, err := newClient(server, 0, &logs).GetNetworkInfo(context.Background(), "193.0.6.139")
+			if tt.status == 0 {
+				if err != nil || info.Prefix != "193.0.0.0/21" {
+					t.Errorf("GetNetworkInfo = %+v, %v, want the prefix", info, err)
+				}
+				return
+			}
+
+			var statusErr *ripestat.StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tt.status {
+				t.Fatalf("GetNetworkInfo error = %v, want a StatusError with status %d", err, tt.status)
+			}
+			if temporary := tt.status == http.StatusTooManyRequests || tt.status >= 500; statusErr.Temporary() != temporary {
+				t.Errorf("Temporary() = %v for status %d", statusErr.Temporary(), tt.status)
+			}
+		})
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	moved := ripestattest.Response{
+		StatusCode: http.StatusMovedPermanently,
+		Header:     http.Header{"Location": {server.BaseURL() + "v2/network-info/data.json?resource=193.0.6.139"}},
+	}
+	server.Handle("network-info", "193.0.6.139", moved, ripestattest.JSON(networkInfo))
+
+	var logs bytes.Buffer
+	client := newClient(server, 0, &logs)
+	for i := 0; i < 2; i++ {
+		info, err := client.GetNetworkInfo(context.Background(), "193.0.6.139")
+		if err != nil || info.Prefix != "193.0.0.0/21" {
+			t.Fatalf("GetNetworkInfo = %+v, %v, want the data of the redirect", info, err)
+		}
+	}
+	// the redirect is followed, and warned about once
+	if warnings := strings.Count(logs.String(), "redirected to /data/v2/network-info/data.json"); warnings != 1 {
+		t.Errorf("warned %d times about the redirect:\n%s", warnings, logs.String())
+	}
+}
+
+func TestRetries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("retries back off for a second")
+	}
+
+	tests := []struct {
+		name  string
+		first ripestattest.Response
+		// retried is set for the failures retried with MaxRetries
+		retried bool
+	}{
+		{"rate limited", ripestattest.RateLimited(), true},
+		{"server error", ripestattest.Error(http.StatusInternalServerError, "internal error"), true},
+		{"empty 200", ripestattest.Response{Body: " "}, true},
+		{"bad request", ripestattest.Error(http.StatusBadRequest, "bad request"), false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := ripestattest.NewServer()
+			defer server.Close()
+			server.Handle("network-info", "193.0.6.139", tt.first, ripestattest.JSON(networkInfo))
+
+			var logs bytes.Buffer
+			client := newClient(server, 2, &logs)
+			info, err := client.GetNetworkInfo(context.Background(), "193.0.6.139")
+			if retried := err == nil && info.Prefix == "193.0.0.0/21"; retried != tt.retried {
+				t.Errorf("GetNetworkInfo = %+v, %v, want retried: %v", info, err, tt.retried)
+			}
+
+			requests := 1
+			if tt.retried {
+				requests = 2
+			}
+			server.AssertRequests(t, "network-info", "193.0.6.139", requests)
+			if rateLimited := client.RateLimited(); (tt.first.StatusCode == http.StatusTooManyRequests) != (rateLimited == 1) {
+				t.Errorf("RateLimited() = %d", rateLimited)
+			}
+		})
+	}
+}
+
+func TestGetASNNeighbours(t *testing.T) {
+	fixture, err := os.ReadFile(filepath.Join("testdata", "asn-neighbours.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("asn-neighbours", "3333", ripestattest.Response{Body: string(fixture)})
+
+	var logs bytes.Buffer
+	neighbours, err := newClient(server, 0, &logs).GetASNNeighbours(context.Background(), "AS3333")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(neighbours.Upstreams) != 2 || neighbours.Upstreams[0].Asn != 1299 || len(neighbours.Downstreams) != 1 ||
+		neighbours.Downstreams[0].Asn != 12654 || len(neighbours.Uncertain) != 1 {
+		t.Errorf("GetASNNeighbours = %+v", neighbours)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("logged for a regular response:\n%s", &logs)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	tests := []struct {
+		fixture string
+		message string
+	}{
+		// announced by the status of the envelope
+		{"maintenance.json", "RIPEstat is undergoing scheduled maintenance, please try again later."},
+		// announced by a message with empty data only
+		{"maintenance-message.json", "This data call is temporarily unavailable due to maintenance of its backend."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := ripestattest.NewServer()
+			defer server.Close()
+			server.Handle("network-info", "193.0.6.139", ripestattest.Response{Body: string(fixture)}, ripestattest.JSON(networkInfo))
+			server.Handle("abuse-contact-finder", "193.0.6.139", ripestattest.JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+
+			var logs bytes.Buffer
+			client := newClient(server, 2, &logs)
+			client.UnavailableCooldown = 100 * time.Millisecond
+
+			_, err = client.GetNetworkInfo(context.Background(), "193.0.6.139")
+			var unavailableErr *ripestat.UnavailableError
+			if !errors.Is(err, ripestat.ErrSourceUnavailable) || !errors.As(err, &unavailableErr) {
+				t.Fatalf("GetNetworkInfo error = %v, want an UnavailableError", err)
+			}
+			if unavailableErr.DataCall != "network-info" || unavailableErr.Message != tt.message || !unavailableErr.Temporary() {
+				t.Errorf("UnavailableError = %+v", unavailableErr)
+			}
+			// the maintenance response is not retried
+			server.AssertRequests(t, "network-info", "193.0.6.139", 1)
+
+			// during the cooldown the other data calls fail without a request either
+			if _, err := client.GetAbuseContacts(context.Background(), "193.0.6.139"); !errors.Is(err, ripestat.ErrSourceUnavailable) ||
+				!strings.Contains(err.Error(), tt.message) {
+				t.Errorf("GetAbuseContacts during the cooldown returned %v, want ErrSourceUnavailable", err)
+			}
+			server.AssertRequests(t, "abuse-contact-finder", "193.0.6.139", 0)
+			if warnings := strings.Count(logs.String(), "RipeSTAT is unavailable"); warnings != 1 {
+				t.Errorf("warned %d times about the maintenance:\n%s", warnings, logs.String())
+			}
+
+			time.Sleep(client.UnavailableCooldown)
+			info, err := client.GetNetworkInfo(context.Background(), "193.0.6.139")
+			if err != nil || info.Prefix != "193.0.0.0/21" {
+				t.Errorf("GetNetworkInfo after the cooldown = %+v, %v, want the prefix", info, err)
+			}
+			server.AssertRequests(t, "network-info", "193.0.6.139", 2)
+		})
+	}
+}
+
+func TestNoData(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		dataCall string
+		resource string
+		get      func(client *ripestat.Client) error
+	}{
+		{"abuse-contact-finder", "193.0.6.139", func(client *ripestat.Client) error {
+			_, err := client.GetAbuseContacts(ctx, "193.0.6.139")
+			return err
+		}},
+		{"network-info", "193.0.6.139", func(client *ripestat.Client) error {
+			_, err := client.GetNetworkInfo(ctx, "193.0.6.139")
+			return err
+		}},
+		{"as-overview", "64496", func(client *ripestat.Client) error {
+			_, err := client.GetASOverview(ctx, "AS64496")
+			return err
+		}},
+		{"maxmind-geo-lite", "193.0.0.0/21", func(client *ripestat.Client) error {
+			_, err := client.GetGeolocationData(ctx, "193.0.0.0/21")
+			return err
+		}},
+		{"routing-status", "193.0.6.139", func(client *ripestat.Client) error {
+			_, err := client.GetRoutingStatus(ctx, "193.0.6.139")
+			return err
+		}},
+		{"whois", "193.0.6.139", func(client *ripestat.Client) error {
+			_, err := client.GetWhois(ctx, "193.0.6.139")
+			return err
+		}},
+		{"asn-neighbours", "3333", func(client *ripestat.Client) error {
+			_, err := client.GetASNNeighbours(ctx, "3333")
+			return err
+		}},
+		{"historical-whois", "193.0.6.139", func(client *ripestat.Client) error {
+			_, err := client.GetHistoricalWhois(ctx, "193.0.6.139", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dataCall, func(t *testing.T) {
+			fixture, err := os.ReadFile(filepath.Join("testdata", "no-data", tt.dataCall+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := ripestattest.NewServer()
+			defer server.Close()
+			server.Handle(tt.dataCall, tt.resource, ripestattest.Response{Body: string(fixture)})
+
+			var logs bytes.Buffer
+			client := newClient(server, 2, &logs)
+			for i := 1; i <= 2; i++ {
+				err := tt.get(client)
+				var noData *ripestat.NoDataError
+				if !errors.Is(err, ripestat.ErrNoData) || !errors.As(err, &noData) || noData.DataCall != tt.dataCall {
+					t.Fatalf("error = %v, want a NoDataError of %s", err, tt.dataCall)
+				}
+				if errors.Is(err, ripestat.ErrSourceUnavailable) {
+					t.Errorf("error = %v, want RipeSTAT available", err)
+				}
+				// no data is no failure to retry, nor a cooldown for the next request
+				server.AssertRequests(t, tt.dataCall, tt.resource, i)
+			}
+			server.AssertNoUnexpected(t)
+
+			// only the typed data is missing, the envelope is there
+			meta, err := client.Get(ctx, tt.dataCall, tt.resource, nil)
+			if err != nil || meta.DataCall != tt.dataCall {
+				t.Errorf("Get without data = %+v, %v", meta, err)
+			}
+		})
+	}
+}
+
+// TestMaxResponseSize accepts a response of exactly MaxResponseSize bytes and fails one byte more
+// without retrying.
+func TestMaxResponseSize(t *testing.T) {
+	const body = `{"status": "ok", "status_code": 200, "data": ` + networkInfo + `}`
+	const limit = 1024
+
+	tests := []struct {
+		name    string
+		size    int
+		tooLong bool
+	}{
+		{"below the limit", limit - 1, false},
+		{"at the limit", limit, false},
+		{"over the limit", limit + 1, true},
+		{"far over the limit", 4 * limit, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ripestattest.NewServer()
+			defer server.Close()
+			// JSON allows trailing white space, which pads the response to its size
+			server.Handle("network-info", "193.0.6.139", ripestattest.Response{Body: body + strings.Repeat(" ", tt.size-len(body))})
+
+			var logs bytes.Buffer
+			client := newClient(server, 3, &logs)
+			client.MaxResponseSize = limit
+			info, err := client.GetNetworkInfo(context.Background(), "193.0.6.139")
+
+			if !tt.tooLong {
+				if err != nil || info.Prefix != "193.0.0.0/21" {
+					t.Errorf("GetNetworkInfo = %+v, %v, want the prefix", info, err)
+				}
+				return
+			}
+			var tooLargeErr *ripestat.ResponseTooLargeError
+			if !errors.As(err, &tooLargeErr) {
+				t.Fatalf("GetNetworkInfo error = %v, want a ResponseTooLargeError", err)
+			}
+			if tooLargeErr.DataCall != "network-info" || tooLargeErr.Limit != limit {
+				t.Errorf("ResponseTooLargeError = %+v, want network-info with limit %d", tooLargeErr, limit)
+			}
+			// a larger response won't get smaller by retrying
+			server.AssertRequests(t, "network-info", "193.0.6.139", 1)
+		})
+	}
+}
+
+// TestDefaultMaxResponseSize bounds responses by DefaultMaxResponseSize without MaxResponseSize.
+func TestDefaultMaxResponseSize(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	server.Handle("network-info", "193.0.6.139", ripestattest.Response{Body: strings.Repeat(" ", ripestat.DefaultMaxResponseSize+1)})
+
+	var logs bytes.Buffer
+	_, err := newClient(server, 0, &logs).GetNetworkInfo(context.Background(), "193.0.6.139")
+	var tooLargeErr *ripestat.ResponseTooLargeError
+	if !errors.As(err, &tooLargeErr) || tooLargeErr.Limit != ripestat.DefaultMaxResponseSize {
+		t.Errorf("GetNetworkInfo error = %v, want a ResponseTooLargeError with the default limit", err)
+	}
+}
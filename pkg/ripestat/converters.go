@@ -9,63 +9,219 @@ package ripestat
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 )
 
-func ConvertAbuseContactsData(data []byte) ([]string, error) {
-	if len(data) == 0 {
+// ErrNoHistoricalRecord is returned by GetHistoricalWhois when no object was registered at the requested time.
+var ErrNoHistoricalRecord = errors.New("no historical whois record at that time")
+
+// decodeResponse decodes the data of a data call response into out, and returns the envelope. It
+// returns a NoDataError when out is set and the data is null or empty.
+func decodeResponse(body []byte, out interface{}) (*Meta, error) {
+	if len(body) == 0 {
 		return nil, fmt.Errorf("empty data")
 	}
 
-	resp := AbuseContactFinderBase{}
+	var envelope struct {
+		Meta
+		Messages json.RawMessage `json:"messages"`
+		Data     json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data: %v", err)
+	}
+
+	meta := envelope.Meta
+	meta.Messages = envelopeMessages(envelope.Messages)
 
-	err := json.NewDecoder(bytes.NewReader(data)).Decode(&resp)
+	data := envelope.Data
+	data, meta.SchemaWarnings = checkSchema(meta, data)
+	meta.QueryTime = parseQueryTime(data)
 
-	if err != nil {
-		return nil, fmt.Errorf("ConvertAbuseContactsData: failed to Unmarshal data: %v", err)
+	if out == nil {
+		return &meta, nil
 	}
+	switch string(bytes.TrimSpace(data)) {
+	case "", "null", "{}", "[]":
+		return &meta, &NoDataError{DataCall: meta.DataCall}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return &meta, fmt.Errorf("failed to unmarshal data: %v", err)
+	}
+	return &meta, nil
+}
 
-	return resp.Data.AbuseContacts, nil
+func ConvertAbuseContactsData(data []byte) ([]string, error) {
+	var resp AbuseContactFinder
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.AbuseContacts, nil
 }
 
 func ConvertNetworkInfoData(data []byte) (NetworkInfo, error) {
+	var resp NetworkInfo
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return NetworkInfo{}, err
+	}
+	return resp, nil
+}
 
-	if len(data) == 0 {
-		return NetworkInfo{}, fmt.Errorf("empty data")
+func ConvertASOverviewData(data []byte) (ASOverview, error) {
+	var resp ASOverview
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return ASOverview{}, err
 	}
+	return resp, nil
+}
 
-	resp := NetworkInfoBase{}
-	err := json.Unmarshal(data, &resp)
+func ConvertGeolocationData(data []byte) (MaxmindGeoLite, error) {
+	var resp MaxmindGeoLite
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return MaxmindGeoLite{}, err
+	}
+	return resp, nil
+}
 
-	if err != nil {
-		return NetworkInfo{}, err
+// ConvertASNNeighboursData groups the neighbours of an asn-neighbours response by relationship.
+func ConvertASNNeighboursData(data []byte) (ASNNeighbours, error) {
+	var resp ASNNeighboursData
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return ASNNeighbours{}, err
 	}
+	return groupNeighbours(resp), nil
+}
 
-	return resp.Data, nil
+func groupNeighbours(data ASNNeighboursData) ASNNeighbours {
+	ret := ASNNeighbours{
+		Resource: data.Resource,
+	}
+	for _, neighbour := range data.Neighbours {
+		switch neighbour.Type {
+		case "left":
+			ret.Upstreams = append(ret.Upstreams, neighbour)
+		case "right":
+			ret.Downstreams = append(ret.Downstreams, neighbour)
+		default:
+			ret.Uncertain = append(ret.Uncertain, neighbour)
+		}
+	}
+	return ret
 }
 
-func ConvertASOverviewData(data []byte) (ASOverview, error) {
-	if len(data) == 0 {
-		return ASOverview{}, fmt.Errorf("empty data")
+// ConvertHistoricalWhoisVersions returns the versions listed in a historical-whois response.
+func ConvertHistoricalWhoisVersions(data []byte) ([]HistoricalWhoisVersion, error) {
+	var resp HistoricalWhoisData
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return nil, err
 	}
+	return resp.Versions, nil
+}
 
-	resp := ASOverviewBase{}
-	err := json.NewDecoder(bytes.NewReader(data)).Decode(&resp)
-	if err != nil {
-		return ASOverview{}, fmt.Errorf("failed to unmarshal data: %v", err)
+// ConvertHistoricalWhoisData returns the object of version from a historical-whois response
+// requested for that version.
+func ConvertHistoricalWhoisData(data []byte, version HistoricalWhoisVersion) (HistoricalWhois, error) {
+	var resp HistoricalWhoisData
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return HistoricalWhois{}, err
 	}
-	return resp.Data, nil
+	return historicalObject(resp, version)
 }
 
-func ConvertGeolocationData(data []byte) (MaxmindGeoLite, error) {
-	if len(data) == 0 {
-		return MaxmindGeoLite{}, fmt.Errorf("empty data")
+// historicalObject returns the object of version from the data of a historical-whois response.
+func historicalObject(data HistoricalWhoisData, version HistoricalWhoisVersion) (HistoricalWhois, error) {
+	var object *HistoricalWhoisObject
+	for i := range data.Objects {
+		candidate := &data.Objects[i]
+		if candidate.Type == version.Type && candidate.Key == version.Key {
+			object = candidate
+			break
+		}
 	}
+	if object == nil {
+		return HistoricalWhois{}, ErrNoHistoricalRecord
+	}
+
+	ret := HistoricalWhois{
+		Type:       version.Type,
+		Key:        version.Key,
+		Version:    version.Version,
+		Attributes: object.Attributes,
+	}
+	ret.From, _ = parseHistoricalTime(version.FromTime)
+	ret.To, _ = parseHistoricalTime(version.ToTime)
+
+	for _, attribute := range object.Attributes {
+		switch attribute.Attribute {
+		case "netname", "as-name":
+			if ret.Holder == "" {
+				ret.Holder = attribute.Value
+			}
+		case "org":
+			if ret.Org == "" {
+				ret.Org = attribute.Value
+			}
+		case "descr":
+			if ret.Description == "" {
+				ret.Description = attribute.Value
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// historicalTypes orders the object types of a historical-whois response by how well they tell
+// who held the resource
+var historicalTypes = []string{"inetnum", "inet6num", "aut-num"}
+
+// versionAt returns the version registered at t, preferring the address and AS objects over
+// route and other objects also listed for the resource.
+func versionAt(versions []HistoricalWhoisVersion, t time.Time) (HistoricalWhoisVersion, bool) {
+	var best HistoricalWhoisVersion
+	bestRank, found := len(historicalTypes)+1, false
+
+	for _, version := range versions {
+		from, err := parseHistoricalTime(version.FromTime)
+		if err != nil || t.Before(from) {
+			continue
+		}
+		if to, err := parseHistoricalTime(version.ToTime); err == nil && !t.Before(to) {
+			continue
+		}
+
+		rank := len(historicalTypes)
+		for i, objectType := range historicalTypes {
+			if version.Type == objectType {
+				rank = i
+				break
+			}
+		}
+		if rank < bestRank {
+			best, bestRank, found = version, rank, true
+		}
+	}
+
+	return best, found
+}
+
+// parseHistoricalTime parses the timestamps of historical-whois, which lack a time zone and are in UTC.
+func parseHistoricalTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("no time")
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
 
-	resp := MaxmindGeoLiteBase{}
-	err := json.NewDecoder(bytes.NewReader(data)).Decode(&resp)
-	if err != nil {
-		return MaxmindGeoLite{}, fmt.Errorf("failed to unmarshal data: %v", err)
+func ConvertWhoisData(data []byte) (WhoisData, error) {
+	var resp WhoisData
+	if _, err := decodeResponse(data, &resp); err != nil {
+		return WhoisData{}, err
 	}
-	return resp.Data, nil
+	return resp, nil
 }
@@ -0,0 +1,44 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConvertASNNeighboursData(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "asn-neighbours.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ConvertASNNeighboursData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ASNNeighbours{
+		Resource: "3333",
+		Upstreams: []ASNNeighbourEntry{
+			{Asn: 1299, Type: "left", Power: 120, V4Peers: 310, V6Peers: 280},
+			{Asn: 3356, Type: "left", Power: 45, V4Peers: 98},
+		},
+		Downstreams: []ASNNeighbourEntry{{Asn: 12654, Type: "right", Power: 3, V4Peers: 5, V6Peers: 5}},
+		Uncertain:   []ASNNeighbourEntry{{Asn: 64512, Type: "uncertain", Power: 1, V4Peers: 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvertASNNeighboursData = %+v, want %+v", got, want)
+	}
+
+	// an AS without neighbours
+	got, err = ConvertASNNeighboursData([]byte(`{"status": "ok", "data": {"resource": "64496", "neighbours": []}}`))
+	if err != nil || !reflect.DeepEqual(got, ASNNeighbours{Resource: "64496"}) {
+		t.Errorf("ConvertASNNeighboursData without neighbours = %+v, %v", got, err)
+	}
+}
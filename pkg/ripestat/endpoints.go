@@ -6,6 +6,13 @@ package ripestat
 * https://www.apache.org/licenses/LICENSE-2.0
  */
 
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type ResponseBase struct {
 	Messages       []string `json:"messages"`
 	SeeAlso        []string `json:"see_also"`
@@ -99,11 +106,167 @@ type LocatedResource struct {
 }
 
 type ResourceLocation struct {
-	Country   string   `json:"country"`
-	City      string   `json:"city"`
-	Resources []string `json:"resources"`
-	// Latitude          float64  `json:"latitude"`  // XXX: another data type?
-	// Longitude         float64  `json:"longitude"` // XXX: another data type?
-	CoveredPercentage float64 `json:"covered_percentage"`
-	UnknownPercentage float64 `json:"unknown_percentage"`
+	Country           string     `json:"country"`
+	City              string     `json:"city"`
+	Resources         []string   `json:"resources"`
+	Latitude          Coordinate `json:"latitude"`
+	Longitude         Coordinate `json:"longitude"`
+	CoveredPercentage float64    `json:"covered_percentage"`
+	UnknownPercentage float64    `json:"unknown_percentage"`
+}
+
+// Coordinate is a latitude or longitude, RipeSTAT has been seen to send them both as numbers and
+// as strings. Missing or unparsable coordinates decode as zero, as do "NaN" and "Inf", which can't
+// be written as JSON.
+type Coordinate float64
+
+func (c *Coordinate) UnmarshalJSON(data []byte) error {
+	f, err := strconv.ParseFloat(strings.Trim(string(data), `"`), 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		*c = 0
+		return nil
+	}
+	*c = Coordinate(f)
+	return nil
+}
+
+type ASNNeighboursBase struct {
+	ResponseBase
+	Data ASNNeighboursData `json:"data"`
+}
+
+type ASNNeighboursData struct {
+	Resource        string              `json:"resource"`
+	QueryStartTime  string              `json:"query_starttime"`
+	QueryEndTime    string              `json:"query_endtime"`
+	LatestTime      string              `json:"latest_time"`
+	EarliestTime    string              `json:"earliest_time"`
+	NeighbourCounts ASNNeighbourCounts  `json:"neighbour_counts"`
+	Neighbours      []ASNNeighbourEntry `json:"neighbours"`
+}
+
+type ASNNeighbourCounts struct {
+	Left      int `json:"left"`
+	Right     int `json:"right"`
+	Unique    int `json:"unique"`
+	Uncertain int `json:"uncertain"`
+}
+
+// ASNNeighbourEntry is a neighbour as seen in BGP paths. Type "left" means the neighbour appears
+// closer to the collectors (an upstream), "right" further away (a downstream).
+type ASNNeighbourEntry struct {
+	Asn     int    `json:"asn"`
+	Type    string `json:"type"`
+	Power   int    `json:"power"`
+	V4Peers int    `json:"v4_peers"`
+	V6Peers int    `json:"v6_peers"`
+}
+
+// ASNNeighbours holds the neighbours of an AS by relationship.
+type ASNNeighbours struct {
+	Resource    string
+	Upstreams   []ASNNeighbourEntry
+	Downstreams []ASNNeighbourEntry
+	Uncertain   []ASNNeighbourEntry
+}
+
+type HistoricalWhoisBase struct {
+	ResponseBase
+	Data HistoricalWhoisData `json:"data"`
+}
+
+type HistoricalWhoisData struct {
+	Resource    string                   `json:"resource"`
+	NumVersions int                      `json:"num_versions"`
+	Versions    []HistoricalWhoisVersion `json:"versions"`
+	Objects     []HistoricalWhoisObject  `json:"objects"`
+}
+
+// HistoricalWhoisVersion is a version of a RIPE database object, registered from FromTime until
+// ToTime. ToTime is empty for the current version.
+type HistoricalWhoisVersion struct {
+	Version  int    `json:"version"`
+	FromTime string `json:"from_time"`
+	ToTime   string `json:"to_time"`
+	Type     string `json:"type"`
+	Key      string `json:"key"`
+}
+
+type HistoricalWhoisObject struct {
+	HistoricalWhoisVersion
+	Attributes []WhoisAttribute `json:"attributes"`
+}
+
+type WhoisAttribute struct {
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+}
+
+// HistoricalWhois is a RIPE database object as it was registered in the past. Holder is its
+// netname or as-name, Org its org handle and Description its first descr line. To is zero when
+// the object is still registered unchanged.
+type HistoricalWhois struct {
+	Type        string
+	Key         string
+	Version     int
+	From        time.Time
+	To          time.Time
+	Holder      string
+	Org         string
+	Description string
+	Attributes  []WhoisAttribute
+}
+
+type WhoisBase struct {
+	ResponseBase
+	Data WhoisData `json:"data"`
+}
+
+// WhoisData holds the registry objects of a resource as returned by the whois data call, every
+// record being the key/value pairs of one object.
+type WhoisData struct {
+	Resource    string            `json:"resource"`
+	Records     [][]WhoisKeyValue `json:"records"`
+	IRRRecords  [][]WhoisKeyValue `json:"irr_records"`
+	Authorities []string          `json:"authorities"`
+}
+
+type WhoisKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// RoutingStatus is the routing status of a prefix. LastSeen is the last time the prefix was seen
+// announced in BGP, with the origin AS of that announcement; it is empty for prefixes never seen.
+// LessSpecifics and MoreSpecifics are the announced prefixes covering and inside the prefix.
+type RoutingStatus struct {
+	Resource      string            `json:"resource"`
+	FirstSeen     RoutingStatusSeen `json:"first_seen"`
+	LastSeen      RoutingStatusSeen `json:"last_seen"`
+	LessSpecifics []RoutedPrefix    `json:"less_specifics"`
+	MoreSpecifics []RoutedPrefix    `json:"more_specifics"`
+}
+
+// RoutedPrefix is an announced prefix with its origin AS.
+type RoutedPrefix struct {
+	Prefix string `json:"prefix"`
+	Origin Origin `json:"origin"`
+}
+
+type RoutingStatusSeen struct {
+	Prefix string `json:"prefix"`
+	Origin Origin `json:"origin"`
+	Time   string `json:"time"`
+}
+
+// Origin is an origin AS number, which RipeSTAT sends both as number and as string.
+type Origin string
+
+func (o *Origin) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		s = ""
+	}
+	*o = Origin(s)
+	return nil
 }
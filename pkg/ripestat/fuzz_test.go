@@ -0,0 +1,65 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// FuzzDecodeResponse decodes any response body as every data call: decoding may fail, but what is
+// decoded can be written out as JSON again, as the outputs do.
+func FuzzDecodeResponse(f *testing.F) {
+	seeds := []string{
+		`{"status": "ok", "data_call_name": "network-info", "version": "1.0", "data": {"asns": ["3333"], "prefix": "193.0.0.0/21"}}`,
+		`{"status": "ok", "data_call_name": "abuse-contact-finder", "version": "2.0", "data": {"abuse_contacts": ["abuse@ripe.net"], "authoritative_rir": "ripe"}}`,
+		`{"status": "ok", "data_call_name": "as-overview", "data": {"holder": "RIPE-NCC-AS - Reseaux IP Europeens Network Coordination Centre (RIPE NCC)", "announced": true}}`,
+		`{"status": "ok", "data_call_name": "maxmind-geo-lite", "data": {"located_resources": [{"resource": "193.0.0.0/21", "locations": [{"country": "NL", "city": "Amsterdam", "latitude": "52.37", "longitude": 4.89}]}]}}`,
+		`{"status": "ok", "data_call_name": "routing-status", "data": {"last_seen": {"prefix": "193.0.0.0/21", "origin": 3333}, "more_specifics": [{"prefix": "193.0.5.0/24", "origin": "1234"}]}}`,
+		`{"status": "ok", "data_call_name": "asn-neighbours", "data": {"resource": "3333", "neighbours": [{"asn": 1299, "type": "left"}, {"asn": 64512, "type": "uncertain"}]}}`,
+		`{"status": "ok", "data_call_name": "whois", "version": "4.0", "data": {"records": [[{"key": "inetnum", "value": "193.0.0.0 - 193.0.7.255"}]], "irr_records": []}}`,
+		`{"status": "ok", "data_call_name": "historical-whois", "data": {"versions": [{"version": 1, "from_time": "2001-09-21T22:08:01", "to_time": ""}], "objects": []}}`,
+		`{"status": "error", "status_code": 500, "messages": [["error", "Internal error"]], "data": null}`,
+		`{"status": "maintenance", "messages": [["info", "down for maintenance"]], "data": {}}`,
+		`{"data": {"located_resources": [{"locations": [{"latitude": "NaN", "longitude": "+Inf"}]}]}}`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		var decoded []interface{}
+		add := func(v interface{}, err error) {
+			if err == nil {
+				decoded = append(decoded, v)
+			}
+		}
+		add(ConvertAbuseContactsData(body))
+		add(ConvertNetworkInfoData(body))
+		add(ConvertASOverviewData(body))
+		add(ConvertGeolocationData(body))
+		add(ConvertASNNeighboursData(body))
+		add(ConvertWhoisData(body))
+		versions, err := ConvertHistoricalWhoisVersions(body)
+		add(versions, err)
+		if len(versions) > 0 {
+			add(ConvertHistoricalWhoisData(body, versions[len(versions)-1]))
+		}
+		var status RoutingStatus
+		meta, err := decodeResponse(body, &status)
+		add(status, err)
+		if meta != nil {
+			decoded = append(decoded, *meta)
+		}
+
+		for _, v := range decoded {
+			if _, err := json.Marshal(v); err != nil {
+				t.Fatalf("decoded %T can't be written: %v", v, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hints collects how fresh the data of the responses to the requests made with a context is, see
+// ContextWithHints: the time RipeSTAT queried the data (query_time in the data of most data calls)
+// and how long RipeSTAT says the responses may be cached (the max-age of their Cache-Control).
+type Hints struct {
+	mu        sync.Mutex
+	queryTime time.Time
+	maxAge    time.Duration
+	hasMaxAge bool
+}
+
+type hintsKey struct{}
+
+// ContextWithHints returns a context collecting the hints of the responses to the requests made
+// with it in hints.
+func ContextWithHints(ctx context.Context, hints *Hints) context.Context {
+	return context.WithValue(ctx, hintsKey{}, hints)
+}
+
+func hintsFrom(ctx context.Context) *Hints {
+	hints, _ := ctx.Value(hintsKey{}).(*Hints)
+	return hints
+}
+
+// QueryTime returns the oldest query time of the responses, or the zero time when none had one.
+func (h *Hints) QueryTime() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.queryTime
+}
+
+// MaxAge returns the shortest time the responses may be cached, and whether any response said so.
+func (h *Hints) MaxAge() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.maxAge, h.hasMaxAge
+}
+
+func (h *Hints) addQueryTime(t time.Time) {
+	if h == nil || t.IsZero() {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.queryTime.IsZero() || t.Before(h.queryTime) {
+		h.queryTime = t
+	}
+}
+
+func (h *Hints) addMaxAge(maxAge time.Duration) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.hasMaxAge || maxAge < h.maxAge {
+		h.maxAge, h.hasMaxAge = maxAge, true
+	}
+}
+
+// queryTimeLayouts are the layouts of query_time, RipeSTAT writes it in UTC without zone
+var queryTimeLayouts = []string{"2006-01-02T15:04:05", time.RFC3339}
+
+// parseQueryTime returns the time the data was queried at, from the query_time of data or else
+// the end of its query_starttime to query_endtime interval, or the zero time when it has neither.
+func parseQueryTime(data json.RawMessage) time.Time {
+	var times struct {
+		QueryTime    string `json:"query_time"`
+		QueryEndTime string `json:"query_endtime"`
+	}
+	if err := json.Unmarshal(data, &times); err != nil {
+		return time.Time{}
+	}
+
+	for _, value := range []string{times.QueryTime, times.QueryEndTime} {
+		for _, layout := range queryTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				return t.UTC()
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// parseMaxAge returns the max-age directive of a Cache-Control header, and whether it has one.
+// no-store and no-cache allow no caching at all.
+func parseMaxAge(cacheControl string) (time.Duration, bool) {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0, true
+		case "max-age":
+			seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,84 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		cacheControl string
+		want         time.Duration
+		found        bool
+	}{
+		{"max-age=300", 5 * time.Minute, true},
+		{"public, max-age=3600", time.Hour, true},
+		{`Max-Age="60"`, time.Minute, true},
+		{"max-age=0", 0, true},
+		// no caching at all, whatever the max-age
+		{"no-cache, max-age=300", 0, true},
+		{"no-store", 0, true},
+		{"max-age=-1", 0, false},
+		{"max-age=soon", 0, false},
+		{"public", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		if got, found := parseMaxAge(tt.cacheControl); got != tt.want || found != tt.found {
+			t.Errorf("parseMaxAge(%q) = %v, %v, want %v, %v", tt.cacheControl, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestParseQueryTime(t *testing.T) {
+	tests := []struct {
+		data string
+		want time.Time
+	}{
+		{`{"query_time": "2024-05-01T12:00:00"}`, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{`{"query_time": "2024-05-01T14:00:00+02:00"}`, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{`{"query_starttime": "2024-04-01T00:00:00", "query_endtime": "2024-05-01T00:00:00"}`, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{`{"query_time": "yesterday"}`, time.Time{}},
+		{`{}`, time.Time{}},
+		{`[]`, time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := parseQueryTime(json.RawMessage(tt.data)); !got.Equal(tt.want) {
+			t.Errorf("parseQueryTime(%s) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestHints(t *testing.T) {
+	var hints Hints
+	if maxAge, found := hints.MaxAge(); found || maxAge != 0 || !hints.QueryTime().IsZero() {
+		t.Errorf("empty hints have max-age %v, %v and query time %v", maxAge, found, hints.QueryTime())
+	}
+
+	// the shortest max-age and the oldest query time count
+	older := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	hints.addMaxAge(time.Hour)
+	hints.addQueryTime(older.Add(time.Hour))
+	hints.addMaxAge(time.Minute)
+	hints.addQueryTime(older)
+	hints.addMaxAge(2 * time.Minute)
+	hints.addQueryTime(time.Time{})
+	if maxAge, found := hints.MaxAge(); !found || maxAge != time.Minute {
+		t.Errorf("MaxAge() = %v, %v, want 1m0s", maxAge, found)
+	}
+	if queryTime := hints.QueryTime(); !queryTime.Equal(older) {
+		t.Errorf("QueryTime() = %v, want %v", queryTime, older)
+	}
+
+	// requests without hints in their context have none to add to
+	var none *Hints
+	none.addMaxAge(time.Minute)
+	none.addQueryTime(older)
+}
@@ -0,0 +1,86 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LatencyStats aggregates the latencies of the calls to one data call, including retries.
+type LatencyStats struct {
+	Count int
+	Total time.Duration
+	Max   time.Duration
+	Slow  int
+}
+
+// Mean returns the average latency.
+func (s LatencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Count)
+}
+
+type latencies struct {
+	mu    sync.Mutex
+	stats map[string]LatencyStats
+}
+
+func (l *latencies) record(dataCall string, d time.Duration, slow bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stats == nil {
+		l.stats = make(map[string]LatencyStats)
+	}
+
+	s := l.stats[dataCall]
+	s.Count++
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+	if slow {
+		s.Slow++
+	}
+	l.stats[dataCall] = s
+}
+
+func (l *latencies) snapshot() map[string]LatencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ret := make(map[string]LatencyStats, len(l.stats))
+	for dataCall, s := range l.stats {
+		ret[dataCall] = s
+	}
+	return ret
+}
+
+// Latencies returns the latencies of the calls made so far, by data call.
+func (c *Client) Latencies() map[string]LatencyStats {
+	return c.latencies.snapshot()
+}
+
+// observe records the latency of a call and warns when it exceeds the SlowThreshold.
+func (c *Client) observe(endpoint, resource string, start time.Time) {
+	d := time.Since(start)
+	slow := c.SlowThreshold > 0 && d > c.SlowThreshold
+	c.latencies.record(endpoint, d, slow)
+
+	if slow {
+		c.Logger.WithFields(logrus.Fields{
+			"data_call": endpoint,
+			"resource":  resource,
+			"duration":  d,
+		}).Warnf("slow RipeSTAT call: %s for %s took %v", endpoint, resource, d.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,61 @@
+package ripestat_test
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/ripestattest"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestSlowCallWarning(t *testing.T) {
+	server := ripestattest.NewServer()
+	defer server.Close()
+	slow := ripestattest.JSON(networkInfo)
+	slow.Latency = 100 * time.Millisecond
+	server.Handle("network-info", "193.0.6.139", slow)
+	server.Handle("network-info", "193.0.6.140", ripestattest.JSON(networkInfo))
+
+	logger, hook := logtest.NewNullLogger()
+	client := ripestat.NewRipeStatClient("nuclei-parse-enrich", 0)
+	client.BaseURL = server.BaseURL()
+	client.Logger = logger
+	client.SlowThreshold = 50 * time.Millisecond
+
+	for _, ipAddr := range []string{"193.0.6.139", "193.0.6.140"} {
+		if _, err := client.GetNetworkInfo(context.Background(), ipAddr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var warnings []*logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("logged %d warnings, want one for the slow call", len(warnings))
+	}
+	if warnings[0].Data["data_call"] != "network-info" || warnings[0].Data["resource"] != "193.0.6.139" {
+		t.Errorf("warned about %v, want the slow call", warnings[0].Data)
+	}
+	if d, _ := warnings[0].Data["duration"].(time.Duration); d < 100*time.Millisecond {
+		t.Errorf("warned of a duration of %v, want at least the latency of the server", d)
+	}
+
+	stats := client.Latencies()["network-info"]
+	if stats.Count != 2 || stats.Slow != 1 || stats.Max < 100*time.Millisecond || stats.Mean() > stats.Max {
+		t.Errorf("latencies = %+v, want 2 calls of which 1 slow", stats)
+	}
+}
@@ -0,0 +1,140 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// urlRecorder is an http.RoundTripper recording the requested URLs and failing every request.
+type urlRecorder struct {
+	urls []string
+}
+
+func (r *urlRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.urls = append(r.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       io.NopCloser(strings.NewReader(`{"status": "error"}`)),
+		Request:    req,
+	}, nil
+}
+
+// TestDataCallURLs checks the URL every data call requests, with the resource and sourceapp
+// encoded in the query string.
+func TestDataCallURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		get  func(c *Client) error
+		want string
+	}{
+		{"abuse-contact-finder", func(c *Client) error {
+			_, err := c.GetAbuseContacts(context.Background(), "193.0.6.139")
+			return err
+		}, "https://stat.ripe.net/data/abuse-contact-finder/data.json?resource=193.0.6.139&sourceapp=nuclei-parse-enrich"},
+		{"network-info of IPv6", func(c *Client) error {
+			_, err := c.GetNetworkInfo(context.Background(), "2001:67c:2e8:22::c100:68b")
+			return err
+		}, "https://stat.ripe.net/data/network-info/data.json?resource=2001%3A67c%3A2e8%3A22%3A%3Ac100%3A68b&sourceapp=nuclei-parse-enrich"},
+		{"as-overview", func(c *Client) error {
+			_, err := c.GetASOverview(context.Background(), "AS3333")
+			return err
+		}, "https://stat.ripe.net/data/as-overview/data.json?resource=3333&sourceapp=nuclei-parse-enrich"},
+		{"maxmind-geo-lite of a prefix", func(c *Client) error {
+			_, err := c.GetGeolocationData(context.Background(), "193.0.0.0/21")
+			return err
+		}, "https://stat.ripe.net/data/maxmind-geo-lite/data.json?resource=193.0.0.0%2F21&sourceapp=nuclei-parse-enrich"},
+		{"routing-status", func(c *Client) error {
+			_, err := c.GetRoutingStatus(context.Background(), "2001:67c:2e8::/48")
+			return err
+		}, "https://stat.ripe.net/data/routing-status/data.json?resource=2001%3A67c%3A2e8%3A%3A%2F48&sourceapp=nuclei-parse-enrich"},
+		{"whois", func(c *Client) error {
+			_, err := c.GetWhois(context.Background(), "193.0.6.139")
+			return err
+		}, "https://stat.ripe.net/data/whois/data.json?resource=193.0.6.139&sourceapp=nuclei-parse-enrich"},
+		{"asn-neighbours", func(c *Client) error {
+			_, err := c.GetASNNeighbours(context.Background(), "as3333")
+			return err
+		}, "https://stat.ripe.net/data/asn-neighbours/data.json?resource=3333&sourceapp=nuclei-parse-enrich"},
+		{"historical-whois", func(c *Client) error {
+			_, err := c.GetHistoricalWhois(context.Background(), "193.0.0.0 - 193.0.7.255", time.Now())
+			return err
+		}, "https://stat.ripe.net/data/historical-whois/data.json?resource=193.0.0.0+-+193.0.7.255&sourceapp=nuclei-parse-enrich"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &urlRecorder{}
+			c := NewRipeStatClient("nuclei-parse-enrich", 0)
+			c.HTTPClient = &http.Client{Transport: recorder}
+
+			if err := tt.get(c); err == nil {
+				t.Fatal("the lookup didn't fail with the error response")
+			}
+			if len(recorder.urls) != 1 || recorder.urls[0] != tt.want {
+				t.Errorf("requested %q, want %s", recorder.urls, tt.want)
+			}
+		})
+	}
+}
+
+func TestSourceAppFallback(t *testing.T) {
+	// an invalid sourceapp is replaced with a warning
+	for _, sourceApp := range []string{"", "ACME scanner", "-scanner", strings.Repeat("x", 65)} {
+		c := NewRipeStatClient(sourceApp, 0)
+		got, err := c.requestURL("network-info", "193.0.6.139", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "https://stat.ripe.net/data/network-info/data.json?resource=193.0.6.139&sourceapp=" + DefaultSourceApp; got != want {
+			t.Errorf("requestURL with sourceapp %q = %s, want %s", sourceApp, got, want)
+		}
+	}
+}
+
+func TestRequestURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		endpoint string
+		resource string
+		params   url.Values
+		want     string
+	}{
+		{"default", "", "network-info", "193.0.6.139", nil,
+			"https://stat.ripe.net/data/network-info/data.json?resource=193.0.6.139&sourceapp=nuclei-parse-enrich"},
+		{"base without slash", "http://127.0.0.1:8080/data", "network-info", "193.0.6.139", nil,
+			"http://127.0.0.1:8080/data/network-info/data.json?resource=193.0.6.139&sourceapp=nuclei-parse-enrich"},
+		{"base with query", "https://stat.example.net/data/?key=secret", "network-info", "193.0.6.139", nil,
+			"https://stat.example.net/data/network-info/data.json?key=secret&resource=193.0.6.139&sourceapp=nuclei-parse-enrich"},
+		{"params", "", "historical-whois", "AS3333", url.Values{"version": {"12"}},
+			"https://stat.ripe.net/data/historical-whois/data.json?resource=AS3333&sourceapp=nuclei-parse-enrich&version=12"},
+		// the resource can't add parameters of its own
+		{"resource with separators", "", "whois", "a&sourceapp=other#x", nil,
+			"https://stat.ripe.net/data/whois/data.json?resource=a%26sourceapp%3Dother%23x&sourceapp=nuclei-parse-enrich"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewRipeStatClient("nuclei-parse-enrich", 0)
+			c.BaseURL = tt.baseURL
+			got, err := c.requestURL(tt.endpoint, tt.resource, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("requestURL = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
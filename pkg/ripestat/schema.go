@@ -0,0 +1,132 @@
+package ripestat
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// expectedField is a field of the data of a data call the client depends on. When the field is
+// missing, its value is taken from the first alternative path found, the path of the field in
+// earlier or later layouts of the data call. Paths are dot separated keys, array indexes or "*"
+// for all elements of an array.
+type expectedField struct {
+	key          string
+	alternatives []string
+}
+
+// expectedFields are the fields the typed methods read, by data call
+var expectedFields = map[string][]expectedField{
+	// version 1 listed the contacts as objects under anti_abuse_contacts
+	"abuse-contact-finder": {{"abuse_contacts", []string{"anti_abuse_contacts.abuse_c.*.email", "anti_abuse_contacts.emails.*.email"}}},
+	"network-info":         {{"asns", nil}, {"prefix", nil}},
+	"as-overview":          {{"holder", nil}},
+	"maxmind-geo-lite":     {{"located_resources", nil}},
+	"whois":                {{"records", nil}},
+	"routing-status":       {{"last_seen", nil}},
+}
+
+// expectedVersions are the major versions of the data calls the layouts in expectedFields were
+// written for. A response of another major version may have moved fields.
+var expectedVersions = map[string]string{
+	"abuse-contact-finder": "2",
+	"network-info":         "1",
+	"as-overview":          "1",
+	"whois":                "4",
+}
+
+// ExpectedVersion returns the major version of dataCall the client was written for, or nothing
+// when it doesn't check the version of dataCall.
+func ExpectedVersion(dataCall string) string {
+	return expectedVersions[dataCall]
+}
+
+// checkSchema checks the version and the data of a data call response against the layout the
+// client expects, and moves fields found at an alternative path to where they are expected. It
+// returns the data and a warning for every deviation.
+func checkSchema(meta Meta, data json.RawMessage) (json.RawMessage, []string) {
+	var warnings []string
+
+	if want, found := expectedVersions[meta.DataCall]; found && meta.Version != "" {
+		if major, _, _ := strings.Cut(meta.Version, "."); major != want {
+			warnings = append(warnings, fmt.Sprintf("%s: unexpected version %s, expected %s.x", meta.DataCall, meta.Version, want))
+		}
+	}
+
+	fields, found := expectedFields[meta.DataCall]
+	if !found || len(data) == 0 {
+		return data, warnings
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		// the typed decoding reports data that is no object
+		return data, warnings
+	}
+
+	moved := false
+	for _, field := range fields {
+		if _, found := object[field.key]; found {
+			continue
+		}
+
+		warning := fmt.Sprintf("%s: expected field %q missing", meta.DataCall, field.key)
+		for _, path := range field.alternatives {
+			if value, found := lookupPath(object, strings.Split(path, ".")); found {
+				object[field.key] = value
+				moved = true
+				warning = fmt.Sprintf("%s: field %q found at %q", meta.DataCall, field.key, path)
+				break
+			}
+		}
+		warnings = append(warnings, warning)
+	}
+
+	if !moved {
+		return data, warnings
+	}
+	rewritten, err := json.Marshal(object)
+	if err != nil {
+		return data, warnings
+	}
+	return rewritten, warnings
+}
+
+// lookupPath returns the value at path in value, see expectedField.
+func lookupPath(value interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return value, true
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, found := v[path[0]]
+		if !found {
+			return nil, false
+		}
+		return lookupPath(child, path[1:])
+	case []interface{}:
+		if path[0] == "*" {
+			values := make([]interface{}, 0, len(v))
+			for _, element := range v {
+				if child, found := lookupPath(element, path[1:]); found {
+					values = append(values, child)
+				}
+			}
+			return values, true
+		}
+		i, err := strconv.Atoi(path[0])
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, false
+		}
+		return lookupPath(v[i], path[1:])
+	}
+	return nil, false
+}
@@ -0,0 +1,364 @@
+package ripestattest
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+)
+
+// Response is a canned response of the fake server. The data is wrapped in the envelope of
+// RipeSTAT, unless Body is set, which is then sent as is.
+type Response struct {
+	// StatusCode is the HTTP status, 200 when zero
+	StatusCode int
+	// Data is the data of the envelope, null when nil
+	Data json.RawMessage
+	// Messages are the [level, text] pairs of the envelope
+	Messages [][]string
+	// Status is the status of the envelope, "ok" or "error" by StatusCode when empty
+	Status string
+	// DataCallStatus is the data_call_status of the envelope, "supported" when empty
+	DataCallStatus string
+	// Version is the version of the data call, when empty the version the ripestat client expects
+	// (see ripestat.ExpectedVersion) or "1.0" for data calls it doesn't check
+	Version string
+	// Header is added to the response headers
+	Header http.Header
+	// Body replaces the envelope when set, e.g. for HTML error pages
+	Body string
+	// Latency delays the response, on top of Server.Latency
+	Latency time.Duration
+}
+
+// Data returns a response with data, marshalled to JSON. It panics when data can't be marshalled.
+func Data(data interface{}) Response {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		panic(fmt.Sprintf("ripestattest: %v", err))
+	}
+	return Response{Data: raw}
+}
+
+// JSON returns a response with the data in JSON, e.g. copied from a real response.
+func JSON(data string) Response {
+	return Response{Data: json.RawMessage(data)}
+}
+
+// NoData returns a response with status 200 and null data.
+func NoData() Response {
+	return Response{}
+}
+
+// Error returns a response with statusCode and an error message in the envelope.
+func Error(statusCode int, message string) Response {
+	return Response{StatusCode: statusCode, Messages: [][]string{{"error", message}}}
+}
+
+// RateLimited returns a response with status 429, as RipeSTAT sends when it rate limits.
+func RateLimited() Response {
+	return Error(http.StatusTooManyRequests, "Too many requests")
+}
+
+// Maintenance returns a response announcing RipeSTAT is in maintenance, served with status 200.
+func Maintenance(message string) Response {
+	return Response{Status: "maintenance", Messages: [][]string{{"info", message}}}
+}
+
+type route struct {
+	dataCall, resource string
+}
+
+// Server is a fake RipeSTAT data API serving canned responses by data call and resource, for
+// testing code using the ripestat client without network:
+//
+//	server := ripestattest.NewServer()
+//	defer server.Close()
+//	server.Handle("network-info", "193.0.6.139", ripestattest.JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+//	client := server.Client()
+//
+// Data calls without a canned response are answered with status 404 and counted as unexpected.
+type Server struct {
+	*httptest.Server
+	// Latency delays every response
+	Latency time.Duration
+
+	mu         sync.Mutex
+	responses  map[route][]Response
+	served     map[route]int
+	requests   map[route]int
+	unexpected []string
+}
+
+// NewServer starts a fake RipeSTAT server, which must be closed with Close.
+func NewServer() *Server {
+	s := &Server{
+		responses: make(map[route][]Response),
+		served:    make(map[route]int),
+		requests:  make(map[route]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// BaseURL returns the URL to use as ripestat.Client.BaseURL.
+func (s *Server) BaseURL() string {
+	return s.URL + "/data/"
+}
+
+// Client returns a ripestat client requesting the server, without retries.
+func (s *Server) Client() *ripestat.Client {
+	client := ripestat.NewRipeStatClient("ripestattest", 0)
+	client.BaseURL = s.BaseURL()
+	return client
+}
+
+// Handle scripts the responses to dataCall for resource, an empty resource matches the resources
+// without responses of their own. The responses are served in order, the last one repeats, e.g. a
+// RateLimited response followed by the data to test retries. Handle replaces the earlier script.
+func (s *Server) Handle(dataCall, resource string, responses ...Response) {
+	if len(responses) == 0 {
+		responses = []Response{NoData()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := route{dataCall, resource}
+	s.responses[key] = responses
+	s.served[key] = 0
+}
+
+// Requests returns the number of requests for dataCall and resource so far, or for all resources
+// of dataCall when resource is empty.
+func (s *Server) Requests(dataCall, resource string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if resource != "" {
+		return s.requests[route{dataCall, resource}]
+	}
+	count := 0
+	for key, n := range s.requests {
+		if key.dataCall == dataCall {
+			count += n
+		}
+	}
+	return count
+}
+
+// TotalRequests returns the number of requests so far, unexpected ones included.
+func (s *Server) TotalRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, n := range s.requests {
+		count += n
+	}
+	return count
+}
+
+// Unexpected returns the data calls and resources requested without a canned response, as
+// "data-call resource".
+func (s *Server) Unexpected() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.unexpected...)
+}
+
+// TB is the part of testing.TB the assertions use.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertRequests fails t when dataCall wasn't requested want times for resource, see Requests.
+func (s *Server) AssertRequests(t TB, dataCall, resource string, want int) {
+	t.Helper()
+	if got := s.Requests(dataCall, resource); got != want {
+		t.Errorf("ripestattest: %s %s requested %d times, want %d", dataCall, resource, got, want)
+	}
+}
+
+// AssertNoUnexpected fails t when data calls without a canned response were requested.
+func (s *Server) AssertNoUnexpected(t TB) {
+	t.Helper()
+	if unexpected := s.Unexpected(); len(unexpected) > 0 {
+		t.Errorf("ripestattest: unexpected requests: %s", strings.Join(unexpected, ", "))
+	}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	dataCall, found := dataCallOf(r.URL.Path)
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	resource := r.URL.Query().Get("resource")
+
+	response, found := s.next(dataCall, resource)
+	latency := s.Latency + response.Latency
+	if latency > 0 {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(latency):
+		}
+	}
+
+	if !found {
+		response = Error(http.StatusNotFound, fmt.Sprintf("ripestattest: no response for %s %s", dataCall, resource))
+	}
+	s.write(w, dataCall, response)
+}
+
+// next counts the request and returns the response it gets, if any.
+func (s *Server) next(dataCall, resource string) (Response, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[route{dataCall, resource}]++
+
+	key := route{dataCall, resource}
+	responses, found := s.responses[key]
+	if !found {
+		key = route{dataCall, ""}
+		responses, found = s.responses[key]
+	}
+	if !found {
+		s.unexpected = append(s.unexpected, dataCall+" "+resource)
+		return Response{}, false
+	}
+
+	i := s.served[key]
+	if i >= len(responses) {
+		i = len(responses) - 1
+	}
+	s.served[key]++
+	return responses[i], true
+}
+
+func (s *Server) write(w http.ResponseWriter, dataCall string, response Response) {
+	for name, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	statusCode := response.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	if response.Body != "" {
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(response.Body))
+		return
+	}
+
+	body, err := json.Marshal(envelope(dataCall, statusCode, response))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(body)
+}
+
+// envelope returns the envelope of RipeSTAT around the data of response.
+func envelope(dataCall string, statusCode int, response Response) interface{} {
+	status := response.Status
+	if status == "" {
+		status = "ok"
+		if statusCode < 200 || statusCode > 299 {
+			status = "error"
+		}
+	}
+	dataCallStatus := response.DataCallStatus
+	if dataCallStatus == "" {
+		dataCallStatus = "supported"
+	}
+	version := response.Version
+	if version == "" {
+		version = defaultVersion(dataCall)
+	}
+	messages := response.Messages
+	if messages == nil {
+		messages = [][]string{}
+	}
+	data := response.Data
+	if data == nil {
+		data = json.RawMessage("null")
+	}
+
+	return struct {
+		Messages       [][]string      `json:"messages"`
+		SeeAlso        []string        `json:"see_also"`
+		Version        string          `json:"version"`
+		DataCallName   string          `json:"data_call_name"`
+		DataCallStatus string          `json:"data_call_status"`
+		Cached         bool            `json:"cached"`
+		Data           json.RawMessage `json:"data"`
+		QueryID        string          `json:"query_id"`
+		ProcessTime    int             `json:"process_time"`
+		ServerID       string          `json:"server_id"`
+		BuildVersion   string          `json:"build_version"`
+		Status         string          `json:"status"`
+		StatusCode     int             `json:"status_code"`
+		Time           string          `json:"time"`
+	}{
+		Messages:       messages,
+		SeeAlso:        []string{},
+		Version:        version,
+		DataCallName:   dataCall,
+		DataCallStatus: dataCallStatus,
+		Data:           data,
+		QueryID:        fmt.Sprintf("ripestattest-%s", dataCall),
+		ServerID:       "ripestattest",
+		BuildVersion:   "ripestattest",
+		Status:         status,
+		StatusCode:     statusCode,
+		Time:           time.Now().UTC().Format("2006-01-02T15:04:05.000000"),
+	}
+}
+
+// defaultVersion returns the version of dataCall the ripestat client expects, so responses
+// without a Version don't trigger its schema warnings.
+func defaultVersion(dataCall string) string {
+	if major := ripestat.ExpectedVersion(dataCall); major != "" {
+		return major + ".0"
+	}
+	return "1.0"
+}
+
+// dataCallOf returns the data call of a request path, /data/<data call>/data.json.
+func dataCallOf(path string) (string, bool) {
+	path, found := cutSuffix(path, "/data.json")
+	if !found {
+		return "", false
+	}
+	i := strings.LastIndex(path, "/")
+	if i < 0 || i == len(path)-1 {
+		return "", false
+	}
+	return path[i+1:], true
+}
+
+func cutSuffix(s, suffix string) (string, bool) {
+	if !strings.HasSuffix(s, suffix) {
+		return s, false
+	}
+	return s[:len(s)-len(suffix)], true
+}
@@ -0,0 +1,148 @@
+package ripestattest
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+)
+
+// recorder is a TB recording the failures of the assertions
+type recorder struct {
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestSetup(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("network-info", "193.0.6.139", JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+
+	info, err := server.Client().GetNetworkInfo(context.Background(), "193.0.6.139")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ripestat.NetworkInfo{ASNs: []string{"3333"}, Prefix: "193.0.0.0/21"}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("GetNetworkInfo = %+v, want %+v", info, want)
+	}
+	server.AssertRequests(t, "network-info", "193.0.6.139", 1)
+	server.AssertNoUnexpected(t)
+}
+
+func TestDefaultVersion(t *testing.T) {
+	tests := []struct {
+		dataCall string
+		data     string
+		version  string
+		want     string
+		warns    bool
+	}{
+		{"abuse-contact-finder", `{"abuse_contacts": ["abuse@ripe.net"]}`, "", "2.0", false},
+		{"whois", `{"records": []}`, "", "4.0", false},
+		{"network-info", `{"asns": ["3333"], "prefix": "193.0.0.0/21"}`, "", "1.0", false},
+		{"routing-status", `{"last_seen": {}}`, "", "1.0", false},
+		{"whois", `{"records": []}`, "5.1", "5.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dataCall+"/"+tt.want, func(t *testing.T) {
+			server := NewServer()
+			defer server.Close()
+			response := JSON(tt.data)
+			response.Version = tt.version
+			server.Handle(tt.dataCall, "", response)
+
+			meta, err := server.Client().Get(context.Background(), tt.dataCall, "193.0.6.139", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Version != tt.want {
+				t.Errorf("version = %q, want %q", meta.Version, tt.want)
+			}
+			if warns := len(meta.SchemaWarnings) > 0; warns != tt.warns {
+				t.Errorf("schema warnings %q, want any: %v", meta.SchemaWarnings, tt.warns)
+			}
+		})
+	}
+}
+
+func TestRateLimitedThenData(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("abuse-contact-finder", "193.0.6.139", RateLimited(), JSON(`{"abuse_contacts": ["abuse@ripe.net"]}`))
+
+	client := server.Client()
+	client.MaxRetries = 2
+	contacts, err := client.GetAbuseContacts(context.Background(), "193.0.6.139")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(contacts, []string{"abuse@ripe.net"}) {
+		t.Errorf("GetAbuseContacts = %q, want the contact after the 429", contacts)
+	}
+	server.AssertRequests(t, "abuse-contact-finder", "193.0.6.139", 2)
+}
+
+func TestRateLimitedWithoutRetries(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("network-info", "", RateLimited())
+
+	_, err := server.Client().GetNetworkInfo(context.Background(), "193.0.6.139")
+	var statusErr *ripestat.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("GetNetworkInfo error = %v, want status 429", err)
+	}
+}
+
+func TestRequestCounting(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	server.Handle("network-info", "", JSON(`{"asns": ["3333"], "prefix": "193.0.0.0/21"}`))
+
+	client := server.Client()
+	for _, ipAddr := range []string{"193.0.6.139", "193.0.6.139", "193.0.0.1"} {
+		if _, err := client.GetNetworkInfo(context.Background(), ipAddr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = client.GetAbuseContacts(context.Background(), "193.0.6.139")
+
+	if got := server.Requests("network-info", "193.0.6.139"); got != 2 {
+		t.Errorf("requests for 193.0.6.139 = %d, want 2", got)
+	}
+	if got := server.Requests("network-info", ""); got != 3 {
+		t.Errorf("requests for network-info = %d, want 3", got)
+	}
+	if got := server.TotalRequests(); got != 4 {
+		t.Errorf("total requests = %d, want 4", got)
+	}
+
+	want := []string{"abuse-contact-finder 193.0.6.139"}
+	if got := server.Unexpected(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected = %q, want %q", got, want)
+	}
+
+	var r recorder
+	server.AssertNoUnexpected(&r)
+	server.AssertRequests(&r, "network-info", "193.0.0.1", 2)
+	if len(r.failures) != 2 {
+		t.Errorf("assertions failed %d times, want 2: %q", len(r.failures), r.failures)
+	}
+}
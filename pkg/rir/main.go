@@ -0,0 +1,96 @@
+package rir
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// The regional internet registries
+const (
+	AFRINIC = "AFRINIC"
+	APNIC   = "APNIC"
+	ARIN    = "ARIN"
+	LACNIC  = "LACNIC"
+	RIPE    = "RIPE"
+)
+
+// All lists the regional internet registries.
+var All = []string{AFRINIC, APNIC, ARIN, LACNIC, RIPE}
+
+//go:embed iana.tsv
+var ianaTSV string
+
+type block struct {
+	prefix netip.Prefix
+	rir    string
+}
+
+// blocks are the blocks of iana.tsv, the most specific first
+var blocks = loadBlocks()
+
+func loadBlocks() []block {
+	var blocks []block
+
+	scanner := bufio.NewScanner(strings.NewReader(ianaTSV))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		value, rir, found := strings.Cut(line, "\t")
+		if !found {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, block{prefix, rir})
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].prefix.Bits() > blocks[j].prefix.Bits()
+	})
+	return blocks
+}
+
+// Of returns the RIR the IANA delegated the block of addr to, or nothing for addresses not
+// delegated to a RIR, such as private and reserved addresses. Legacy IPv4 space is attributed to
+// the RIR administering it. The delegation is at the granularity of the IANA registries (a /8 for
+// IPv4), so space transferred between RIRs since is attributed to the RIR it was delegated to.
+func Of(addr netip.Addr) string {
+	addr = addr.Unmap()
+	for _, b := range blocks {
+		if b.prefix.Contains(addr) {
+			return b.rir
+		}
+	}
+	return ""
+}
+
+// Normalize returns the name of the RIR value names, case insensitive and with or without the
+// NCC of the RIPE NCC, e.g. "ripe-ncc" is RIPE.
+func Normalize(value string) (string, error) {
+	name := strings.ToUpper(strings.TrimSpace(value))
+	name = strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name)
+	if name == "RIPENCC" {
+		name = RIPE
+	}
+	for _, rir := range All {
+		if name == rir {
+			return rir, nil
+		}
+	}
+	return "", fmt.Errorf("rir: unknown RIR %q, expected one of %s", value, strings.Join(All, ", "))
+}
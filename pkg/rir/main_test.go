@@ -0,0 +1,65 @@
+package rir
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	tests := []struct {
+		addr string
+		want string
+	}{
+		{"193.0.6.139", RIPE},
+		{"8.8.8.8", ARIN},
+		{"1.1.1.1", APNIC},
+		{"200.160.2.3", LACNIC},
+		{"41.0.0.1", AFRINIC},
+		{"2001:67c:2e8::1", RIPE},
+		{"2001:500::1", ARIN},
+		// IPv4-mapped IPv6 is attributed like the IPv4 address
+		{"::ffff:193.0.6.139", RIPE},
+		// not delegated to a RIR
+		{"10.0.0.1", ""},
+		{"127.0.0.1", ""},
+		{"::1", ""},
+		{"fe80::1", ""},
+	}
+	for _, tt := range tests {
+		if got := Of(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Of(%s) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"RIPE", RIPE, false},
+		{"ripe", RIPE, false},
+		{"RIPE NCC", RIPE, false},
+		{"ripe-ncc", RIPE, false},
+		{" arin ", ARIN, false},
+		{"Afrinic", AFRINIC, false},
+		{"lacnic", LACNIC, false},
+		{"apnic", APNIC, false},
+		{"IANA", "", true},
+		{"NCC", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.value)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("Normalize(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
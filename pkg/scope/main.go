@@ -0,0 +1,59 @@
+package scope
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"nuclei-parse-enrich/pkg/annotate"
+)
+
+// Scope decides which IP addresses may be looked up at all. An IP address covered by an exclude
+// entry is out of scope, and once include entries are loaded so is every IP address not covered
+// by one of them. Exclude entries win over overlapping include entries.
+type Scope struct {
+	include *annotate.Annotator
+	exclude *annotate.Annotator
+}
+
+// Verdict is the outcome of checking an IP address against a Scope.
+type Verdict int
+
+const (
+	InScope Verdict = iota
+	Excluded
+	NotIncluded
+)
+
+func NewScope() *Scope {
+	return &Scope{
+		include: annotate.NewAnnotator(),
+		exclude: annotate.NewAnnotator(),
+	}
+}
+
+// LoadIncludeFile reads the IP addresses and CIDRs in path into the include list, in the format
+// of annotation files.
+func (s *Scope) LoadIncludeFile(path string) error {
+	return s.include.LoadFile("include", path)
+}
+
+// LoadExcludeFile reads the IP addresses and CIDRs in path into the exclude list, in the format
+// of annotation files.
+func (s *Scope) LoadExcludeFile(path string) error {
+	return s.exclude.LoadFile("exclude", path)
+}
+
+// Check returns whether ipAddr is in scope. Values that are not an IP address are never covered
+// by an entry, so they are only out of scope when there is an include list.
+func (s *Scope) Check(ipAddr string) Verdict {
+	if s.exclude.Covers(ipAddr) {
+		return Excluded
+	}
+	if s.include.Len() > 0 && !s.include.Covers(ipAddr) {
+		return NotIncluded
+	}
+	return InScope
+}
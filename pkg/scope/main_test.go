@@ -0,0 +1,90 @@
+package scope
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newScope returns a scope with the include and exclude entries, none loaded when empty.
+func newScope(t *testing.T, include, exclude string) *Scope {
+	t.Helper()
+	s := NewScope()
+	for _, list := range []struct {
+		entries string
+		load    func(string) error
+	}{{include, s.LoadIncludeFile}, {exclude, s.LoadExcludeFile}} {
+		if list.entries == "" {
+			continue
+		}
+		path := filepath.Join(t.TempDir(), "scope.txt")
+		if err := os.WriteFile(path, []byte(list.entries), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := list.load(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestCheck(t *testing.T) {
+	// an excluded /24 in an included /16, with an included /28 in the excluded /24 again
+	include := "193.0.0.0/16\n193.0.6.128/28 # a host of the excluded range\n2001:67c:2e8::/48\n"
+	exclude := "193.0.6.0/24\n2001:67c:2e8:22::/64\n198.51.100.7\n"
+
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		ipAddr  string
+		want    Verdict
+	}{
+		{"included", include, exclude, "193.0.0.1", InScope},
+		{"last of include", include, exclude, "193.0.255.255", InScope},
+		{"excluded within include", include, exclude, "193.0.6.1", Excluded},
+		{"first of exclude", include, exclude, "193.0.6.0", Excluded},
+		{"last of exclude", include, exclude, "193.0.6.255", Excluded},
+		{"after exclude", include, exclude, "193.0.7.0", InScope},
+		// exclude entries win over overlapping include entries, even more specific ones
+		{"included within exclude", include, exclude, "193.0.6.130", Excluded},
+		{"not included", include, exclude, "192.0.2.1", NotIncluded},
+		{"excluded, not included", include, exclude, "198.51.100.7", Excluded},
+		{"IPv6 included", include, exclude, "2001:67c:2e8:1::1", InScope},
+		{"IPv6 excluded", include, exclude, "[2001:67c:2e8:22::c100:68b]", Excluded},
+		{"IPv6 not included", include, exclude, "2001:db8::1", NotIncluded},
+		{"invalid with include", include, exclude, "not an IP", NotIncluded},
+		// without an include list everything not excluded is in scope
+		{"only exclude", "", exclude, "192.0.2.1", InScope},
+		{"only exclude, excluded", "", exclude, "193.0.6.1", Excluded},
+		{"invalid without include", "", exclude, "not an IP", InScope},
+		{"empty", "", "", "192.0.2.1", InScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newScope(t, tt.include, tt.exclude).Check(tt.ipAddr); got != tt.want {
+				t.Errorf("Check(%q) = %v, want %v", tt.ipAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scope.txt")
+	if err := os.WriteFile(path, []byte("193.0.0.0/16\nnot a prefix\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewScope().LoadExcludeFile(path); err == nil {
+		t.Error("loaded an invalid entry")
+	}
+	if err := NewScope().LoadIncludeFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("loaded a missing file")
+	}
+}
@@ -0,0 +1,219 @@
+package server
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"nuclei-parse-enrich/pkg/bogon"
+	"nuclei-parse-enrich/pkg/enricher"
+	"nuclei-parse-enrich/pkg/types"
+	"nuclei-parse-enrich/pkg/version"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxBatch is the maximum number of IP addresses in a single POST /enrich request
+	DefaultMaxBatch = 1000
+	maxBodySize     = 1 << 20
+)
+
+// Server answers enrichment requests over HTTP:
+//
+//	GET  /enrich/{ip}  the EnrichInfo of ip
+//	POST /enrich       a JSON array of IP addresses, answered with an array of EnrichInfo
+//	GET  /healthz      liveness and version
+//
+// All requests share one Enricher, so its cache and rate limits apply across requests, and
+// concurrent requests for the same IP address are answered by a single enrichment.
+type Server struct {
+	MaxBatch int
+	Logger   logrus.FieldLogger
+	// MarshalOptions is the layout the enrichment is answered in
+	MarshalOptions types.MarshalOptions
+	// MappedAsIPv6 keeps IPv6 addresses embedding an IPv4 address as IPv6, set it like the
+	// enricher.WithMappedAsIPv6 option of the enricher
+	MappedAsIPv6 bool
+
+	enricher enricher.IPEnricher
+	// ctx bounds the shared enrichments, they outlive the requests that started them
+	ctx context.Context
+
+	// workers bounds the number of enrichments running at once over all requests
+	workers chan struct{}
+
+	mu       sync.Mutex
+	inFlight map[string]*call
+}
+
+// call is an enrichment shared by all requests for the same IP address.
+type call struct {
+	done   chan struct{}
+	result types.EnrichInfo
+}
+
+// NewServer returns a Server enriching with e, at most workers IP addresses at once. Enrichments
+// are aborted once ctx is done.
+func NewServer(ctx context.Context, e enricher.IPEnricher, workers int) *Server {
+	if workers < 1 {
+		workers = enricher.DefaultWorkers
+	}
+
+	return &Server{
+		workers:  make(chan struct{}, workers),
+		MaxBatch: DefaultMaxBatch,
+		Logger:   logrus.StandardLogger(),
+		enricher: e,
+		ctx:      ctx,
+		inFlight: make(map[string]*call),
+	}
+}
+
+// Handler returns the HTTP handler of the server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enrich/", s.handleEnrichIP)
+	mux.HandleFunc("/enrich", s.handleEnrichBatch)
+	mux.HandleFunc("/healthz", s.handleHealth)
+	return mux
+}
+
+func (s *Server) handleEnrichIP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "use GET")
+		return
+	}
+
+	ipAddr, err := s.validateIP(strings.TrimPrefix(r.URL.Path, "/enrich/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := s.enrich(r.Context(), ipAddr)
+	if err != nil {
+		// the client went away
+		return
+	}
+	writeJSON(w, http.StatusOK, s.MarshalOptions.EnrichInfo(result))
+}
+
+func (s *Server) handleEnrichBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "use POST")
+		return
+	}
+
+	var ipAddrs []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&ipAddrs); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("expected a JSON array of IP addresses: %v", err))
+		return
+	}
+	if len(ipAddrs) > s.MaxBatch {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d IP addresses per request", s.MaxBatch))
+		return
+	}
+	for i, ipAddr := range ipAddrs {
+		canonical, err := s.validateIP(ipAddr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("entry %d: %v", i, err))
+			return
+		}
+		ipAddrs[i] = canonical
+	}
+
+	results := make([]types.EnrichInfo, len(ipAddrs))
+	errs := make(chan error, len(ipAddrs))
+	for i, ipAddr := range ipAddrs {
+		go func(i int, ipAddr string) {
+			var err error
+			results[i], err = s.enrich(r.Context(), ipAddr)
+			errs <- err
+		}(i, ipAddr)
+	}
+	for range ipAddrs {
+		if err := <-errs; err != nil {
+			return
+		}
+	}
+
+	answer := make([]json.Marshaler, len(results))
+	for i, result := range results {
+		answer[i] = s.MarshalOptions.EnrichInfo(result)
+	}
+	writeJSON(w, http.StatusOK, answer)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, struct {
+		Status  string       `json:"status"`
+		Version version.Info `json:"version"`
+	}{"ok", version.Get()})
+}
+
+// enrich enriches ipAddr, joining an enrichment of the same IP address already in flight. It
+// only returns an error when ctx is done before the enrichment.
+func (s *Server) enrich(ctx context.Context, ipAddr string) (types.EnrichInfo, error) {
+	s.mu.Lock()
+	c, found := s.inFlight[ipAddr]
+	if !found {
+		c = &call{done: make(chan struct{})}
+		s.inFlight[ipAddr] = c
+
+		go func() {
+			s.workers <- struct{}{}
+			c.result = s.enricher.EnrichIP(s.ctx, ipAddr)
+			<-s.workers
+
+			s.mu.Lock()
+			delete(s.inFlight, ipAddr)
+			s.mu.Unlock()
+			close(c.done)
+		}()
+	} else {
+		s.Logger.Debugf("server: joining the enrichment of %s in flight", ipAddr)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return types.EnrichInfo{}, ctx.Err()
+	case <-c.done:
+		return c.result, nil
+	}
+}
+
+// validateIP returns the canonical form of an IP address worth enriching.
+func (s *Server) validateIP(ipAddr string) (string, error) {
+	addr, err := netip.ParseAddr(strings.Trim(ipAddr, "[]"))
+	if err != nil {
+		return "", fmt.Errorf("invalid IP address %q", ipAddr)
+	}
+	if bogon.IsBogon(addr) {
+		return "", fmt.Errorf("%s is a private or reserved IP address", ipAddr)
+	}
+	return enricher.CanonicalIPAs(ipAddr, s.MappedAsIPv6), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{msg})
+}
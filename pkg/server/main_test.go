@@ -0,0 +1,184 @@
+package server
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"nuclei-parse-enrich/pkg/enrichertest"
+	"nuclei-parse-enrich/pkg/types"
+)
+
+// newTestServer returns a server enriching with fake, and its test HTTP server.
+func newTestServer(t *testing.T, ctx context.Context, fake *enrichertest.FakeEnricher) *httptest.Server {
+	srv := NewServer(ctx, fake, 4)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	srv.Logger = logger
+
+	httpServer := httptest.NewServer(srv.Handler())
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+// get requests path and decodes the answer into v, returning the status.
+func get(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Errorf("GET %s: %v", url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestEnrichCoalescing(t *testing.T) {
+	fake := enrichertest.NewFakeEnricher()
+	fake.Delay("193.0.6.139", 200*time.Millisecond)
+	httpServer := newTestServer(t, context.Background(), fake)
+
+	// concurrent requests for the same IP address in different forms share one enrichment
+	paths := []string{"/enrich/193.0.6.139", "/enrich/[193.0.6.139]", "/enrich/::ffff:193.0.6.139"}
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			var info map[string]interface{}
+			if status := get(t, httpServer.URL+path, &info); status != http.StatusOK || info["ip"] != "193.0.6.139" {
+				t.Errorf("GET %s = %d %v", path, status, info)
+			}
+		}(paths[i%len(paths)])
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resp, err := http.Post(httpServer.URL+"/enrich", "application/json", strings.NewReader(`["193.0.6.139", "193.0.6.139"]`))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		var infos []map[string]interface{}
+		if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil || len(infos) != 2 {
+			t.Errorf("POST /enrich = %v, %v", infos, err)
+		}
+	}()
+	wg.Wait()
+
+	if calls := fake.Calls("193.0.6.139"); calls != 1 {
+		t.Errorf("enriched %d times, want once for all concurrent requests", calls)
+	}
+
+	// once done, the next request enriches again
+	var info map[string]interface{}
+	get(t, httpServer.URL+"/enrich/193.0.6.139", &info)
+	if calls := fake.Calls("193.0.6.139"); calls != 2 {
+		t.Errorf("enriched %d times, want another enrichment after the first finished", calls)
+	}
+}
+
+func TestEnrichBogons(t *testing.T) {
+	fake := enrichertest.NewFakeEnricher()
+	httpServer := newTestServer(t, context.Background(), fake)
+
+	for _, ipAddr := range []string{"10.0.0.1", "127.0.0.1", "192.168.1.1", "100.64.0.1", "::1", "fe80::1", "[fc00::1]", "::ffff:10.0.0.1", "not-an-ip"} {
+		var answer struct{ Error string }
+		if status := get(t, httpServer.URL+"/enrich/"+ipAddr, &answer); status != http.StatusBadRequest || answer.Error == "" {
+			t.Errorf("GET /enrich/%s = %d %+v, want 400 with an error", ipAddr, status, answer)
+		}
+	}
+
+	resp, err := http.Post(httpServer.URL+"/enrich", "application/json", strings.NewReader(`["193.0.6.139", "10.0.0.1"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "entry 1") {
+		t.Errorf("POST with a bogon = %d %s, want 400 naming the entry", resp.StatusCode, body)
+	}
+
+	if calls := fake.Calls("193.0.6.139") + fake.Calls("10.0.0.1"); calls != 0 {
+		t.Errorf("enriched %d times for rejected requests", calls)
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	fake := enrichertest.NewFakeEnricher()
+	fake.Delay("193.0.6.139", 300*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := NewServer(ctx, fake, 4)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := &http.Server{Handler: srv.Handler()}
+	go httpServer.Serve(listener)
+	url := "http://" + listener.Addr().String()
+
+	// a request in flight is finished, ...
+	start := time.Now()
+	answer := make(chan int, 1)
+	go func() {
+		var info types.EnrichInfo
+		answer <- get(t, url+"/enrich/193.0.6.139", &info)
+	}()
+	for fake.Calls("193.0.6.139") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Shutdown returned after %v, before the enrichment in flight was done", elapsed)
+	}
+	if status := <-answer; status != http.StatusOK {
+		t.Errorf("the request in flight got %d", status)
+	}
+
+	// ... while new requests are refused
+	if _, err := http.Get(url + "/healthz"); err == nil {
+		t.Error("a request after the shutdown was answered")
+	}
+
+	// cancelling the context of the server aborts the enrichments still running
+	fake.Delay("193.0.6.140", time.Minute)
+	done := make(chan types.EnrichInfo, 1)
+	go func() {
+		info, _ := srv.enrich(context.Background(), "193.0.6.140")
+		done <- info
+	}()
+	cancel()
+	select {
+	case info := <-done:
+		if info.Errors["Timeout"] == "" {
+			t.Errorf("aborted enrichment %+v, want a Timeout error", info)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the enrichment wasn't aborted")
+	}
+}
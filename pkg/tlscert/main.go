@@ -0,0 +1,158 @@
+package tlscert
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultPort    = "443"
+	DefaultTimeout = 5 * time.Second
+)
+
+// Target is the HTTPS endpoint of a finding on an IP address.
+type Target struct {
+	// ServerName is sent as SNI and used to verify the certificate, empty when the target was
+	// addressed by IP
+	ServerName string
+	Port       string
+}
+
+// Certificate describes the leaf certificate presented by a target.
+type Certificate struct {
+	Issuer    string
+	SubjectCN string
+	SANs      []string
+	NotAfter  time.Time
+	// VerifyErr is set when the certificate doesn't verify against the system roots for the
+	// server name, the other fields are filled regardless
+	VerifyErr error
+}
+
+// Client fetches the TLS certificates of the HTTPS targets registered with AddTarget.
+type Client struct {
+	Timeout time.Duration
+	// Roots verifies the certificates, nil means the system roots
+	Roots *x509.CertPool
+
+	mu      sync.RWMutex
+	targets map[string]Target
+}
+
+func NewTLSCertClient() *Client {
+	return &Client{
+		Timeout: DefaultTimeout,
+		targets: make(map[string]Target),
+	}
+}
+
+// AddTarget registers rawURL as the target of ipAddr when it is an https URL and reports whether
+// it did. The first target registered for an IP address is kept.
+func (c *Client) AddTarget(ipAddr, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, found := c.targets[ipAddr]; found {
+		return true
+	}
+
+	target := Target{Port: u.Port()}
+	if target.Port == "" {
+		target.Port = DefaultPort
+	}
+	if _, err := netip.ParseAddr(u.Hostname()); err != nil {
+		target.ServerName = strings.ToLower(u.Hostname())
+	}
+
+	c.targets[ipAddr] = target
+	return true
+}
+
+// Target returns the target registered for ipAddr.
+func (c *Client) Target(ipAddr string) (Target, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	target, found := c.targets[ipAddr]
+	return target, found
+}
+
+// Fetch performs a TLS handshake with target on ipAddr and returns its leaf certificate. The
+// handshake doesn't verify the certificate, verification failures are reported in VerifyErr.
+func (c *Client) Fetch(ctx context.Context, ipAddr string, target Target) (Certificate, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	dialer := &tls.Dialer{
+		Config: &tls.Config{
+			ServerName: target.ServerName,
+			// verified below, so the certificate is also recorded when it doesn't verify
+			InsecureSkipVerify: true,
+		},
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(strings.Trim(ipAddr, "[]"), target.Port))
+	if err != nil {
+		return Certificate{}, fmt.Errorf("tlscert: %v", err)
+	}
+	defer conn.Close()
+
+	peerCertificates := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peerCertificates) == 0 {
+		return Certificate{}, errors.New("tlscert: no certificate presented")
+	}
+
+	leaf := peerCertificates[0]
+	cert := Certificate{
+		Issuer:    leaf.Issuer.CommonName,
+		SubjectCN: leaf.Subject.CommonName,
+		SANs:      leaf.DNSNames,
+		NotAfter:  leaf.NotAfter.UTC(),
+	}
+	if cert.Issuer == "" {
+		cert.Issuer = leaf.Issuer.String()
+	}
+	for _, ip := range leaf.IPAddresses {
+		cert.SANs = append(cert.SANs, ip.String())
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, intermediate := range peerCertificates[1:] {
+		intermediates.AddCert(intermediate)
+	}
+	verifyOptions := x509.VerifyOptions{
+		Roots:         c.Roots,
+		Intermediates: intermediates,
+		DNSName:       target.ServerName,
+	}
+	if verifyOptions.DNSName == "" {
+		verifyOptions.DNSName = strings.Trim(ipAddr, "[]")
+	}
+	if _, err := leaf.Verify(verifyOptions); err != nil {
+		cert.VerifyErr = err
+	}
+
+	return cert, nil
+}
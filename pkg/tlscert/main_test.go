@@ -0,0 +1,119 @@
+package tlscert
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// newTLSServer returns a test HTTPS server, the IP address and the port it listens on.
+func newTLSServer(t *testing.T) (*httptest.Server, string, string) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, host, port
+}
+
+func TestFetch(t *testing.T) {
+	server, ipAddr, port := newTLSServer(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	tests := []struct {
+		name       string
+		serverName string
+		roots      *x509.CertPool
+		verifies   bool
+	}{
+		{"server name", "example.com", roots, true},
+		{"by IP", "", roots, true},
+		{"other server name", "www.ripe.net", roots, false},
+		{"unknown authority", "example.com", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewTLSCertClient()
+			c.Roots = tt.roots
+
+			cert, err := c.Fetch(context.Background(), ipAddr, Target{ServerName: tt.serverName, Port: port})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the certificate of httptest, recorded whether it verifies or not
+			if cert.Issuer != "O=Acme Co" || cert.SubjectCN != "" || !cert.NotAfter.Equal(server.Certificate().NotAfter) {
+				t.Errorf("certificate %+v, want the one of httptest", cert)
+			}
+			if want := wantSANs(server.Certificate()); !reflect.DeepEqual(cert.SANs, want) {
+				t.Errorf("SANs %q, want %q", cert.SANs, want)
+			}
+			if (cert.VerifyErr == nil) != tt.verifies {
+				t.Errorf("VerifyErr = %v, want verified %v", cert.VerifyErr, tt.verifies)
+			}
+		})
+	}
+}
+
+// wantSANs returns the names and IP addresses of cert, which depend on the Go version.
+func wantSANs(cert *x509.Certificate) []string {
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+func TestFetchError(t *testing.T) {
+	// a plain HTTP server doesn't complete the handshake
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	if _, err := NewTLSCertClient().Fetch(context.Background(), host, Target{Port: port}); err == nil {
+		t.Error("Fetch of a plain HTTP server succeeded")
+	}
+}
+
+func TestAddTarget(t *testing.T) {
+	c := NewTLSCertClient()
+	tests := []struct {
+		ipAddr string
+		rawURL string
+		added  bool
+		want   Target
+	}{
+		{"193.0.6.139", "https://WWW.ripe.net/login", true, Target{ServerName: "www.ripe.net", Port: "443"}},
+		// the first target is kept
+		{"193.0.6.139", "https://stat.ripe.net:8443/", true, Target{ServerName: "www.ripe.net", Port: "443"}},
+		{"193.0.6.140", "https://193.0.6.140:8443/", true, Target{Port: "8443"}},
+		{"193.0.6.141", "http://www.ripe.net/", false, Target{}},
+		{"193.0.6.142", "193.0.6.142:443", false, Target{}},
+	}
+	for _, tt := range tests {
+		if added := c.AddTarget(tt.ipAddr, tt.rawURL); added != tt.added {
+			t.Errorf("AddTarget(%s, %s) = %v, want %v", tt.ipAddr, tt.rawURL, added, tt.added)
+		}
+		if got, _ := c.Target(tt.ipAddr); got != tt.want {
+			t.Errorf("Target(%s) = %+v after adding %s, want %+v", tt.ipAddr, got, tt.rawURL, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package types
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaVersion is the version of the layout the enrichment is written in, in its schema_version
+// field. Version 1, written without schema_version, named the fields after the Go fields (Ip,
+// AbuseSource, ...); version 2 names them in snake_case (ip, abuse_source, ...).
+const SchemaVersion = 2
+
+// MarshalOptions chooses the layout the enrichment is written in, the zero value writes the layout
+// of SchemaVersion. The enrichment is read in either layout regardless.
+type MarshalOptions struct {
+	// LegacyFieldNames writes the layout of schema version 1, for consumers that haven't moved to
+	// snake_case yet. It will be removed after a transition release.
+	LegacyFieldNames bool
+}
+
+// EnrichInfo returns info marshalling in the layout of o.
+func (o MarshalOptions) EnrichInfo(info EnrichInfo) json.Marshaler {
+	if o.LegacyFieldNames {
+		return legacyEnrichInfo(info)
+	}
+	return info
+}
+
+// MergeResult returns m marshalling with its enrichment in the layout of o.
+func (o MarshalOptions) MergeResult(m MergeResult) json.Marshaler {
+	if o.LegacyFieldNames {
+		return legacyMergeResult(m)
+	}
+	return m
+}
+
+// MergeResults returns results marshalling with their enrichment in the layout of o.
+func (o MarshalOptions) MergeResults(results []MergeResult) []json.Marshaler {
+	marshalers := make([]json.Marshaler, len(results))
+	for i, result := range results {
+		marshalers[i] = o.MergeResult(result)
+	}
+	return marshalers
+}
+
+// legacyEnrichInfo is an EnrichInfo written with the names of schema version 1
+type legacyEnrichInfo EnrichInfo
+
+func (info legacyEnrichInfo) MarshalJSON() ([]byte, error) {
+	return marshalLegacy(reflect.ValueOf(EnrichInfo(info)))
+}
+
+// legacyMergeResult is a MergeResult with its enrichment written with the names of schema version 1
+type legacyMergeResult MergeResult
+
+func (m legacyMergeResult) MarshalJSON() ([]byte, error) {
+	return MergeResult(m).marshalJSON(legacyEnrichInfo(m.EnrichInfo))
+}
+
+// MarshalJSON writes the enrichment with snake_case names after its schema_version. See
+// MarshalOptions for the names of schema version 1.
+func (info EnrichInfo) MarshalJSON() ([]byte, error) {
+	type enrichInfo EnrichInfo
+	data, err := json.Marshal(enrichInfo(info))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `{"schema_version":%d`, SchemaVersion)
+	if fields := data[1 : len(data)-1]; len(fields) > 0 {
+		buf.WriteByte(',')
+		buf.Write(fields)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads the enrichment in the layout of any schema version.
+func (info *EnrichInfo) UnmarshalJSON(data []byte) error {
+	type enrichInfo EnrichInfo
+	if err := json.Unmarshal(data, (*enrichInfo)(info)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	// the names only differing in case from the snake_case ones were read already
+	value := reflect.ValueOf(info).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, _ := jsonName(field)
+		raw, found := fields[field.Name]
+		if !found || strings.EqualFold(name, field.Name) {
+			continue
+		}
+		if _, found := fields[name]; found {
+			continue
+		}
+		if err := json.Unmarshal(raw, value.Field(i).Addr().Interface()); err != nil {
+			return fmt.Errorf("field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// LegacyFieldName returns the name of schema version 1 of the field of EnrichInfo, AbuseContact or
+// Facility with the snake_case name, see MarshalOptions.
+func LegacyFieldName(name string) (string, bool) {
+	for _, t := range []reflect.Type{reflect.TypeOf(EnrichInfo{}), reflect.TypeOf(AbuseContact{}), reflect.TypeOf(Facility{})} {
+		for i := 0; i < t.NumField(); i++ {
+			if snake, _ := jsonName(t.Field(i)); snake == name {
+				return t.Field(i).Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// marshalLegacy writes value like encoding/json does, but with the names of the struct fields
+// instead of the names of their tags. The omitempty options of the tags are kept.
+func marshalLegacy(value reflect.Value) ([]byte, error) {
+	switch value.Kind() {
+	case reflect.Struct:
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			name, omitEmpty := jsonName(field)
+			if name == "-" || field.PkgPath != "" || (omitEmpty && isEmptyValue(value.Field(i))) {
+				continue
+			}
+
+			data, err := marshalLegacy(value.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(field.Name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(data)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		if value.IsNil() {
+			return []byte("null"), nil
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i := 0; i < value.Len(); i++ {
+			data, err := marshalLegacy(value.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(data)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	}
+	return json.Marshal(value.Interface())
+}
+
+// jsonName returns the name of the json tag of field, or the name of the field without tag, and
+// whether the tag has the omitempty option.
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+options+",", ",omitempty,")
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	}
+	return value.IsZero()
+}
@@ -0,0 +1,320 @@
+package types
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// schemaV1 is EnrichInfo as written by the releases before snake_case, the layout
+// MarshalOptions.LegacyFieldNames must keep byte for byte
+type schemaV1 struct {
+	Ip               string
+	IpRaw            string `json:"IpRaw,omitempty"`
+	AbuseSource      string
+	Abuse            string           `json:"Abuse,omitempty"`
+	AbuseAddresses   []string         `json:"AbuseAddresses,omitempty"`
+	AbuseContacts    []abuseContactV1 `json:"AbuseContacts,omitempty"`
+	AbuseTo          string           `json:"AbuseTo,omitempty"`
+	NationalCERT     string           `json:"NationalCERT,omitempty"`
+	AbuseHandle      string           `json:"AbuseHandle,omitempty"`
+	OrgHandle        string           `json:"OrgHandle,omitempty"`
+	RegistrationDate string           `json:"RegistrationDate,omitempty"`
+	AllocationStatus string           `json:"AllocationStatus,omitempty"`
+	Prefix           string           `json:"Prefix,omitempty"`
+	Asn              string           `json:"Asn,omitempty"`
+	AsnSource        string           `json:"AsnSource,omitempty"`
+	WhoisAsn         string           `json:"WhoisAsn,omitempty"`
+	ASNCategory      string           `json:"ASNCategory,omitempty"`
+	AsnDiscrepancy   bool             `json:"AsnDiscrepancy,omitempty"`
+	Holder           string           `json:"Holder,omitempty"`
+	HolderSource     string           `json:"HolderSource,omitempty"`
+	HolderName       string           `json:"HolderName,omitempty"`
+	HolderCountry    string           `json:"HolderCountry,omitempty"`
+	Country          string           `json:"Country,omitempty"`
+	CountryName      string           `json:"CountryName,omitempty"`
+	City             string           `json:"City,omitempty"`
+	Latitude         float64          `json:"Latitude,omitempty"`
+	Longitude        float64          `json:"Longitude,omitempty"`
+	GeoSource        string
+	EnrichedAt       string            `json:"EnrichedAt,omitempty"`
+	NetworkType      string            `json:"NetworkType,omitempty"`
+	Ptr              string            `json:"Ptr,omitempty"`
+	ProviderHint     string            `json:"ProviderHint,omitempty"`
+	CloudProvider    string            `json:"CloudProvider,omitempty"`
+	CloudRegion      string            `json:"CloudRegion,omitempty"`
+	HistoricalHolder string            `json:"HistoricalHolder,omitempty"`
+	HistoricalOrg    string            `json:"HistoricalOrg,omitempty"`
+	HistoricalObject string            `json:"HistoricalObject,omitempty"`
+	CertIssuer       string            `json:"CertIssuer,omitempty"`
+	CertSubject      string            `json:"CertSubject,omitempty"`
+	CertNames        []string          `json:"CertNames,omitempty"`
+	CertNotAfter     string            `json:"CertNotAfter,omitempty"`
+	Tags             []string          `json:"Tags,omitempty"`
+	Errors           map[string]string `json:"Errors,omitempty"`
+}
+
+type abuseContactV1 struct {
+	Email  string
+	Kind   string
+	Source string `json:"Source,omitempty"`
+}
+
+// fullV1 has every field of schema version 1 set
+func fullV1() EnrichInfo {
+	return EnrichInfo{
+		Ip:               "2001:db8::1",
+		IpRaw:            "[2001:DB8::1]",
+		AbuseSource:      AbuseSourceRipeSTAT,
+		Abuse:            "abuse@example.net",
+		AbuseAddresses:   []string{"abuse@example.net", "noc@example.net"},
+		AbuseContacts:    []AbuseContact{{Email: "abuse@example.net", Kind: "role", Source: "ripestat"}, {Email: "noc@example.net", Kind: "role"}},
+		AbuseTo:          "abuse@example.net",
+		NationalCERT:     "cert@example.nl",
+		AbuseHandle:      "AB1-RIPE",
+		OrgHandle:        "ORG-EX1-RIPE",
+		RegistrationDate: "2001-01-01T00:00:00Z",
+		AllocationStatus: "ALLOCATED",
+		Prefix:           "2001:db8::/32",
+		Asn:              "64512",
+		AsnSource:        "ripestat",
+		WhoisAsn:         "64513",
+		ASNCategory:      "hosting",
+		AsnDiscrepancy:   true,
+		Holder:           "EXAMPLE-AS Example, NL",
+		HolderSource:     "ripestat",
+		HolderName:       "EXAMPLE-AS Example",
+		HolderCountry:    "NL",
+		Country:          "NL",
+		CountryName:      "Netherlands",
+		City:             "Amsterdam",
+		Latitude:         52.37,
+		Longitude:        4.89,
+		GeoSource:        "RipeSTAT",
+		EnrichedAt:       "2024-01-01T00:00:00Z",
+		NetworkType:      "hosting",
+		Ptr:              "host.example.net",
+		ProviderHint:     "example",
+		CloudProvider:    "example-cloud",
+		CloudRegion:      "eu-west",
+		HistoricalHolder: "OLD-AS",
+		HistoricalOrg:    "ORG-OLD1-RIPE",
+		HistoricalObject: "inet6num",
+		CertIssuer:       "CN=Example CA",
+		CertSubject:      "CN=host.example.net",
+		CertNames:        []string{"host.example.net"},
+		CertNotAfter:     "2025-01-01T00:00:00Z",
+		Tags:             []string{"own"},
+		Errors:           map[string]string{"Holder": "timeout", "Abuse": "no data"},
+	}
+}
+
+func toV1(info EnrichInfo) schemaV1 {
+	v1 := schemaV1{
+		Ip: info.Ip, IpRaw: info.IpRaw, AbuseSource: info.AbuseSource, Abuse: info.Abuse,
+		AbuseAddresses: info.AbuseAddresses, AbuseTo: info.AbuseTo, NationalCERT: info.NationalCERT,
+		AbuseHandle: info.AbuseHandle, OrgHandle: info.OrgHandle, RegistrationDate: info.RegistrationDate,
+		AllocationStatus: info.AllocationStatus, Prefix: info.Prefix, Asn: info.Asn, AsnSource: info.AsnSource,
+		WhoisAsn: info.WhoisAsn, ASNCategory: info.ASNCategory, AsnDiscrepancy: info.AsnDiscrepancy,
+		Holder: info.Holder, HolderSource: info.HolderSource, HolderName: info.HolderName,
+		HolderCountry: info.HolderCountry, Country: info.Country, CountryName: info.CountryName,
+		City: info.City, Latitude: info.Latitude, Longitude: info.Longitude, GeoSource: info.GeoSource,
+		EnrichedAt: info.EnrichedAt, NetworkType: info.NetworkType, Ptr: info.Ptr,
+		ProviderHint: info.ProviderHint, CloudProvider: info.CloudProvider, CloudRegion: info.CloudRegion,
+		HistoricalHolder: info.HistoricalHolder, HistoricalOrg: info.HistoricalOrg,
+		HistoricalObject: info.HistoricalObject, CertIssuer: info.CertIssuer, CertSubject: info.CertSubject,
+		CertNames: info.CertNames, CertNotAfter: info.CertNotAfter, Tags: info.Tags, Errors: info.Errors,
+	}
+	for _, c := range info.AbuseContacts {
+		v1.AbuseContacts = append(v1.AbuseContacts, abuseContactV1{c.Email, c.Kind, c.Source})
+	}
+	return v1
+}
+
+func TestLegacyLayoutMatchesSchemaV1(t *testing.T) {
+	for name, info := range map[string]EnrichInfo{
+		"full":  fullV1(),
+		"empty": {},
+		"bogon": {Ip: "10.0.0.1", AbuseSource: AbuseSourceSkippedPrivate},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := json.Marshal(MarshalOptions{LegacyFieldNames: true}.EnrichInfo(info))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := json.Marshal(toV1(info))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("legacy layout differs from schema version 1\n got: %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestLegacyLayoutGolden(t *testing.T) {
+	info := EnrichInfo{
+		Ip:            "193.0.6.139",
+		AbuseSource:   AbuseSourceRipeSTAT,
+		Abuse:         "abuse@ripe.net",
+		AbuseContacts: []AbuseContact{{Email: "abuse@ripe.net", Kind: "role"}},
+		Asn:           "3333",
+		GeoSource:     "RipeSTAT",
+		Errors:        map[string]string{"Holder": "timeout"},
+	}
+	want := `{"Ip":"193.0.6.139","AbuseSource":"RipeSTAT","Abuse":"abuse@ripe.net","AbuseContacts":[{"Email":"abuse@ripe.net","Kind":"role"}],"Asn":"3333","GeoSource":"RipeSTAT","Errors":{"Holder":"timeout"}}`
+
+	got, err := json.Marshal(MarshalOptions{LegacyFieldNames: true}.EnrichInfo(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestSnakeCaseLayoutGolden(t *testing.T) {
+	info := EnrichInfo{Ip: "193.0.6.139", AbuseSource: AbuseSourceRipeSTAT, Asn: "3333", GeoSource: "RipeSTAT"}
+	want := `{"schema_version":2,"ip":"193.0.6.139","abuse_source":"RipeSTAT","asn":"3333","geo_source":"RipeSTAT"}`
+
+	for name, marshaler := range map[string]json.Marshaler{
+		"default":        info,
+		"MarshalOptions": MarshalOptions{}.EnrichInfo(info),
+	} {
+		got, err := json.Marshal(marshaler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got  %s\nwant %s", name, got, want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	info := fullV1()
+	info.IpMapping = "ipv4-mapped"
+	info.RIR = "RIPE"
+	info.CoveringPrefix, info.CoveringAsn = "2001:db8::/29", "64500"
+	info.IXPCount = 2
+	info.Facilities = []Facility{{Name: "Example DC", City: "Amsterdam", Country: "NL"}}
+	info.QueryTime = "2024-01-01T00:00:00Z"
+
+	for _, opts := range []MarshalOptions{{}, {LegacyFieldNames: true}} {
+		data, err := json.Marshal(opts.EnrichInfo(info))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got EnrichInfo
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("legacy %v: %v", opts.LegacyFieldNames, err)
+		}
+		if !reflect.DeepEqual(got, info) {
+			t.Errorf("legacy %v: round trip changed the enrichment\n got: %+v\nwant: %+v", opts.LegacyFieldNames, got, info)
+		}
+	}
+}
+
+func TestMergeResultLayout(t *testing.T) {
+	var result MergeResult
+	result.EnrichInfo = EnrichInfo{Ip: "193.0.6.139", AbuseSource: AbuseSourceRipeSTAT, Asn: "3333"}
+	result.NucleiJsonRecord.TemplateId = "tech-detect"
+	result.NucleiJsonRecord.Ip = "193.0.6.139"
+
+	for _, tt := range []struct {
+		opts    MarshalOptions
+		want    []string
+		notWant []string
+	}{
+		{MarshalOptions{}, []string{`"schema_version":2`, `"ip":"193.0.6.139"`, `"asn":"3333"`, `"template-id":"tech-detect"`}, []string{`"Ip"`, `"Asn"`}},
+		{MarshalOptions{LegacyFieldNames: true}, []string{`"Ip":"193.0.6.139"`, `"Asn":"3333"`, `"template-id":"tech-detect"`}, []string{`"schema_version"`, `"asn"`}},
+	} {
+		data, err := json.Marshal(tt.opts.MergeResults([]MergeResult{result}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("legacy %v: %s lacks %s", tt.opts.LegacyFieldNames, data, want)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(string(data), notWant) {
+				t.Errorf("legacy %v: %s has %s", tt.opts.LegacyFieldNames, data, notWant)
+			}
+		}
+	}
+}
+
+func TestMigrateV1(t *testing.T) {
+	// as written by the releases before snake_case
+	v1 := `{"Ip":"193.0.6.139","AbuseSource":"RipeSTAT","Abuse":"abuse@ripe.net","AbuseContacts":[{"Email":"abuse@ripe.net","Kind":"role"}],"Asn":"3333","GeoSource":"RipeSTAT","Errors":{"Holder":"timeout"}}`
+	v2 := `{"schema_version":2,"ip":"193.0.6.139","abuse_source":"RipeSTAT","abuse":"abuse@ripe.net","abuse_contacts":[{"email":"abuse@ripe.net","kind":"role"}],"asn":"3333","geo_source":"RipeSTAT","errors":{"Holder":"timeout"}}`
+
+	var info EnrichInfo
+	if err := json.Unmarshal([]byte(v1), &info); err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != v2 {
+		t.Errorf("schema version 1 migrated to\n got: %s\nwant: %s", got, v2)
+	}
+
+	// and back, byte for byte
+	legacy, err := json.Marshal(MarshalOptions{LegacyFieldNames: true}.EnrichInfo(info))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(legacy) != v1 {
+		t.Errorf("legacy layout of the migrated enrichment\n got: %s\nwant: %s", legacy, v1)
+	}
+
+	// every field of schema version 1 is read
+	full, err := json.Marshal(toV1(fullV1()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var migrated EnrichInfo
+	if err := json.Unmarshal(full, &migrated); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(migrated, fullV1()) {
+		t.Errorf("schema version 1 read as\n got: %+v\nwant: %+v", migrated, fullV1())
+	}
+}
+
+func TestLegacyFieldName(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  string
+		found bool
+	}{
+		{"ip", "Ip", true},
+		{"abuse_source", "AbuseSource", true},
+		{"asn_category", "ASNCategory", true},
+		{"national_cert", "NationalCERT", true},
+		// of AbuseContact and Facility
+		{"email", "Email", true},
+		{"kind", "Kind", true},
+		{"Ip", "", false},
+		{"schema_version", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, found := LegacyFieldName(tt.name); got != tt.want || found != tt.found {
+			t.Errorf("LegacyFieldName(%q) = %q, %v, want %q, %v", tt.name, got, found, tt.want, tt.found)
+		}
+	}
+}
@@ -6,12 +6,48 @@ package types
 * https://www.apache.org/licenses/LICENSE-2.0
  */
 
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// The AbuseSource of every enriched record is one of these values.
+const (
+	// AbuseSourceRipeSTAT and AbuseSourceWhois name the source the abuse contacts were found in
+	AbuseSourceRipeSTAT = "RipeSTAT"
+	AbuseSourceWhois    = "whois"
+	// AbuseSourceNone is used when all sources were queried, but none had an abuse contact
+	AbuseSourceNone = "none"
+	// AbuseSourceError is used when the abuse contact lookup failed, see the Errors of the record
+	AbuseSourceError = "error"
+	// AbuseSourceSkippedPrivate is used for private and reserved IP addresses, which are not looked up
+	AbuseSourceSkippedPrivate = "skipped-private"
+	// AbuseSourceUnavailable is used when RipeSTAT was in maintenance, the lookup can be retried later
+	AbuseSourceUnavailable = SourceUnavailable
+	// AbuseSourceSkippedRIR is used for IP addresses of other RIRs than the enrichment or the whois
+	// lookups are limited to, see enricher.WithRIRs and enricher.WithWhoisRIRs
+	AbuseSourceSkippedRIR = "skipped-rir"
+)
+
+// AbuseSourceNationalCERT is the Source of the abuse contact of the national CERT the country of
+// an IP address is routed to, see enricher.WithNationalCERTs.
+const AbuseSourceNationalCERT = "national-cert"
+
+// SourceUnavailable replaces "unknown" in the fields that could not be looked up because RipeSTAT
+// was in maintenance, to tell them from fields RipeSTAT has no data for.
+const SourceUnavailable = "source_unavailable"
+
+// PassthroughKey is the key the enrichment is written under when the original record is passed through
+const PassthroughKey = "enrichment"
+
 type (
 	MergeResultsMap map[string]*MergeResult
 
+	// MergeResult is written by its MarshalJSON, the record is tagged to be left out as both have an ip
 	MergeResult struct {
 		EnrichInfo
-		NucleiJsonRecord
+		NucleiJsonRecord `json:"-"`
 	}
 
 	SimpleIPRecord struct {
@@ -34,20 +70,212 @@ type (
 		MatchedAt        string   `json:"matched-at"`
 		ExtractedResults []string `json:"extracted-results"`
 		Ip               string   `json:"ip"`
+		AdditionalIPs    []string `json:"additional-ips,omitempty"`
 		Timestamp        string   `json:"timestamp"`
 		CurlCommand      string   `json:"curl-command"`
 		MatcherStatus    bool     `json:"matcher-status"`
 		MatchedLine      string   `json:"matched-line"`
+		// Raw is the original JSON object of the record, kept in passthrough mode
+		Raw json.RawMessage `json:"-"`
+	}
+
+	// AbuseContact is a single abuse email address, classified as role or personal mailbox, with
+	// the source it was found in
+	AbuseContact struct {
+		Email  string `json:"email"`
+		Kind   string `json:"kind"`
+		Source string `json:"source,omitempty"`
+	}
+
+	// Facility is a colocation facility the AS of an IP address is present at, according to PeeringDB
+	Facility struct {
+		Name    string `json:"name"`
+		City    string `json:"city,omitempty"`
+		Country string `json:"country,omitempty"`
 	}
 
 	EnrichInfo struct {
-		Ip          string
-		AbuseSource string
-		Abuse       string
-		Prefix      string
-		Asn         string
-		Holder      string
-		Country     string
-		City        string
+		Ip               string            `json:"ip"`
+		IpRaw            string            `json:"ip_raw,omitempty"`
+		IpMapping        string            `json:"ip_mapping,omitempty"`
+		AbuseSource      string            `json:"abuse_source"`
+		Abuse            string            `json:"abuse,omitempty"`
+		AbuseAddresses   []string          `json:"abuse_addresses,omitempty"`
+		AbuseContacts    []AbuseContact    `json:"abuse_contacts,omitempty"`
+		AbuseTo          string            `json:"abuse_to,omitempty"`
+		NationalCERT     string            `json:"national_cert,omitempty"`
+		AbuseHandle      string            `json:"abuse_handle,omitempty"`
+		OrgHandle        string            `json:"org_handle,omitempty"`
+		RegistrationDate string            `json:"registration_date,omitempty"`
+		AllocationStatus string            `json:"allocation_status,omitempty"`
+		RIR              string            `json:"rir,omitempty"`
+		Prefix           string            `json:"prefix,omitempty"`
+		CoveringPrefix   string            `json:"covering_prefix,omitempty"`
+		CoveringAsn      string            `json:"covering_asn,omitempty"`
+		Asn              string            `json:"asn,omitempty"`
+		AsnSource        string            `json:"asn_source,omitempty"`
+		WhoisAsn         string            `json:"whois_asn,omitempty"`
+		ASNCategory      string            `json:"asn_category,omitempty"`
+		IXPCount         int               `json:"ixp_count,omitempty"`
+		Facilities       []Facility        `json:"facilities,omitempty"`
+		AsnDiscrepancy   bool              `json:"asn_discrepancy,omitempty"`
+		Holder           string            `json:"holder,omitempty"`
+		HolderSource     string            `json:"holder_source,omitempty"`
+		HolderName       string            `json:"holder_name,omitempty"`
+		HolderCountry    string            `json:"holder_country,omitempty"`
+		Country          string            `json:"country,omitempty"`
+		CountryName      string            `json:"country_name,omitempty"`
+		City             string            `json:"city,omitempty"`
+		Latitude         float64           `json:"latitude,omitempty"`
+		Longitude        float64           `json:"longitude,omitempty"`
+		GeoSource        string            `json:"geo_source"`
+		EnrichedAt       string            `json:"enriched_at,omitempty"`
+		QueryTime        string            `json:"query_time,omitempty"`
+		NetworkType      string            `json:"network_type,omitempty"`
+		Ptr              string            `json:"ptr,omitempty"`
+		ProviderHint     string            `json:"provider_hint,omitempty"`
+		CloudProvider    string            `json:"cloud_provider,omitempty"`
+		CloudRegion      string            `json:"cloud_region,omitempty"`
+		HistoricalHolder string            `json:"historical_holder,omitempty"`
+		HistoricalOrg    string            `json:"historical_org,omitempty"`
+		HistoricalObject string            `json:"historical_object,omitempty"`
+		CertIssuer       string            `json:"cert_issuer,omitempty"`
+		CertSubject      string            `json:"cert_subject,omitempty"`
+		CertNames        []string          `json:"cert_names,omitempty"`
+		CertNotAfter     string            `json:"cert_not_after,omitempty"`
+		Tags             []string          `json:"tags,omitempty"`
+		Errors           map[string]string `json:"errors,omitempty"`
+	}
+
+	// DomainInfo holds the registration details of a domain, see enricher.EnrichDomain
+	DomainInfo struct {
+		Domain       string
+		Registrar    string            `json:"Registrar,omitempty"`
+		CreationDate string            `json:"CreationDate,omitempty"`
+		NameServers  []string          `json:"NameServers,omitempty"`
+		Abuse        string            `json:"Abuse,omitempty"`
+		EnrichedAt   string            `json:"EnrichedAt,omitempty"`
+		Errors       map[string]string `json:"Errors,omitempty"`
 	}
 )
+
+// MarshalJSON writes the original record with the enrichment under PassthroughKey when the record
+// was kept in passthrough mode, so fields unknown to NucleiJsonRecord survive. Otherwise the
+// fields of the enrichment and of the record are written side by side, the ip of the enrichment
+// taking the place of the ip of the record.
+func (m MergeResult) MarshalJSON() ([]byte, error) {
+	return m.marshalJSON(m.EnrichInfo)
+}
+
+// marshalJSON writes m with info as its enrichment, see MarshalOptions.
+func (m MergeResult) marshalJSON(info json.Marshaler) ([]byte, error) {
+	enrichment, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Raw) == 0 {
+		record, err := json.Marshal(m.NucleiJsonRecord)
+		if err != nil {
+			return nil, err
+		}
+		return joinObjects(enrichment, record)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(m.Raw, &fields); err != nil {
+		return nil, err
+	}
+	fields[PassthroughKey] = enrichment
+
+	return json.Marshal(fields)
+}
+
+// AbuseList returns the abuse addresses of the enrichment, from the ";" separated Abuse for
+// records written before AbuseAddresses existed. It is empty when the abuse contacts are unknown.
+func (info EnrichInfo) AbuseList() []string {
+	if len(info.AbuseAddresses) > 0 {
+		return info.AbuseAddresses
+	}
+
+	switch info.Abuse {
+	case "", "unknown", SourceUnavailable:
+		return nil
+	}
+
+	var addresses []string
+	for _, address := range strings.Split(info.Abuse, ";") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// Unresolved returns the names of the key fields of the enrichment that were not found: Abuse,
+// Prefix, Asn, Holder and Country. An enrichment is complete when there are none; private and
+// reserved IP addresses, which are not looked up, are complete as well.
+func (info EnrichInfo) Unresolved() []string {
+	if info.AbuseSource == AbuseSourceSkippedPrivate {
+		return nil
+	}
+
+	var unresolved []string
+	for _, field := range []struct {
+		name  string
+		value string
+	}{
+		{"Abuse", info.Abuse},
+		{"Prefix", info.Prefix},
+		{"Asn", info.Asn},
+		{"Holder", info.Holder},
+		{"Country", info.Country},
+	} {
+		switch field.value {
+		case "", "unknown", SourceUnavailable:
+			unresolved = append(unresolved, field.name)
+		}
+	}
+	return unresolved
+}
+
+// joinObjects returns the JSON object with the fields of object a followed by the fields of object
+// b that a doesn't have.
+func joinObjects(a, b []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(a, &fields); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimSuffix(bytes.TrimSpace(a), []byte("}")))
+
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, found := fields[key]; found {
+			continue
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
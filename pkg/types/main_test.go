@@ -0,0 +1,50 @@
+package types
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAbuseList(t *testing.T) {
+	tests := []struct {
+		name string
+		info EnrichInfo
+		want []string
+	}{
+		{"no contacts", EnrichInfo{}, nil},
+		{"unknown", EnrichInfo{Abuse: "unknown"}, nil},
+		{"source unavailable", EnrichInfo{Abuse: SourceUnavailable}, nil},
+		{"one contact", EnrichInfo{Abuse: "abuse@ripe.net", AbuseAddresses: []string{"abuse@ripe.net"}}, []string{"abuse@ripe.net"}},
+		{
+			"several contacts",
+			EnrichInfo{Abuse: "abuse@ripe.net;noc@ripe.net", AbuseAddresses: []string{"abuse@ripe.net", "noc@ripe.net"}},
+			[]string{"abuse@ripe.net", "noc@ripe.net"},
+		},
+		{
+			"addresses take precedence",
+			EnrichInfo{Abuse: "old@example.net", AbuseAddresses: []string{"abuse@ripe.net"}},
+			[]string{"abuse@ripe.net"},
+		},
+		{"legacy one contact", EnrichInfo{Abuse: "abuse@ripe.net"}, []string{"abuse@ripe.net"}},
+		{"legacy several contacts", EnrichInfo{Abuse: "abuse@ripe.net;noc@ripe.net"}, []string{"abuse@ripe.net", "noc@ripe.net"}},
+		{"legacy with spaces and empty parts", EnrichInfo{Abuse: " abuse@ripe.net ; ;noc@ripe.net;"}, []string{"abuse@ripe.net", "noc@ripe.net"}},
+		{"legacy separators only", EnrichInfo{Abuse: ";"}, nil},
+		// the addresses are listed as recorded, the enricher only records valid ones
+		{"legacy invalid address", EnrichInfo{Abuse: "abuse@ripe.net;not an address"}, []string{"abuse@ripe.net", "not an address"}},
+		{"invalid address", EnrichInfo{AbuseAddresses: []string{"not an address"}}, []string{"not an address"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.AbuseList(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AbuseList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
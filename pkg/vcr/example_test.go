@@ -0,0 +1,60 @@
+package vcr_test
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+
+	"nuclei-parse-enrich/pkg/ripestat"
+	"nuclei-parse-enrich/pkg/vcr"
+)
+
+// The RipeSTAT client looks up abuse contacts offline, from the cassette committed in testdata.
+func ExampleRecorder() {
+	recorder, err := vcr.New("testdata/abuse-contact-finder.json", vcr.ModeReplay)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer recorder.Stop()
+
+	client := ripestat.NewRipeStatClient("nuclei-parse-enrich", 0)
+	client.HTTPClient = recorder.Client()
+
+	for _, ipAddr := range []string{"193.0.6.139", "2001:67c:2e8::1"} {
+		contacts, err := client.GetAbuseContacts(context.Background(), ipAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(ipAddr, contacts)
+	}
+	// Output:
+	// 193.0.6.139 [abuse@ripe.net]
+	// 2001:67c:2e8::1 [abuse@ripe.net]
+}
+
+func TestReplayMissing(t *testing.T) {
+	recorder, err := vcr.New("testdata/abuse-contact-finder.json", vcr.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ripestat.NewRipeStatClient("nuclei-parse-enrich", 0)
+	client.HTTPClient = recorder.Client()
+
+	// repeated requests replay the last recorded response
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetAbuseContacts(context.Background(), "193.0.6.139"); err != nil {
+			t.Fatalf("replay %d: %v", i, err)
+		}
+	}
+	if _, err := client.GetAbuseContacts(context.Background(), "193.0.6.140"); !errors.Is(err, vcr.ErrNoInteraction) {
+		t.Errorf("GetAbuseContacts of a request not on the cassette = %v, want ErrNoInteraction", err)
+	}
+}
@@ -0,0 +1,273 @@
+package vcr
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+type Mode int
+
+const (
+	// ModeReplayOrRecord replays the cassette when it exists and records a new one otherwise
+	ModeReplayOrRecord Mode = iota
+	// ModeReplay only replays, requests missing from the cassette fail
+	ModeReplay
+	// ModeRecord always sends the requests and overwrites the cassette
+	ModeRecord
+)
+
+// ErrNoInteraction is returned when replaying a request that is not on the cassette.
+var ErrNoInteraction = errors.New("vcr: request not found on cassette")
+
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper recording HTTP interactions to a cassette file and replaying
+// them, so code talking to RipeSTAT and other sources can be exercised offline and
+// deterministically once recorded:
+//
+//	recorder, err := vcr.New("testdata/abuse-contact-finder.json", vcr.ModeReplayOrRecord)
+//	...
+//	defer recorder.Stop()
+//	client := ripestat.NewRipeStatClient("test", 0)
+//	client.HTTPClient = recorder.Client()
+type Recorder struct {
+	path      string
+	recording bool
+	// Transport sends the requests while recording, http.DefaultTransport when nil
+	Transport http.RoundTripper
+	// Normalize rewrites the recorded responses before they are stored, e.g. NormalizeRipeStat,
+	// so recording again only changes the cassette when the data changed
+	Normalize func(*Response)
+
+	mu           sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// New opens the cassette at path in the given mode.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case mode == ModeRecord:
+		r.recording = true
+		return r, nil
+	case errors.Is(err, os.ErrNotExist) && mode == ModeReplayOrRecord:
+		r.recording = true
+		return r, nil
+	case err != nil:
+		return nil, fmt.Errorf("vcr: %v", err)
+	}
+
+	var contents cassette
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("vcr: cassette %s: %v", path, err)
+	}
+	r.interactions = contents.Interactions
+	r.replayed = make([]bool, len(contents.Interactions))
+
+	return r, nil
+}
+
+// Recording reports whether requests are sent and recorded rather than replayed.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Client returns an http.Client using the recorder as transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	request := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Body:   string(body),
+	}
+
+	if r.recording {
+		return r.record(req, request)
+	}
+
+	return r.replay(req, request)
+}
+
+func (r *Recorder) record(req *http.Request, request Request) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	interaction := Interaction{
+		Request: request,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       string(body),
+		},
+	}
+	if r.Normalize != nil {
+		r.Normalize(&interaction.Response)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.replayed = append(r.replayed, true)
+	r.mu.Unlock()
+
+	return interaction.Response.toHTTP(req), nil
+}
+
+// replay returns the first interaction matching request that was not replayed yet, so repeated
+// requests get their responses in the recorded order. Once all are replayed, the last one repeats.
+func (r *Recorder) replay(req *http.Request, request Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, interaction := range r.interactions {
+		if interaction.Request != request {
+			continue
+		}
+		if !r.replayed[i] {
+			r.replayed[i] = true
+			return interaction.Response.toHTTP(req), nil
+		}
+		last = i
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, request.Method, request.URL)
+	}
+
+	return r.interactions[last].Response.toHTTP(req), nil
+}
+
+func (resp Response) toHTTP(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		StatusCode:    resp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(resp.Body))),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}
+}
+
+// Stop writes the cassette when recording, it is a no-op when replaying.
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, err := json.MarshalIndent(cassette{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("vcr: %v", err)
+	}
+
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("vcr: %v", err)
+	}
+
+	return nil
+}
+
+// ripeStatVolatile are the fields of the RipeSTAT envelope differing between identical responses,
+// with the value they are normalized to
+var ripeStatVolatile = map[string]interface{}{
+	"query_id":     "",
+	"time":         "",
+	"process_time": 0,
+	"server_id":    "",
+	"cached":       false,
+}
+
+// NormalizeRipeStat clears the fields of a RipeSTAT response that differ between identical
+// responses: the query_id, time, process_time, server_id and cached of the envelope and the Date
+// header. Bodies that are no JSON object are left as is.
+func NormalizeRipeStat(resp *Response) {
+	resp.Header.Del("Date")
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(resp.Body), &envelope); err != nil || envelope == nil {
+		return
+	}
+	for field, value := range ripeStatVolatile {
+		if _, found := envelope[field]; !found {
+			continue
+		}
+		raw, _ := json.Marshal(value)
+		envelope[field] = raw
+	}
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return
+	}
+	resp.Body = string(body)
+	resp.Header.Del("Content-Length")
+}
@@ -0,0 +1,96 @@
+package version
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+const Name = "nuclei-parse-enrich"
+
+// Set at build time to override the build info, e.g.
+// go build -ldflags "-X nuclei-parse-enrich/pkg/version.Version=v1.2.0 -X nuclei-parse-enrich/pkg/version.Commit=abc1234"
+var (
+	Version string
+	Commit  string
+	Date    string
+)
+
+// readBuildInfo returns the build info embedded by the Go toolchain, replaced in tests
+var readBuildInfo = debug.ReadBuildInfo
+
+// Info describes the running build.
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// Get returns the version of the running build. Values not set with ldflags are taken from the
+// build info embedded by the Go toolchain, and are "unknown" when that is unavailable.
+func Get() Info {
+	buildInfo, ok := readBuildInfo()
+	if !ok {
+		buildInfo = nil
+	}
+	return fromBuildInfo(buildInfo)
+}
+
+func fromBuildInfo(buildInfo *debug.BuildInfo) Info {
+	info := Info{
+		Version: Version,
+		Commit:  Commit,
+		Date:    Date,
+	}
+
+	if buildInfo != nil {
+		if info.Version == "" && buildInfo.Main.Version != "" && buildInfo.Main.Version != "(devel)" {
+			info.Version = buildInfo.Main.Version
+		}
+
+		modified := false
+		for _, setting := range buildInfo.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && Commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+
+	return info
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%s %s (commit %s, built %s)", Name, i.Version, i.Commit, i.Date)
+}
+
+// UserAgent returns the User-Agent header identifying this build to the data sources.
+func UserAgent() string {
+	return Name + "/" + Get().Version
+}
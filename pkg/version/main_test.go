@@ -0,0 +1,103 @@
+package version
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+// stub replaces the build info and the ldflags variables for the duration of the test.
+func stub(t *testing.T, buildInfo *debug.BuildInfo, version, commit, date string) {
+	t.Helper()
+	oldRead, oldVersion, oldCommit, oldDate := readBuildInfo, Version, Commit, Date
+	t.Cleanup(func() {
+		readBuildInfo, Version, Commit, Date = oldRead, oldVersion, oldCommit, oldDate
+	})
+
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		return buildInfo, buildInfo != nil
+	}
+	Version, Commit, Date = version, commit, date
+}
+
+func vcsBuildInfo(modified string) *debug.BuildInfo {
+	return &debug.BuildInfo{
+		Main: debug.Module{Path: "nuclei-parse-enrich", Version: "v1.4.0"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2024-05-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: modified},
+		},
+	}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name                  string
+		buildInfo             *debug.BuildInfo
+		version, commit, date string
+		want                  Info
+	}{
+		{
+			name: "no build info",
+			want: Info{Version: "dev", Commit: "unknown", Date: "unknown"},
+		},
+		{
+			name:    "no build info with ldflags",
+			version: "v1.2.0",
+			commit:  "abc1234",
+			date:    "2024-01-01",
+			want:    Info{Version: "v1.2.0", Commit: "abc1234", Date: "2024-01-01"},
+		},
+		{
+			name:      "build info",
+			buildInfo: vcsBuildInfo("false"),
+			want:      Info{Version: "v1.4.0", Commit: "0123456789abcdef", Date: "2024-05-01T12:00:00Z"},
+		},
+		{
+			name:      "modified checkout",
+			buildInfo: vcsBuildInfo("true"),
+			want:      Info{Version: "v1.4.0", Commit: "0123456789abcdef-dirty", Date: "2024-05-01T12:00:00Z"},
+		},
+		{
+			name:      "ldflags override build info",
+			buildInfo: vcsBuildInfo("true"),
+			version:   "v1.2.0",
+			commit:    "abc1234",
+			date:      "2024-01-01",
+			want:      Info{Version: "v1.2.0", Commit: "abc1234", Date: "2024-01-01"},
+		},
+		{
+			name:      "ldflags override part of build info",
+			buildInfo: vcsBuildInfo("false"),
+			version:   "v1.2.0",
+			want:      Info{Version: "v1.2.0", Commit: "0123456789abcdef", Date: "2024-05-01T12:00:00Z"},
+		},
+		{
+			name:      "devel build",
+			buildInfo: &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			want:      Info{Version: "dev", Commit: "unknown", Date: "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub(t, tt.buildInfo, tt.version, tt.commit, tt.date)
+			if got := Get(); got != tt.want {
+				t.Errorf("Get() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	stub(t, nil, "v1.2.0", "", "")
+	if got, want := UserAgent(), "nuclei-parse-enrich/v1.2.0"; got != want {
+		t.Errorf("UserAgent() = %q, want %q", got, want)
+	}
+}
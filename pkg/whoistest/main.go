@@ -0,0 +1,232 @@
+package whoistest
+
+/*
+* https://www.DIVD.nl
+* released under the Apache 2.0 license
+* https://www.apache.org/licenses/LICENSE-2.0
+ */
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Response is a canned whois response of the fake client.
+type Response struct {
+	// Body is the raw whois response
+	Body string
+	// Err fails the lookup instead
+	Err error
+	// Delay delays the response, on top of Client.Delay
+	Delay time.Duration
+}
+
+// Text returns a response with the raw whois text body.
+func Text(body string) Response {
+	return Response{Body: body}
+}
+
+// Error returns a response failing the lookup with err.
+func Error(err error) Response {
+	return Response{Err: err}
+}
+
+// Timeout returns a response failing the lookup with a network timeout after delay, as the whois
+// package does when a server doesn't answer. The enricher counts these for its circuit breaker.
+func Timeout(delay time.Duration) Response {
+	return Response{Err: timeoutError{}, Delay: delay}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "whoistest: i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// ErrNoResponse is returned for lookups without a canned response.
+var ErrNoResponse = errors.New("whoistest: no response")
+
+type route struct {
+	resource, server string
+}
+
+// Client is a fake enricher.WhoisClient serving canned responses by resource and server, for
+// testing the whois paths of the enricher without network:
+//
+//	client := whoistest.NewClient()
+//	client.Handle("193.0.6.139", "", whoistest.Text("abuse-mailbox: abuse@ripe.net"))
+//	e := enricher.NewEnricher(enricher.WithWhoisClient(client))
+//
+// Lookups without a canned response fail with ErrNoResponse and are counted as unexpected.
+type Client struct {
+	// Delay delays every response
+	Delay time.Duration
+
+	mu         sync.Mutex
+	responses  map[route][]Response
+	served     map[route]int
+	lookups    map[route]int
+	unexpected []string
+}
+
+func NewClient() *Client {
+	return &Client{
+		responses: make(map[route][]Response),
+		served:    make(map[route]int),
+		lookups:   make(map[route]int),
+	}
+}
+
+// Handle scripts the responses to lookups of resource at server. An empty resource matches the
+// resources without responses of their own, an empty server the lookups at the default server and
+// the servers without responses of their own. The responses are served in order, the last one
+// repeats, e.g. a rate limit answer followed by the data. Handle replaces the earlier script.
+func (c *Client) Handle(resource, server string, responses ...Response) {
+	if len(responses) == 0 {
+		responses = []Response{Text("")}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := route{resource, server}
+	c.responses[key] = responses
+	c.served[key] = 0
+}
+
+// Lookup implements enricher.WhoisClient. It returns ctx.Err() when ctx is done before the delay of
+// the response has passed.
+func (c *Client) Lookup(ctx context.Context, resource, server string) (string, error) {
+	response, found := c.next(resource, server)
+
+	delay := c.Delay + response.Delay
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("%w for %s at %q", ErrNoResponse, resource, server)
+	}
+	return response.Body, response.Err
+}
+
+// Dial serves the lookups of clients dialing whois servers themselves, e.g. cymru.Client through
+// its SetDialer: the query read from the connection is looked up at the host of addr and the
+// response written back. A failed lookup closes the connection without response.
+func (c *Client) Dial(network, addr string) (net.Conn, error) {
+	server, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		server = addr
+	}
+
+	local, remote := net.Pipe()
+	go func() {
+		defer remote.Close()
+		query, err := bufio.NewReader(remote).ReadString('\n')
+		if err != nil {
+			return
+		}
+		info, err := c.Lookup(context.Background(), strings.TrimSpace(query), server)
+		if err != nil {
+			return
+		}
+		_, _ = io.WriteString(remote, info)
+	}()
+	return local, nil
+}
+
+// next counts the lookup and returns the response it gets, if any.
+func (c *Client) next(resource, server string) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lookups[route{resource, server}]++
+
+	var key route
+	var responses []Response
+	found := false
+	for _, candidate := range []route{{resource, server}, {resource, ""}, {"", server}, {"", ""}} {
+		if responses, found = c.responses[candidate]; found {
+			key = candidate
+			break
+		}
+	}
+	if !found {
+		c.unexpected = append(c.unexpected, strings.TrimSpace(resource+" "+server))
+		return Response{}, false
+	}
+
+	i := c.served[key]
+	if i >= len(responses) {
+		i = len(responses) - 1
+	}
+	c.served[key]++
+	return responses[i], true
+}
+
+// Lookups returns the number of lookups of resource so far, at any server.
+func (c *Client) Lookups(resource string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for key, n := range c.lookups {
+		if key.resource == resource {
+			count += n
+		}
+	}
+	return count
+}
+
+// TotalLookups returns the number of lookups so far, unexpected ones included.
+func (c *Client) TotalLookups() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	for _, n := range c.lookups {
+		count += n
+	}
+	return count
+}
+
+// Unexpected returns the resources looked up without a canned response, as "resource server".
+func (c *Client) Unexpected() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.unexpected...)
+}
+
+// TB is the part of testing.TB the assertions use.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertLookups fails t when resource wasn't looked up want times, see Lookups.
+func (c *Client) AssertLookups(t TB, resource string, want int) {
+	t.Helper()
+	if got := c.Lookups(resource); got != want {
+		t.Errorf("whoistest: %s looked up %d times, want %d", resource, got, want)
+	}
+}
+
+// AssertNoUnexpected fails t when resources without a canned response were looked up.
+func (c *Client) AssertNoUnexpected(t TB) {
+	t.Helper()
+	if unexpected := c.Unexpected(); len(unexpected) > 0 {
+		t.Errorf("whoistest: unexpected lookups: %s", strings.Join(unexpected, ", "))
+	}
+}